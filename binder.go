@@ -1,6 +1,11 @@
 package revel
 
 import (
+	"database/sql"
+	"encoding"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -50,37 +55,79 @@ func ValueBinder(f func(value string, typ reflect.Type) reflect.Value) func(*Par
 	}
 }
 
+// valueBinder is ValueBinder for values that may fail to parse.  Params that
+// fail are bound as the zero value, with a ValidationError recorded for the
+// param (see bindError).  Empty params are bound as the zero value.
+func valueBinder(f func(value string, typ reflect.Type) (reflect.Value, error)) func(*Params, string, reflect.Type) reflect.Value {
+	return func(params *Params, name string, typ reflect.Type) reflect.Value {
+		vals, ok := params.Values[name]
+		if !ok || len(vals) == 0 || len(vals[0]) == 0 {
+			return reflect.Zero(typ)
+		}
+		value, err := f(vals[0], typ)
+		if err != nil {
+			WARN.Printf("revel/binder: can not bind %s=%q as %s: %s", name, vals[0], typ, err)
+			params.bindError(name, fmt.Sprintf("%q is not a valid %s", vals[0], typ))
+			return reflect.Zero(typ)
+		}
+		return value
+	}
+}
+
 const (
 	DEFAULT_DATE_FORMAT     = "2006-01-02"
 	DEFAULT_DATETIME_FORMAT = "2006-01-02 15:04"
 )
 
 var (
+	// MaxSliceLength limits the indexes of the slices bound from params (e.g.
+	// items[10000].qty), so that a request can not make a huge slice.
+	MaxSliceLength = 10000
+
+	// ParamBytesEncoding is the encoding of the params bound to []byte (other
+	// than uploaded files), "base64" (standard or URL-safe, with or without
+	// padding) or "hex", from "params.bytes".  Struct fields may choose
+	// their own with the bytes tag, e.g. `bytes:"hex"`.
+	ParamBytesEncoding = "base64"
+
+	// ListSeparator splits the values of params bound to slices without
+	// brackets, e.g. ids=1,2,3 as well as ids=1&ids=2, from
+	// "params.listSeparator".  "" does not split them.  Struct fields may
+	// choose their own with the split tag, e.g. `split:"|"`, or `split:"-"`
+	// for none.
+	ListSeparator = ","
+
+	// MaxParamBytes limits the size of the []byte decoded from a param, from
+	// "params.maxBytes" (e.g. "4KB").  Struct fields may lower (or raise) it
+	// with the bytes tag, e.g. `bytes:"hex,max=32"`.
+	MaxParamBytes int64 = 4 << 10
+
 	// These are the lookups to find a Binder for any type of data.
 	// The most specific binder found will be used (Type before Kind)
 	TypeBinders = make(map[reflect.Type]Binder)
 	KindBinders = make(map[reflect.Kind]Binder)
 
 	// Applications can add custom time formats to this array, and they will be
-	// automatically attempted when binding a time.Time.
+	// automatically attempted when binding a time.Time.  Formats may also be
+	// "unix" or "unixmilli", for seconds or milliseconds since the epoch.  The
+	// formats in "format.times" (separated by "|"), format.datetime,
+	// format.date and RFC 3339 (e.g. "2013-06-01T12:00:00+02:00") are added on
+	// startup.  Fields of structs may have their own, in a time tag, e.g.
+	//   Start time.Time `time:"unix|2006-01-02"`
 	TimeFormats = []string{}
 
 	DateFormat     string
 	DateTimeFormat string
 
 	IntBinder = Binder{
-		Bind: ValueBinder(func(val string, typ reflect.Type) reflect.Value {
-			if len(val) == 0 {
-				return reflect.Zero(typ)
-			}
-			intValue, err := strconv.ParseInt(val, 10, 64)
+		Bind: valueBinder(func(val string, typ reflect.Type) (reflect.Value, error) {
+			intValue, err := strconv.ParseInt(val, 10, typ.Bits())
 			if err != nil {
-				WARN.Println(err)
-				return reflect.Zero(typ)
+				return reflect.Value{}, err
 			}
 			pValue := reflect.New(typ)
 			pValue.Elem().SetInt(intValue)
-			return pValue.Elem()
+			return pValue.Elem(), nil
 		}),
 		Unbind: func(output map[string]string, key string, val interface{}) {
 			output[key] = fmt.Sprintf("%d", val)
@@ -88,18 +135,14 @@ var (
 	}
 
 	UintBinder = Binder{
-		Bind: ValueBinder(func(val string, typ reflect.Type) reflect.Value {
-			if len(val) == 0 {
-				return reflect.Zero(typ)
-			}
-			uintValue, err := strconv.ParseUint(val, 10, 64)
+		Bind: valueBinder(func(val string, typ reflect.Type) (reflect.Value, error) {
+			uintValue, err := strconv.ParseUint(val, 10, typ.Bits())
 			if err != nil {
-				WARN.Println(err)
-				return reflect.Zero(typ)
+				return reflect.Value{}, err
 			}
 			pValue := reflect.New(typ)
 			pValue.Elem().SetUint(uintValue)
-			return pValue.Elem()
+			return pValue.Elem(), nil
 		}),
 		Unbind: func(output map[string]string, key string, val interface{}) {
 			output[key] = fmt.Sprintf("%d", val)
@@ -107,18 +150,14 @@ var (
 	}
 
 	FloatBinder = Binder{
-		Bind: ValueBinder(func(val string, typ reflect.Type) reflect.Value {
-			if len(val) == 0 {
-				return reflect.Zero(typ)
-			}
-			floatValue, err := strconv.ParseFloat(val, 64)
+		Bind: valueBinder(func(val string, typ reflect.Type) (reflect.Value, error) {
+			floatValue, err := strconv.ParseFloat(val, typ.Bits())
 			if err != nil {
-				WARN.Println(err)
-				return reflect.Zero(typ)
+				return reflect.Value{}, err
 			}
 			pValue := reflect.New(typ)
 			pValue.Elem().SetFloat(floatValue)
-			return pValue.Elem()
+			return pValue.Elem(), nil
 		}),
 		Unbind: func(output map[string]string, key string, val interface{}) {
 			output[key] = fmt.Sprintf("%f", val)
@@ -126,9 +165,13 @@ var (
 	}
 
 	StringBinder = Binder{
-		Bind: ValueBinder(func(val string, typ reflect.Type) reflect.Value {
-			return reflect.ValueOf(val)
-		}),
+		Bind: func(params *Params, name string, typ reflect.Type) reflect.Value {
+			vals, ok := params.Values[name]
+			if !ok || len(vals) == 0 {
+				return reflect.Zero(typ)
+			}
+			return reflect.ValueOf(params.transform(vals[0]))
+		},
 		Unbind: func(output map[string]string, name string, val interface{}) {
 			output[name] = val.(string)
 		},
@@ -138,15 +181,11 @@ var (
 	// "true" and "false"
 	// "on" and "" (a checkbox)
 	// "1" and "0" (why not)
+	// Others are bound as false, with a binding error.
 	BoolBinder = Binder{
-		Bind: ValueBinder(func(val string, typ reflect.Type) reflect.Value {
-			v := strings.TrimSpace(strings.ToLower(val))
-			switch v {
-			case "true", "on", "1":
-				return reflect.ValueOf(true)
-			}
-			// Return false by default.
-			return reflect.ValueOf(false)
+		Bind: valueBinder(func(val string, typ reflect.Type) (reflect.Value, error) {
+			b, err := parseBool(val)
+			return reflect.ValueOf(b), err
 		}),
 		Unbind: func(output map[string]string, name string, val interface{}) {
 			output[name] = fmt.Sprintf("%t", val)
@@ -155,7 +194,13 @@ var (
 
 	PointerBinder = Binder{
 		Bind: func(params *Params, name string, typ reflect.Type) reflect.Value {
-			return Bind(params, name, typ.Elem()).Addr()
+			value := Bind(params, name, typ.Elem())
+			if value.CanAddr() {
+				return value.Addr()
+			}
+			pValue := reflect.New(typ.Elem())
+			pValue.Elem().Set(value)
+			return pValue
 		},
 		Unbind: func(output map[string]string, name string, val interface{}) {
 			Unbind(output, name, reflect.ValueOf(val).Elem().Interface())
@@ -163,13 +208,9 @@ var (
 	}
 
 	TimeBinder = Binder{
-		Bind: ValueBinder(func(val string, typ reflect.Type) reflect.Value {
-			for _, f := range TimeFormats {
-				if r, err := time.Parse(f, val); err == nil {
-					return reflect.ValueOf(r)
-				}
-			}
-			return reflect.Zero(typ)
+		Bind: valueBinder(func(val string, typ reflect.Type) (reflect.Value, error) {
+			t, err := parseTime(val, TimeFormats)
+			return reflect.ValueOf(t), err
 		}),
 		Unbind: func(output map[string]string, name string, val interface{}) {
 			var (
@@ -183,11 +224,27 @@ var (
 			output[name] = t.Format(format)
 		},
 	}
+
+	// UnmarshalerBinder binds the types that implement encoding.TextUnmarshaler,
+	// encoding.BinaryUnmarshaler or sql.Scanner, and have no TypeBinder, with
+	// their UnmarshalText, UnmarshalBinary or Scan.  Those include big.Int,
+	// big.Float, net.IP, and the usual UUID and decimal types (e.g.
+	// github.com/google/uuid and github.com/shopspring/decimal).  A param that
+	// is not a valid value of its type fails the request with 400 Bad Request,
+	// rather than binding the zero value.  Values are unbound with their
+	// MarshalText or MarshalBinary, if they have one.
+	UnmarshalerBinder = Binder{
+		// Bind is set by init, to bind structs by their fields when they
+		// are not given as a whole.
+		Unbind: unbindMarshaler,
+	}
 )
 
 // Sadly, the binder lookups can not be declared initialized -- that results in
 // an "initialization loop" compile error.
 func init() {
+	UnmarshalerBinder.Bind = bindUnmarshaler
+
 	KindBinders[reflect.Int] = IntBinder
 	KindBinders[reflect.Int8] = IntBinder
 	KindBinders[reflect.Int16] = IntBinder
@@ -207,20 +264,42 @@ func init() {
 	KindBinders[reflect.Bool] = BoolBinder
 	KindBinders[reflect.Slice] = Binder{bindSlice, unbindSlice}
 	KindBinders[reflect.Struct] = Binder{bindStruct, unbindStruct}
+	KindBinders[reflect.Map] = Binder{bindMap, unbindMap}
 	KindBinders[reflect.Ptr] = PointerBinder
+	for kind, typ := range paramTypes {
+		if typ != nil {
+			builtinBinders[kind] = KindBinders[reflect.Kind(kind)]
+		}
+	}
 
-	TypeBinders[reflect.TypeOf(time.Time{})] = TimeBinder
+	TypeBinders[timeType] = TimeBinder
 
 	// Uploads
 	TypeBinders[reflect.TypeOf(&os.File{})] = Binder{bindFile, nil}
-	TypeBinders[reflect.TypeOf([]byte{})] = Binder{bindByteArray, nil}
+	TypeBinders[bytesType] = Binder{bindByteArray, nil}
 	TypeBinders[reflect.TypeOf((*io.Reader)(nil)).Elem()] = Binder{bindReadSeeker, nil}
 	TypeBinders[reflect.TypeOf((*io.ReadSeeker)(nil)).Elem()] = Binder{bindReadSeeker, nil}
+	TypeBinders[reflect.TypeOf((*Upload)(nil))] = Binder{bindUploadHandle, nil}
 
 	OnAppStart(func() {
 		DateTimeFormat = Config.StringDefault("format.datetime", DEFAULT_DATETIME_FORMAT)
 		DateFormat = Config.StringDefault("format.date", DEFAULT_DATE_FORMAT)
-		TimeFormats = append(TimeFormats, DateTimeFormat, DateFormat)
+		if formats := Config.StringDefault("format.times", ""); formats != "" {
+			TimeFormats = append(TimeFormats, strings.Split(formats, "|")...)
+		}
+		TimeFormats = append(TimeFormats, DateTimeFormat, DateFormat, time.RFC3339)
+
+		ListSeparator = Config.StringDefault("params.listSeparator", ListSeparator)
+		ParamBytesEncoding = Config.StringDefault("params.bytes", ParamBytesEncoding)
+		if ParamBytesEncoding != "base64" && ParamBytesEncoding != "hex" {
+			ERROR.Fatalln("Invalid params.bytes:", ParamBytesEncoding)
+		}
+		if size, ok := Config.String("params.maxBytes"); ok {
+			var err error
+			if MaxParamBytes, err = parseByteSize(size); err != nil {
+				ERROR.Fatalln("Invalid params.maxBytes:", size)
+			}
+		}
 	})
 }
 
@@ -233,29 +312,49 @@ type sliceValue struct {
 // This function creates a slice of the given type, Binds each of the individual
 // elements, and then sets them to their appropriate location in the slice.
 // If elements are provided without an explicit index, they are added (in
-// unspecified order) to the end of the slice.
+// unspecified order) to the end of the slice.  Those without brackets may be
+// lists, split by the ListSeparator (see splitList).
 func bindSlice(params *Params, name string, typ reflect.Type) reflect.Value {
 	// Collect an array of slice elements with their indexes (and the max index).
 	maxIndex := -1
 	numNoIndex := 0
 	sliceValues := []sliceValue{}
 
+	bound := make(map[int]bool) // e.g. 0 once for both field[0].a and field[0].b
+
 	// Factor out the common slice logic (between form values and files).
-	processElement := func(key string, vals []string, files []*multipart.FileHeader) {
-		if !strings.HasPrefix(key, name+"[") {
+	processElement := func(key string, vals []string, uploads []*Upload) {
+		// Elements given without brackets are un-indexed, whether repeated
+		// (e.g. ids=1&ids=2) or in a list (e.g. ids=1,2,3).
+		index, subKeyIndex := -1, len(key)
+		if key == name {
+			vals = params.splitList(vals)
+		} else if !strings.HasPrefix(key, name+"[") || !strings.Contains(key[len(name):], "]") {
 			return
+		} else {
+			// Extract the index, and the index where a sub-key starts. (e.g. field[0].subkey)
+			leftBracket, rightBracket := len(name), strings.Index(key[len(name):], "]")+len(name)
+			subKeyIndex = rightBracket + 1
+			if rightBracket > leftBracket+1 {
+				var err error
+				index, err = strconv.Atoi(key[leftBracket+1 : rightBracket])
+				if err != nil || index < 0 {
+					params.bindError(key[:subKeyIndex], fmt.Sprintf("%q is not a valid index", key[leftBracket+1:rightBracket]))
+					return
+				}
+				if index >= MaxSliceLength {
+					params.bindError(key[:subKeyIndex], fmt.Sprintf("The index may be at most %d", MaxSliceLength-1))
+					return
+				}
+			}
 		}
 
-		// Extract the index, and the index where a sub-key starts. (e.g. field[0].subkey)
-		index := -1
-		leftBracket, rightBracket := len(name), strings.Index(key[len(name):], "]")+len(name)
-		if rightBracket > leftBracket+1 {
-			index, _ = strconv.Atoi(key[leftBracket+1 : rightBracket])
-		}
-		subKeyIndex := rightBracket + 1
-
 		// Handle the indexed case.
 		if index > -1 {
+			if bound[index] {
+				return
+			}
+			bound[index] = true
 			if index > maxIndex {
 				maxIndex = index
 			}
@@ -267,19 +366,22 @@ func bindSlice(params *Params, name string, typ reflect.Type) reflect.Value {
 		}
 
 		// It's an un-indexed element.  (e.g. element[])
-		numNoIndex += len(vals) + len(files)
+		numNoIndex += len(vals) + len(uploads)
 		for _, val := range vals {
-			// Unindexed values can only be direct-bound.
-			sliceValues = append(sliceValues, sliceValue{
-				index: -1,
-				value: BindValue(val, typ.Elem()),
-			})
+			// Unindexed values can only be direct-bound.  Those that are not
+			// valid are left out (with their binding errors).
+			numErrors := len(params.bindErrors) + len(params.badValues)
+			value := bindValue(params, key, val, typ.Elem())
+			if len(params.bindErrors)+len(params.badValues) > numErrors {
+				continue
+			}
+			sliceValues = append(sliceValues, sliceValue{index: -1, value: value})
 		}
 
-		for _, fileHeader := range files {
+		for _, upload := range uploads {
 			sliceValues = append(sliceValues, sliceValue{
 				index: -1,
-				value: BindFile(fileHeader, typ.Elem()),
+				value: bindUpload(params, upload, typ.Elem()),
 			})
 		}
 	}
@@ -287,8 +389,8 @@ func bindSlice(params *Params, name string, typ reflect.Type) reflect.Value {
 	for key, vals := range params.Values {
 		processElement(key, vals, nil)
 	}
-	for key, fileHeaders := range params.Files {
-		processElement(key, nil, fileHeaders)
+	for key, uploads := range params.Uploads {
+		processElement(key, nil, uploads)
 	}
 
 	resultArray := reflect.MakeSlice(typ, maxIndex+1, maxIndex+1+numNoIndex)
@@ -303,6 +405,44 @@ func bindSlice(params *Params, name string, typ reflect.Type) reflect.Value {
 	return resultArray
 }
 
+// bindMap binds a map from the params that key it, e.g. prices[small]=1, or,
+// for values that are structs (or slices, or maps), items[a].qty=2.  Keys are
+// bound as the map's key type, e.g. ids[1]=a for a map[int]string.
+func bindMap(params *Params, name string, typ reflect.Type) reflect.Value {
+	result := reflect.MakeMap(typ)
+	bindEntry := func(key string) {
+		if !strings.HasPrefix(key, name+"[") {
+			return
+		}
+		rightBracket := strings.Index(key[len(name):], "]") + len(name)
+		if rightBracket <= len(name)+1 {
+			return
+		}
+		entryName := key[:rightBracket+1]
+		numErrors := len(params.bindErrors)
+		mapKey := bindValue(params, entryName, key[len(name)+1:rightBracket], typ.Key())
+		if len(params.bindErrors) > numErrors || result.MapIndex(mapKey).IsValid() {
+			return
+		}
+		result.SetMapIndex(mapKey, Bind(params, entryName, typ.Elem()))
+	}
+
+	for key := range params.Values {
+		bindEntry(key)
+	}
+	for key := range params.Uploads {
+		bindEntry(key)
+	}
+	return result
+}
+
+func unbindMap(output map[string]string, name string, val interface{}) {
+	v := reflect.ValueOf(val)
+	for _, key := range v.MapKeys() {
+		Unbind(output, fmt.Sprintf("%s[%v]", name, key.Interface()), v.MapIndex(key).Interface())
+	}
+}
+
 // Break on dots and brackets.
 // e.g. bar => "bar", bar.baz => "bar", bar[0] => "bar"
 func nextKey(key string) string {
@@ -323,6 +463,7 @@ func unbindSlice(output map[string]string, name string, val interface{}) {
 func bindStruct(params *Params, name string, typ reflect.Type) reflect.Value {
 	result := reflect.New(typ).Elem()
 	fieldValues := make(map[string]reflect.Value)
+	bound := make(map[int]bool) // the fields of the struct itself that were bound
 	for key, _ := range params.Values {
 		if !strings.HasPrefix(key, name+".") {
 			continue
@@ -336,24 +477,167 @@ func bindStruct(params *Params, name string, typ reflect.Type) reflect.Value {
 
 		if _, ok := fieldValues[fieldName]; !ok {
 			// Time to bind this field.  Get it and make sure we can set it.
-			fieldValue := result.FieldByName(fieldName)
-			if !fieldValue.IsValid() {
+			index := structFieldIndex(typ, fieldName)
+			if index == nil {
 				WARN.Println("W: bindStruct: Field not found:", fieldName)
 				continue
 			}
+			fieldValue := result.FieldByIndex(index)
 			if !fieldValue.CanSet() {
 				WARN.Println("W: bindStruct: Field not settable:", fieldName)
 				continue
 			}
-			boundVal := Bind(params, key[:len(name)+1+fieldLen], fieldValue.Type())
+			boundVal := bindField(params, key[:len(name)+1+fieldLen], typ.FieldByIndex(index))
 			fieldValue.Set(boundVal)
 			fieldValues[fieldName] = boundVal
+			if len(index) == 1 {
+				bound[index[0]] = true
+			}
+		}
+	}
+
+	// Fields that were not given take the value of their default tag, if
+	// any, e.g. `default:"10"`.
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		value, ok := field.Tag.Lookup("default")
+		if ok && !bound[i] && field.PkgPath == "" {
+			result.Field(i).Set(bindField(&Params{Values: map[string][]string{"": {value}}}, "", field))
 		}
 	}
 
 	return result
 }
 
+// structFieldIndex returns the index of the field of the struct that the param
+// binds: the field with that name in its param tag (e.g. `param:"user_id"`),
+// or else the field of that name, or else that with the name in its json tag,
+// or else that with the name in another case (as encoding/json matches them),
+// e.g. "Name" for "name".  Fields with a param tag are bound only by it, and
+// those tagged `param:"-"` not at all.  It returns nil if no field binds it.
+func structFieldIndex(typ reflect.Type, name string) []int {
+	for i := 0; i < typ.NumField(); i++ {
+		if tag := typ.Field(i).Tag.Get("param"); tag == name && tag != "-" {
+			return []int{i}
+		}
+	}
+	if field, ok := typ.FieldByName(name); ok && field.Tag.Get("param") == "" {
+		return field.Index
+	}
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if tag := strings.Split(field.Tag.Get("json"), ",")[0]; tag == name && field.Tag.Get("param") == "" {
+			return []int{i}
+		}
+	}
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if strings.EqualFold(field.Name, name) && field.Tag.Get("param") == "" {
+			return []int{i}
+		}
+	}
+	return nil
+}
+
+// bindField binds the param to the field of a struct.  Times (and pointers to
+// them) are parsed with the formats in the field's time tag, if it has one,
+// rather than TimeFormats, e.g. `time:"unix|2006-01-02"`.  The strings bound
+// to the field (and within it) are transformed as its transform tag names,
+// e.g. `transform:"trim,lower"` (see ParamTransformers).  A []byte with the
+// bytes tag is decoded as it gives, e.g. `bytes:"hex,max=32"`.  Lists given to
+// the field (and within it) are split by its split tag, if any.
+func bindField(params *Params, name string, field reflect.StructField) reflect.Value {
+	if tag, ok := field.Tag.Lookup("transform"); ok {
+		defer func(transforms []func(string) string) { params.transforms = transforms }(params.transforms)
+		params.transforms = fieldTransforms(params, tag)
+	}
+	if tag, ok := field.Tag.Lookup("split"); ok && tag != "" {
+		defer func(separator string) { params.separator = separator }(params.separator)
+		params.separator = tag
+	}
+	if tag, ok := field.Tag.Lookup("bytes"); ok && field.Type == bytesType && len(params.Uploads[name]) == 0 {
+		encoding, max := ParamBytesEncoding, MaxParamBytes
+		for _, option := range strings.Split(tag, ",") {
+			switch {
+			case option == "base64" || option == "hex":
+				encoding = option
+			case strings.HasPrefix(option, "max="):
+				size, err := parseByteSize(option[len("max="):])
+				if err != nil {
+					WARN.Println("W: bindField: Invalid bytes tag:", tag)
+					continue
+				}
+				max = size
+			default:
+				WARN.Println("W: bindField: Invalid bytes tag:", tag)
+			}
+		}
+		return bindBytes(params, name, encoding, max)
+	}
+
+	formats, ok := field.Tag.Lookup("time")
+	if !ok || (field.Type != timeType && field.Type != reflect.PtrTo(timeType)) {
+		return Bind(params, name, field.Type)
+	}
+	value := valueBinder(func(val string, typ reflect.Type) (reflect.Value, error) {
+		t, err := parseTime(val, strings.Split(formats, "|"))
+		return reflect.ValueOf(t), err
+	})(params, name, timeType)
+	if field.Type == timeType {
+		return value
+	}
+	pValue := reflect.New(timeType)
+	pValue.Elem().Set(value)
+	return pValue
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// parseBool parses a boolean in the formats of the BoolBinder.
+func parseBool(val string) (bool, error) {
+	switch strings.TrimSpace(strings.ToLower(val)) {
+	case "true", "on", "1":
+		return true, nil
+	case "false", "off", "0", "":
+		return false, nil
+	}
+	return false, fmt.Errorf("not a boolean")
+}
+
+// parseTime parses the time in the first of the formats that it matches, which
+// are layouts for time.Parse, or "unix" or "unixmilli" for (the decimal)
+// seconds or milliseconds since the epoch.
+func parseTime(val string, formats []string) (time.Time, error) {
+	for _, format := range formats {
+		switch format {
+		case "unix", "unixmilli":
+			n, err := strconv.ParseInt(val, 10, 64)
+			if err != nil {
+				continue
+			}
+			if format == "unix" {
+				return time.Unix(n, 0).UTC(), nil
+			}
+			return time.UnixMilli(n).UTC(), nil
+		}
+		if t, err := time.Parse(format, val); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("no time format matches")
+}
+
+// formatTime formats the time in a format of parseTime.
+func formatTime(t time.Time, format string) string {
+	switch format {
+	case "unix":
+		return strconv.FormatInt(t.Unix(), 10)
+	case "unixmilli":
+		return strconv.FormatInt(t.UnixMilli(), 10)
+	}
+	return t.Format(format)
+}
+
 func unbindStruct(output map[string]string, name string, iface interface{}) {
 	val := reflect.ValueOf(iface)
 	typ := val.Type()
@@ -361,18 +645,39 @@ func unbindStruct(output map[string]string, name string, iface interface{}) {
 		structField := typ.Field(i)
 		fieldValue := val.Field(i)
 
+		// Fields are unbound by the name in their param tag, if any.
+		fieldName := structField.Name
+		if tag := structField.Tag.Get("param"); tag == "-" {
+			continue
+		} else if tag != "" {
+			fieldName = tag
+		}
+
 		// PkgPath is specified to be empty exactly for exported fields.
-		if structField.PkgPath == "" {
-			Unbind(output, fmt.Sprintf("%s.%s", name, structField.Name), fieldValue.Interface())
+		if structField.PkgPath != "" {
+			continue
+		}
+
+		// Times are unbound in the first format of their time tag, if any.
+		if fieldValue.Kind() == reflect.Ptr && !fieldValue.IsNil() {
+			fieldValue = fieldValue.Elem()
 		}
+		if formats, ok := structField.Tag.Lookup("time"); ok && fieldValue.Type() == timeType {
+			t := fieldValue.Interface().(time.Time)
+			output[name+"."+fieldName] = formatTime(t, strings.Split(formats, "|")[0])
+			continue
+		}
+		Unbind(output, fmt.Sprintf("%s.%s", name, fieldName), fieldValue.Interface())
 	}
 }
 
-// Helper that returns an upload of the given name, or nil.
-func getMultipartFile(params *Params, name string) multipart.File {
-	for _, fileHeader := range params.Files[name] {
-		file, err := fileHeader.Open()
+// Helper that opens an upload of the given name, or returns nil.  It is closed
+// once the request's result has been applied.
+func openUpload(params *Params, name string) io.ReadCloser {
+	for _, upload := range params.Uploads[name] {
+		file, err := upload.Open()
 		if err == nil {
+			params.opened = append(params.opened, file)
 			return file
 		}
 		WARN.Println("Failed to open uploaded file", name, ":", err)
@@ -381,12 +686,13 @@ func getMultipartFile(params *Params, name string) multipart.File {
 }
 
 func bindFile(params *Params, name string, typ reflect.Type) reflect.Value {
-	reader := getMultipartFile(params, name)
+	reader := openUpload(params, name)
 	if reader == nil {
 		return reflect.Zero(typ)
 	}
 
-	// If it's already stored in a temp file, just return that.
+	// If it's already stored in a file (e.g. by the DiskUploadStore), just
+	// return that.
 	if osFile, ok := reader.(*os.File); ok {
 		return reflect.ValueOf(osFile)
 	}
@@ -416,8 +722,13 @@ func bindFile(params *Params, name string, typ reflect.Type) reflect.Value {
 	return reflect.ValueOf(tmpFile)
 }
 
+// bindByteArray binds the content of an uploaded file, or else the param,
+// decoded from the ParamBytesEncoding.
 func bindByteArray(params *Params, name string, typ reflect.Type) reflect.Value {
-	if reader := getMultipartFile(params, name); reader != nil {
+	if len(params.Uploads[name]) == 0 {
+		return bindBytes(params, name, ParamBytesEncoding, MaxParamBytes)
+	}
+	if reader := openUpload(params, name); reader != nil {
 		b, err := ioutil.ReadAll(reader)
 		if err == nil {
 			return reflect.ValueOf(b)
@@ -427,16 +738,83 @@ func bindByteArray(params *Params, name string, typ reflect.Type) reflect.Value
 	return reflect.Zero(typ)
 }
 
+// bindBytes binds the param decoded from the encoding ("base64" or "hex").
+// Params that are not valid, or decode to more than max bytes, are bound as
+// nil, with a binding error.
+func bindBytes(params *Params, name, encoding string, max int64) reflect.Value {
+	val := params.Get(name)
+	if val == "" {
+		return reflect.Zero(bytesType)
+	}
+
+	decode := hex.DecodeString
+	size := hex.DecodedLen(len(val))
+	if encoding == "base64" {
+		// Padding is optional, and the alphabet is chosen by its characters.
+		val = strings.TrimRight(val, "=")
+		enc := base64.RawStdEncoding
+		if strings.ContainsAny(val, "-_") {
+			enc = base64.RawURLEncoding
+		}
+		decode, size = enc.DecodeString, enc.DecodedLen(len(val))
+	}
+	if int64(size) > max {
+		params.bindError(name, fmt.Sprintf("The value may be at most %d bytes", max))
+		return reflect.Zero(bytesType)
+	}
+	b, err := decode(val)
+	if err != nil {
+		params.bindError(name, "The value is not valid "+encoding)
+		return reflect.Zero(bytesType)
+	}
+	return reflect.ValueOf(b)
+}
+
+var bytesType = reflect.TypeOf([]byte{})
+
 func bindReader(params *Params, name string, typ reflect.Type) reflect.Value {
-	if reader := getMultipartFile(params, name); reader != nil {
+	if reader := openUpload(params, name); reader != nil {
 		return reflect.ValueOf(reader.(io.Reader))
 	}
 	return reflect.Zero(typ)
 }
 
 func bindReadSeeker(params *Params, name string, typ reflect.Type) reflect.Value {
-	if reader := getMultipartFile(params, name); reader != nil {
-		return reflect.ValueOf(reader.(io.ReadSeeker))
+	// Uploads that can not seek are copied to a temp file.
+	file := bindFile(params, name, reflect.TypeOf((*os.File)(nil)))
+	if file.IsNil() {
+		return reflect.Zero(typ)
+	}
+	return reflect.ValueOf(file.Interface().(io.ReadSeeker))
+}
+
+// splitList splits the values of a param by the separator of its lists, with
+// the empty values left out, e.g. ["1,2", "3", ""] as ["1", "2", "3"].
+func (p *Params) splitList(vals []string) []string {
+	separator := p.separator
+	if separator == "" {
+		separator = ListSeparator
+	}
+	var list []string
+	for _, val := range vals {
+		items := []string{val}
+		if separator != "" && separator != "-" {
+			items = strings.Split(val, separator)
+		}
+		for _, item := range items {
+			if item != "" {
+				list = append(list, item)
+			}
+		}
+	}
+	return list
+}
+
+// bindUploadHandle binds the upload itself, for the action to read, or keep in
+// the store.
+func bindUploadHandle(params *Params, name string, typ reflect.Type) reflect.Value {
+	if uploads := params.Uploads[name]; len(uploads) > 0 {
+		return reflect.ValueOf(uploads[0])
 	}
 	return reflect.Zero(typ)
 }
@@ -444,7 +822,14 @@ func bindReadSeeker(params *Params, name string, typ reflect.Type) reflect.Value
 // Bind takes the name and type of the desired parameter and constructs it
 // from one or more values from Params.
 // Returns the zero value of the type upon any sort of failure.
+//
+// A JSON (or XML) body's value that is of the wrong kind for the type, e.g.
+// an object for an int, is bound as the zero value, with a binding error.
 func Bind(params *Params, name string, typ reflect.Type) reflect.Value {
+	if kind, ok := params.bodyKinds[name]; ok && !bodyFits(kind, typ) {
+		params.bindError(name, fmt.Sprintf("%s is not a valid %s", kind, typ))
+		return reflect.Zero(typ)
+	}
 	if binder, found := binderForType(typ); found {
 		return binder.Bind(params, name, typ)
 	}
@@ -455,8 +840,95 @@ func BindValue(val string, typ reflect.Type) reflect.Value {
 	return Bind(&Params{Values: map[string][]string{"": {val}}}, "", typ)
 }
 
+// bindValue binds one value of the named param, as BindValue does, with the
+// error in binding it, if any, recorded in params.
+func bindValue(params *Params, name, val string, typ reflect.Type) reflect.Value {
+	valueParams := &Params{
+		Values:     map[string][]string{name: {val}},
+		transforms: params.transforms,
+		separator:  params.separator,
+	}
+	value := Bind(valueParams, name, typ)
+	params.bindErrors = append(params.bindErrors, valueParams.bindErrors...)
+	params.badValues = append(params.badValues, valueParams.badValues...)
+	return value
+}
+
+// bodyFits returns true if a body's value of the kind (as flattenBody records
+// it, e.g. "an object") may be bound to the type.  Pointers are checked by
+// their elements.
+func bodyFits(kind string, typ reflect.Type) bool {
+	container := kind == "an object" || kind == "an array"
+	switch typ.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return true
+	case reflect.Map:
+		return kind == "an object"
+	case reflect.Slice:
+		return kind == "an array" || TypeBinders[typ].Bind != nil
+	case reflect.Struct:
+		if TypeBinders[typ].Bind != nil {
+			return !container
+		}
+		if isUnmarshaler(typ) {
+			return kind != "an array"
+		}
+		return kind == "an object"
+	}
+	return !container
+}
+
+// bindError records that the param could not be bound, for the Validation of
+// the request, e.g. ("ids[1]", `"abc" is not a valid int`).
+// Each param is reported once, though it may be met more than once, e.g. as
+// both items[x].qty and items[x].sku.
+func (p *Params) bindError(name, message string) {
+	for _, err := range p.bindErrors {
+		if err.Key == name {
+			return
+		}
+	}
+	p.bindErrors = append(p.bindErrors, &ValidationError{Key: name, Message: message})
+}
+
+// BindFile binds the uploaded file to the type, e.g. *os.File or []byte.
+//
+// Deprecated: bind the Upload (see bindUpload) rather than its FileHeader.
 func BindFile(fileHeader *multipart.FileHeader, typ reflect.Type) reflect.Value {
-	return Bind(&Params{Files: map[string][]*multipart.FileHeader{"": {fileHeader}}}, "", typ)
+	upload := &Upload{
+		Filename: fileHeader.Filename,
+		Header:   fileHeader.Header,
+		Size:     fileHeader.Size,
+		store:    fileHeaderStore{fileHeader},
+	}
+	return Bind(&Params{Uploads: map[string][]*Upload{"": {upload}}}, "", typ)
+}
+
+// fileHeaderStore opens the file of a FileHeader as an upload, for BindFile.
+type fileHeaderStore struct {
+	fileHeader *multipart.FileHeader
+}
+
+func (s fileHeaderStore) Save(upload *Upload, content io.Reader) error {
+	return errors.New("revel/binder: can not save to a FileHeader")
+}
+
+func (s fileHeaderStore) Open(upload *Upload) (io.ReadCloser, error) {
+	return s.fileHeader.Open()
+}
+
+func (s fileHeaderStore) Remove(upload *Upload) error {
+	return nil
+}
+
+// bindUpload binds the upload to the type, with the files that it opens closed
+// along with those of the params.
+func bindUpload(params *Params, upload *Upload, typ reflect.Type) reflect.Value {
+	uploadParams := &Params{Uploads: map[string][]*Upload{"": {upload}}}
+	value := Bind(uploadParams, "", typ)
+	params.opened = append(params.opened, uploadParams.opened...)
+	params.tmpFiles = append(params.tmpFiles, uploadParams.tmpFiles...)
+	return value
 }
 
 func Unbind(output map[string]string, name string, val interface{}) {
@@ -471,6 +943,9 @@ func Unbind(output map[string]string, name string, val interface{}) {
 
 func binderForType(typ reflect.Type) (Binder, bool) {
 	binder, ok := TypeBinders[typ]
+	if !ok && isUnmarshaler(typ) {
+		binder, ok = UnmarshalerBinder, true
+	}
 	if !ok {
 		binder, ok = KindBinders[typ.Kind()]
 		if !ok {
@@ -480,3 +955,148 @@ func binderForType(typ reflect.Type) (Binder, bool) {
 	}
 	return binder, true
 }
+
+var (
+	// paramTypes are the types of the action arguments that BindParam binds,
+	// by kind.
+	paramTypes = [reflect.UnsafePointer + 1]reflect.Type{
+		reflect.Bool:    reflect.TypeOf(false),
+		reflect.String:  reflect.TypeOf(""),
+		reflect.Int:     reflect.TypeOf(int(0)),
+		reflect.Int8:    reflect.TypeOf(int8(0)),
+		reflect.Int16:   reflect.TypeOf(int16(0)),
+		reflect.Int32:   reflect.TypeOf(int32(0)),
+		reflect.Int64:   reflect.TypeOf(int64(0)),
+		reflect.Uint:    reflect.TypeOf(uint(0)),
+		reflect.Uint8:   reflect.TypeOf(uint8(0)),
+		reflect.Uint16:  reflect.TypeOf(uint16(0)),
+		reflect.Uint32:  reflect.TypeOf(uint32(0)),
+		reflect.Uint64:  reflect.TypeOf(uint64(0)),
+		reflect.Float32: reflect.TypeOf(float32(0)),
+		reflect.Float64: reflect.TypeOf(float64(0)),
+	}
+
+	// builtinBinders are revel's own binders of those kinds.
+	builtinBinders [reflect.UnsafePointer + 1]Binder
+
+	// directKinds are the kinds that BindParam binds itself, rather than
+	// through BindArg: those whose binders are still revel's own once the
+	// application has started (see findDirectKinds).
+	directKinds [reflect.UnsafePointer + 1]bool
+)
+
+// findDirectKinds finds the kinds of arguments that BindParam may bind itself,
+// as their binders are revel's own, with none registered for their types.
+func findDirectKinds() {
+	for kind, typ := range paramTypes {
+		if typ == nil {
+			continue
+		}
+		binder, builtin := KindBinders[reflect.Kind(kind)], builtinBinders[kind]
+		_, custom := TypeBinders[typ]
+		directKinds[kind] = !custom && sameFunc(binder.Bind, builtin.Bind) && sameFunc(binder.Unbind, builtin.Unbind)
+	}
+}
+
+// isDirect returns true if BindParam binds arguments of the type itself.
+func isDirect(typ reflect.Type) bool {
+	kind := typ.Kind()
+	return int(kind) < len(paramTypes) && paramTypes[kind] == typ && directKinds[kind]
+}
+
+func sameFunc(f, g interface{}) bool {
+	return reflect.ValueOf(f).Pointer() == reflect.ValueOf(g).Pointer()
+}
+
+var (
+	textUnmarshalerType   = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	binaryUnmarshalerType = reflect.TypeOf((*encoding.BinaryUnmarshaler)(nil)).Elem()
+	scannerType           = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+)
+
+// bindUnmarshaler binds a param with the UnmarshalText, UnmarshalBinary or
+// Scan of its type.  A struct that is not given as a whole (e.g. as "amount",
+// rather than "amount.Currency") is bound by its fields instead.  The error in
+// binding an invalid value fails the request (see ActionInvoker).
+func bindUnmarshaler(params *Params, name string, typ reflect.Type) reflect.Value {
+	if _, given := params.Values[name]; !given && typ.Kind() == reflect.Struct {
+		return bindStruct(params, name, typ)
+	}
+	numErrors := len(params.bindErrors)
+	value := valueBinder(unmarshalValue)(params, name, typ)
+	params.badValues = append(params.badValues, params.bindErrors[numErrors:]...)
+	params.bindErrors = params.bindErrors[:numErrors]
+	return value
+}
+
+func unmarshalValue(val string, typ reflect.Type) (reflect.Value, error) {
+	var (
+		pValue = reflect.New(typ)
+		err    error
+	)
+	switch u := pValue.Interface().(type) {
+	case encoding.TextUnmarshaler:
+		err = u.UnmarshalText([]byte(val))
+	case encoding.BinaryUnmarshaler:
+		err = u.UnmarshalBinary([]byte(val))
+	case sql.Scanner:
+		err = u.Scan(val)
+	}
+	return pValue.Elem(), err
+}
+
+// isUnmarshaler returns true if values of the type may be bound by the
+// UnmarshalerBinder.
+func isUnmarshaler(typ reflect.Type) bool {
+	if typ.Kind() == reflect.Ptr || typ.Kind() == reflect.Interface {
+		return false
+	}
+	ptr := reflect.PtrTo(typ)
+	return ptr.Implements(textUnmarshalerType) || ptr.Implements(binaryUnmarshalerType) ||
+		ptr.Implements(scannerType)
+}
+
+// unbindMarshaler unbinds a value with its MarshalText or MarshalBinary, or
+// else as it prints.
+func unbindMarshaler(output map[string]string, name string, val interface{}) {
+	pValue := reflect.New(reflect.TypeOf(val))
+	pValue.Elem().Set(reflect.ValueOf(val))
+	var (
+		b   []byte
+		err error
+	)
+	switch m := pValue.Interface().(type) {
+	case encoding.TextMarshaler:
+		b, err = m.MarshalText()
+	case encoding.BinaryMarshaler:
+		b, err = m.MarshalBinary()
+	default:
+		output[name] = fmt.Sprint(val)
+		return
+	}
+	if err != nil {
+		ERROR.Printf("revel/binder: can not unbind %s=%v: %s", name, val, err)
+		return
+	}
+	output[name] = string(b)
+}
+
+// RegisterBinder adds a binder for the type T, which binds a param with the
+// parse function, e.g.
+//
+//     revel.RegisterBinder(func(value string) (models.Slug, error) {
+//     	return models.ParseSlug(value)
+//     })
+//
+// A missing or empty param, or one that fails to parse (which is logged),
+// binds the zero value.  Values of T are unbound with their MarshalText or
+// MarshalBinary, if they have one, or else as they print.
+func RegisterBinder[T any](parse func(value string) (T, error)) {
+	TypeBinders[reflect.TypeOf((*T)(nil)).Elem()] = Binder{
+		Bind: valueBinder(func(val string, typ reflect.Type) (reflect.Value, error) {
+			value, err := parse(val)
+			return reflect.ValueOf(&value).Elem(), err
+		}),
+		Unbind: unbindMarshaler,
+	}
+}
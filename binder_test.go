@@ -1,12 +1,18 @@
 package revel
 
 import (
+	"database/sql"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/url"
 	"os"
 	"reflect"
 	"sort"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -158,6 +164,7 @@ func TestBinder(t *testing.T) {
 	// Reuse the mvc_test.go multipart request to test the binder.
 	params := &Params{}
 	ParseParams(params, NewRequest(getMultipartRequest()))
+	defer params.removeUploads()
 	params.Values = PARAMS
 
 	// Values
@@ -312,6 +319,347 @@ func TestUnbinder(t *testing.T) {
 	}
 }
 
+// A color given as "#ff8000", bound by its UnmarshalText.
+type hexColor uint32
+
+func (c *hexColor) UnmarshalText(text []byte) error {
+	value, err := strconv.ParseUint(strings.TrimPrefix(string(text), "#"), 16, 32)
+	*c = hexColor(value)
+	return err
+}
+
+func (c hexColor) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprintf("#%06x", uint32(c))), nil
+}
+
+// A struct bound by its UnmarshalBinary, rather than by its fields.
+type token struct {
+	Value string
+}
+
+func (tok *token) UnmarshalBinary(data []byte) error {
+	tok.Value = strings.ToUpper(string(data))
+	return nil
+}
+
+func TestUnmarshalerBinder(t *testing.T) {
+	params := &Params{Values: url.Values{
+		"color":    {"#ff8000"},
+		"bad":      {"orange"},
+		"colors[]": {"#000001"},
+		"ip":       {"10.0.0.1"},
+		"token":    {"abc"},
+	}}
+	eq(t, "Color", Bind(params, "color", reflect.TypeOf(hexColor(0))).Interface(), hexColor(0xff8000))
+	eq(t, "Bad color", Bind(params, "bad", reflect.TypeOf(hexColor(0))).Interface(), hexColor(0))
+	eq(t, "Color pointer", *Bind(params, "color", reflect.TypeOf((*hexColor)(nil))).Interface().(*hexColor), hexColor(0xff8000))
+	eq(t, "Colors", fmt.Sprint(Bind(params, "colors", reflect.TypeOf([]hexColor{})).Interface()), "[1]")
+	eq(t, "IP", Bind(params, "ip", reflect.TypeOf(net.IP{})).Interface().(net.IP).String(), "10.0.0.1")
+	eq(t, "Token", Bind(params, "token", reflect.TypeOf(token{})).Interface(), token{"ABC"})
+
+	output := map[string]string{}
+	Unbind(output, "color", hexColor(0xff8000))
+	Unbind(output, "ip", net.IPv4(10, 0, 0, 1))
+	eq(t, "Unbound", fmt.Sprint(output), "map[color:#ff8000 ip:10.0.0.1]")
+}
+
+type slug string
+
+func TestRegisterBinder(t *testing.T) {
+	RegisterBinder(func(value string) (slug, error) {
+		if strings.Contains(value, " ") {
+			return "", fmt.Errorf("invalid slug: %q", value)
+		}
+		return slug(strings.ToLower(value)), nil
+	})
+	defer delete(TypeBinders, reflect.TypeOf(slug("")))
+
+	params := &Params{Values: url.Values{"slug": {"Grand-Hotel"}, "bad": {"Grand Hotel"}}}
+	eq(t, "Slug", Bind(params, "slug", reflect.TypeOf(slug(""))).Interface(), slug("grand-hotel"))
+	eq(t, "Bad slug", Bind(params, "bad", reflect.TypeOf(slug(""))).Interface(), slug(""))
+	eq(t, "Slug pointer", *Bind(params, "slug", reflect.TypeOf((*slug)(nil))).Interface().(*slug), slug("grand-hotel"))
+
+	output := map[string]string{}
+	Unbind(output, "slug", slug("grand-hotel"))
+	eq(t, "Unbound", output["slug"], "grand-hotel")
+}
+
+type search struct {
+	UserId int    `param:"user_id"`
+	Limit  int    `default:"10"`
+	Sort   string `param:"sort" default:"name"`
+	Token  string `param:"-"`
+}
+
+func TestStructTags(t *testing.T) {
+	for _, test := range []struct {
+		values   url.Values
+		expected search
+	}{
+		{url.Values{}, search{Limit: 10, Sort: "name"}},
+		{url.Values{"s.user_id": {"5"}, "s.limit": {"20"}, "s.sort": {"stars"}}, search{5, 20, "stars", ""}},
+		{url.Values{"s.UserId": {"5"}, "s.Sort": {"stars"}, "s.Token": {"x"}}, search{0, 10, "name", ""}},
+		{url.Values{"s.Limit": {"0"}, "s.sort": {""}}, search{0, 0, "", ""}},
+	} {
+		actual := Bind(&Params{Values: test.values}, "s", reflect.TypeOf(search{})).Interface()
+		eq(t, "Bound "+fmt.Sprint(test.values), actual, test.expected)
+	}
+
+	output := map[string]string{}
+	Unbind(output, "s", search{5, 20, "stars", "x"})
+	eq(t, "Unbound", fmt.Sprint(output), "map[s.Limit:20 s.sort:stars s.user_id:5]")
+}
+
+type lineItem struct {
+	Sku string
+	Qty int
+}
+
+type order struct {
+	Customer struct {
+		Address struct {
+			City string
+		}
+	}
+	Items  []lineItem
+	Extras map[string]*lineItem
+	Prices map[string]float64
+	Counts map[int]int
+}
+
+func TestNestedBinding(t *testing.T) {
+	c := NewController(nil, nil)
+	c.Validation = &Validation{}
+	c.Params.Values = url.Values{
+		"o.Customer.Address.City": {"Paris"},
+		"o.Items[0].Sku":          {"a"},
+		"o.Items[0].Qty":          {"2"},
+		"o.Items[1].Sku":          {"b"},
+		"o.Items[1].Qty":          {"two"},
+		"o.Items[x].Qty":          {"1"},
+		"o.Items[x].Sku":          {"c"},
+		"o.Items[100000].Qty":     {"1"},
+		"o.Extras[gift].Qty":      {"1"},
+		"o.Prices[small]":         {"1.5"},
+		"o.Counts[3]":             {"4"},
+		"o.Counts[x]":             {"5"},
+	}
+	actual := bindArg(c, &MethodArg{Name: "o", Type: reflect.TypeOf(order{})}).Interface().(order)
+
+	eq(t, "City", actual.Customer.Address.City, "Paris")
+	eq(t, "Items", fmt.Sprint(actual.Items), "[{a 2} {b 0}]")
+	if eq(t, "Extras", len(actual.Extras), 1) {
+		eq(t, "Gift", *actual.Extras["gift"], lineItem{Qty: 1})
+	}
+	eq(t, "Prices", fmt.Sprint(actual.Prices), "map[small:1.5]")
+	eq(t, "Counts", fmt.Sprint(actual.Counts), "map[3:4]")
+
+	var errors []string
+	for _, err := range c.Validation.Errors {
+		errors = append(errors, err.Key+": "+err.Message)
+	}
+	sort.Strings(errors)
+	eq(t, "Errors", strings.Join(errors, "\n"), strings.Join([]string{
+		`o.Counts[x]: "x" is not a valid int`,
+		`o.Items[100000]: The index may be at most 9999`,
+		`o.Items[1].Qty: "two" is not a valid int`,
+		`o.Items[x]: "x" is not a valid index`,
+	}, "\n"))
+	eq(t, "Params errors", len(c.Params.bindErrors), 0)
+
+	output := map[string]string{}
+	Unbind(output, "o", map[string]int{"a": 1, "b": 2})
+	eq(t, "Unbound", fmt.Sprint(output), "map[o[a]:1 o[b]:2]")
+}
+
+func TestJsonBindingErrors(t *testing.T) {
+	orderArg := &MethodArg{"o", reflect.TypeOf(order{})}
+	idArg := &MethodArg{"id", reflect.TypeOf(0)}
+	adminArg := &MethodArg{"admin", reflect.TypeOf(false)}
+	for _, test := range []struct {
+		body     string
+		args     []*MethodArg
+		expected []string
+	}{
+		{`{"customer": {"address": {"city": {"name": "Paris"}}},
+		   "items": [{"sku": "a", "qty": "two"}, {"sku": ["b"], "qty": 1}],
+		   "extras": [1], "counts": "4"}`,
+			[]*MethodArg{orderArg},
+			[]string{
+				`o.counts: a string is not a valid map[int]int`,
+				`o.customer.address.city: an object is not a valid string`,
+				`o.extras: an array is not a valid map[string]*revel.lineItem`,
+				`o.items[0].qty: "two" is not a valid int`,
+				`o.items[1].sku: an array is not a valid string`,
+			}},
+		{`{"id": {"value": 3}, "admin": "maybe"}`,
+			[]*MethodArg{idArg, adminArg},
+			[]string{
+				`admin: "maybe" is not a valid bool`,
+				`id: an object is not a valid int`,
+			}},
+		{`{"id": 3, "admin": true}`, []*MethodArg{idArg, adminArg}, nil},
+	} {
+		req, _ := http.NewRequest("POST", "/orders", strings.NewReader(test.body))
+		req.Header.Set("Content-Type", "application/json")
+		c := NewController(NewRequest(req), nil)
+		c.MethodType = &MethodType{Name: "Create", Args: test.args}
+		c.Validation = &Validation{}
+		ParamsFilter(c, NilChain)
+		for _, arg := range test.args {
+			bindArg(c, arg)
+		}
+
+		var errors []string
+		for _, err := range c.Validation.Errors {
+			errors = append(errors, err.Key+": "+err.Message)
+		}
+		sort.Strings(errors)
+		eq(t, "Errors for "+test.body, strings.Join(errors, "\n"), strings.Join(test.expected, "\n"))
+	}
+}
+
+type webhook struct {
+	Signature []byte `bytes:"hex,max=4"`
+	Token     []byte
+}
+
+func TestBytesBinding(t *testing.T) {
+	params := &Params{Values: url.Values{
+		"std":            {"aGk/Pz4+"},
+		"url":            {"aGk_Pz4-"},
+		"unpadded":       {"aGk"},
+		"invalid":        {"a*b"},
+		"long":           {strings.Repeat("QUFB", 2000)},
+		"h.Signature":    {"DEADbeef"},
+		"h.Token":        {"dG9rZW4="},
+		"bad.Signature":  {"xyz"},
+		"long.Signature": {"0102030405"},
+	}}
+	for name, expected := range map[string]string{
+		"std":      "hi??>>",
+		"url":      "hi??>>",
+		"unpadded": "hi",
+		"invalid":  "",
+		"long":     "",
+		"missing":  "",
+	} {
+		eq(t, "Bytes of "+name, string(Bind(params, name, reflect.TypeOf([]byte{})).Bytes()), expected)
+	}
+	h := Bind(params, "h", reflect.TypeOf(webhook{})).Interface().(webhook)
+	eq(t, "Signature", fmt.Sprintf("%x", h.Signature), "deadbeef")
+	eq(t, "Token", string(h.Token), "token")
+	for _, name := range []string{"bad", "long"} {
+		Bind(params, name, reflect.TypeOf(webhook{}))
+	}
+
+	var errors []string
+	for _, err := range params.bindErrors {
+		errors = append(errors, err.Key+": "+err.Message)
+	}
+	sort.Strings(errors)
+	eq(t, "Errors", strings.Join(errors, "\n"), strings.Join([]string{
+		"bad.Signature: The value is not valid hex",
+		"invalid: The value is not valid base64",
+		"long.Signature: The value may be at most 4 bytes",
+		"long: The value may be at most 4096 bytes",
+	}, "\n"))
+}
+
+type listQuery struct {
+	Tags  []string `split:"|"`
+	Names []string `split:"-"`
+	Ids   []int
+}
+
+func TestListBinding(t *testing.T) {
+	params := &Params{Values: url.Values{
+		"ids":     {"1,2", "3", ""},
+		"bad":     {"1,x,3"},
+		"words":   {"a,b"},
+		"grid[0]": {"1,2"},
+		"grid[1]": {"3"},
+		"s.Tags":  {"a,b|c"},
+		"s.Names": {"Smith, John", "Doe, Jane"},
+		"s.Ids":   {"4,5"},
+	}}
+	for _, test := range []struct {
+		name     string
+		typ      reflect.Type
+		expected string
+	}{
+		{"ids", reflect.TypeOf([]int{}), "[1 2 3]"},
+		{"bad", reflect.TypeOf([]int{}), "[1 3]"},
+		{"words", reflect.TypeOf([]string{}), "[a b]"},
+		{"grid", reflect.TypeOf([][]int{}), "[[1 2] [3]]"},
+		{"s", reflect.TypeOf(listQuery{}), "{Tags:[a,b c] Names:[Smith, John Doe, Jane] Ids:[4 5]}"},
+	} {
+		eq(t, "List "+test.name, fmt.Sprintf("%+v", Bind(params, test.name, test.typ).Interface()), test.expected)
+	}
+	if eq(t, "Errors", len(params.bindErrors), 1) {
+		eq(t, "Error", params.bindErrors[0].Key+": "+params.bindErrors[0].Message, `bad: "x" is not a valid int`)
+	}
+
+	defer func(separator string) { ListSeparator = separator }(ListSeparator)
+	ListSeparator = ""
+	eq(t, "Unsplit", fmt.Sprint(Bind(params, "words", reflect.TypeOf([]string{})).Interface()), "[a,b]")
+}
+
+type event struct {
+	Start time.Time  `time:"unix|2006-01-02"`
+	End   *time.Time `time:"02/01/2006"`
+	At    time.Time
+}
+
+func TestTimeFormats(t *testing.T) {
+	defer func(formats []string) { TimeFormats = formats }(TimeFormats)
+	TimeFormats = []string{"unixmilli", time.RFC3339}
+
+	june1 := time.Date(2013, 6, 1, 0, 0, 0, 0, time.UTC)
+	for _, test := range []struct {
+		start, end, at string
+		expected       string
+	}{
+		{"1370044800", "01/06/2013", "2013-06-01T12:00:00+02:00", "2013-06-01 00:00:00 +0000 UTC 2013-06-01 10:00:00 +0000 UTC"},
+		{"2013-06-01", "", "1370044800000", "2013-06-01 00:00:00 +0000 UTC 2013-06-01 00:00:00 +0000 UTC"},
+	} {
+		params := &Params{Values: url.Values{"e.Start": {test.start}, "e.At": {test.at}}}
+		if test.end != "" {
+			params.Values.Set("e.End", test.end)
+		}
+		actual := Bind(params, "e", reflect.TypeOf(event{})).Interface().(event)
+		eq(t, "Start "+test.start, actual.Start.Equal(june1), true)
+		eq(t, "End "+test.end, actual.End == nil || actual.End.Equal(june1), true)
+		eq(t, "Times "+test.at, actual.Start.String()+" "+actual.At.UTC().String(), test.expected)
+		eq(t, "Errors", len(params.bindErrors), 0)
+	}
+
+	params := &Params{Values: url.Values{"e.Start": {"06/01/2013"}}}
+	Bind(params, "e", reflect.TypeOf(event{}))
+	eq(t, "Invalid time", len(params.bindErrors), 1)
+
+	output := map[string]string{}
+	Unbind(output, "e", event{Start: june1, End: &june1, At: june1})
+	eq(t, "Unbound start", output["e.Start"], "1370044800")
+	eq(t, "Unbound end", output["e.End"], "01/06/2013")
+}
+
+func TestScannerBinder(t *testing.T) {
+	params := &Params{Values: url.Values{
+		"count":       {"5"},
+		"name.String": {"Bob"},
+		"big":         {"123456789012345678901234567890"},
+		"bad":         {"x"},
+	}}
+	eq(t, "Scanned", Bind(params, "count", reflect.TypeOf(sql.NullInt64{})).Interface(), sql.NullInt64{Int64: 5, Valid: true})
+	eq(t, "By fields", Bind(params, "name", reflect.TypeOf(sql.NullString{})).Interface(), sql.NullString{String: "Bob"})
+	eq(t, "Big", Bind(params, "big", reflect.TypeOf((*big.Int)(nil))).Interface().(*big.Int).String(), "123456789012345678901234567890")
+
+	Bind(params, "bad", reflect.TypeOf(sql.NullInt64{}))
+	eq(t, "Bad values", len(params.badValues), 1)
+	eq(t, "Validation errors", len(params.bindErrors), 0)
+}
+
 // Helpers
 
 func valEq(t *testing.T, name string, actual, expected reflect.Value) {
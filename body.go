@@ -0,0 +1,259 @@
+package revel
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// A BodyParser parses a request body into the params, typically into their
+// Form, named as a form would name the values, to be bound to the action's
+// arguments.  It returns the error in reading the body.  Bodies that are not
+// valid (e.g. malformed JSON) are better reported as validation errors, as the
+// JSON parser does.
+type BodyParser func(params *Params, req *Request) error
+
+// BodyParsers maps the content types of request bodies to their parsers.  The
+// types may be patterns: "text/*" matches any text, and "application/*+json"
+// any JSON type, such as application/vnd.api+json.  An exact type is preferred
+// to a pattern with a suffix, and that to a pattern of any subtype.  Modules
+// add parsers for other formats, e.g. on initialization:
+//
+//     revel.BodyParsers["text/csv"] = func(params *revel.Params, req *revel.Request) error {
+//     	rows, err := csv.NewReader(req.Body).ReadAll()
+//     	params.Form = url.Values{}
+//     	for i, row := range rows {
+//     		params.Form[fmt.Sprintf("rows[%d][]", i)] = row
+//     	}
+//     	return err
+//     }
+var BodyParsers = map[string]BodyParser{
+	"application/x-www-form-urlencoded": parseForm,
+	"multipart/form-data":               parseMultipart,
+	"application/json":                  parseJson,
+	"application/*+json":                parseJson,
+	"application/xml":                   parseXml,
+	"text/xml":                          parseXml,
+	"application/*+xml":                 parseXml,
+	"application/yaml":                  parseYaml,
+	"application/x-yaml":                parseYaml,
+	"text/yaml":                         parseYaml,
+	"application/*+yaml":                parseYaml,
+	"application/x-protobuf":            parseProto,
+	"application/protobuf":              parseProto,
+	"application/vnd.google.protobuf":   parseProto,
+}
+
+// MaxBodySize limits the size of the request bodies that are read whole to be
+// parsed (e.g. JSON or XML), from "params.maxBody" (e.g. "1MB"), as ParseForm
+// limits forms.  A larger body is answered with 413 Request Entity Too Large.
+// Routes may set their own limit with {maxbody=...}.  0 lifts the limit.
+var MaxBodySize int64 = 10 << 20
+
+func init() {
+	OnAppStart(func() {
+		if size, ok := Config.String("params.maxBody"); ok {
+			var err error
+			if MaxBodySize, err = parseByteSize(size); err != nil {
+				ERROR.Fatalln("Invalid params.maxBody:", size)
+			}
+		}
+	})
+}
+
+// limitBody limits the request body to MaxBodySize, unless the route limits it
+// itself.  Forms and uploads are left to their own limits (see ParseForm and
+// "upload.maxSize").
+func limitBody(c *Controller) {
+	if MaxBodySize <= 0 || c.Request.Body == nil ||
+		c.Route != nil && c.Route.Route != nil && c.Route.Route.maxBody > 0 {
+		return
+	}
+	switch c.Request.ContentType {
+	case "application/x-www-form-urlencoded", "multipart/form-data":
+		return
+	}
+	var w http.ResponseWriter
+	if c.Response != nil {
+		w = c.Response.Out
+	}
+	c.Request.Body = http.MaxBytesReader(w, c.Request.Body, MaxBodySize)
+}
+
+// bodyParser returns the parser for the content type, or nil if there is none.
+func bodyParser(contentType string) BodyParser {
+	if parser, ok := BodyParsers[contentType]; ok {
+		return parser
+	}
+	slash := strings.Index(contentType, "/")
+	if slash < 0 {
+		return nil
+	}
+	if plus := strings.LastIndex(contentType, "+"); plus > slash {
+		if parser, ok := BodyParsers[contentType[:slash+1]+"*"+contentType[plus:]]; ok {
+			return parser
+		}
+	}
+	return BodyParsers[contentType[:slash+1]+"*"]
+}
+
+// parseForm parses a typical form.
+func parseForm(params *Params, req *Request) error {
+	err := req.ParseForm()
+	if err == nil {
+		params.Form = req.Form
+	}
+	return err
+}
+
+// parseJson parses JSON, bound as though it were a form, e.g.
+// {"user": {"name": "Bob"}} as "user.name=Bob".
+func parseJson(params *Params, req *Request) (err error) {
+	if req.Body == nil {
+		return nil
+	}
+	if params.JSON, err = ioutil.ReadAll(req.Body); err != nil || len(params.JSON) == 0 {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(params.JSON))
+	decoder.UseNumber()
+	if decodeErr := decoder.Decode(&params.body); decodeErr != nil {
+		params.bodyErrors = append(params.bodyErrors, &ValidationError{
+			Key:     "body",
+			Message: "Invalid JSON: " + decodeErr.Error(),
+		})
+		return nil
+	}
+	params.Form = make(url.Values)
+	params.bodyKinds = make(map[string]string)
+	params.flattenBody("", params.body)
+	return nil
+}
+
+// maxXmlDepth limits the nesting of the elements of an XML body.
+const maxXmlDepth = 10000
+
+// parseXml parses XML, bound as though it were the JSON object of its root
+// element: an element is the object of its attributes and child elements, or
+// its text if it has neither, and elements that repeat are arrays.  e.g.
+// <order id="3"><item>a</item><item>b</item></order> as "id=3", "item[0]=a"
+// and "item[1]=b".
+func parseXml(params *Params, req *Request) error {
+	if req.Body == nil {
+		return nil
+	}
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil || len(bytes.TrimSpace(body)) == 0 {
+		return err
+	}
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	var root interface{}
+	for root == nil && err == nil {
+		var token xml.Token
+		if token, err = decoder.Token(); err == nil {
+			if start, ok := token.(xml.StartElement); ok {
+				root, err = decodeXml(decoder, start, 1)
+			}
+		}
+	}
+	if err != nil {
+		params.bodyErrors = append(params.bodyErrors, &ValidationError{
+			Key:     "body",
+			Message: "Invalid XML: " + err.Error(),
+		})
+		return nil
+	}
+	params.body = root
+	params.Form = make(url.Values)
+	params.bodyKinds = make(map[string]string)
+	params.flattenBody("", root)
+	return nil
+}
+
+// decodeXml decodes the element that the token starts, as parseXml binds it.
+func decodeXml(decoder *xml.Decoder, start xml.StartElement, depth int) (interface{}, error) {
+	if depth > maxXmlDepth {
+		return nil, errors.New("the elements are nested too deeply")
+	}
+	object := map[string]interface{}{}
+	for _, attr := range start.Attr {
+		object[attr.Name.Local] = attr.Value
+	}
+	var text bytes.Buffer
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			value, err := decodeXml(decoder, t, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			switch existing := object[t.Name.Local].(type) {
+			case nil:
+				object[t.Name.Local] = value
+			case []interface{}:
+				object[t.Name.Local] = append(existing, value)
+			default:
+				object[t.Name.Local] = []interface{}{existing, value}
+			}
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			if len(object) == 0 {
+				return strings.TrimSpace(text.String()), nil
+			}
+			return object, nil
+		}
+	}
+}
+
+// parseProto reads a Protocol Buffers message, bound to the action's argument
+// that is one.
+func parseProto(params *Params, req *Request) (err error) {
+	if req.Body != nil {
+		params.Proto, err = ioutil.ReadAll(req.Body)
+	}
+	return err
+}
+
+// flattenBody adds the values of the decoded body to the form, named as they
+// would be by a form, e.g. {"user": {"name": "Bob", "ids": [1, 2]}} as
+// "user.name=Bob", "user.ids[0]=1" and "user.ids[1]=2".  Nulls are omitted.
+// The kinds of the values are recorded by name, e.g. "user" as "an object",
+// to report those bound to types of another kind (see Bind).
+func (p *Params) flattenBody(name string, value interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		p.bodyKinds[name] = "an object"
+		for key, elem := range v {
+			if name != "" {
+				key = name + "." + key
+			}
+			p.flattenBody(key, elem)
+		}
+	case []interface{}:
+		p.bodyKinds[name] = "an array"
+		for i, elem := range v {
+			p.flattenBody(fmt.Sprintf("%s[%d]", name, i), elem)
+		}
+	case nil:
+	case string:
+		p.bodyKinds[name] = "a string"
+		p.Form.Add(name, v)
+	case bool:
+		p.bodyKinds[name] = "a boolean"
+		p.Form.Add(name, fmt.Sprint(v))
+	default:
+		p.bodyKinds[name] = "a number"
+		p.Form.Add(name, fmt.Sprint(v))
+	}
+}
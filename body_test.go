@@ -0,0 +1,100 @@
+package revel
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBodyParsers(t *testing.T) {
+	BodyParsers["text/*"] = func(params *Params, req *Request) error {
+		rows, err := csv.NewReader(req.Body).ReadAll()
+		params.Form = url.Values{}
+		for i, row := range rows {
+			params.Form[fmt.Sprintf("rows[%d][]", i)] = row
+		}
+		return err
+	}
+	defer delete(BodyParsers, "text/*")
+
+	userArg := &MethodArg{"user", reflect.TypeOf((*jsonUser)(nil))}
+	for _, test := range []struct {
+		contentType, body string
+		expected          string
+	}{
+		{"application/json", `{"name": "Bob", "tags": ["a", "b"]}`, "{Name:Bob Age:0 Tags:[a b] Address:{City:}}"},
+		{"application/vnd.api+json", `{"name": "Bob", "age": 30}`, "{Name:Bob Age:30 Tags:[] Address:{City:}}"},
+		{"application/xml", `<?xml version="1.0"?>
+			<user age="30"><name>Bob</name><tags>a</tags><tags>b</tags><address><city>NYC</city></address></user>`,
+			"{Name:Bob Age:30 Tags:[a b] Address:{City:NYC}}"},
+		{"application/atom+xml", `<user><user><name>Bob</name></user></user>`, "{Name:Bob Age:0 Tags:[] Address:{City:}}"},
+		{"text/csv", "name,Bob\nage,30\n", "{Name: Age:0 Tags:[] Address:{City:}}"},
+		{"application/octet-stream", `{"name": "Bob"}`, "{Name: Age:0 Tags:[] Address:{City:}}"},
+	} {
+		req, _ := http.NewRequest("POST", "/users", strings.NewReader(test.body))
+		req.Header.Set("Content-Type", test.contentType)
+		c := NewController(NewRequest(req), nil)
+		c.MethodType = &MethodType{Name: "Create", Args: []*MethodArg{userArg}}
+		ParamsFilter(c, NilChain)
+
+		user := Bind(c.Params, "user", userArg.Type).Interface().(*jsonUser)
+		eq(t, "User from "+test.contentType, fmt.Sprintf("%+v", *user), test.expected)
+		if test.contentType == "text/csv" {
+			eq(t, "CSV rows", fmt.Sprint(c.Params.Values["rows[1][]"]), "[age 30]")
+		}
+	}
+
+	req, _ := http.NewRequest("POST", "/users", strings.NewReader(`<user><name>Bob</user>`))
+	req.Header.Set("Content-Type", "text/xml")
+	c := NewController(NewRequest(req), nil)
+	ParamsFilter(c, NilChain)
+	if eq(t, "Body errors", len(c.Params.bodyErrors), 1) {
+		eq(t, "Body error", strings.HasPrefix(c.Params.bodyErrors[0].Message, "Invalid XML"), true)
+	}
+}
+
+// Test that bodies read whole are limited to MaxBodySize, unless the route
+// sets its own maxbody, and that forms are left to their own limit.
+func TestMaxBodySize(t *testing.T) {
+	startFakeBookingApp()
+	defer func(size int64) { MaxBodySize = size }(MaxBodySize)
+	MaxBodySize = 1 << 10
+
+	post := func(path, contentType, body string) (called bool, status int) {
+		req, _ := http.NewRequest("POST", path, strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		req.ContentLength = -1
+		resp := httptest.NewRecorder()
+		c := NewController(NewRequest(req), NewResponse(resp))
+		c.Route = MainRouter.Route(req)
+		ParamsFilter(c, []Filter{func(*Controller, []Filter) { called = true }})
+		return called, c.Response.Status
+	}
+
+	large := `{"name": "` + strings.Repeat("x", 2<<10) + `"}`
+	for _, contentType := range []string{"application/json", "application/xml", "application/yaml", "application/x-protobuf"} {
+		called, status := post("/users", contentType, large)
+		eq(t, "Called action with large "+contentType, called, false)
+		eq(t, "Status of large "+contentType, status, http.StatusRequestEntityTooLarge)
+	}
+	called, _ := post("/users", "application/json", `{"name": "Bob"}`)
+	eq(t, "Called action with small JSON", called, true)
+	called, _ = post("/users", "application/x-www-form-urlencoded", "name="+strings.Repeat("x", 2<<10))
+	eq(t, "Called action with large form", called, true)
+
+	defer func(router *Router) { MainRouter = router }(MainRouter)
+	MainRouter = NewRouter("")
+	MainRouter.Routes, _ = parseRoutes("", "POST /users Hotels.Index {maxbody=4KB}", false)
+	MainRouter.updateTree()
+	called, _ = post("/users", "application/json", large)
+	eq(t, "Called action with JSON under the route's maxbody", called, true)
+
+	MaxBodySize = 0
+	called, _ = post("/hotels", "application/json", large)
+	eq(t, "Called action without a limit", called, true)
+}
@@ -0,0 +1,311 @@
+package revel
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// An Encoder returns a writer that compresses what is written to it into w, at
+// the level, or at its default level if the level is -1.  Closing it finishes
+// the compressed stream (but does not close w).
+type Encoder func(w io.Writer, level int) io.WriteCloser
+
+// Encoders maps content codings (e.g. "gzip") to the encoders that the
+// CompressFilter compresses responses with.  Brotli ("br") is added by
+// building with the brotli tag (go build -tags brotli), which needs
+// github.com/andybalholm/brotli.  Modules may add others.
+//
+// Of the codings that a request accepts equally, those of encodingPreference
+// are preferred, in its order.
+var Encoders = map[string]Encoder{
+	"gzip": func(w io.Writer, level int) io.WriteCloser {
+		writer, err := gzip.NewWriterLevel(w, level)
+		if err != nil {
+			writer = gzip.NewWriter(w)
+		}
+		return writer
+	},
+	"deflate": func(w io.Writer, level int) io.WriteCloser {
+		writer, err := flate.NewWriter(w, level)
+		if err != nil {
+			writer, _ = flate.NewWriter(w, flate.DefaultCompression)
+		}
+		return writer
+	},
+}
+
+// encodingPreference orders the content codings that a request accepts
+// equally, from most preferred.
+var encodingPreference = []string{"br", "gzip", "deflate"}
+
+var (
+	// CompressLevel is the level that responses are compressed at, from
+	// "results.compressLevel", e.g. 1 (fastest) to 9 (smallest) for gzip, or
+	// -1 for the default level of each coding.
+	CompressLevel = -1
+
+	// CompressMinSize is the size of the smallest response that is compressed,
+	// in bytes, from "results.compressMinSize".  Smaller ones are sent as they
+	// are, as compressing them saves little, if anything.
+	CompressMinSize = 1024
+
+	// compressTypes are the content types of the responses that are
+	// compressed, from "results.compressTypes", as patterns such as those of
+	// BodyParsers, e.g. "text/*" or "application/*+json".
+	compressTypes = defaultCompressTypes
+)
+
+var defaultCompressTypes = map[string]bool{
+	"text/*":                 true,
+	"application/json":       true,
+	"application/*+json":     true,
+	"application/x-ndjson":   true,
+	"application/javascript": true,
+	"application/xml":        true,
+	"application/*+xml":      true,
+	"image/svg+xml":          true,
+}
+
+func init() {
+	OnAppStart(func() {
+		CompressLevel = Config.IntDefault("results.compressLevel", CompressLevel)
+		if CompressLevel < -1 {
+			ERROR.Fatalln("Invalid results.compressLevel:", CompressLevel)
+		}
+		CompressMinSize = Config.IntDefault("results.compressMinSize", CompressMinSize)
+		compressTypes = defaultCompressTypes
+		if types, ok := Config.String("results.compressTypes"); ok {
+			compressTypes = map[string]bool{}
+			for _, contentType := range strings.Split(types, ",") {
+				if contentType = strings.TrimSpace(contentType); contentType != "" {
+					compressTypes[contentType] = true
+				}
+			}
+		}
+	})
+}
+
+// CompressFilter compresses responses by the best coding (of Encoders) that
+// the request's Accept-Encoding accepts, if their content type is one of
+// "results.compressTypes" and they are at least CompressMinSize.  Responses
+// are compressed as they are written, holding no more than CompressMinSize of
+// them, so large ones (and streams, which are flushed as they are written)
+// take no more memory than small ones.  The filter is opt-in, e.g.
+//   revel.Filters = []revel.Filter{..., revel.PanicFilter, revel.CompressFilter, ...}
+// It should precede the ETagFilter, if both are used, so that ETags are given
+// to the content before it is compressed.
+func CompressFilter(c *Controller, fc []Filter) {
+	encoding := acceptedEncoding(c.Request.Header.Get("Accept-Encoding"))
+	if encoding == "" || c.Request.Websocket != nil {
+		fc[0](c, fc[1:])
+		return
+	}
+
+	w := &compressWriter{w: c.Response.Out, encoding: encoding, head: c.Request.Method == "HEAD"}
+	c.Response.Out = w
+	// The result is applied once the filters have returned, so the response
+	// is finished after that, as the request is cleaned up.  If the action
+	// panics, what it has written is dropped, for the error.
+	completed := false
+	c.Cleanup(func() {
+		if completed {
+			w.close()
+		}
+	})
+	defer func() {
+		if !completed {
+			c.Response.Out = w.w
+		}
+	}()
+	fc[0](c, fc[1:])
+	completed = true
+}
+
+// acceptedEncoding returns the content coding of Encoders that the
+// Accept-Encoding header prefers, or "" if it accepts none of them.
+// e.g. "gzip;q=0.5, br" => "br" (if it is registered), "identity" => ""
+func acceptedEncoding(acceptEncoding string) string {
+	qualities := map[string]float64{}
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		fields := strings.Split(part, ";")
+		coding := strings.ToLower(strings.TrimSpace(fields[0]))
+		quality := 1.0
+		for _, param := range fields[1:] {
+			if name, value, ok := strings.Cut(strings.TrimSpace(param), "="); ok && strings.TrimSpace(name) == "q" {
+				if q, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+					quality = q
+				}
+			}
+		}
+		if coding != "" {
+			qualities[coding] = quality
+		}
+	}
+
+	quality := func(coding string) float64 {
+		if quality, ok := qualities[coding]; ok {
+			return quality
+		}
+		return qualities["*"]
+	}
+	rank := func(coding string) int {
+		for i, preferred := range encodingPreference {
+			if coding == preferred {
+				return i
+			}
+		}
+		return len(encodingPreference)
+	}
+	var codings []string
+	for coding := range Encoders {
+		if quality(coding) > 0 {
+			codings = append(codings, coding)
+		}
+	}
+	sort.Slice(codings, func(i, j int) bool {
+		if qi, qj := quality(codings[i]), quality(codings[j]); qi != qj {
+			return qi > qj
+		}
+		if ri, rj := rank(codings[i]), rank(codings[j]); ri != rj {
+			return ri < rj
+		}
+		return codings[i] < codings[j]
+	})
+	if len(codings) == 0 {
+		return ""
+	}
+	return codings[0]
+}
+
+// compressible returns true if responses of the content type are compressed.
+func compressible(contentType string) bool {
+	contentType = strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	if compressTypes[contentType] {
+		return true
+	}
+	slash := strings.Index(contentType, "/")
+	if slash < 0 {
+		return false
+	}
+	if plus := strings.LastIndex(contentType, "+"); plus > slash && compressTypes[contentType[:slash+1]+"*"+contentType[plus:]] {
+		return true
+	}
+	return compressTypes[contentType[:slash+1]+"*"]
+}
+
+// compressWriter is a ResponseWriter that compresses the response as it is
+// written, once it has decided to: when CompressMinSize of it has been
+// written, or it is flushed or finished.  Until then, the response (and its
+// header) is held.
+type compressWriter struct {
+	w        http.ResponseWriter
+	encoding string
+	head     bool
+
+	status      int
+	wroteHeader bool
+	decided     bool
+	held        []byte
+	encoder     io.WriteCloser // the encoder, if the response is compressed
+}
+
+func (w *compressWriter) Header() http.Header {
+	return w.w.Header()
+}
+
+func (w *compressWriter) WriteHeader(status int) {
+	if isInformational(status) && !w.wroteHeader {
+		w.w.WriteHeader(status)
+		return
+	}
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.status = status
+	if w.head || status < http.StatusOK || status == http.StatusNoContent ||
+		status == http.StatusNotModified || status == http.StatusPartialContent {
+		// There is no body to compress.
+		w.decide(false)
+	}
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	if !w.decided {
+		if len(w.held)+len(b) < CompressMinSize {
+			w.held = append(w.held, b...)
+			return len(b), nil
+		}
+		w.decide(true)
+	}
+	if w.encoder != nil {
+		return w.encoder.Write(b)
+	}
+	return w.w.Write(b)
+}
+
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.w
+}
+
+// Flush sends what has been written so far, compressed if the response is
+// compressible, whatever its size, as it is being streamed.
+func (w *compressWriter) Flush() {
+	if !w.wroteHeader {
+		return
+	}
+	if !w.decided {
+		w.decide(true)
+	}
+	if flusher, ok := w.encoder.(interface{ Flush() error }); ok {
+		flusher.Flush()
+	}
+	if flusher, ok := w.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// close finishes the response, deciding whether to compress it by its size if
+// it has not already.
+func (w *compressWriter) close() {
+	if !w.wroteHeader {
+		return
+	}
+	if !w.decided {
+		w.decide(len(w.held) >= CompressMinSize)
+	}
+	if w.encoder != nil {
+		w.encoder.Close()
+	}
+}
+
+// decide sends the header, compressing the response if it may (e.g. it is
+// large enough) and its content is compressible, and then what is held.
+func (w *compressWriter) decide(mayCompress bool) {
+	w.decided = true
+	header := w.w.Header()
+	if w.status != http.StatusNotModified && compressible(header.Get("Content-Type")) {
+		header.Add("Vary", "Accept-Encoding")
+		if mayCompress && header.Get("Content-Encoding") == "" {
+			header.Set("Content-Encoding", w.encoding)
+			header.Del("Content-Length")
+			w.encoder = Encoders[w.encoding](w.w, CompressLevel)
+		}
+	}
+	w.w.WriteHeader(w.status)
+	if len(w.held) == 0 {
+		return
+	}
+	if w.encoder != nil {
+		w.encoder.Write(w.held)
+	} else {
+		w.w.Write(w.held)
+	}
+	w.held = nil
+}
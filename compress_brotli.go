@@ -0,0 +1,20 @@
+//go:build brotli
+// +build brotli
+
+package revel
+
+import (
+	"github.com/andybalholm/brotli"
+	"io"
+)
+
+// Apps built with the brotli tag (go build -tags brotli) compress responses
+// with brotli too, for the requests that accept it.
+func init() {
+	Encoders["br"] = func(w io.Writer, level int) io.WriteCloser {
+		if level < brotli.BestSpeed || level > brotli.BestCompression {
+			level = brotli.DefaultCompression
+		}
+		return brotli.NewWriterLevel(w, level)
+	}
+}
@@ -0,0 +1,42 @@
+//go:build brotli
+// +build brotli
+
+package revel
+
+import (
+	"github.com/andybalholm/brotli"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBrotli(t *testing.T) {
+	large := strings.Repeat("A Hotel, ", 200)
+	for _, test := range []struct {
+		acceptEncoding, encoding string
+	}{
+		{"br", "br"},
+		{"gzip, deflate, br", "br"},
+		{"gzip, br;q=0.5", "gzip"},
+	} {
+		req, _ := http.NewRequest("GET", "/hotels", nil)
+		req.Header.Set("Accept-Encoding", test.acceptEncoding)
+		resp := httptest.NewRecorder()
+		c := NewController(NewRequest(req), NewResponse(resp))
+		CompressFilter(c, []Filter{func(c *Controller, _ []Filter) {
+			c.Response.WriteHeader(http.StatusOK, "application/json")
+			io.WriteString(c.Response.Out, large)
+		}})
+		c.cleanup()
+
+		eq(t, "Content-Encoding for "+test.acceptEncoding, resp.Header().Get("Content-Encoding"), test.encoding)
+		if test.encoding == "br" {
+			b, err := ioutil.ReadAll(brotli.NewReader(resp.Body))
+			eq(t, "Read error", err, nil)
+			eq(t, "Body", string(b), large)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package revel
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type nopEncoder struct{ io.Writer }
+
+func (nopEncoder) Close() error { return nil }
+
+func TestAcceptedEncoding(t *testing.T) {
+	Encoders["br"] = func(w io.Writer, level int) io.WriteCloser { return nopEncoder{w} }
+	defer delete(Encoders, "br")
+
+	for acceptEncoding, expected := range map[string]string{
+		"":                      "",
+		"identity":              "",
+		"gzip":                  "gzip",
+		"gzip, deflate, br":     "br",
+		"gzip;q=1.0, br;q=0.5":  "gzip",
+		"*":                     "br",
+		"*, br;q=0":             "gzip",
+		"gzip;q=0, deflate":     "deflate",
+		"GZIP, x-unknown;q=0.9": "gzip",
+	} {
+		eq(t, "Encoding for "+acceptEncoding, acceptedEncoding(acceptEncoding), expected)
+	}
+}
+
+func TestCompressFilter(t *testing.T) {
+	large := strings.Repeat("A Hotel, ", 200)
+	for _, test := range []struct {
+		name, acceptEncoding, contentType, body string
+		encoding                                string
+	}{
+		{"Large JSON", "gzip, deflate", "application/json", large, "gzip"},
+		{"Deflate", "deflate", "text/html; charset=utf-8", large, "deflate"},
+		{"Small", "gzip", "application/json", "{}", ""},
+		{"Image", "gzip", "image/png", large, ""},
+		{"Not accepted", "identity", "application/json", large, ""},
+	} {
+		req, _ := http.NewRequest("GET", "/hotels", nil)
+		req.Header.Set("Accept-Encoding", test.acceptEncoding)
+		resp := httptest.NewRecorder()
+		c := NewController(NewRequest(req), NewResponse(resp))
+		CompressFilter(c, []Filter{func(c *Controller, _ []Filter) {
+			c.Response.WriteHeader(http.StatusOK, test.contentType)
+			// Written in parts, to be compressed as they are written.
+			for i := 0; i < len(test.body); i += 100 {
+				io.WriteString(c.Response.Out, test.body[i:min(i+100, len(test.body))])
+			}
+		}})
+		c.cleanup()
+
+		eq(t, "Content-Encoding: "+test.name, resp.Header().Get("Content-Encoding"), test.encoding)
+		var body io.Reader = resp.Body
+		switch test.encoding {
+		case "gzip":
+			body, _ = gzip.NewReader(resp.Body)
+		case "deflate":
+			body = flate.NewReader(resp.Body)
+		}
+		b, err := ioutil.ReadAll(body)
+		eq(t, "Read error: "+test.name, err, nil)
+		eq(t, "Body: "+test.name, string(b), test.body)
+	}
+
+	// Streams are compressed as they are flushed, whatever their size.
+	req, _ := http.NewRequest("GET", "/hotels", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	resp := httptest.NewRecorder()
+	c := NewController(NewRequest(req), NewResponse(resp))
+	CompressFilter(c, []Filter{func(c *Controller, _ []Filter) {
+		items := make(chan string, 1)
+		items <- "A Hotel"
+		close(items)
+		c.RenderNDJson(items).Apply(c.Request, c.Response)
+	}})
+	c.cleanup()
+	eq(t, "Content-Encoding of stream", resp.Header().Get("Content-Encoding"), "gzip")
+	eq(t, "Flushed", resp.Flushed, true)
+	if reader, err := gzip.NewReader(resp.Body); eq(t, "Gzip error", err, nil) {
+		b, _ := ioutil.ReadAll(reader)
+		eq(t, "Stream", string(b), "\"A Hotel\"\n")
+	}
+}
+
+// Test that the results of actions, which the server applies once the filters
+// have returned, are compressed.
+func TestCompressResult(t *testing.T) {
+	startFakeBookingApp()
+	defer func(filters []Filter) { Filters = filters }(Filters)
+	hotels := make([]map[string]string, 50)
+	for i := range hotels {
+		hotels[i] = map[string]string{"name": "A Hotel", "city": "New York"}
+	}
+	Filters = []Filter{PanicFilter, CompressFilter, func(c *Controller, _ []Filter) {
+		if c.Request.URL.Path == "/panic" {
+			panic("no hotels")
+		}
+		c.Result = c.RenderJson(hotels)
+	}}
+
+	req, _ := http.NewRequest("GET", "/hotels", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	resp := httptest.NewRecorder()
+	handle(resp, req)
+	eq(t, "Content-Encoding", resp.Header().Get("Content-Encoding"), "gzip")
+	if reader, err := gzip.NewReader(resp.Body); eq(t, "Gzip error", err, nil) {
+		var decoded []map[string]string
+		eq(t, "JSON error", json.NewDecoder(reader).Decode(&decoded), nil)
+		eq(t, "Hotels", fmt.Sprint(decoded), fmt.Sprint(hotels))
+	}
+
+	// What a panicking action has written is dropped, for the error.
+	req, _ = http.NewRequest("GET", "/panic", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	resp = httptest.NewRecorder()
+	handle(resp, req)
+	eq(t, "Status of panic", resp.Code, http.StatusInternalServerError)
+	eq(t, "Content-Encoding of panic", resp.Header().Get("Content-Encoding"), "")
+}
@@ -0,0 +1,238 @@
+package revel
+
+import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// TemplateETags, if set, gives each rendered template an ETag of its content,
+// so that a request for an unchanged page is answered with 304 Not Modified
+// (though the template is still rendered).  It is set by "results.etag", which
+// defaults to false.
+var TemplateETags bool
+
+// ETagMaxBody is the size of the largest response that the ETagFilter holds
+// to hash, from "results.etagMaxBody", in bytes.  Larger responses are sent
+// as they are written, without an ETag.
+var ETagMaxBody = 1 << 20
+
+func init() {
+	OnAppStart(func() {
+		TemplateETags = Config.BoolDefault("results.etag", false)
+		ETagMaxBody = Config.IntDefault("results.etagMaxBody", ETagMaxBody)
+	})
+}
+
+// ETagFilter gives the successful responses of GET (and HEAD) requests an ETag
+// of their content, so that requests for unchanged ones are answered with 304
+// Not Modified, whatever they render: JSON, XML or pages.  The response is
+// held until the action has rendered it, to hash it, except for responses
+// that have an ETag already (see Controller.ETag), that are larger than
+// ETagMaxBody, or that are flushed as they are written, e.g. event streams,
+// which are sent as they are.  The action still does the work of rendering,
+// so those that can tell that nothing has changed more cheaply should use
+// Controller.ETag.  The filter is opt-in, e.g.
+//   revel.Filters = []revel.Filter{..., revel.PanicFilter, revel.ETagFilter, ...}
+// or for some routes, as a named filter:
+//   revel.NamedFilters["etag"] = revel.ETagFilter
+func ETagFilter(c *Controller, fc []Filter) {
+	if !isConditionalMethod(c.Request.Method) || c.Request.Websocket != nil {
+		fc[0](c, fc[1:])
+		return
+	}
+
+	w := &etagWriter{w: c.Response.Out}
+	c.Response.Out = w
+	// The result is applied once the filters have returned, so the response
+	// is hashed after that, as the request is cleaned up.  If the action
+	// panics, what it has written is dropped, for the error.
+	completed := false
+	c.Cleanup(func() {
+		if completed {
+			w.finish(c)
+		}
+	})
+	defer func() {
+		if !completed {
+			c.Response.Out = w.w
+		}
+	}()
+	fc[0](c, fc[1:])
+	completed = true
+}
+
+// etagWriter is a ResponseWriter that holds a successful response, for the
+// ETagFilter to hash, until it is sent.
+type etagWriter struct {
+	w           http.ResponseWriter
+	status      int
+	body        bytes.Buffer
+	wroteHeader bool
+	holding     bool // true until the response is sent
+}
+
+func (w *etagWriter) Header() http.Header {
+	return w.w.Header()
+}
+
+func (w *etagWriter) WriteHeader(status int) {
+	if isInformational(status) && !w.wroteHeader {
+		w.w.WriteHeader(status)
+		return
+	}
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.status = status
+	w.holding = status == http.StatusOK && w.w.Header().Get("ETag") == ""
+	if !w.holding {
+		w.w.WriteHeader(status)
+	}
+}
+
+func (w *etagWriter) Write(b []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	if !w.holding {
+		return w.w.Write(b)
+	}
+	if w.body.Len()+len(b) > ETagMaxBody {
+		w.send()
+		return w.w.Write(b)
+	}
+	return w.body.Write(b)
+}
+
+func (w *etagWriter) Unwrap() http.ResponseWriter {
+	return w.w
+}
+
+// Flush sends the response, which is being streamed, as it is.
+func (w *etagWriter) Flush() {
+	if w.holding {
+		w.send()
+	}
+	if flusher, ok := w.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// finish sends the response held, or answers the request with 304 Not
+// Modified if its If-None-Match has the response's ETag.
+func (w *etagWriter) finish(c *Controller) {
+	if !w.holding {
+		return
+	}
+	if contentETag(c.Request, &Response{Out: w.w}, w.body.Bytes()) {
+		c.Response.Status = http.StatusNotModified
+		NotModifiedResult{}.Apply(c.Request, &Response{Out: w.w})
+		return
+	}
+	w.send()
+}
+
+// send sends the response held so far, and the rest as it is written.
+func (w *etagWriter) send() {
+	w.holding = false
+	w.w.WriteHeader(w.status)
+	w.body.WriteTo(w.w)
+}
+
+// ETag sets the ETag of the response.  If the request is a GET (or HEAD) whose
+// If-None-Match has the tag, it returns a result of 304 Not Modified, which the
+// action should return before doing the work of rendering the response, e.g.
+//
+//     if notModified := c.ETag(hotel.Version); notModified != nil {
+//     	return notModified
+//     }
+//
+// Else, it returns nil.  The tag is quoted, unless it is already, e.g. `"v2"`
+// or `W/"v2"`.
+func (c *Controller) ETag(tag string) Result {
+	etag := quoteETag(tag)
+	c.Response.Out.Header().Set("ETag", etag)
+	if !isConditionalMethod(c.Request.Method) {
+		return nil
+	}
+	if etagMatches(c.Request.Header.Get("If-None-Match"), etag) {
+		return NotModifiedResult{}
+	}
+	return nil
+}
+
+// LastModified sets the Last-Modified time of the response.  If the request is
+// a GET (or HEAD) whose If-Modified-Since is no earlier, it returns a result
+// of 304 Not Modified, which the action should return, as for ETag.  Else, it
+// returns nil.  If-Modified-Since is ignored if the request has an
+// If-None-Match.
+func (c *Controller) LastModified(t time.Time) Result {
+	t = t.UTC().Truncate(time.Second)
+	c.Response.Out.Header().Set("Last-Modified", t.Format(http.TimeFormat))
+	if !isConditionalMethod(c.Request.Method) || c.Request.Header.Get("If-None-Match") != "" {
+		return nil
+	}
+	since, err := http.ParseTime(c.Request.Header.Get("If-Modified-Since"))
+	if err == nil && !t.After(since) {
+		return NotModifiedResult{}
+	}
+	return nil
+}
+
+// NotModifiedResult answers a conditional request with 304 Not Modified, with
+// no body.
+type NotModifiedResult struct{}
+
+func (r NotModifiedResult) Apply(req *Request, resp *Response) {
+	header := resp.Out.Header()
+	header.Del("Content-Type")
+	header.Del("Content-Length")
+	resp.Status = http.StatusNotModified
+	resp.Out.WriteHeader(http.StatusNotModified)
+}
+
+// contentETag sets the ETag of the response to the hash of its content, and
+// returns true if the request's If-None-Match has it, so that it may be
+// answered with 304 Not Modified.
+func contentETag(req *Request, resp *Response, content []byte) bool {
+	hash := fnv.New64a()
+	hash.Write(content)
+	etag := fmt.Sprintf(`"%x"`, hash.Sum64())
+	resp.Out.Header().Set("ETag", etag)
+	return isConditionalMethod(req.Method) && etagMatches(req.Header.Get("If-None-Match"), etag)
+}
+
+// isConditionalMethod returns true if requests of the method may be answered
+// with 304 Not Modified.
+func isConditionalMethod(method string) bool {
+	return method == "GET" || method == "HEAD"
+}
+
+// quoteETag quotes the tag, unless it is already.
+// e.g. `v2` => `"v2"`, `W/"v2"` => `W/"v2"`
+func quoteETag(tag string) string {
+	if strings.HasSuffix(tag, `"`) && (strings.HasPrefix(tag, `"`) || strings.HasPrefix(tag, `W/"`)) {
+		return tag
+	}
+	return `"` + tag + `"`
+}
+
+// etagMatches returns true if the If-None-Match header has the ETag, or is
+// "*".  ETags are compared weakly, ignoring any "W/" prefix.
+// e.g. (`"v1", W/"v2"`, `"v2"`) => true
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
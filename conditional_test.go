@@ -0,0 +1,158 @@
+package revel
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestETag(t *testing.T) {
+	for _, test := range []struct {
+		method, ifNoneMatch, tag string
+		notModified              bool
+	}{
+		{"GET", "", "v2", false},
+		{"GET", `"v1"`, "v2", false},
+		{"GET", `"v1", "v2"`, "v2", true},
+		{"GET", `W/"v2"`, "v2", true},
+		{"HEAD", `"v2"`, `W/"v2"`, true},
+		{"GET", "*", "v2", true},
+		{"POST", `"v2"`, "v2", false},
+	} {
+		req, _ := http.NewRequest(test.method, "/hotels/3", nil)
+		req.Header.Set("If-None-Match", test.ifNoneMatch)
+		resp := httptest.NewRecorder()
+		c := NewController(NewRequest(req), NewResponse(resp))
+
+		result := c.ETag(test.tag)
+		name := test.method + " " + test.ifNoneMatch + " for " + test.tag
+		eq(t, "Not modified: "+name, result != nil, test.notModified)
+		eq(t, "ETag: "+name, resp.Header().Get("ETag"), quoteETag(test.tag))
+		if result != nil {
+			result.Apply(c.Request, c.Response)
+			eq(t, "Status: "+name, resp.Code, http.StatusNotModified)
+		}
+	}
+}
+
+func TestLastModified(t *testing.T) {
+	modified := time.Date(2013, 6, 1, 12, 0, 0, 500, time.UTC)
+	for _, test := range []struct {
+		ifModifiedSince, ifNoneMatch string
+		notModified                  bool
+	}{
+		{"", "", false},
+		{"Sat, 01 Jun 2013 12:00:00 GMT", "", true},
+		{"Sat, 01 Jun 2013 13:00:00 GMT", "", true},
+		{"Sat, 01 Jun 2013 11:59:59 GMT", "", false},
+		{"Sat, 01 Jun 2013 12:00:00 GMT", `"v1"`, false},
+	} {
+		req, _ := http.NewRequest("GET", "/hotels/3", nil)
+		req.Header.Set("If-Modified-Since", test.ifModifiedSince)
+		req.Header.Set("If-None-Match", test.ifNoneMatch)
+		resp := httptest.NewRecorder()
+		c := NewController(NewRequest(req), NewResponse(resp))
+
+		result := c.LastModified(modified)
+		eq(t, "Not modified since "+test.ifModifiedSince, result != nil, test.notModified)
+		eq(t, "Last-Modified", resp.Header().Get("Last-Modified"), "Sat, 01 Jun 2013 12:00:00 GMT")
+	}
+}
+
+func TestTemplateETags(t *testing.T) {
+	startFakeBookingApp()
+	defer func(etags bool) { TemplateETags = etags }(TemplateETags)
+	TemplateETags = true
+
+	req, _ := http.NewRequest("GET", "/hotels/3", nil)
+	resp := httptest.NewRecorder()
+	handle(resp, req)
+	etag := resp.Header().Get("ETag")
+	eq(t, "Status", resp.Code, http.StatusOK)
+	eq(t, "Has ETag", etag != "", true)
+
+	req, _ = http.NewRequest("GET", "/hotels/3", nil)
+	req.Header.Set("If-None-Match", etag)
+	resp = httptest.NewRecorder()
+	handle(resp, req)
+	eq(t, "Status if not modified", resp.Code, http.StatusNotModified)
+	eq(t, "Body if not modified", resp.Body.Len(), 0)
+}
+
+func TestETagFilter(t *testing.T) {
+	startFakeBookingApp()
+	defer func(filters []Filter) { Filters = filters }(Filters)
+	defer func(max int) { ETagMaxBody = max }(ETagMaxBody)
+	ETagMaxBody = 32
+	hotel := map[string]string{"name": "A Hotel"}
+	for _, test := range []struct {
+		name        string
+		method      string
+		render      func(c *Controller) Result
+		ifNoneMatch bool
+		status      int
+		etag        bool
+	}{
+		{"JSON", "GET", func(c *Controller) Result { return c.RenderJson(hotel) }, false, http.StatusOK, true},
+		{"JSON not modified", "GET", func(c *Controller) Result { return c.RenderJson(hotel) }, true, http.StatusNotModified, true},
+		{"Text not modified", "HEAD", func(c *Controller) Result { return c.RenderText("A Hotel") }, true, http.StatusNotModified, true},
+		{"POST", "POST", func(c *Controller) Result { return c.RenderJson(hotel) }, true, http.StatusOK, false},
+		{"Not found", "GET", func(c *Controller) Result { return c.NotFound("No hotel") }, true, http.StatusNotFound, false},
+		{"Too large", "GET", func(c *Controller) Result { return c.RenderText("%s", strings.Repeat("A Hotel", 10)) }, true, http.StatusOK, false},
+		{"Stream", "GET", func(c *Controller) Result {
+			items := make(chan string, 1)
+			items <- "A Hotel"
+			close(items)
+			return c.RenderNDJson(items)
+		}, true, http.StatusOK, false},
+	} {
+		var etag string
+		for _, conditional := range []bool{false, test.ifNoneMatch} {
+			req, _ := http.NewRequest(test.method, "/hotels/3", nil)
+			if conditional {
+				req.Header.Set("If-None-Match", etag)
+			}
+			resp := httptest.NewRecorder()
+			Filters = []Filter{ETagFilter, func(c *Controller, _ []Filter) {
+				c.Result = test.render(c)
+			}}
+			handle(resp, req)
+			if !conditional {
+				etag = resp.Header().Get("ETag")
+				eq(t, "Has ETag: "+test.name, etag != "", test.etag)
+				eq(t, "Body: "+test.name, resp.Body.Len() > 0, true)
+				continue
+			}
+			eq(t, "Status: "+test.name, resp.Code, test.status)
+			if test.status == http.StatusNotModified {
+				eq(t, "Body if not modified: "+test.name, resp.Body.Len(), 0)
+			}
+		}
+	}
+}
+
+// Test that ETags are given to the content of responses before they are
+// compressed.
+func TestETagCompressed(t *testing.T) {
+	startFakeBookingApp()
+	defer func(filters []Filter) { Filters = filters }(Filters)
+	Filters = []Filter{CompressFilter, ETagFilter, func(c *Controller, _ []Filter) {
+		c.Result = c.RenderText("%s", strings.Repeat("A Hotel, ", 200))
+	}}
+
+	req, _ := http.NewRequest("GET", "/hotels", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	resp := httptest.NewRecorder()
+	handle(resp, req)
+	etag := resp.Header().Get("ETag")
+	eq(t, "Has ETag", etag != "", true)
+	eq(t, "Content-Encoding", resp.Header().Get("Content-Encoding"), "gzip")
+
+	req.Header.Set("If-None-Match", etag)
+	resp = httptest.NewRecorder()
+	handle(resp, req)
+	eq(t, "Status if not modified", resp.Code, http.StatusNotModified)
+	eq(t, "Body if not modified", resp.Body.Len(), 0)
+}
@@ -1,14 +1,18 @@
 package revel
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"reflect"
 	"runtime"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -19,6 +23,8 @@ type Controller struct {
 	MethodType    *MethodType     // A description of the invoked action type.
 	AppController interface{}     // The controller that was instantiated.
 	Action        string          // The fully qualified action name, e.g. "App.Index"
+	Route         *RouteMatch     // The route that matched the request.
+	RequestId     string          // The request's ID, set by the RequestIdFilter.
 
 	Request  *Request
 	Response *Response
@@ -27,13 +33,91 @@ type Controller struct {
 	Flash      Flash                  // User cookie, cleared after 1 request.
 	Session    Session                // Session, stored in cookie, signed.
 	Params     *Params                // Parameters from URL and form (including multipart).
-	Args       map[string]interface{} // Per-request scratch space.
+	Args       map[string]interface{} // Per-request scratch space.  See ArgKey.
 	RenderArgs map[string]interface{} // Args passed to the template.
 	Validation *Validation            // Data validation helpers
+	Log        RequestLogger          // Logs with the request's ID, action and remote IP.
+
+	JsonOptions *JsonOptions // Overrides DefaultJsonOptions for this request's JSON, if set.
+
+	pooled   bool            // true if the controller was taken from controllerPool
+	released bool            // true if the controller was released with PoolCheck set
+	timer    filterTimer     // the times of the filters, with FilterTiming set
+	cleanups []func()        // called once the result has been applied
+	bound    []reflect.Value // the action's arguments, bound by the ActionInvoker
+}
+
+// PoolCheck, if set, makes the controllers and route matches of handled
+// requests unusable, rather than reusing them, to detect their use after their
+// request (e.g. by a goroutine started by an action).  It is set by
+// "pool.check", which defaults to true in dev mode.
+var PoolCheck bool
+
+func init() {
+	OnAppStart(func() {
+		PoolCheck = Config.BoolDefault("pool.check", DevMode)
+	})
+}
+
+// controllerPool holds Controllers, with their Params, Args and RenderArgs, for
+// reuse, so that serving a request need not allocate them.  A controller is
+// taken for each request, and returned once the request has been handled, so
+// neither it nor its app controller may be kept after then.
+var controllerPool = sync.Pool{
+	New: func() interface{} {
+		return &Controller{
+			Params:     new(Params),
+			Args:       map[string]interface{}{},
+			RenderArgs: map[string]interface{}{},
+		}
+	},
+}
+
+func acquireController(req *Request, resp *Response) *Controller {
+	c := controllerPool.Get().(*Controller)
+	c.Request, c.Response = req, resp
+	c.Log = RequestLogger{c: c}
+	c.RenderArgs["RunMode"] = RunMode
+	c.RenderArgs["DevMode"] = DevMode
+	c.pooled = true
+	return c
+}
+
+// releaseController returns a controller to the pool, or, with PoolCheck set,
+// marks it released, so that using it panics.  Controllers that were not
+// taken from the pool are ignored.
+func releaseController(c *Controller) {
+	if c == nil || !c.pooled {
+		return
+	}
+	if PoolCheck {
+		*c = Controller{Action: c.Action, released: true}
+		return
+	}
+	params, args, renderArgs := c.Params, c.Args, c.RenderArgs
+	*params = Params{}
+	for key := range args {
+		delete(args, key)
+	}
+	for key := range renderArgs {
+		delete(renderArgs, key)
+	}
+	times := c.timer.times[:0]
+	*c = Controller{Params: params, Args: args, RenderArgs: renderArgs}
+	c.timer.times = times
+	controllerPool.Put(c)
+}
+
+// checkReleased panics if the controller's request has already been handled.
+func (c *Controller) checkReleased() {
+	if c.released {
+		panic("revel/controller: the controller for " + c.Action +
+			" was used after its request was handled")
+	}
 }
 
 func NewController(req *Request, resp *Response) *Controller {
-	return &Controller{
+	c := &Controller{
 		Request:  req,
 		Response: resp,
 		Params:   new(Params),
@@ -43,25 +127,149 @@ func NewController(req *Request, resp *Response) *Controller {
 			"DevMode": DevMode,
 		},
 	}
+	c.Log = RequestLogger{c: c}
+	return c
+}
+
+// Cleanup adds a function to be called once the request's result has been
+// applied, e.g. to remove the temp file that a RenderFile result sends.  The
+// functions are called in the reverse of the order they were added in.
+func (c *Controller) Cleanup(f func()) {
+	c.cleanups = append(c.cleanups, f)
+}
+
+// cleanup calls the functions added by Cleanup.
+func (c *Controller) cleanup() {
+	for i := len(c.cleanups) - 1; i >= 0; i-- {
+		c.cleanups[i]()
+	}
+	c.cleanups = nil
+}
+
+// releaseAfterApply arranges for release (e.g. to cancel a context) to be
+// called once the request's result has been applied, as results (e.g.
+// streams) still use the request then.  It returns a function for the filter
+// to defer, which calls release instead if the connection is hijacked, as the
+// action may go on using it after the cleanups have been called.
+func (c *Controller) releaseAfterApply(release func()) func() {
+	c.Cleanup(func() {
+		if !c.Response.hijacked {
+			release()
+		}
+	})
+	return func() {
+		if c.Response.hijacked {
+			release()
+		}
+	}
+}
+
+// Context returns the request's context.  It is cancelled when the client
+// disconnects, when the request's deadline (see WithTimeout) passes, or when
+// the request has been handled, so pass it to anything (database queries,
+// outgoing requests) that should stop when the request is no longer wanted.
+func (c *Controller) Context() context.Context {
+	c.checkReleased()
+	if c.Request == nil || c.Request.Request == nil {
+		return context.Background()
+	}
+	return c.Request.Context()
+}
+
+// Aborted returns true if the client has disconnected, so that a long
+// computation, or a streaming result, may stop early.  The request's Context
+// is done then too.
+func (c *Controller) Aborted() bool {
+	return c.Context().Err() == context.Canceled
+}
+
+// WithTimeout sets a deadline on the request's context, returned by Context
+// from then on, in the later filters and the action.  The caller must call
+// the returned cancel function once the request (or its part that should be
+// limited) is done, e.g.
+//
+//     ctx, cancel := c.WithTimeout(2 * time.Second)
+//     defer cancel()
+//     rows, err := db.QueryContext(ctx, "SELECT ...")
+func (c *Controller) WithTimeout(timeout time.Duration) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(c.Context(), timeout)
+	if c.Request != nil && c.Request.Request != nil {
+		c.Request.Request = c.Request.WithContext(ctx)
+	}
+	return ctx, cancel
+}
+
+// ArgKey is a typed key for a value in a controller's Args, through which
+// filters and interceptors pass values (e.g. the current user) to the action.
+// Declare one key per value, e.g.
+//
+//     var CurrentUser = revel.NewArgKey[*models.User]("user")
+//
+// and set it in a filter:
+//
+//     CurrentUser.Set(c, user)
+//
+// and get it in the action:
+//
+//     if user, ok := CurrentUser.Get(c); ok { ... }
+type ArgKey[T any] struct {
+	name string
+}
+
+// NewArgKey returns the key for the value stored in Args under the name.
+func NewArgKey[T any](name string) ArgKey[T] {
+	return ArgKey[T]{name}
+}
+
+// Name returns the name of the value in Args.
+func (k ArgKey[T]) Name() string {
+	return k.name
+}
+
+// Set stores the value in the controller's Args.
+func (k ArgKey[T]) Set(c *Controller, value T) {
+	c.Args[k.name] = value
+}
+
+// Get returns the value from the controller's Args, and true, or the zero value
+// and false if there is no value of the key's type.
+func (k ArgKey[T]) Get(c *Controller) (T, bool) {
+	value, ok := c.Args[k.name].(T)
+	return value, ok
+}
+
+// Delete removes the value from the controller's Args.
+func (k ArgKey[T]) Delete(c *Controller) {
+	delete(c.Args, k.name)
 }
 
 func (c *Controller) FlashParams() {
+	c.checkReleased()
 	for key, vals := range c.Params.Values {
 		c.Flash.Out[key] = vals[0]
 	}
 }
 
 func (c *Controller) PushParams() {
+	c.checkReleased()
 	for key, vals := range c.Params.Values {
 		c.Flash.Data[key] = vals[0]
 	}
 }
 
 func (c *Controller) SetCookie(cookie *http.Cookie) {
+	c.checkReleased()
 	http.SetCookie(c.Response.Out, cookie)
 }
 
+// RenderError renders the error with the response's status, by the error page
+// for the status in the request's format, or as problem details where
+// ProblemErrors (or the route) says so.
 func (c *Controller) RenderError(err error) Result {
+	c.checkReleased()
+	if c.problemErrors() {
+		return c.problemDetails(err)
+	}
 	return ErrorResult{c.RenderArgs, err}
 }
 
@@ -79,6 +287,8 @@ func (c *Controller) RenderError(err error) Result {
 // This action will render views/Users/ShowUser.html, passing in an extra
 // key-value "user": (User).
 func (c *Controller) Render(extraRenderArgs ...interface{}) Result {
+	c.checkReleased()
+
 	// Get the calling function name.
 	_, _, line, ok := runtime.Caller(1)
 	if !ok {
@@ -104,8 +314,10 @@ func (c *Controller) Render(extraRenderArgs ...interface{}) Result {
 }
 
 // A less magical way to render a template.
-// Renders the given template, using the current RenderArgs.
+// Renders the given template, using the current RenderArgs.  Requests of htmx
+// or Turbo for a fragment of the page get just that (see FragmentRendering).
 func (c *Controller) RenderTemplate(templatePath string) Result {
+	c.checkReleased()
 
 	// Get the Template.
 	template, err := MainTemplateLoader.Template(templatePath)
@@ -114,14 +326,22 @@ func (c *Controller) RenderTemplate(templatePath string) Result {
 	}
 
 	return &RenderTemplateResult{
-		Template:   template,
+		Template:   c.pageFragment(templatePath, template),
 		RenderArgs: c.RenderArgs,
 	}
 }
 
+// RenderToString renders the template with the current RenderArgs, as
+// RenderTemplate would, but to a string, e.g. for the body of an email that
+// the action sends.
+func (c *Controller) RenderToString(templatePath string) (string, error) {
+	c.checkReleased()
+	return RenderTemplateToString(templatePath, c.RenderArgs)
+}
+
 // Uses encoding/json.Marshal to return JSON to the client.
 func (c *Controller) RenderJson(o interface{}) Result {
-	return RenderJsonResult{o}
+	return RenderJsonResult{o, c.jsonOptions()}
 }
 
 // Uses encoding/xml.Marshal to return XML to the client.
@@ -171,6 +391,30 @@ func (c *Controller) Forbidden(msg string, objs ...interface{}) Result {
 	})
 }
 
+// Defer returns a result that is produced by the given function, run in its own
+// goroutine, so that the filters after the action (e.g. to save the session)
+// need not wait for it, e.g. to long-poll or to aggregate several backends:
+//
+//     renderJson := c.JsonRenderer()
+//     return c.Defer(func(ctx context.Context) revel.Result {
+//         messages, err := waitForMessages(ctx, room)
+//         if err != nil {
+//             return revel.ErrorResult{Error: err}
+//         }
+//         return renderJson(messages)
+//     })
+//
+// The function is passed the request's context, which is cancelled if the
+// client disconnects or the request times out, and should then return
+// promptly, as the response waits for it.  As the filters run meanwhile, it
+// must not use the controller (c.RenderJson, c.RenderError, etc. included):
+// it captures what it needs beforehand, as above, and builds its result from
+// plain values.
+func (c *Controller) Defer(produce func(ctx context.Context) Result) *DeferredResult {
+	c.checkReleased()
+	return newDeferredResult(c.Context(), c.RenderArgs, produce)
+}
+
 // Return a file, either displayed inline or downloaded as an attachment.
 // The name and size are taken from the file info.
 func (c *Controller) RenderFile(file *os.File, delivery ContentDisposition) Result {
@@ -193,18 +437,116 @@ func (c *Controller) RenderFile(file *os.File, delivery ContentDisposition) Resu
 	}
 }
 
+// RenderDownload returns the content of the reader, to be downloaded as an
+// attachment by the file name, which may be any Unicode, e.g. "Résumé.pdf".
+// Its type is given by the name's extension, or else sniffed from the content,
+// and its length (and time modified) by the reader, if it knows, e.g. a file,
+// or a bytes.Reader.  The result's Delivery may be set to Inline, to display
+// it instead.
+func (c *Controller) RenderDownload(content io.Reader, filename string) *BinaryResult {
+	result := &BinaryResult{
+		Reader:   content,
+		Name:     filename,
+		Delivery: Attachment,
+		Length:   -1,
+	}
+	switch sized := content.(type) {
+	case interface{ Stat() (os.FileInfo, error) }:
+		if info, err := sized.Stat(); err == nil && info.Mode().IsRegular() {
+			result.Length, result.ModTime = info.Size(), info.ModTime()
+		}
+	case interface{ Size() int64 }:
+		result.Length = sized.Size()
+	}
+	return result
+}
+
+// RenderBinary returns the content of the reader, e.g. generated in memory,
+// either displayed inline or downloaded as an attachment, by the name.  Range
+// requests are answered with part of it if it can seek (e.g. a bytes.Reader),
+// and were last modified at modtime.
+func (c *Controller) RenderBinary(content io.Reader, name string, delivery ContentDisposition, modtime time.Time) Result {
+	length := int64(-1)
+	if sized, ok := content.(interface{ Size() int64 }); ok {
+		length = sized.Size()
+	}
+	return &BinaryResult{
+		Reader:   content,
+		Name:     name,
+		Delivery: delivery,
+		Length:   length,
+		ModTime:  modtime,
+	}
+}
+
 // Redirect to an action or to a URL.
 //   c.Redirect(Controller.Action)
 //   c.Redirect("/controller/action")
 //   c.Redirect("/controller/%d/action", id)
 func (c *Controller) Redirect(val interface{}, args ...interface{}) Result {
+	return redirect(http.StatusFound, val, args...)
+}
+
+// RedirectPermanently redirects to an action or to a URL, as Redirect does,
+// with 301 Moved Permanently, e.g. from the old URL of a page.
+func (c *Controller) RedirectPermanently(val interface{}, args ...interface{}) Result {
+	return redirect(http.StatusMovedPermanently, val, args...)
+}
+
+// RedirectWithFlash redirects to an action or to a URL, as Redirect does,
+// with the flash (e.g. {"success": "Saved"}) for the next request.
+func (c *Controller) RedirectWithFlash(val interface{}, flash map[string]string, args ...interface{}) Result {
+	c.checkReleased()
+	if c.Flash.Out == nil {
+		c.Flash.Out = make(map[string]string)
+	}
+	for key, value := range flash {
+		c.Flash.Out[key] = value
+	}
+	return c.Redirect(val, args...)
+}
+
+// RedirectBack redirects to the page that the request came from, by its
+// Referer, e.g. after a form is posted from several pages.  Referers of other
+// hosts are ignored, as they could redirect the user anywhere, and then (or
+// without one) it redirects to the fallback, an action or a URL.
+func (c *Controller) RedirectBack(fallback interface{}) Result {
+	if back := c.sameHostReferer(); back != "" {
+		return &RedirectToUrlResult{url: back}
+	}
+	return c.Redirect(fallback)
+}
+
+// sameHostReferer returns the path and query of the request's Referer, or ""
+// if it has none, or is not of the request's host.
+func (c *Controller) sameHostReferer() string {
+	if c.Request == nil || c.Request.Request == nil {
+		return ""
+	}
+	referer, err := url.Parse(c.Request.Referer())
+	if err != nil || !strings.HasPrefix(referer.Path, "/") ||
+		// Browsers would take "//host" (or "/\host") for another host.
+		strings.HasPrefix(referer.Path, "//") || strings.HasPrefix(referer.Path, "/\\") {
+		return ""
+	}
+	if referer.Host != "" && !strings.EqualFold(referer.Host, c.Request.Host) ||
+		referer.Scheme != "" && referer.Scheme != "http" && referer.Scheme != "https" {
+		return ""
+	}
+	back := &url.URL{Path: referer.Path, RawPath: referer.RawPath, RawQuery: referer.RawQuery}
+	return back.String()
+}
+
+// redirect returns a result of the status that redirects to an action or to
+// a URL.
+func redirect(status int, val interface{}, args ...interface{}) Result {
 	if url, ok := val.(string); ok {
 		if len(args) == 0 {
-			return &RedirectToUrlResult{url}
+			return &RedirectToUrlResult{url: url, status: status}
 		}
-		return &RedirectToUrlResult{fmt.Sprintf(url, args...)}
+		return &RedirectToUrlResult{url: fmt.Sprintf(url, args...), status: status}
 	}
-	return &RedirectToActionResult{val}
+	return &RedirectToActionResult{val: val, status: status}
 }
 
 // Perform a message lookup for the given message name using the given arguments
@@ -212,12 +554,14 @@ func (c *Controller) Redirect(val interface{}, args ...interface{}) Result {
 //
 // The current language is set by the i18n plugin.
 func (c *Controller) Message(message string, args ...interface{}) (value string) {
+	c.checkReleased()
 	return Message(c.Request.Locale, message, args...)
 }
 
 // SetAction sets the action that is being invoked in the current request.
 // It sets the following properties: Name, Action, Type, MethodType
 func (c *Controller) SetAction(controllerName, methodName string) error {
+	c.checkReleased()
 
 	// Look up the controller and method types.
 	var ok bool
@@ -310,7 +654,22 @@ type MethodType struct {
 	Name           string
 	Args           []*MethodArg
 	RenderArgNames map[int][]string
+	Routes         []string          // Routes declared in the action's comments, e.g. "GET /users/:id"
+	Redirects      []ActionReference // Actions redirected to by the action, e.g. Redirect(Users.Show)
 	lowerName      string
+
+	// Invoke calls the action directly, rather than through the reflection of
+	// ActionInvoker's default dispatch, binding its arguments of builtin types
+	// without reflection (see BindParam).  It is generated by the harness.
+	Invoke func(c *Controller) Result
+}
+
+// ActionReference is a use of an action's URL in the application source or
+// templates, e.g. a call to Redirect(Users.Show).
+type ActionReference struct {
+	Action string // e.g. "Users.Show"
+	File   string // e.g. "/Users/robfig/gocode/src/myapp/app/controllers/app.go"
+	Line   int
 }
 
 type MethodArg struct {
@@ -0,0 +1,87 @@
+package revel
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestArgKey(t *testing.T) {
+	var (
+		hotelKey  = NewArgKey[*Hotel]("hotel")
+		tenantKey = NewArgKey[string]("tenant")
+		c         = NewController(nil, nil)
+	)
+
+	_, ok := hotelKey.Get(c)
+	eq(t, "Unset", ok, false)
+
+	hotel := &Hotel{HotelId: 3}
+	hotelKey.Set(c, hotel)
+	tenantKey.Set(c, "acme")
+	actual, ok := hotelKey.Get(c)
+	eq(t, "Hotel set", ok, true)
+	eq(t, "Hotel", actual, hotel)
+	tenant, _ := tenantKey.Get(c)
+	eq(t, "Tenant", tenant, "acme")
+	eq(t, "Stored in Args", c.Args["tenant"], "acme")
+
+	// A value of another type under the same name is not returned.
+	c.Args["hotel"] = "Hotel 3"
+	actual, ok = hotelKey.Get(c)
+	eq(t, "Other type", ok, false)
+	eq(t, "Zero value", actual == nil, true)
+
+	tenantKey.Delete(c)
+	_, ok = tenantKey.Get(c)
+	eq(t, "Deleted", ok, false)
+}
+
+func TestRedirects(t *testing.T) {
+	startFakeBookingApp()
+	redirect := func(referer string, result func(c *Controller) Result) (*Controller, *httptest.ResponseRecorder) {
+		req, _ := http.NewRequest("POST", "http://example.com/hotels/3/book", nil)
+		req.Header.Set("Referer", referer)
+		resp := httptest.NewRecorder()
+		c := NewController(NewRequest(req), NewResponse(resp))
+		FlashFilter(c, []Filter{func(c *Controller, _ []Filter) {
+			c.Result = result(c)
+		}})
+		c.Result.Apply(c.Request, c.Response)
+		return c, resp
+	}
+
+	c, resp := redirect("", func(c *Controller) Result {
+		return c.RedirectWithFlash("/hotels/%d", map[string]string{"success": "Booked"}, 3)
+	})
+	eq(t, "Status with flash", resp.Code, http.StatusFound)
+	eq(t, "Location with flash", resp.Header().Get("Location"), "/hotels/3")
+	eq(t, "Flash", c.Flash.Out["success"], "Booked")
+	eq(t, "Flash cookie", strings.Contains(resp.Header().Get("Set-Cookie"), "success%3ABooked"), true)
+
+	_, resp = redirect("", func(c *Controller) Result { return c.RedirectPermanently(Hotels.Show) })
+	eq(t, "Status of permanent action redirect", resp.Code, http.StatusMovedPermanently)
+	_, resp = redirect("", func(c *Controller) Result { return c.RedirectPermanently("/hotels") })
+	eq(t, "Status of permanent redirect", resp.Code, http.StatusMovedPermanently)
+	eq(t, "Location of permanent redirect", resp.Header().Get("Location"), "/hotels")
+
+	for referer, expected := range map[string]string{
+		"":                                      "/hotels",
+		"http://example.com/hotels/3?page=2":    "/hotels/3?page=2",
+		"https://EXAMPLE.com/hotels/3#rooms":    "/hotels/3",
+		"/search?q=new+york":                    "/search?q=new+york",
+		"http://evil.com/hotels/3":              "/hotels",
+		"//evil.com/hotels":                     "/hotels",
+		"http://example.com//evil.com":          "/hotels",
+		"http://example.com/\\evil.com":         "/hotels",
+		"javascript:alert(1)":                   "/hotels",
+		"ftp://example.com/hotels/3":            "/hotels",
+		"http://example.com:8080/hotels/3":      "/hotels",
+		"http://example.com/hotels/3%2F4?a=%20": "/hotels/3%2F4?a=%20",
+	} {
+		_, resp = redirect(referer, func(c *Controller) Result { return c.RedirectBack("/hotels") })
+		eq(t, "Status back from "+referer, resp.Code, http.StatusFound)
+		eq(t, "Back from "+referer, resp.Header().Get("Location"), expected)
+	}
+}
@@ -0,0 +1,235 @@
+package revel
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"net/http"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// actionDeadlines maps actions (e.g. "Hotels.Show"), controllers (e.g.
+// "Hotels") and "" (for all actions) to the time that their actions may take,
+// from "deadline.Hotels.Show", "deadline.Hotels" and "deadline".
+var actionDeadlines = map[string]time.Duration{}
+
+func init() {
+	OnAppStart(func() {
+		actionDeadlines = map[string]time.Duration{}
+		for _, option := range Config.Options("deadline") {
+			if option != "deadline" && !strings.HasPrefix(option, "deadline.") {
+				continue
+			}
+			key := strings.TrimPrefix(strings.TrimPrefix(option, "deadline"), ".")
+			value, _ := Config.String(option)
+			deadline, err := time.ParseDuration(value)
+			if err != nil || deadline <= 0 {
+				ERROR.Fatalf("Invalid %s: %s", option, value)
+			}
+			actionDeadlines[key] = deadline
+		}
+	})
+}
+
+// DeadlineFilter limits the time that actions may take, to keep stuck requests
+// from piling up.  An action that takes longer than its deadline is answered
+// with 503 Service Unavailable, and the stack of the goroutine running it is
+// logged, to show where it is stuck.  The deadline covers applying the result
+// too, e.g. rendering its template.  (The action itself can not be stopped, so
+// the request is done only once it returns, but its context is cancelled, and
+// the rest of its response is discarded.)
+//
+// The deadline of an action is the {timeout} of its route, or else that given
+// by "deadline.Hotels.Show" (for the action), "deadline.Hotels" (for the
+// controller), or "deadline" (for all actions) in app.conf, e.g.
+//   deadline = 30s
+//   deadline.Reports = 2m
+// Actions with no deadline are not limited.  The filter must follow the
+// RouterFilter, e.g.
+//   revel.Filters = []revel.Filter{..., revel.RouterFilter, revel.DeadlineFilter, ...}
+func DeadlineFilter(c *Controller, fc []Filter) {
+	deadline := c.deadline()
+	if deadline <= 0 || c.Request.Websocket != nil {
+		fc[0](c, fc[1:])
+		return
+	}
+
+	_, cancel := c.WithTimeout(deadline)
+	w := &deadlineWriter{w: c.Response.Out, header: c.Response.Out.Header().Clone()}
+	c.Response.Out = w
+
+	// The controller may be released by the time the deadline passes, so
+	// nothing is read from it then.
+	var (
+		logger    = c.Log.Unbound()
+		goroutine = goroutineId()
+	)
+	fired := make(chan struct{})
+	timer := time.AfterFunc(deadline, func() {
+		defer close(fired)
+		w.timeout()
+		logger.Error("Exceeded its deadline of " + deadline.String() + ":\n" + goroutineStack(goroutine))
+	})
+
+	// The result is applied once the filters have returned, so the deadline
+	// lasts until it has been.
+	defer c.releaseAfterApply(func() {
+		if !timer.Stop() {
+			<-fired
+		}
+		cancel()
+	})()
+	fc[0](c, fc[1:])
+}
+
+// deadline returns the time that the action may take, or 0 if it has no
+// deadline.
+func (c *Controller) deadline() time.Duration {
+	if c.Route != nil && c.Route.Route != nil && c.Route.Route.timeout > 0 {
+		return c.Route.Route.timeout
+	}
+	for _, key := range []string{c.Action, c.Name, ""} {
+		if deadline, ok := actionDeadlines[key]; ok {
+			return deadline
+		}
+	}
+	return 0
+}
+
+// deadlineWriter is a ResponseWriter that stops writing the response once the
+// deadline has passed, when it has been answered with 503.  The response's
+// header is kept apart from that of the underlying writer until it is written,
+// so that the answer may be written while the action sets the header.
+type deadlineWriter struct {
+	w      http.ResponseWriter
+	header http.Header
+
+	mu          sync.Mutex
+	wroteHeader bool
+	timedOut    bool
+}
+
+func (w *deadlineWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *deadlineWriter) WriteHeader(status int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.writeHeader(status)
+}
+
+func (w *deadlineWriter) writeHeader(status int) {
+	if w.timedOut || w.wroteHeader {
+		return
+	}
+	// Informational responses (e.g. 103 Early Hints) precede the response.
+	w.wroteHeader = !isInformational(status)
+	header := w.w.Header()
+	for key := range header {
+		delete(header, key)
+	}
+	for key, values := range w.header {
+		header[key] = values
+	}
+	w.w.WriteHeader(status)
+}
+
+func (w *deadlineWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	w.writeHeader(http.StatusOK)
+	return w.w.Write(b)
+}
+
+func (w *deadlineWriter) Unwrap() http.ResponseWriter {
+	return w.w
+}
+
+func (w *deadlineWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if flusher, ok := w.w.(http.Flusher); ok && !w.timedOut {
+		flusher.Flush()
+	}
+}
+
+// Hijack hijacks the connection, unless the deadline has passed, after which
+// the request is not answered with 503.
+func (w *deadlineWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return nil, nil, http.ErrHandlerTimeout
+	}
+	conn, rw, err := http.NewResponseController(w.w).Hijack()
+	if err == nil {
+		w.wroteHeader = true
+	}
+	return conn, rw, err
+}
+
+// timeout answers the request with 503 Service Unavailable, and stops further
+// writes, unless the response has already been started.
+func (w *deadlineWriter) timeout() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.wroteHeader {
+		// The response is being written, so let it finish.
+		return
+	}
+	w.timedOut = true
+
+	body := []byte("Service Unavailable: the request took too long")
+	header := w.w.Header()
+	header.Set("Content-Type", "text/plain; charset=utf-8")
+	header.Set("Content-Length", strconv.Itoa(len(body)))
+	w.w.WriteHeader(http.StatusServiceUnavailable)
+	w.w.Write(body)
+	if flusher, ok := w.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// goroutineId returns the ID of the calling goroutine, e.g. "18".
+func goroutineId() string {
+	buf := make([]byte, 64)
+	buf = buf[:runtime.Stack(buf, false)]
+	// e.g. "goroutine 18 [running]:"
+	fields := bytes.Fields(buf)
+	if len(fields) < 2 {
+		return ""
+	}
+	return string(fields[1])
+}
+
+// goroutineStack returns the stack of the goroutine with the given ID, or ""
+// if it is not found.
+func goroutineStack(id string) string {
+	buf := make([]byte, 1<<16)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+
+	start := bytes.Index(buf, []byte("goroutine "+id+" ["))
+	if id == "" || start == -1 {
+		return ""
+	}
+	stack := buf[start:]
+	if end := bytes.Index(stack, []byte("\n\n")); end != -1 {
+		stack = stack[:end]
+	}
+	return string(stack)
+}
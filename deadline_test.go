@@ -0,0 +1,105 @@
+package revel
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDeadlineFilter(t *testing.T) {
+	defer func(deadlines map[string]time.Duration, logger *log.Logger) {
+		actionDeadlines, ERROR = deadlines, logger
+	}(actionDeadlines, ERROR)
+	actionDeadlines = map[string]time.Duration{"Hotels.Show": 20 * time.Millisecond, "": time.Minute}
+	var logs bytes.Buffer
+	ERROR = log.New(&logs, "", 0)
+
+	for _, test := range []struct {
+		action string
+		sleep  time.Duration
+		status int
+		body   string
+	}{
+		{"Hotels.Show", 100 * time.Millisecond, http.StatusServiceUnavailable, "Service Unavailable"},
+		{"Hotels.Index", 0, http.StatusOK, "done"},
+	} {
+		logs.Reset()
+		resp := httptest.NewRecorder()
+		c := NewController(NewRequest(showRequest), NewResponse(resp))
+		c.Action = test.action
+
+		var writeErr error
+		DeadlineFilter(c, []Filter{func(c *Controller, _ []Filter) {
+			time.Sleep(test.sleep)
+			c.Response.Out.Header().Set("X-Done", "true")
+			_, writeErr = c.Response.Out.Write([]byte("done"))
+		}})
+		c.cleanup()
+
+		eq(t, "Status of "+test.action, resp.Code, test.status)
+		eq(t, "Body of "+test.action, strings.HasPrefix(resp.Body.String(), test.body), true)
+		if test.status == http.StatusOK {
+			eq(t, "Write error", writeErr, nil)
+			eq(t, "Header", resp.Header().Get("X-Done"), "true")
+			eq(t, "Logs", logs.String(), "")
+		} else {
+			eq(t, "Write error", writeErr, http.ErrHandlerTimeout)
+			eq(t, "Header", resp.Header().Get("X-Done"), "")
+			if !strings.Contains(logs.String(), "Exceeded its deadline of 20ms") ||
+				!strings.Contains(logs.String(), "action=Hotels.Show") ||
+				!strings.Contains(logs.String(), "TestDeadlineFilter") {
+				t.Errorf("Expected a stack dump of the action, got:\n%s", logs.String())
+			}
+		}
+	}
+}
+
+type slowResult struct{ sleep time.Duration }
+
+func (r slowResult) Apply(req *Request, resp *Response) {
+	time.Sleep(r.sleep)
+	resp.WriteHeader(http.StatusOK, "text/plain")
+	resp.Out.Write([]byte("done"))
+}
+
+// Test that the deadline covers applying the result, which the server does
+// once the filters have returned, and that streams are not ended by it early.
+func TestDeadlineResult(t *testing.T) {
+	startFakeBookingApp()
+	defer func(filters []Filter, deadlines map[string]time.Duration) {
+		Filters, actionDeadlines = filters, deadlines
+	}(Filters, actionDeadlines)
+	actionDeadlines = map[string]time.Duration{"Hotels.Show": 20 * time.Millisecond, "": time.Minute}
+
+	var render func(c *Controller) Result
+	Filters = []Filter{func(c *Controller, fc []Filter) {
+		c.Action = "Hotels.Show"
+		if c.Request.URL.Path == "/events" {
+			c.Action = "Hotels.Index"
+		}
+		fc[0](c, fc[1:])
+	}, DeadlineFilter, func(c *Controller, _ []Filter) {
+		c.Result = render(c)
+	}}
+
+	render = func(c *Controller) Result { return slowResult{100 * time.Millisecond} }
+	resp := httptest.NewRecorder()
+	handle(resp, showRequest)
+	eq(t, "Status of a slow result", resp.Code, http.StatusServiceUnavailable)
+	eq(t, "Body of a slow result", strings.HasPrefix(resp.Body.String(), "Service Unavailable"), true)
+
+	items := make(chan string, 2)
+	items <- "A Hotel"
+	items <- "Another Hotel"
+	close(items)
+	req, _ := http.NewRequest("GET", "/events", nil)
+	resp = httptest.NewRecorder()
+	render = func(c *Controller) Result { return c.RenderNDJson(items) }
+	handle(resp, req)
+	eq(t, "Status of a stream", resp.Code, http.StatusOK)
+	eq(t, "Body of a stream", resp.Body.String(), "\"A Hotel\"\n\"Another Hotel\"\n")
+}
@@ -0,0 +1,164 @@
+package revel
+
+import (
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// ErrNotFound may be returned by an action (or wrapped in the error it
+// returns) to respond with 404 Not Found.
+var ErrNotFound = errors.New("not found")
+
+// ValidationErrors is a list of validation errors that may be returned by an
+// action to respond with 422 Unprocessable Entity, e.g.
+//
+//     if c.Validation.HasErrors() {
+//     	return nil, revel.ValidationErrors(c.Validation.Errors)
+//     }
+type ValidationErrors []*ValidationError
+
+// Returns the messages of the errors, separated by semicolons.
+func (errs ValidationErrors) Error() string {
+	messages := make([]string, len(errs))
+	for i, e := range errs {
+		messages[i] = e.String()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// ErrorMapper converts an error returned by an action into the result to
+// apply.  If it returns nil, the next mapper (or the mappings given to
+// MapError) is used.
+type ErrorMapper func(c *Controller, err error) Result
+
+// ErrorRenderer renders the result for an error that MapError maps to the
+// status.
+type ErrorRenderer func(c *Controller, err error, status int) Result
+
+// errorMapping maps the errors that match a target to a status.
+type errorMapping struct {
+	target   error
+	typ      reflect.Type // the type of error matched, if the target is nil
+	status   int
+	renderer ErrorRenderer
+}
+
+var (
+	errorMappers  []ErrorMapper
+	errorMappings []errorMapping
+)
+
+func init() {
+	MapError(ErrNotFound, http.StatusNotFound, nil)
+	MapError((*ValidationError)(nil), http.StatusUnprocessableEntity, nil)
+	MapError(ValidationErrors(nil), http.StatusUnprocessableEntity, nil)
+	MapError((*http.MaxBytesError)(nil), http.StatusRequestEntityTooLarge, nil)
+	MapError((*UploadTooLargeError)(nil), http.StatusRequestEntityTooLarge, nil)
+	MapError(multipart.ErrMessageTooLarge, http.StatusRequestEntityTooLarge, nil)
+}
+
+// RegisterErrorMapper adds a mapper for the errors returned by actions.
+// Mappers are tried in the order they were registered, before the mappings
+// given to MapError.
+func RegisterErrorMapper(mapper ErrorMapper) {
+	errorMappers = append(errorMappers, mapper)
+}
+
+// MapError maps errors that match target to the status, rendered by the
+// renderer, or, if it is nil, by the error page for the status (e.g.
+// errors/404.json), in the request's format.  The mapping applies to the errors
+// returned by actions, the errors that actions panic with, and the errors in
+// binding the request's parameters (e.g. a body over the route's maxbody).
+//
+// An error matches a target that is a nil value of an error type if it is (or
+// wraps) an error of that type, and matches other targets if errors.Is does,
+// e.g.
+//
+//     revel.MapError(sql.ErrNoRows, http.StatusNotFound, nil)
+//     revel.MapError((*models.ConflictError)(nil), http.StatusConflict, nil)
+//
+// Mappings made later take precedence, so that the defaults may be replaced:
+//
+//   - ErrNotFound renders a 404 Not Found.
+//   - ValidationErrors and *ValidationError render a 422 Unprocessable Entity.
+//   - *http.MaxBytesError, *UploadTooLargeError and multipart.ErrMessageTooLarge
+//     render a 413 Request Entity Too Large.
+//
+// Errors that are not mapped render a 500 Server Error.  Errors that are (or
+// wrap) *ProblemDetails render themselves.  Where errors are rendered as
+// problem details (see ProblemErrors), those of the mappings without a
+// renderer are.
+func MapError(target error, status int, renderer ErrorRenderer) {
+	mapping := errorMapping{target: target, status: status, renderer: renderer}
+	if value := reflect.ValueOf(target); isNilValue(value) {
+		mapping.typ = value.Type()
+	}
+	errorMappings = append(errorMappings, mapping)
+}
+
+// ActionResult returns the result of an action that returns (Result, error):
+// the result if err is nil, or else the result that err maps to.  It is called
+// by the generated adapters (MethodType.Invoke) for such actions.
+func ActionResult(c *Controller, result Result, err error) Result {
+	if err == nil {
+		return result
+	}
+	if mapped := mappedErrorResult(c, err); mapped != nil {
+		return mapped
+	}
+	c.Log.Error("Action failed", "error", err)
+	c.Response.Status = http.StatusInternalServerError
+	return c.RenderError(err)
+}
+
+// mappedErrorResult returns the result that the error maps to, from the
+// registered mappers or MapError, or nil if it is not mapped.
+func mappedErrorResult(c *Controller, err error) Result {
+	for _, mapper := range errorMappers {
+		if mapped := mapper(c, err); mapped != nil {
+			return mapped
+		}
+	}
+	var problem *ProblemDetails
+	if errors.As(err, &problem) {
+		return problem
+	}
+	for i := len(errorMappings) - 1; i >= 0; i-- {
+		mapping := errorMappings[i]
+		if !mapping.matches(err) {
+			continue
+		}
+		if mapping.renderer != nil {
+			return mapping.renderer(c, err, mapping.status)
+		}
+		c.Response.Status = mapping.status
+		if c.problemErrors() {
+			return c.problemDetails(err)
+		}
+		return c.RenderError(&Error{
+			Title:       http.StatusText(mapping.status),
+			Description: err.Error(),
+		})
+	}
+	return nil
+}
+
+// matches returns true if the error matches the mapping's target.
+func (m errorMapping) matches(err error) bool {
+	if m.typ == nil {
+		return errors.Is(err, m.target)
+	}
+	return errors.As(err, reflect.New(m.typ).Interface())
+}
+
+// isNilValue returns true if the value is a nil pointer, slice, map, etc.
+func isNilValue(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Chan, reflect.Func, reflect.Interface, reflect.Map, reflect.Ptr, reflect.Slice:
+		return value.IsNil()
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package revel
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type conflictError struct{ name string }
+
+func (e *conflictError) Error() string { return e.name + " is taken" }
+
+var errGone = errors.New("gone")
+
+func TestMapError(t *testing.T) {
+	startFakeBookingApp()
+	defer func(mappings []errorMapping) { errorMappings = mappings }(errorMappings)
+	MapError((*conflictError)(nil), http.StatusConflict, nil)
+	MapError(errGone, http.StatusGone, func(c *Controller, err error, status int) Result {
+		c.Response.Status = status
+		return c.RenderText("Gone for good")
+	})
+
+	newController := func() (*Controller, *httptest.ResponseRecorder) {
+		req, _ := http.NewRequest("GET", "/hotels", nil)
+		req.Header.Set("Accept", "application/json")
+		resp := httptest.NewRecorder()
+		return NewController(NewRequest(req), NewResponse(resp)), resp
+	}
+
+	for _, test := range []struct {
+		err    error
+		status int
+		body   string
+	}{
+		{fmt.Errorf("hotel 3: %w", ErrNotFound), http.StatusNotFound, "hotel 3: not found"},
+		{ValidationErrors{{Message: "Required", Key: "name"}}, http.StatusUnprocessableEntity, "Required"},
+		{fmt.Errorf("booking: %w", &conflictError{"Bob"}), http.StatusConflict, "Bob is taken"},
+		{errGone, http.StatusGone, "Gone for good"},
+		{errors.New("broken"), http.StatusInternalServerError, ""},
+	} {
+		// Returned by an action.
+		c, resp := newController()
+		ActionResult(c, nil, test.err).Apply(c.Request, c.Response)
+		eq(t, "Status of returned "+test.err.Error(), resp.Code, test.status)
+		if !strings.Contains(resp.Body.String(), test.body) {
+			t.Errorf("Expected the body for %s to contain %q, got %q", test.err, test.body, resp.Body.String())
+		}
+
+		// Panicked with by an action.
+		c, resp = newController()
+		PanicFilter(c, []Filter{func(*Controller, []Filter) { panic(test.err) }})
+		if c.Result != nil {
+			c.Result.Apply(c.Request, c.Response)
+		}
+		eq(t, "Status of panic with "+test.err.Error(), resp.Code, test.status)
+	}
+
+	// Later mappings take precedence.
+	MapError(ErrNotFound, http.StatusGone, nil)
+	c, resp := newController()
+	ActionResult(c, nil, ErrNotFound).Apply(c.Request, c.Response)
+	eq(t, "Status of remapped error", resp.Code, http.StatusGone)
+}
+
+// Test that a body over the route's maxbody, without a Content-Length, is
+// rendered as its mapped error.
+func TestMapBodyError(t *testing.T) {
+	startFakeBookingApp()
+	req, _ := http.NewRequest("POST", "/hotels/1/book", strings.NewReader(strings.Repeat("x=y&", 500)))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.ContentLength = -1
+	resp := httptest.NewRecorder()
+	c := NewController(NewRequest(req), NewResponse(resp))
+	c.Request.Body = http.MaxBytesReader(resp, c.Request.Body, 1024)
+
+	called := false
+	ParamsFilter(c, []Filter{func(*Controller, []Filter) { called = true }})
+	eq(t, "Called action", called, false)
+	eq(t, "Status", c.Response.Status, http.StatusRequestEntityTooLarge)
+}
@@ -5,6 +5,7 @@ type Filter func(c *Controller, filterChain []Filter)
 // Filters is the default set of global filters.
 // It may be set by the application on initialization.
 var Filters = []Filter{
+	RequestIdFilter,         // Assign the request an ID, to correlate its logs.
 	PanicFilter,             // Recover from panics and display an error page instead.
 	RouterFilter,            // Use the routing table to select the right Action
 	FilterConfiguringFilter, // A hook for adding or removing per-Action filters.
@@ -17,6 +18,16 @@ var Filters = []Filter{
 	ActionInvoker,           // Invoke the action.
 }
 
+// NamedFilters maps names to filters, so that they may be applied to
+// individual routes in the routes file.  For example:
+//   revel.NamedFilters["auth"] = AuthFilter
+// allows:
+//   GET  /admin  Admin.Index  [auth]
+//
+// Route filters are inserted before the last stage of the filter chain (the
+// ActionInvoker) by the FilterConfiguringFilter.
+var NamedFilters = map[string]Filter{}
+
 // NilFilter and NilChain are helpful in writing filter tests.
 var (
 	NilFilter = func(_ *Controller, _ []Filter) {}
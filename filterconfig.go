@@ -5,7 +5,8 @@ import (
 	"strings"
 )
 
-// Map from "Controller" or "Controller.Method" to the Filter chain
+// Map from "Controller", "Controller.Method", or a path (e.g. "/admin/*") to the
+// Filter chain
 var filterOverrides = make(map[string][]Filter)
 
 // FilterConfigurator allows the developer configure the filter chain on a
@@ -46,11 +47,18 @@ var filterOverrides = make(map[string][]Filter)
 //
 //  .. would result in App.Action being filtered by both Filter1 and Filter2.
 //
+// Filters may also be configured for the routes with a path (or under it), e.g.
+//   FilterPath("/admin/*").
+//     Add(AdminFilter)
+//
+// The filters configured for an action or its controller take precedence over
+// those configured for its route's path.
+//
 // Note: the last filter stage is not subject to the configurator.  In
 // particular, Add() adds a filter to the second-to-last place.
 type FilterConfigurator struct {
-	key            string // e.g. "App", "App.Action"
-	controllerName string // e.g. "App"
+	key            string // e.g. "App", "App.Action", "/admin/*"
+	controllerName string // e.g. "App", or "" for a path
 }
 
 func newFilterConfigurator(controllerName, methodName string) FilterConfigurator {
@@ -97,6 +105,17 @@ func FilterAction(methodRef interface{}) FilterConfigurator {
 	return newFilterConfigurator(controllerType.Name(), method.Name)
 }
 
+// FilterPath returns a configurator for the filters applied to the actions of
+// the routes with the given path, as declared in the routes file.  A path
+// ending in "/*" applies to all routes under it.  For example:
+//   FilterPath("/admin/*")
+func FilterPath(path string) FilterConfigurator {
+	if !strings.HasPrefix(path, "/") {
+		panic("Expecting a path starting with /, got " + path)
+	}
+	return FilterConfigurator{key: path}
+}
+
 // Add the given filter in the second-to-last position in the filter chain.
 // (Second-to-last so that it is before ActionInvoker)
 func (conf FilterConfigurator) Add(f Filter) FilterConfigurator {
@@ -199,15 +218,38 @@ func FilterEq(a, b Filter) bool {
 }
 
 // FilterConfiguringFilter is a filter stage that customizes the remaining
-// filter chain for the action being invoked.
+// filter chain for the action being invoked.  This includes any filters
+// declared on the matched route.
 func FilterConfiguringFilter(c *Controller, fc []Filter) {
-	if newChain := getOverrideChain(c.Name, c.Action); newChain != nil {
-		newChain[0](c, newChain[1:])
-		return
+	newChain := getOverrideChain(c.Name, c.Action)
+	if newChain == nil && c.Route != nil && c.Route.Route != nil {
+		newChain = getPathOverrideChain(c.Route.Route.Path)
+	}
+	if newChain != nil {
+		if c.timer.timed {
+			newChain = timedFilters(newChain)
+		}
+		fc = newChain
+	}
+	if c.Route != nil && len(c.Route.Filters) > 0 {
+		routeFilters := c.Route.Filters
+		if c.timer.timed {
+			routeFilters = timedFilters(routeFilters)
+		}
+		fc = addRouteFilters(routeFilters, fc)
 	}
 	fc[0](c, fc[1:])
 }
 
+// addRouteFilters returns a copy of the filter chain with the given filters
+// inserted in the second-to-last position.  (Before ActionInvoker)
+func addRouteFilters(filters, fc []Filter) []Filter {
+	chain := make([]Filter, 0, len(fc)+len(filters))
+	chain = append(chain, fc[:len(fc)-1]...)
+	chain = append(chain, filters...)
+	return append(chain, fc[len(fc)-1])
+}
+
 // getOverrideChain retrieves the overrides for the action that is set
 func getOverrideChain(controllerName, action string) []Filter {
 	if newChain, ok := filterOverrides[action]; ok {
@@ -218,3 +260,21 @@ func getOverrideChain(controllerName, action string) []Filter {
 	}
 	return nil
 }
+
+// getPathOverrideChain retrieves the overrides for the route's path, or else
+// for the longest path ending in "/*" that covers it, if any.
+func getPathOverrideChain(routePath string) []Filter {
+	if newChain, ok := filterOverrides[routePath]; ok {
+		return newChain
+	}
+	var (
+		chain   []Filter
+		longest = -1
+	)
+	for key, newChain := range filterOverrides {
+		if strings.HasPrefix(key, "/") && len(key) > longest && pathCovers(key, routePath) {
+			chain, longest = newChain, len(key)
+		}
+	}
+	return chain
+}
@@ -123,6 +123,43 @@ func TestFilterConfigurator(t *testing.T) {
 	}
 }
 
+func TestFilterPath(t *testing.T) {
+	oldFilters := make([]Filter, len(Filters))
+	copy(oldFilters, Filters)
+	defer func() {
+		Filters = oldFilters
+		filterOverrides = make(map[string][]Filter)
+	}()
+
+	Filters = []Filter{
+		RouterFilter,
+		FilterConfiguringFilter,
+		SessionFilter,
+		ActionInvoker,
+	}
+	filterOverrides = make(map[string][]Filter)
+	FilterPath("/admin/*").Add(NilFilter)
+	FilterPath("/admin/users/*").Remove(SessionFilter)
+	FilterPath("/admin/users").Add(PanicFilter)
+
+	for _, test := range []struct {
+		path     string
+		expected []Filter
+	}{
+		{"/admin", []Filter{SessionFilter, NilFilter, ActionInvoker}},
+		{"/admin/hotels/:id", []Filter{SessionFilter, NilFilter, ActionInvoker}},
+		{"/admin/users/:id", []Filter{ActionInvoker}},
+		{"/admin/users", []Filter{SessionFilter, PanicFilter, ActionInvoker}},
+		{"/administrator", nil},
+		{"/hotels", nil},
+	} {
+		actual := getPathOverrideChain(test.path)
+		if len(actual) != len(test.expected) || !filterSliceEqual(actual, test.expected) {
+			t.Errorf("%s:\nActual: %#v\nExpect: %#v", test.path, actual, test.expected)
+		}
+	}
+}
+
 func filterSliceEqual(a, e []Filter) bool {
 	for i, f := range a {
 		if !FilterEq(f, e[i]) {
@@ -0,0 +1,180 @@
+package revel
+
+import (
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// FilterTiming, if set, measures the time spent in each filter of the
+	// chain, not counting the filters after it, for FilterTimes.  The time
+	// spent in the action is that of the ActionInvoker.  It is set by
+	// "filters.timing", which defaults to true.
+	FilterTiming = true
+
+	// FilterTimingLog, if set, logs the times of each request's filters (at
+	// INFO), e.g.
+	//   Filter times revel.PanicFilter=2µs revel.RouterFilter=31µs ... action=Hotels.Show
+	// It is set by "filters.timing.log", which defaults to true in dev mode.
+	FilterTimingLog bool
+)
+
+func init() {
+	OnAppStart(func() {
+		FilterTiming = Config.BoolDefault("filters.timing", true)
+		FilterTimingLog = FilterTiming && Config.BoolDefault("filters.timing.log", DevMode)
+	})
+}
+
+// FilterTime is the time spent in a filter, over the requests that it has
+// handled.
+type FilterTime struct {
+	Name  string        // e.g. "revel.RouterFilter"
+	Count int64         // The number of requests.
+	Total time.Duration // The time spent in all of the requests.
+	Max   time.Duration // The most time spent in one request.
+}
+
+// filterTimeCounter accumulates the times of a filter.
+type filterTimeCounter struct {
+	name              string
+	count, total, max int64
+}
+
+// requestFilterTime is the time spent in a filter in one request.
+type requestFilterTime struct {
+	name     string
+	duration time.Duration
+}
+
+// filterTimer measures the times of the filters of a request.
+type filterTimer struct {
+	timed  bool          // true if the request's filter chain is timed
+	nested time.Duration // the time spent in the filters after the current one
+	times  []requestFilterTime
+}
+
+var (
+	// timedChains maps each chain (by its first element and length) to its
+	// timed copy, so that it is made once.
+	timedChains sync.Map
+
+	filterTimesMutex    sync.Mutex
+	filterTimeCounters  = map[string]*filterTimeCounter{}
+	filterTimeCountList []*filterTimeCounter
+)
+
+// FilterTimes returns the time spent in each filter that has been timed (see
+// FilterTiming), the slowest first, e.g. to export as metrics.
+func FilterTimes() []FilterTime {
+	filterTimesMutex.Lock()
+	counters := append([]*filterTimeCounter(nil), filterTimeCountList...)
+	filterTimesMutex.Unlock()
+
+	times := make([]FilterTime, len(counters))
+	for i, counter := range counters {
+		times[i] = FilterTime{
+			Name:  counter.name,
+			Count: atomic.LoadInt64(&counter.count),
+			Total: time.Duration(atomic.LoadInt64(&counter.total)),
+			Max:   time.Duration(atomic.LoadInt64(&counter.max)),
+		}
+	}
+	sort.SliceStable(times, func(i, j int) bool { return times[i].Total > times[j].Total })
+	return times
+}
+
+// timedFilters returns a copy of the chain with each filter timed.
+func timedFilters(chain []Filter) []Filter {
+	if len(chain) == 0 {
+		return chain
+	}
+	type chainKey struct {
+		first *Filter
+		n     int
+	}
+	key := chainKey{&chain[0], len(chain)}
+	if timed, ok := timedChains.Load(key); ok {
+		return timed.([]Filter)
+	}
+
+	timed := make([]Filter, len(chain))
+	for i, filter := range chain {
+		timed[i] = timedFilter(filter, filterTimeCounterFor(filterName(filter)))
+	}
+	timedChains.Store(key, timed)
+	return timed
+}
+
+// timedFilter returns the filter, timed by the counter.
+func timedFilter(filter Filter, counter *filterTimeCounter) Filter {
+	return func(c *Controller, fc []Filter) {
+		start := time.Now()
+		outerNested := c.timer.nested
+		c.timer.nested = 0
+
+		filter(c, fc)
+
+		elapsed := time.Since(start)
+		own := elapsed - c.timer.nested
+		c.timer.nested = outerNested + elapsed
+		c.timer.times = append(c.timer.times, requestFilterTime{counter.name, own})
+		counter.add(own)
+	}
+}
+
+// add counts a request that spent the duration in the filter.
+func (counter *filterTimeCounter) add(duration time.Duration) {
+	atomic.AddInt64(&counter.count, 1)
+	atomic.AddInt64(&counter.total, int64(duration))
+	for {
+		max := atomic.LoadInt64(&counter.max)
+		if int64(duration) <= max || atomic.CompareAndSwapInt64(&counter.max, max, int64(duration)) {
+			return
+		}
+	}
+}
+
+// filterTimeCounterFor returns the counter of the named filter.
+func filterTimeCounterFor(name string) *filterTimeCounter {
+	filterTimesMutex.Lock()
+	defer filterTimesMutex.Unlock()
+	counter, ok := filterTimeCounters[name]
+	if !ok {
+		counter = &filterTimeCounter{name: name}
+		filterTimeCounters[name] = counter
+		filterTimeCountList = append(filterTimeCountList, counter)
+	}
+	return counter
+}
+
+// filterName returns the name of the filter's function, qualified by its
+// package name.
+// e.g. "revel.PanicFilter", "controllers.AuthFilter", "app.init.func1"
+func filterName(filter Filter) string {
+	fn := runtime.FuncForPC(reflect.ValueOf(filter).Pointer())
+	if fn == nil {
+		return "unknown"
+	}
+	name := fn.Name()
+	if i := strings.LastIndex(name, "/"); i != -1 {
+		name = name[i+1:]
+	}
+	return strings.TrimSuffix(name, "-fm")
+}
+
+// logFilterTimes logs the times of the request's filters, in the order of the
+// chain.
+func logFilterTimes(c *Controller) {
+	times := c.timer.times
+	keyvals := make([]interface{}, 0, 2*len(times))
+	for i := len(times) - 1; i >= 0; i-- {
+		keyvals = append(keyvals, times[i].name, times[i].duration)
+	}
+	c.Log.Info("Filter times", keyvals...)
+}
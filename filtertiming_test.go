@@ -0,0 +1,68 @@
+package revel
+
+import (
+	"bytes"
+	"log"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func sleepingFilter(c *Controller, fc []Filter) {
+	time.Sleep(5 * time.Millisecond)
+	fc[0](c, fc[1:])
+}
+
+func sleepingAction(c *Controller, _ []Filter) {
+	time.Sleep(50 * time.Millisecond)
+}
+
+// Test that each filter is timed without the filters after it.
+func TestTimedFilters(t *testing.T) {
+	filters := []Filter{sleepingFilter, sleepingAction}
+	chain := timedFilters(filters)
+	eq(t, "Cached", &timedFilters(filters)[0] == &chain[0], true)
+
+	c := NewController(nil, nil)
+	chain[0](c, chain[1:])
+	times := c.timer.times
+	if eq(t, "Times", len(times), 2) {
+		eq(t, "Action name", times[0].name, "revel.sleepingAction")
+		eq(t, "Filter name", times[1].name, "revel.sleepingFilter")
+		if times[0].duration < 50*time.Millisecond {
+			t.Errorf("Expected the action to take at least 50ms, took %s", times[0].duration)
+		}
+		if times[1].duration < 5*time.Millisecond || times[1].duration >= 50*time.Millisecond {
+			t.Errorf("Expected the filter alone to take 5ms, took %s", times[1].duration)
+		}
+	}
+
+	var found bool
+	for _, filterTime := range FilterTimes() {
+		if filterTime.Name == "revel.sleepingAction" {
+			found = true
+			eq(t, "Count", filterTime.Count, int64(1))
+			eq(t, "Max", filterTime.Max, filterTime.Total)
+		}
+	}
+	eq(t, "Found the action's time", found, true)
+}
+
+func TestFilterTimingLog(t *testing.T) {
+	startFakeBookingApp()
+	defer func(logger *log.Logger, timing, timingLog bool) {
+		INFO, FilterTiming, FilterTimingLog = logger, timing, timingLog
+	}(INFO, FilterTiming, FilterTimingLog)
+	var buf bytes.Buffer
+	INFO = log.New(&buf, "", 0)
+	FilterTiming, FilterTimingLog = true, true
+
+	handle(httptest.NewRecorder(), plaintextRequest)
+	line := buf.String()
+	router := strings.Index(line, " revel.RouterFilter=")
+	invoker := strings.Index(line, " revel.ActionInvoker=")
+	if !strings.HasPrefix(line, "Filter times ") || router == -1 || invoker < router {
+		t.Errorf("Unexpected log line: %s", line)
+	}
+}
@@ -0,0 +1,105 @@
+package revel
+
+import (
+	"fmt"
+)
+
+// FragmentRendering, if set, renders just a fragment of a page for the
+// requests of hypermedia frontends that swap it into the page they have: of
+// htmx (with an HX-Request header) for the element given by HX-Target, and of
+// Turbo for the frame given by Turbo-Frame.  The fragment is the block (or
+// other template) of the page's template file named after the element, e.g.
+//
+//     {{block "results" .}}
+//     <div id="results">{{range .hotels}}...{{end}}</div>
+//     {{end}}
+//
+// for hx-target="#results", or <turbo-frame id="results">.  Pages that have no
+// such block are rendered whole.  So an action serves both the page and its
+// fragments, with no partial-only actions.  It is set by "results.fragments",
+// which defaults to true.
+var FragmentRendering = true
+
+func init() {
+	OnAppStart(func() {
+		FragmentRendering = Config.BoolDefault("results.fragments", true)
+	})
+}
+
+// RenderFragment renders the named block (or other template defined in it) of
+// the action's template, rather than the whole page, using the current
+// RenderArgs, e.g. c.RenderFragment("results") of views/Hotels/Index.html.
+func (c *Controller) RenderFragment(name string) Result {
+	return c.RenderTemplateFragment(c.Name+"/"+c.MethodType.Name+"."+c.Request.Format, name)
+}
+
+// RenderTemplateFragment renders the named block (or other template defined in
+// it) of the given template, using the current RenderArgs.
+func (c *Controller) RenderTemplateFragment(templatePath, name string) Result {
+	c.checkReleased()
+
+	template, err := MainTemplateLoader.Fragment(templatePath, name)
+	if err != nil {
+		return c.RenderError(err)
+	}
+
+	return &RenderTemplateResult{
+		Template:   template,
+		RenderArgs: c.RenderArgs,
+	}
+}
+
+// Fragment returns the template named name that the template file at
+// templatePath defines, by {{block}} or {{define}}, e.g. "results" of
+// "Hotels/Index.html".
+//
+// An Error is returned if there was any problem with any of the templates, as
+// by Template.
+func (loader *TemplateLoader) Fragment(templatePath, name string) (Template, error) {
+	if loader.compileError != nil {
+		return nil, loader.compileError
+	}
+	if loader.templateSet != nil {
+		tmpl := loader.templateSet.Lookup(name)
+		if tmpl != nil && tmpl.Tree != nil && tmpl.Tree.ParseName == templatePath {
+			return GoTemplate{tmpl, loader}, nil
+		}
+	}
+	return nil, fmt.Errorf("Template fragment %s not found in %s.", name, templatePath)
+}
+
+// requestedFragment returns the name of the fragment of the page that the
+// request is for, and the headers that name it, or "" if it is for the whole
+// page.  Boosted htmx requests (with HX-Boosted) are for whole pages.
+func requestedFragment(req *Request) (name, header string) {
+	if req == nil || req.Request == nil {
+		return "", ""
+	}
+	if frame := req.Header.Get("Turbo-Frame"); frame != "" {
+		return frame, "Turbo-Frame"
+	}
+	if req.Header.Get("HX-Request") == "true" && req.Header.Get("HX-Boosted") != "true" {
+		return req.Header.Get("HX-Target"), "HX-Request, HX-Target"
+	}
+	return "", ""
+}
+
+// pageFragment returns the template of the fragment of the page that the
+// request is for, if FragmentRendering is set and the page has it, or else
+// the page's template.
+func (c *Controller) pageFragment(templatePath string, page Template) Template {
+	if !FragmentRendering {
+		return page
+	}
+	name, header := requestedFragment(c.Request)
+	if name == "" {
+		return page
+	}
+	fragment, err := MainTemplateLoader.Fragment(templatePath, name)
+	if err != nil {
+		return page
+	}
+	// Caches must not give the fragment for the page, or that of another frame.
+	c.Response.Out.Header().Add("Vary", header)
+	return fragment
+}
@@ -0,0 +1,83 @@
+package revel
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFragments(t *testing.T) {
+	startFakeBookingApp()
+	dir, err := ioutil.TempDir("", "views")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.Mkdir(filepath.Join(dir, "Hotels"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "Hotels", "Index.html"), []byte(
+		`<h1>Hotels</h1>{{block "results" .}}<ul>{{range .hotels}}<li>{{.}}</li>{{end}}</ul>{{end}}`), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "Hotels", "Show.html"), []byte(
+		`{{define "hotel"}}<p>{{.hotel}}</p>{{end}}<h1>Hotel</h1>{{template "hotel" .}}`), 0644)
+
+	defer func(loader *TemplateLoader) { MainTemplateLoader = loader }(MainTemplateLoader)
+	MainTemplateLoader = NewTemplateLoader([]string{dir})
+	if err := MainTemplateLoader.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = MainTemplateLoader.Fragment("Hotels/Show.html", "results")
+	eq(t, "Fragment of another page", err.Error(), "Template fragment results not found in Hotels/Show.html.")
+
+	for _, test := range []struct {
+		name     string
+		header   map[string]string
+		render   func(c *Controller) Result
+		expected string
+		vary     string
+	}{
+		{"Page", nil, nil, "<h1>Hotels</h1><ul><li>A</li><li>B</li></ul>", ""},
+		{"htmx", map[string]string{"HX-Request": "true", "HX-Target": "results"}, nil,
+			"<ul><li>A</li><li>B</li></ul>", "HX-Request, HX-Target"},
+		{"Boosted htmx", map[string]string{"HX-Request": "true", "HX-Boosted": "true", "HX-Target": "results"}, nil,
+			"<h1>Hotels</h1><ul><li>A</li><li>B</li></ul>", ""},
+		{"Turbo", map[string]string{"Turbo-Frame": "results"}, nil, "<ul><li>A</li><li>B</li></ul>", "Turbo-Frame"},
+		{"Unknown frame", map[string]string{"Turbo-Frame": "map"}, nil, "<h1>Hotels</h1><ul><li>A</li><li>B</li></ul>", ""},
+		{"Frame of another page", map[string]string{"Turbo-Frame": "hotel"}, nil,
+			"<h1>Hotels</h1><ul><li>A</li><li>B</li></ul>", ""},
+		{"RenderFragment", nil, func(c *Controller) Result { return c.RenderFragment("results") },
+			"<ul><li>A</li><li>B</li></ul>", ""},
+		{"RenderTemplateFragment", nil, func(c *Controller) Result { return c.RenderTemplateFragment("Hotels/Show.html", "hotel") },
+			"<p>A</p>", ""},
+	} {
+		req, _ := http.NewRequest("GET", "/hotels", nil)
+		for key, value := range test.header {
+			req.Header.Set(key, value)
+		}
+		resp := httptest.NewRecorder()
+		c := NewController(NewRequest(req), NewResponse(resp))
+		c.SetAction("Hotels", "Index")
+		c.RenderArgs["hotels"] = []string{"A", "B"}
+		c.RenderArgs["hotel"] = "A"
+		result := c.RenderTemplate("Hotels/Index.html")
+		if test.render != nil {
+			result = test.render(c)
+		}
+		result.Apply(c.Request, c.Response)
+		eq(t, "Body of "+test.name, strings.TrimSpace(resp.Body.String()), test.expected)
+		eq(t, "Vary of "+test.name, resp.Header().Get("Vary"), test.vary)
+	}
+
+	defer func(fragments bool) { FragmentRendering = fragments }(FragmentRendering)
+	FragmentRendering = false
+	req, _ := http.NewRequest("GET", "/hotels", nil)
+	req.Header.Set("Turbo-Frame", "results")
+	resp := httptest.NewRecorder()
+	c := NewController(NewRequest(req), NewResponse(resp))
+	c.RenderArgs["hotels"] = []string{"A"}
+	c.RenderTemplate("Hotels/Index.html").Apply(c.Request, c.Response)
+	eq(t, "Body without FragmentRendering", resp.Body.String(), "<h1>Hotels</h1><ul><li>A</li></ul>")
+}
@@ -283,6 +283,21 @@ func main() {
 						"{{.}}",{{end}}
 					},{{end}}
 				},
+				Routes: []string{ {{range .Routes}}
+					{{printf "%q" .}},{{end}}
+				},
+				Redirects: []revel.ActionReference{ {{range .RedirectCalls}}
+					{Action: {{printf "%q" .Action}}, File: {{printf "%q" .File}}, Line: {{.Line}}},{{end}}
+				},
+				Invoke: func(c *revel.Controller) revel.Result { {{range $j, $a := .Args}}
+					var arg{{$j}} {{index $.ImportPaths .ImportPath | .TypeExpr.TypeName}}
+					revel.{{if .IsParam}}BindParam{{else}}BindArg{{end}}(c, {{$j}}, &arg{{$j}}){{end}}
+					{{if .ReturnsError}}result, err := {{else if .ReturnsValue}}result := {{else}}return {{end}}c.AppController.(*{{index $.ImportPaths $c.ImportPath}}.{{$c.StructName}}).{{.Name}}({{range $j, $a := .Args}}
+						arg{{$j}}{{if .Variadic}}...{{end}},{{end}}
+					){{if .ReturnsValue}}
+					return revel.ValueResult(c, result, {{if .ReturnsError}}err{{else}}nil{{end}}){{else if .ReturnsError}}
+					return revel.ActionResult(c, result, err){{end}}
+				},
 			},
 			{{end}}
 		})
@@ -313,8 +328,8 @@ func (_ t{{$c.StructName}}) {{.Name}}({{range .Args}}
 		{{.Name}} {{if .ImportPath}}interface{}{{else}}{{.TypeExpr.TypeName ""}}{{end}},{{end}}
 		) string {
 	args := make(map[string]string)
-	{{range .Args}}
-	revel.Unbind(args, "{{.Name}}", {{.Name}}){{end}}
+	{{range .Args}}{{if not .IsWebsocket}}
+	revel.Unbind(args, "{{.Name}}", {{.Name}}){{end}}{{end}}
 	return revel.MainRouter.Reverse("{{$c.StructName}}.{{.Name}}", args).Url
 }
 {{end}}
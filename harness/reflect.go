@@ -59,16 +59,48 @@ type methodCall struct {
 	Names []string
 }
 
+// redirectCall describes a call to c.Redirect(Controller.Action)
+type redirectCall struct {
+	Action string // e.g. "Users.Show"
+	File   string
+	Line   int
+}
+
 type MethodSpec struct {
-	Name        string        // Name of the method, e.g. "Index"
-	Args        []*MethodArg  // Argument descriptors
-	RenderCalls []*methodCall // Descriptions of Render() invocations from this Method.
+	Name          string          // Name of the method, e.g. "Index"
+	Args          []*MethodArg    // Argument descriptors
+	RenderCalls   []*methodCall   // Descriptions of Render() invocations from this Method.
+	Routes        []string        // Routes declared in the method's comments, e.g. "GET /users/:id"
+	RedirectCalls []*redirectCall // Redirect() invocations to an action from this Method.
+	ReturnsError  bool            // True if the method also returns an error.
+	ReturnsValue  bool            // True if the method returns a value to render, rather than a revel.Result.
 }
 
 type MethodArg struct {
 	Name       string   // Name of the argument.
 	TypeExpr   TypeExpr // The name of the type, e.g. "int", "*pkg.UserType"
 	ImportPath string   // If the arg is of an imported type, this is the import path.
+	Variadic   bool     // True if this is a variadic (...) final argument.
+}
+
+// IsWebsocket returns true if the argument is the websocket connection that
+// revel passes to actions of WS routes, which does not appear in their URLs.
+func (a *MethodArg) IsWebsocket() bool {
+	return a.ImportPath == "code.google.com/p/go.net/websocket" && a.TypeExpr.Expr == "*Conn"
+}
+
+// IsParam returns true if the argument is of a builtin type that the generated
+// adapter binds straight from its param, with revel.BindParam.
+func (a *MethodArg) IsParam() bool {
+	if a.ImportPath != "" || a.Variadic {
+		return false
+	}
+	switch a.TypeExpr.Expr {
+	case "bool", "string", "int", "int8", "int16", "int32", "int64", "rune",
+		"uint", "uint8", "uint16", "uint32", "uint64", "byte", "float32", "float64":
+		return true
+	}
+	return false
 }
 
 type embeddedTypeName struct {
@@ -116,7 +148,7 @@ func ProcessSource(roots []string) (*SourceInfo, *revel.Error) {
 			fset := token.NewFileSet()
 			pkgs, err = parser.ParseDir(fset, path, func(f os.FileInfo) bool {
 				return !f.IsDir() && !strings.HasPrefix(f.Name(), ".") && strings.HasSuffix(f.Name(), ".go")
-			}, 0)
+			}, parser.ParseComments)
 			if err != nil {
 				if errList, ok := err.(scanner.ErrorList); ok {
 					var pos token.Position = errList[0].Pos
@@ -381,6 +413,38 @@ func appendStruct(specs []*TypeInfo, pkgImportPath string, pkg *ast.Package, dec
 	return append(specs, controllerSpec)
 }
 
+// getActionRoutes returns the routes declared in an action's comments.
+// e.g. "// @Route GET /users/:id" => "GET /users/:id"
+func getActionRoutes(doc *ast.CommentGroup) []string {
+	if doc == nil {
+		return nil
+	}
+	var routes []string
+	for _, comment := range doc.List {
+		text := strings.TrimSpace(strings.TrimPrefix(comment.Text, "//"))
+		if strings.HasPrefix(text, "@Route ") {
+			routes = append(routes, strings.TrimSpace(text[len("@Route "):]))
+		}
+	}
+	return routes
+}
+
+// isDeclaredAction returns true if the method's comments declare it an
+// action, by "@Action" or a route ("@Route ...").
+// e.g. "// @Action" or "// @Route GET /users/:id"
+func isDeclaredAction(doc *ast.CommentGroup) bool {
+	if doc == nil {
+		return false
+	}
+	for _, comment := range doc.List {
+		text := strings.TrimSpace(strings.TrimPrefix(comment.Text, "//"))
+		if text == "@Action" || strings.HasPrefix(text, "@Route ") {
+			return true
+		}
+	}
+	return false
+}
+
 // If decl is a Method declaration, it is summarized and added to the array
 // underneath its receiver type.
 // e.g. "Login" => {MethodSpec, MethodSpec, ..}
@@ -401,27 +465,36 @@ func appendAction(fset *token.FileSet, mm methodMap, decl ast.Decl, pkgImportPat
 		return
 	}
 
-	// Does it return a Result?
-	if funcDecl.Type.Results == nil || len(funcDecl.Type.Results.List) != 1 {
-		return
-	}
-	selExpr, ok := funcDecl.Type.Results.List[0].Type.(*ast.SelectorExpr)
-	if !ok {
+	// Does it return a Result or a value, and optionally an error?
+	if funcDecl.Type.Results == nil {
 		return
 	}
-	if selExpr.Sel.Name != "Result" {
+	results := funcDecl.Type.Results.List
+	if len(results) == 2 && len(results[0].Names) <= 1 && len(results[1].Names) <= 1 {
+		if errIdent, ok := results[1].Type.(*ast.Ident); !ok || errIdent.Name != "error" {
+			return
+		}
+	} else if len(results) != 1 || len(results[0].Names) > 1 {
 		return
 	}
-	if pkgIdent, ok := selExpr.X.(*ast.Ident); !ok || imports[pkgIdent.Name] != revel.REVEL_IMPORT_PATH {
+	// Methods that return values are actions only if they say so, so that the
+	// helpers of controllers are not exposed by routes such as
+	// "/:controller/:action".
+	returnsValue := !isRevelResult(results[0].Type, imports)
+	if returnsValue && (!isValueType(results[0].Type) || !isDeclaredAction(funcDecl.Doc)) {
 		return
 	}
 
 	method := &MethodSpec{
-		Name: funcDecl.Name.Name,
+		Name:         funcDecl.Name.Name,
+		Routes:       getActionRoutes(funcDecl.Doc),
+		ReturnsError: len(results) == 2,
+		ReturnsValue: returnsValue,
 	}
 
 	// Add a description of the arguments to the method.
 	for _, field := range funcDecl.Type.Params.List {
+		_, variadic := field.Type.(*ast.Ellipsis)
 		for _, name := range field.Names {
 			var importPath string
 			typeExpr := NewTypeExpr(pkgName, field.Type)
@@ -438,6 +511,7 @@ func appendAction(fset *token.FileSet, mm methodMap, decl ast.Decl, pkgImportPat
 				Name:       name.Name,
 				TypeExpr:   typeExpr,
 				ImportPath: importPath,
+				Variadic:   variadic,
 			})
 		}
 	}
@@ -458,6 +532,20 @@ func appendAction(fset *token.FileSet, mm methodMap, decl ast.Decl, pkgImportPat
 			return true
 		}
 
+		// Record redirects to an action, so that they may be checked against
+		// the routes.
+		if selExpr.Sel.Name == "Redirect" {
+			if action := getRedirectAction(callExpr); action != "" {
+				pos := fset.Position(callExpr.Lparen)
+				method.RedirectCalls = append(method.RedirectCalls, &redirectCall{
+					Action: action,
+					File:   pos.Filename,
+					Line:   pos.Line,
+				})
+			}
+			return true
+		}
+
 		// The type of the receiver is not easily available, so just store every
 		// call to any method called Render.
 		if selExpr.Sel.Name != "Render" {
@@ -492,6 +580,55 @@ func appendAction(fset *token.FileSet, mm methodMap, decl ast.Decl, pkgImportPat
 	mm[recvTypeName] = append(mm[recvTypeName], method)
 }
 
+// isRevelResult returns true if the type expression is revel.Result.
+func isRevelResult(expr ast.Expr, imports map[string]string) bool {
+	selExpr, ok := expr.(*ast.SelectorExpr)
+	if !ok || selExpr.Sel.Name != "Result" {
+		return false
+	}
+	pkgIdent, ok := selExpr.X.(*ast.Ident)
+	return ok && imports[pkgIdent.Name] == revel.REVEL_IMPORT_PATH
+}
+
+// isValueType returns true if the type expression is of a value that an action
+// may return to be rendered, e.g. "*models.User" or "[]string".  Errors,
+// functions and channels may not be rendered.
+func isValueType(expr ast.Expr) bool {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		return t.Name != "error"
+	case *ast.FuncType, *ast.ChanType:
+		return false
+	}
+	return true
+}
+
+// getRedirectAction returns the action passed to a call to Redirect, or "" if
+// it was not given an action.
+// e.g. c.Redirect(Users.Show) => "Users.Show", c.Redirect("/login") => ""
+func getRedirectAction(callExpr *ast.CallExpr) string {
+	if len(callExpr.Args) == 0 {
+		return ""
+	}
+	selExpr, ok := callExpr.Args[0].(*ast.SelectorExpr)
+	if !ok {
+		return ""
+	}
+
+	// The receiver may be a value or a pointer, e.g. (*Users).Show
+	recv := selExpr.X
+	if parenExpr, ok := recv.(*ast.ParenExpr); ok {
+		if starExpr, ok := parenExpr.X.(*ast.StarExpr); ok {
+			recv = starExpr.X
+		}
+	}
+	recvIdent, ok := recv.(*ast.Ident)
+	if !ok || !recvIdent.IsExported() {
+		return ""
+	}
+	return recvIdent.Name + "." + selExpr.Sel.Name
+}
+
 // Scan app source code for calls to X.Y(), where X is of type *Validation.
 //
 // Recognize these scenarios:
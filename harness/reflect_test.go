@@ -105,6 +105,96 @@ var TypeExprs = map[string]TypeExpr{
 	"...*MyType": TypeExpr{"[]*MyType", "pkg", 3, true},
 }
 
+const actionRoutesSource = `
+package test
+
+// Show displays a user.
+// @Route GET /users/:id
+//   @Route GET /u/:id as users.short
+func (c Users) Show(id int) revel.Result {
+	return nil
+}
+`
+
+func TestGetActionRoutes(t *testing.T) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "users.go", actionRoutesSource, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	routes := getActionRoutes(file.Decls[0].(*ast.FuncDecl).Doc)
+	expected := []string{"GET /users/:id", "GET /u/:id as users.short"}
+	if !reflect.DeepEqual(routes, expected) {
+		t.Errorf("Expected routes %v, got %v", expected, routes)
+	}
+}
+
+const valueActionsSource = `
+package test
+
+import "github.com/robfig/revel"
+
+func (c Users) Index() revel.Result {
+	return nil
+}
+
+// DisplayName is a helper, not an action.
+func (c Users) DisplayName() string {
+	return ""
+}
+
+// @Action
+func (c Users) Profile() *User {
+	return nil
+}
+
+// @Route GET /users/:id
+func (c Users) Show(id int) (User, error) {
+	return User{}, nil
+}
+`
+
+func TestValueActions(t *testing.T) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "users.go", valueActionsSource, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mm := make(methodMap)
+	imports := map[string]string{"revel": revel.REVEL_IMPORT_PATH}
+	for _, decl := range file.Decls {
+		appendAction(fset, mm, decl, "test", "test", imports)
+	}
+	var actions []string
+	for _, method := range mm["Users"] {
+		actions = append(actions, method.Name)
+	}
+	expected := []string{"Index", "Profile", "Show"}
+	if !reflect.DeepEqual(actions, expected) {
+		t.Errorf("Expected actions %v, got %v", expected, actions)
+	}
+}
+
+func TestGetRedirectAction(t *testing.T) {
+	for src, expected := range map[string]string{
+		`c.Redirect(Users.Show)`:        "Users.Show",
+		`c.Redirect((*Users).Show)`:     "Users.Show",
+		`c.Redirect("/users/%d", id)`:   "",
+		`c.Redirect(c.next)`:            "",
+		`c.Redirect(routes.Users.Show)`: "",
+	} {
+		expr, err := parser.ParseExpr(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if action := getRedirectAction(expr.(*ast.CallExpr)); action != expected {
+			t.Errorf("%s: expected action %q, got %q", src, expected, action)
+		}
+	}
+}
+
 func TestTypeExpr(t *testing.T) {
 	for typeStr, expected := range TypeExprs {
 		// Handle arrays and ... myself, since ParseExpr() does not.
@@ -138,6 +228,32 @@ func TestTypeExpr(t *testing.T) {
 	}
 }
 
+func TestIsParam(t *testing.T) {
+	for typeStr, expected := range map[string]bool{
+		"int":        true,
+		"string":     true,
+		"byte":       true,
+		"float64":    true,
+		"UserID":     false,
+		"*int":       false,
+		"time.Time":  false,
+		"complex128": false,
+	} {
+		expr, err := parser.ParseExpr(typeStr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		arg := &MethodArg{Name: "arg", TypeExpr: NewTypeExpr("pkg", expr)}
+		if arg.IsParam() != expected {
+			t.Errorf("%s: expected IsParam %v", typeStr, expected)
+		}
+	}
+	variadic := &MethodArg{Name: "ids", TypeExpr: TypeExpr{"[]int", "", 0, true}, Variadic: true}
+	if variadic.IsParam() {
+		t.Error("Variadic argument is a param")
+	}
+}
+
 func TestProcessBookingSource(t *testing.T) {
 	revel.Init("prod", "github.com/robfig/revel/samples/booking", "")
 	sourceInfo, err := ProcessSource([]string{revel.AppPath})
@@ -0,0 +1,48 @@
+package revel
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+)
+
+// Hijack takes over the request's connection from the server, for the action
+// to speak a protocol of its own on it, e.g. to tunnel a CONNECT request:
+//
+//     conn, rw, err := c.Hijack()
+//     if err != nil {
+//     	return c.RenderError(err)
+//     }
+//     defer conn.Close()
+//     rw.WriteString("HTTP/1.1 200 Connection established\r\n\r\n")
+//     rw.Flush()
+//     ...
+//     return nil
+//
+// The functions added by Cleanup are called then, and nothing more is written
+// to the response: the action's result (which may be nil) is not applied, nor
+// are error pages, and the filters that hold the response (e.g. the
+// CompressFilter) have nothing to send.  The caller must close the connection.
+//
+// It returns an error wrapping http.ErrNotSupported if the connection can not
+// be hijacked (e.g. it is HTTP/2), http.ErrHijacked if it has been already
+// (as those of websockets have), or http.ErrHandlerTimeout if the action's
+// deadline (see DeadlineFilter) has passed.
+func (c *Controller) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if c.Response.hijacked || c.Request.Websocket != nil {
+		return nil, nil, http.ErrHijacked
+	}
+	conn, rw, err := http.NewResponseController(c.Response.Out).Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+	c.Response.hijacked = true
+	c.cleanup()
+	return conn, rw, nil
+}
+
+// Hijacked returns true if the request's connection has been hijacked (see
+// Hijack), so that filters know not to write the response.
+func (c *Controller) Hijacked() bool {
+	return c.Response.hijacked
+}
@@ -0,0 +1,61 @@
+package revel
+
+import (
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHijack(t *testing.T) {
+	startFakeBookingApp()
+	defer func(filters []Filter) { Filters = filters }(Filters)
+	defer func(deadlines map[string]time.Duration) { actionDeadlines = deadlines }(actionDeadlines)
+	actionDeadlines = map[string]time.Duration{"": time.Minute}
+
+	var cleanedUp bool
+	var hijackErr error
+	Filters = []Filter{CompressFilter, ETagFilter, DeadlineFilter, func(c *Controller, fc []Filter) {
+		c.Cleanup(func() { cleanedUp = true })
+		conn, rw, err := c.Hijack()
+		if err != nil {
+			t.Error("Hijack:", err)
+			return
+		}
+		_, _, hijackErr = c.Hijack()
+		eq(t, "Cleaned up", cleanedUp, true)
+		eq(t, "Hijacked", c.Hijacked(), true)
+		rw.WriteString("HTTP/1.1 200 OK\r\nConnection: close\r\n\r\nraw")
+		rw.Flush()
+		conn.Close()
+		// The result is not applied.
+		c.Result = c.RenderText("Hello, World!")
+	}}
+
+	server := httptest.NewServer(http.HandlerFunc(handle))
+	defer server.Close()
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.Write([]byte("GET /hotels HTTP/1.1\r\nHost: localhost\r\nAccept-Encoding: gzip\r\n\r\n"))
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	response, err := ioutil.ReadAll(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	eq(t, "Response", string(response), "HTTP/1.1 200 OK\r\nConnection: close\r\n\r\nraw")
+	eq(t, "Hijacked again", hijackErr, http.ErrHijacked)
+}
+
+func TestHijackNotSupported(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/hotels", nil)
+	c := NewController(NewRequest(req), NewResponse(httptest.NewRecorder()))
+	_, _, err := c.Hijack()
+	eq(t, "Not supported", errors.Is(err, http.ErrNotSupported), true)
+	eq(t, "Not hijacked", c.Hijacked(), false)
+}
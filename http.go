@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"code.google.com/p/go.net/websocket"
 	"fmt"
+	"io"
 	"net/http"
 	"sort"
 	"strconv"
@@ -24,12 +25,26 @@ type Response struct {
 	ContentType string
 
 	Out http.ResponseWriter
+
+	hijacked bool // true once the connection has been hijacked
 }
 
 func NewResponse(w http.ResponseWriter) *Response {
 	return &Response{Out: w}
 }
 
+// BodyReader returns the request's body, to be read as a stream, e.g. by an
+// action that uploads or proxies a large body.  Routes with the {body=stream}
+// option leave the body unread, rather than parsing a form body into the
+// Params; on other routes, a form body has already been read.  The route's
+// {maxbody} limit still applies.
+func (req *Request) BodyReader() io.Reader {
+	if req.Body == nil {
+		return http.NoBody
+	}
+	return req.Body
+}
+
 func NewRequest(r *http.Request) *Request {
 	return &Request{
 		Request:         r,
@@ -74,9 +89,15 @@ func ResolveFormat(req *http.Request) string {
 		strings.Contains(accept, "application/xhtml"),
 		strings.Contains(accept, "text/html"):
 		return "html"
+	case strings.Contains(accept, "application/x-protobuf"),
+		strings.Contains(accept, "application/protobuf"),
+		strings.Contains(accept, "application/vnd.google.protobuf"):
+		return "proto"
 	case strings.Contains(accept, "application/xml"),
 		strings.Contains(accept, "text/xml"):
 		return "xml"
+	case strings.Contains(accept, "yaml"):
+		return "yaml"
 	case strings.Contains(accept, "text/plain"):
 		return "txt"
 	case strings.Contains(accept, "application/json"),
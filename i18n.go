@@ -143,7 +143,10 @@ func init() {
 }
 
 func I18nFilter(c *Controller, fc []Filter) {
-	if foundCookie, cookieValue := hasLocaleCookie(c.Request); foundCookie {
+	if c.Route != nil && c.Route.Locale != "" {
+		TRACE.Printf("Found locale path prefix: %s", c.Route.Locale)
+		setCurrentLocaleControllerArguments(c, c.Route.Locale)
+	} else if foundCookie, cookieValue := hasLocaleCookie(c.Request); foundCookie {
 		TRACE.Printf("Found locale cookie value: %s", cookieValue)
 		setCurrentLocaleControllerArguments(c, cookieValue)
 	} else if foundHeader, headerValue := hasAcceptLanguageHeader(c.Request); foundHeader {
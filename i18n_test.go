@@ -130,6 +130,12 @@ func TestBeforeRequest(t *testing.T) {
 	if I18nFilter(c, NilChain); c.Request.Locale != "en-GB" {
 		t.Errorf("Expected to find current language '%s' in controller, found '%s' instead", "en-GB", c.Request.Locale)
 	}
+
+	c = NewController(buildRequestWithCookie("APP_LANG", "en-US"), nil)
+	c.Route = &RouteMatch{Locale: "nl"}
+	if I18nFilter(c, NilChain); c.Request.Locale != "nl" {
+		t.Errorf("Expected to find current language '%s' in controller, found '%s' instead", "nl", c.Request.Locale)
+	}
 }
 
 func BenchmarkI18nLoadMessages(b *testing.B) {
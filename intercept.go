@@ -3,6 +3,7 @@ package revel
 import (
 	"log"
 	"reflect"
+	"sort"
 )
 
 // An "interceptor" is functionality invoked by the framework BEFORE or AFTER
@@ -18,7 +19,14 @@ import (
 // in the AFTER case it is possible that a further interceptor could emit its
 // own Result.
 //
-// Interceptors are called in the order that they are added.
+// Interceptors are called in the order of their priorities, lowest first, and
+// those of the same priority in the order that they are added.  The priority
+// is 0 unless set, e.g.
+//
+//   revel.InterceptMethod(App.checkUser, revel.BEFORE).SetPriority(-10)
+//
+// Modules whose interceptors should run before or after the application's
+// should set their priorities, rather than depend on the order of the imports.
 //
 // ***
 //
@@ -34,6 +42,13 @@ import (
 //   func (c AppController) example() revel.Result
 //   func (c *AppController) example() revel.Result
 //
+// Plain functions taking an application controller, or an interface, may also
+// be installed as Method Interceptors, to share them across controllers.  Those
+// taking an interface apply to each controller that implements it.
+//
+//   func example(c *AppController) revel.Result
+//   func example(c Tenanted) revel.Result
+//
 type InterceptorFunc func(*Controller) Result
 type InterceptorMethod interface{}
 type When int
@@ -52,8 +67,10 @@ const (
 )
 
 type Interception struct {
-	When When
+	When     When
+	Priority int // Interceptors with lower priorities are called first.
 
+	order    int // The number of interceptors added before this one.
 	function InterceptorFunc
 	method   InterceptorMethod
 
@@ -130,8 +147,8 @@ var interceptors []*Interception
 // This can be applied to any Controller.
 // It must have the signature of:
 //   func example(c *revel.Controller) revel.Result
-func InterceptFunc(intc InterceptorFunc, when When, target interface{}) {
-	interceptors = append(interceptors, &Interception{
+func InterceptFunc(intc InterceptorFunc, when When, target interface{}) *Interception {
+	return addInterceptor(&Interception{
 		When:         when,
 		function:     intc,
 		callable:     reflect.ValueOf(intc),
@@ -140,16 +157,21 @@ func InterceptFunc(intc InterceptorFunc, when When, target interface{}) {
 	})
 }
 
-// Install an interceptor method that applies to its own Controller.
+// Install an interceptor method that applies to its own Controller, or a
+// function that applies to the Controllers of its argument's type, or that
+// implement its argument's interface.
 //   func (c AppController) example() revel.Result
 //   func (c *AppController) example() revel.Result
-func InterceptMethod(intc InterceptorMethod, when When) {
+//   func example(c *AppController) revel.Result
+//   func example(c Tenanted) revel.Result
+func InterceptMethod(intc InterceptorMethod, when When) *Interception {
 	methodType := reflect.TypeOf(intc)
-	if methodType.Kind() != reflect.Func || methodType.NumOut() != 1 || methodType.NumIn() != 1 {
+	if methodType.Kind() != reflect.Func || methodType.NumOut() != 1 || methodType.NumIn() != 1 ||
+		methodType.Out(0) != resultType {
 		log.Fatalln("Interceptor method should have signature like",
 			"'func (c *AppController) example() revel.Result' but was", methodType)
 	}
-	interceptors = append(interceptors, &Interception{
+	return addInterceptor(&Interception{
 		When:     when,
 		method:   intc,
 		callable: reflect.ValueOf(intc),
@@ -157,6 +179,32 @@ func InterceptMethod(intc InterceptorMethod, when When) {
 	})
 }
 
+// SetPriority sets the priority of the interceptor, which is called before
+// those with higher priorities, and after those with lower ones.
+func (i *Interception) SetPriority(priority int) *Interception {
+	i.Priority = priority
+	sortInterceptors()
+	return i
+}
+
+func addInterceptor(intc *Interception) *Interception {
+	intc.order = len(interceptors)
+	interceptors = append(interceptors, intc)
+	sortInterceptors()
+	return intc
+}
+
+// sortInterceptors orders the interceptors by priority, and then by the order
+// in which they were added.
+func sortInterceptors() {
+	sort.Slice(interceptors, func(i, j int) bool {
+		if interceptors[i].Priority != interceptors[j].Priority {
+			return interceptors[i].Priority < interceptors[j].Priority
+		}
+		return interceptors[i].order < interceptors[j].order
+	})
+}
+
 func getInterceptors(when When, val reflect.Value) []*Interception {
 	result := []*Interception{}
 	for _, intc := range interceptors {
@@ -180,10 +228,19 @@ func findTarget(val reflect.Value, target reflect.Type) reflect.Value {
 	for len(valueQueue) > 0 {
 		val, valueQueue = valueQueue[0], valueQueue[1:]
 
-		// Check if val is of a similar type to the target type.
+		// Check if val is of a similar type to the target type, or implements
+		// the target interface.
 		if val.Type() == target {
 			return val
 		}
+		if target.Kind() == reflect.Interface {
+			if val.Type().Implements(target) {
+				return val
+			}
+			if val.CanAddr() && val.Addr().Type().Implements(target) {
+				return val.Addr()
+			}
+		}
 		if val.Kind() == reflect.Ptr && val.Elem().Type() == target {
 			return val.Elem()
 		}
@@ -1,6 +1,7 @@
 package revel
 
 import (
+	"fmt"
 	"reflect"
 	"testing"
 )
@@ -79,3 +80,69 @@ func testInterception(t *testing.T, intc *Interception, arg reflect.Value) {
 		t.Errorf("Failed (%s): Expected nil got %s", intc, val)
 	}
 }
+
+func TestInterceptorPriority(t *testing.T) {
+	defer func(saved []*Interception) { interceptors = saved }(interceptors)
+	interceptors = []*Interception{}
+
+	var called []string
+	record := func(name string) InterceptorFunc {
+		return func(c *Controller) Result {
+			called = append(called, name)
+			return nil
+		}
+	}
+	InterceptFunc(record("first added"), BEFORE, ALL_CONTROLLERS)
+	InterceptFunc(record("late"), BEFORE, ALL_CONTROLLERS).SetPriority(10)
+	InterceptFunc(record("second added"), BEFORE, ALL_CONTROLLERS)
+	InterceptFunc(record("early"), BEFORE, ALL_CONTROLLERS).SetPriority(-10)
+	InterceptFunc(record("after"), AFTER, ALL_CONTROLLERS).SetPriority(-20)
+
+	c := &Controller{}
+	c.AppController = &InterceptController{c}
+	invokeInterceptors(BEFORE, c)
+	expected := []string{"early", "first added", "second added", "late"}
+	if !reflect.DeepEqual(called, expected) {
+		t.Errorf("Expected interceptors to be called in order %v, got %v", expected, called)
+	}
+}
+
+type tenanted interface {
+	Tenant() string
+}
+
+type TenantController struct{ *Controller }
+
+func (c TenantController) Tenant() string { return "acme" }
+
+type AccountsController struct{ TenantController }
+
+func TestInterceptTypedFunction(t *testing.T) {
+	defer func(saved []*Interception) { interceptors = saved }(interceptors)
+	interceptors = []*Interception{}
+
+	var called []string
+	InterceptMethod(func(c tenanted) Result {
+		called = append(called, "tenanted "+c.Tenant())
+		return nil
+	}, BEFORE)
+	InterceptMethod(func(c *TenantController) Result {
+		called = append(called, "tenant controller")
+		return nil
+	}, BEFORE)
+
+	for _, test := range []struct {
+		app      func(c *Controller) interface{}
+		expected string
+	}{
+		{func(c *Controller) interface{} { return &TenantController{c} }, "[tenanted acme tenant controller]"},
+		{func(c *Controller) interface{} { return &AccountsController{TenantController{c}} }, "[tenanted acme tenant controller]"},
+		{func(c *Controller) interface{} { return &InterceptController{c} }, "[]"},
+	} {
+		called = nil
+		c := &Controller{}
+		c.AppController = test.app(c)
+		invokeInterceptors(BEFORE, c)
+		eq(t, fmt.Sprintf("Interceptors of %T", c.AppController), fmt.Sprint(called), test.expected)
+	}
+}
@@ -2,7 +2,11 @@ package revel
 
 import (
 	"code.google.com/p/go.net/websocket"
+	"fmt"
+	"net/http"
 	"reflect"
+	"strconv"
+	"strings"
 )
 
 var (
@@ -12,30 +16,208 @@ var (
 )
 
 func ActionInvoker(c *Controller, _ []Filter) {
+	// Bind the action's arguments first, so that a param that is not a valid
+	// value of its type (e.g. a malformed UUID, see UnmarshalerBinder) fails
+	// the request with 400 Bad Request, rather than being bound as the zero
+	// value.  The generated adapter binds the arguments of builtin types
+	// itself (see BindParam), which are never bad values.
+	direct := c.MethodType.Invoke != nil && c.Params.Proto == nil
+	c.bound = nil
+	for i, arg := range c.MethodType.Args {
+		if direct && isDirect(arg.Type) {
+			continue
+		}
+		if c.bound == nil {
+			c.bound = make([]reflect.Value, len(c.MethodType.Args))
+		}
+		c.bound[i] = bindArg(c, arg)
+	}
+	if len(c.Params.badValues) > 0 {
+		c.Result = badValuesResult(c)
+		return
+	}
+
+	// Call the action through its generated adapter, if it has one.
+	if c.MethodType.Invoke != nil {
+		c.Result = c.MethodType.Invoke(c)
+		return
+	}
+
 	// Instantiate the method.
 	methodValue := reflect.ValueOf(c.AppController).MethodByName(c.MethodType.Name)
 
-	// Collect the values for the method's arguments.
-	var methodArgs []reflect.Value
-	for _, arg := range c.MethodType.Args {
-		// If they accept a websocket connection, treat that arg specially.
-		var boundArg reflect.Value
-		if arg.Type == websocketType {
-			boundArg = reflect.ValueOf(c.Request.Websocket)
+	methodArgs := c.bound
+	var resultValues []reflect.Value
+	if methodValue.Type().IsVariadic() {
+		resultValues = methodValue.CallSlice(methodArgs)
+	} else {
+		resultValues = methodValue.Call(methodArgs)
+	}
+
+	// Actions may also return an error, which is mapped to a result.
+	var err error
+	if len(resultValues) == 2 && !resultValues[1].IsNil() {
+		err = resultValues[1].Interface().(error)
+	}
+
+	// Actions that return a value rather than a Result have it rendered.
+	if resultValue := resultValues[0]; resultValue.Type() != resultType {
+		c.Result = ValueResult(c, resultValue.Interface(), err)
+	} else if err != nil {
+		c.Result = ActionResult(c, nil, err)
+	} else if !resultValue.IsNil() {
+		c.Result = resultValue.Interface().(Result)
+	}
+}
+
+// BindArg binds the i'th argument of the controller's action into dest, which
+// must be a pointer to a variable of the argument's type.  It is called by the
+// generated adapters (MethodType.Invoke) that call the actions directly, for
+// the arguments that BindParam does not bind.
+func BindArg(c *Controller, i int, dest interface{}) {
+	if i < len(c.bound) && c.bound[i].IsValid() {
+		reflect.ValueOf(dest).Elem().Set(c.bound[i])
+		return
+	}
+	reflect.ValueOf(dest).Elem().Set(bindArg(c, c.MethodType.Args[i]))
+}
+
+// ParamType is the types of the action arguments that the generated adapters
+// bind with BindParam.
+type ParamType interface {
+	bool | string | int | int8 | int16 | int32 | int64 |
+		uint | uint8 | uint16 | uint32 | uint64 | float32 | float64
+}
+
+// BindParam binds the i'th argument of the controller's action into dest, as
+// BindArg does, but parses the param straight into it, without reflection.
+// Arguments of kinds whose binders the application has replaced (see
+// KindBinders and TypeBinders), and those of protobuf requests, are bound by
+// BindArg.
+func BindParam[T ParamType](c *Controller, i int, dest *T) {
+	switch dest := any(dest).(type) {
+	case *bool:
+		bindParam(c, i, dest, reflect.Bool, parseBoolParam)
+	case *string:
+		bindParam(c, i, dest, reflect.String, parseStringParam)
+	case *int:
+		bindParam(c, i, dest, reflect.Int, parseIntParam[int])
+	case *int8:
+		bindParam(c, i, dest, reflect.Int8, parseIntParam[int8])
+	case *int16:
+		bindParam(c, i, dest, reflect.Int16, parseIntParam[int16])
+	case *int32:
+		bindParam(c, i, dest, reflect.Int32, parseIntParam[int32])
+	case *int64:
+		bindParam(c, i, dest, reflect.Int64, parseIntParam[int64])
+	case *uint:
+		bindParam(c, i, dest, reflect.Uint, parseUintParam[uint])
+	case *uint8:
+		bindParam(c, i, dest, reflect.Uint8, parseUintParam[uint8])
+	case *uint16:
+		bindParam(c, i, dest, reflect.Uint16, parseUintParam[uint16])
+	case *uint32:
+		bindParam(c, i, dest, reflect.Uint32, parseUintParam[uint32])
+	case *uint64:
+		bindParam(c, i, dest, reflect.Uint64, parseUintParam[uint64])
+	case *float32:
+		bindParam(c, i, dest, reflect.Float32, parseFloatParam[float32])
+	case *float64:
+		bindParam(c, i, dest, reflect.Float64, parseFloatParam[float64])
+	}
+}
+
+// bindParam binds the argument as the builtin binder of its kind would: a
+// missing (or, but for strings, empty) param as the zero value, and one that
+// fails to parse as the zero value, with a binding error.
+func bindParam[T ParamType](c *Controller, i int, dest *T, kind reflect.Kind, parse func(p *Params, val string, bits int) (T, error)) {
+	if !directKinds[kind] || c.Params.Proto != nil || i < len(c.bound) && c.bound[i].IsValid() {
+		BindArg(c, i, dest)
+		return
+	}
+	params, name := c.Params, c.MethodType.Args[i].Name
+	if body, ok := params.bodyKinds[name]; ok && (body == "an object" || body == "an array") {
+		params.bindError(name, fmt.Sprintf("%s is not a valid %s", body, kind))
+	} else if vals := params.Values[name]; len(vals) > 0 && (len(vals[0]) > 0 || kind == reflect.String) {
+		value, err := parse(params, vals[0], paramBits[kind])
+		if err != nil {
+			WARN.Printf("revel/binder: can not bind %s=%q as %s: %s", name, vals[0], kind, err)
+			params.bindError(name, fmt.Sprintf("%q is not a valid %s", vals[0], kind))
 		} else {
-			TRACE.Println("Binding:", arg.Name, "as", arg.Type)
-			boundArg = Bind(c.Params, arg.Name, arg.Type)
+			*dest = value
 		}
-		methodArgs = append(methodArgs, boundArg)
 	}
 
-	var resultValue reflect.Value
-	if methodValue.Type().IsVariadic() {
-		resultValue = methodValue.CallSlice(methodArgs)[0]
+	// As in bindArg, binding errors are reported as validation errors.
+	if c.Validation != nil {
+		c.Validation.Errors = append(c.Validation.Errors, params.bindErrors...)
+	}
+	params.bindErrors = nil
+}
+
+// paramBits are the sizes of the numbers that BindParam parses, by kind.
+var paramBits = [reflect.UnsafePointer + 1]int{
+	reflect.Int: strconv.IntSize, reflect.Int8: 8, reflect.Int16: 16, reflect.Int32: 32, reflect.Int64: 64,
+	reflect.Uint: strconv.IntSize, reflect.Uint8: 8, reflect.Uint16: 16, reflect.Uint32: 32, reflect.Uint64: 64,
+	reflect.Float32: 32, reflect.Float64: 64,
+}
+
+func parseBoolParam(_ *Params, val string, _ int) (bool, error) {
+	return parseBool(val)
+}
+
+func parseStringParam(p *Params, val string, _ int) (string, error) {
+	return p.transform(val), nil
+}
+
+func parseIntParam[T int | int8 | int16 | int32 | int64](_ *Params, val string, bits int) (T, error) {
+	n, err := strconv.ParseInt(val, 10, bits)
+	return T(n), err
+}
+
+func parseUintParam[T uint | uint8 | uint16 | uint32 | uint64](_ *Params, val string, bits int) (T, error) {
+	n, err := strconv.ParseUint(val, 10, bits)
+	return T(n), err
+}
+
+func parseFloatParam[T float32 | float64](_ *Params, val string, bits int) (T, error) {
+	f, err := strconv.ParseFloat(val, bits)
+	return T(f), err
+}
+
+// bindArg returns the value of the action's argument, from the params.
+func bindArg(c *Controller, arg *MethodArg) reflect.Value {
+	// If they accept a websocket connection, treat that arg specially.
+	if arg.Type == websocketType {
+		return reflect.ValueOf(c.Request.Websocket)
+	}
+	TRACE.Println("Binding:", arg.Name, "as", arg.Type)
+	var value reflect.Value
+	if c.Params.Proto != nil && protoArg(c) == arg {
+		value = bindProto(c.Params, arg.Name, arg.Type)
 	} else {
-		resultValue = methodValue.Call(methodArgs)[0]
+		value = Bind(c.Params, arg.Name, arg.Type)
 	}
-	if resultValue.Kind() == reflect.Interface && !resultValue.IsNil() {
-		c.Result = resultValue.Interface().(Result)
+
+	// Params that could not be bound, e.g. "abc" for an int, are reported as
+	// validation errors.
+	if c.Validation != nil {
+		c.Validation.Errors = append(c.Validation.Errors, c.Params.bindErrors...)
+	}
+	c.Params.bindErrors = nil
+	return value
+}
+
+// badValuesResult answers a request whose params are not valid values of their
+// types with 400 Bad Request, listing them.
+func badValuesResult(c *Controller) Result {
+	var invalid []string
+	for _, err := range c.Params.badValues {
+		invalid = append(invalid, err.Key+" ("+err.Message+")")
 	}
+	c.Response.Status = http.StatusBadRequest
+	return c.RenderError(&Error{
+		Title:       "Bad Request",
+		Description: "Invalid parameters: " + strings.Join(invalid, ", "),
+	})
 }
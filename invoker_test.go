@@ -1,8 +1,15 @@
 package revel
 
 import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -131,3 +138,388 @@ func BenchmarkInvoker(b *testing.B) {
 		ActionInvoker(&c, nil)
 	}
 }
+
+func BenchmarkGeneratedInvoker(b *testing.B) {
+	startFakeBookingApp()
+	c := Controller{
+		RenderArgs: make(map[string]interface{}),
+	}
+	if err := c.SetAction("Hotels", "Show"); err != nil {
+		b.Errorf("Failed to set action: %s", err)
+		return
+	}
+	methodType := *c.MethodType
+	methodType.Invoke = func(c *Controller) Result {
+		var arg0 int
+		BindParam(c, 0, &arg0)
+		return c.AppController.(*Hotels).Show(arg0)
+	}
+	c.MethodType = &methodType
+	c.Request = NewRequest(showRequest)
+	c.Params = &Params{Values: make(url.Values)}
+	c.Params.Set("id", "3")
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ActionInvoker(&c, nil)
+	}
+}
+
+// Benchmark a small action with a few params, through reflection and through
+// the generated adapter.
+func BenchmarkInvokerParams(b *testing.B) {
+	startFakeBookingApp()
+	invoke := func(c *Controller) Result {
+		var arg0 int
+		BindParam(c, 0, &arg0)
+		var arg1 uint8
+		BindParam(c, 1, &arg1)
+		var arg2 float64
+		BindParam(c, 2, &arg2)
+		var arg3 string
+		BindParam(c, 3, &arg3)
+		var arg4 bool
+		BindParam(c, 4, &arg4)
+		return c.AppController.(*Greeter).Page(arg0, arg1, arg2, arg3, arg4)
+	}
+	for _, generated := range []bool{false, true} {
+		methodType := &MethodType{
+			Name: "Page",
+			Args: []*MethodArg{
+				{"page", reflect.TypeOf((*int)(nil))},
+				{"size", reflect.TypeOf((*uint8)(nil))},
+				{"ratio", reflect.TypeOf((*float64)(nil))},
+				{"q", reflect.TypeOf((*string)(nil))},
+				{"all", reflect.TypeOf((*bool)(nil))},
+			},
+		}
+		if generated {
+			methodType.Invoke = invoke
+		}
+		RegisterController((*Greeter)(nil), []*MethodType{methodType})
+		c := NewController(NewRequest(showRequest), nil)
+		c.Params.Values = url.Values{"page": {"2"}, "size": {"10"}, "ratio": {"0.5"}, "q": {"hi"}, "all": {"on"}}
+		if err := c.SetAction("Greeter", "Page"); err != nil {
+			b.Fatal(err)
+		}
+
+		b.Run(fmt.Sprintf("generated=%v", generated), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				ActionInvoker(c, nil)
+			}
+		})
+	}
+}
+
+type Greeter struct{ *Controller }
+
+func (c Greeter) Greet(name string, ids ...int) Result {
+	return c.RenderText("%s %v", name, ids)
+}
+
+var errConflict = errors.New("conflict")
+
+func (c Greeter) Find(name string) (Result, error) {
+	switch name {
+	case "missing":
+		return nil, fmt.Errorf("greeting %q: %w", name, ErrNotFound)
+	case "invalid":
+		return nil, &ValidationError{Message: "Name is invalid", Key: "name"}
+	case "taken":
+		return nil, errConflict
+	case "broken":
+		return nil, errors.New("greeting failed")
+	}
+	return c.RenderText("Hello %s", name), nil
+}
+
+// Test that the errors returned by actions are mapped to results, through
+// both a generated adapter and reflection.
+func TestActionError(t *testing.T) {
+	startFakeBookingApp()
+	RegisterErrorMapper(func(c *Controller, err error) Result {
+		if err != errConflict {
+			return nil
+		}
+		c.Response.Status = http.StatusConflict
+		return c.RenderText("Name is taken")
+	})
+	defer func() { errorMappers = nil }()
+
+	invoke := func(c *Controller) Result {
+		var arg0 string
+		BindParam(c, 0, &arg0)
+		result, err := c.AppController.(*Greeter).Find(arg0)
+		return ActionResult(c, result, err)
+	}
+	for _, generated := range []bool{false, true} {
+		methodType := &MethodType{
+			Name: "Find",
+			Args: []*MethodArg{{"name", reflect.TypeOf((*string)(nil))}},
+		}
+		if generated {
+			methodType.Invoke = invoke
+		}
+		RegisterController((*Greeter)(nil), []*MethodType{methodType})
+
+		for name, status := range map[string]int{
+			"Bob":     http.StatusOK,
+			"missing": http.StatusNotFound,
+			"invalid": http.StatusUnprocessableEntity,
+			"taken":   http.StatusConflict,
+			"broken":  http.StatusInternalServerError,
+		} {
+			resp := httptest.NewRecorder()
+			c := NewController(NewRequest(showRequest), NewResponse(resp))
+			c.Params.Values = url.Values{"name": {name}}
+			if err := c.SetAction("Greeter", "Find"); err != nil {
+				t.Fatal(err)
+			}
+			ActionInvoker(c, nil)
+			c.Result.Apply(c.Request, c.Response)
+			eq(t, fmt.Sprintf("Status of %s (generated: %v)", name, generated), resp.Code, status)
+		}
+	}
+}
+
+type greeting struct{ Name string }
+
+func (c Greeter) Profile(name string) (*greeting, error) {
+	if name == "missing" {
+		return nil, ErrNotFound
+	}
+	return &greeting{name}, nil
+}
+
+// Test that the values returned by actions are rendered in the format of the
+// route, or of the request, or else ValueFormat.
+func TestValueResult(t *testing.T) {
+	startFakeBookingApp()
+	invoke := func(c *Controller) Result {
+		var arg0 string
+		BindParam(c, 0, &arg0)
+		result, err := c.AppController.(*Greeter).Profile(arg0)
+		return ValueResult(c, result, err)
+	}
+	tests := []struct {
+		name, accept, render, expected string
+	}{
+		{"Bob", "application/json", "", "revel.RenderJsonResult"},
+		{"Bob", "application/xml", "", "revel.RenderXmlResult"},
+		{"Bob", "*/*", "", "revel.RenderJsonResult"},
+		{"Bob", "", "", "revel.RenderJsonResult"},
+		{"Bob", "application/json", "xml", "revel.RenderXmlResult"},
+		{"Bob", "text/html", "", "revel.RenderJsonResult"}, // No template
+		{"missing", "application/json", "", "revel.ErrorResult"},
+	}
+	for _, generated := range []bool{false, true} {
+		methodType := &MethodType{
+			Name: "Profile",
+			Args: []*MethodArg{{"name", reflect.TypeOf((*string)(nil))}},
+		}
+		if generated {
+			methodType.Invoke = invoke
+		}
+		RegisterController((*Greeter)(nil), []*MethodType{methodType})
+
+		for _, test := range tests {
+			req, _ := http.NewRequest("GET", "/greeter/"+test.name, nil)
+			req.Header.Set("Accept", test.accept)
+			c := NewController(NewRequest(req), NewResponse(httptest.NewRecorder()))
+			c.Params.Values = url.Values{"name": {test.name}}
+			if test.render != "" {
+				c.Route = &RouteMatch{Route: &Route{render: test.render}}
+			}
+			if err := c.SetAction("Greeter", "Profile"); err != nil {
+				t.Fatal(err)
+			}
+			ActionInvoker(c, nil)
+			eq(t, fmt.Sprintf("Result of %v (generated: %v)", test, generated),
+				fmt.Sprintf("%T", c.Result), test.expected)
+		}
+	}
+}
+
+// Test that an action is called the same way through a generated adapter as
+// through reflection.
+func TestGeneratedInvoke(t *testing.T) {
+	invoke := func(c *Controller) Result {
+		var arg0 string
+		BindParam(c, 0, &arg0)
+		var arg1 []int
+		BindArg(c, 1, &arg1)
+		return c.AppController.(*Greeter).Greet(arg0, arg1...)
+	}
+	for _, generated := range []bool{false, true} {
+		controllers = make(map[string]*ControllerType)
+		methodType := &MethodType{
+			Name: "Greet",
+			Args: []*MethodArg{
+				{"name", reflect.TypeOf((*string)(nil))},
+				{"ids", reflect.TypeOf((*[]int)(nil))},
+			},
+		}
+		if generated {
+			methodType.Invoke = invoke
+		}
+		RegisterController((*Greeter)(nil), []*MethodType{methodType})
+
+		c := NewController(NewRequest(showRequest), nil)
+		c.Params.Values = url.Values{"name": {"Bob"}, "ids[]": {"1", "2"}}
+		if err := c.SetAction("Greeter", "Greet"); err != nil {
+			t.Fatal(err)
+		}
+		ActionInvoker(c, nil)
+		result, ok := c.Result.(*RenderTextResult)
+		if eq(t, "Text result", ok, true) {
+			eq(t, "Text", result.text, "Bob [1 2]")
+		}
+	}
+}
+
+// A UUID, as in github.com/google/uuid, bound by its UnmarshalText.
+type testUUID [16]byte
+
+func (u *testUUID) UnmarshalText(text []byte) error {
+	b, err := hex.DecodeString(strings.Replace(string(text), "-", "", -1))
+	if err == nil && len(b) != len(u) {
+		err = fmt.Errorf("invalid UUID length: %d", len(b))
+	}
+	copy(u[:], b)
+	return err
+}
+
+func (c Greeter) Tip(id testUUID, amount *big.Float) Result {
+	return c.RenderText("%x %s", id[:2], amount.Text('f', 2))
+}
+
+// Test that params that are invalid for their types fail with 400, before the
+// action is called.
+func TestBadValues(t *testing.T) {
+	startFakeBookingApp()
+	invoke := func(c *Controller) Result {
+		var arg0 testUUID
+		BindArg(c, 0, &arg0)
+		var arg1 *big.Float
+		BindArg(c, 1, &arg1)
+		return c.AppController.(*Greeter).Tip(arg0, arg1)
+	}
+	for _, generated := range []bool{false, true} {
+		methodType := &MethodType{
+			Name: "Tip",
+			Args: []*MethodArg{
+				{"id", reflect.TypeOf((*testUUID)(nil))},
+				{"amount", reflect.TypeOf((**big.Float)(nil))},
+			},
+		}
+		if generated {
+			methodType.Invoke = invoke
+		}
+		RegisterController((*Greeter)(nil), []*MethodType{methodType})
+
+		for _, test := range []struct {
+			id, amount string
+			status     int
+			body       string
+		}{
+			{"12345678-9abc-def0-1234-56789abcdef0", "2.5", http.StatusOK, "1234 2.50"},
+			{"12345678", "2.5", http.StatusBadRequest, "Invalid parameters: id"},
+			{"12345678-9abc-def0-1234-56789abcdef0", "two", http.StatusBadRequest, "Invalid parameters: amount"},
+		} {
+			resp := httptest.NewRecorder()
+			c := NewController(NewRequest(showRequest), NewResponse(resp))
+			c.Params.Values = url.Values{"id": {test.id}, "amount": {test.amount}}
+			if err := c.SetAction("Greeter", "Tip"); err != nil {
+				t.Fatal(err)
+			}
+			ActionInvoker(c, nil)
+			c.Result.Apply(c.Request, c.Response)
+			name := fmt.Sprintf("%s %s (generated: %v)", test.id, test.amount, generated)
+			eq(t, "Status of "+name, resp.Code, test.status)
+			eq(t, "Body of "+name, strings.Contains(resp.Body.String(), test.body), true)
+		}
+	}
+}
+
+func (c Greeter) Page(page int, size uint8, ratio float64, q string, all bool) Result {
+	return c.RenderText("%d %d %v %q %v", page, size, ratio, q, all)
+}
+
+// Test that the arguments that generated adapters bind with BindParam are
+// bound as through reflection, and by the application's own binders, if it has
+// any.
+func TestBindParam(t *testing.T) {
+	startFakeBookingApp()
+	invoke := func(c *Controller) Result {
+		var arg0 int
+		BindParam(c, 0, &arg0)
+		var arg1 uint8
+		BindParam(c, 1, &arg1)
+		var arg2 float64
+		BindParam(c, 2, &arg2)
+		var arg3 string
+		BindParam(c, 3, &arg3)
+		var arg4 bool
+		BindParam(c, 4, &arg4)
+		return c.AppController.(*Greeter).Page(arg0, arg1, arg2, arg3, arg4)
+	}
+	page := func(generated bool, query string) (text string, errors []string) {
+		methodType := &MethodType{
+			Name: "Page",
+			Args: []*MethodArg{
+				{"page", reflect.TypeOf((*int)(nil))},
+				{"size", reflect.TypeOf((*uint8)(nil))},
+				{"ratio", reflect.TypeOf((*float64)(nil))},
+				{"q", reflect.TypeOf((*string)(nil))},
+				{"all", reflect.TypeOf((*bool)(nil))},
+			},
+		}
+		if generated {
+			methodType.Invoke = invoke
+		}
+		RegisterController((*Greeter)(nil), []*MethodType{methodType})
+
+		c := NewController(NewRequest(showRequest), NewResponse(httptest.NewRecorder()))
+		c.Params.Values, _ = url.ParseQuery(query)
+		c.Validation = &Validation{}
+		if err := c.SetAction("Greeter", "Page"); err != nil {
+			t.Fatal(err)
+		}
+		ActionInvoker(c, nil)
+		for _, err := range c.Validation.Errors {
+			errors = append(errors, err.Key+": "+err.Message)
+		}
+		return c.Result.(*RenderTextResult).text, errors
+	}
+
+	for _, test := range []struct {
+		query, text, errors string
+	}{
+		{"page=2&size=10&ratio=0.5&q=hi&all=on", `2 10 0.5 "hi" true`, "[]"},
+		{"page=&q=", `0 0 0 "" false`, "[]"},
+		{"page=two&size=300&all=yes", `0 0 0 "" false`,
+			`[page: "two" is not a valid int size: "300" is not a valid uint8 all: "yes" is not a valid bool]`},
+	} {
+		for _, generated := range []bool{false, true} {
+			text, errors := page(generated, test.query)
+			name := fmt.Sprintf("%s (generated: %v)", test.query, generated)
+			eq(t, "Text of "+name, text, test.text)
+			eq(t, "Errors of "+name, fmt.Sprint(errors), test.errors)
+		}
+	}
+
+	eq(t, "Ints bound directly", isDirect(reflect.TypeOf(0)), true)
+	defer func(binder Binder) {
+		KindBinders[reflect.Int] = binder
+		findDirectKinds()
+	}(KindBinders[reflect.Int])
+	KindBinders[reflect.Int] = Binder{
+		Bind: func(params *Params, name string, typ reflect.Type) reflect.Value {
+			return reflect.ValueOf(42)
+		},
+	}
+	findDirectKinds()
+	eq(t, "Ints bound directly", isDirect(reflect.TypeOf(0)), false)
+	text, _ := page(true, "page=2")
+	eq(t, "Text with the application's binder", text, `42 0 0 "" false`)
+}
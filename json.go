@@ -0,0 +1,224 @@
+package revel
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// JsonOptions are the options of rendered JSON (see RenderJson).
+type JsonOptions struct {
+	Pretty bool // true to indent it
+
+	// Envelope is the key of the object that the value is wrapped in, e.g.
+	// "data" for {"data": ..., "meta": ...}, or "" to render it as it is.
+	Envelope string
+
+	// Meta is rendered beside the value, as "meta", if it is wrapped in an
+	// envelope and Meta is not nil, e.g. {"total": 120}.
+	Meta map[string]interface{}
+
+	// FieldCase, if set, transforms the keys of objects, e.g. to snake case.
+	FieldCase func(string) string
+}
+
+// DefaultJsonOptions are the options of rendered JSON, except for requests
+// that override them (see Controller.JsonOptions).  They are set by
+// "results.pretty", "results.jsonEnvelope", and "results.jsonCase", the name
+// of one of JsonCases.
+var DefaultJsonOptions JsonOptions
+
+// JsonCases are the transforms of the keys of JSON objects, by name, to set
+// "results.jsonCase" to.  Apps may add their own.
+var JsonCases = map[string]func(string) string{
+	"snake": snakeCase,
+	"kebab": kebabCase,
+	"camel": camelCase,
+}
+
+func init() {
+	OnAppStart(func() {
+		DefaultJsonOptions = JsonOptions{
+			Pretty:   Config.BoolDefault("results.pretty", false),
+			Envelope: Config.StringDefault("results.jsonEnvelope", ""),
+		}
+		if name := Config.StringDefault("results.jsonCase", ""); name != "" {
+			if DefaultJsonOptions.FieldCase = JsonCases[name]; DefaultJsonOptions.FieldCase == nil {
+				ERROR.Fatalln("Invalid results.jsonCase:", name)
+			}
+		}
+	})
+}
+
+// jsonOptions returns the options of the JSON rendered for the request.
+func (c *Controller) jsonOptions() JsonOptions {
+	if c.JsonOptions != nil {
+		return *c.JsonOptions
+	}
+	return DefaultJsonOptions
+}
+
+// JsonRenderer returns a func that renders JSON as RenderJson does, with the
+// request's options as they are now.  It does not use the controller, so it may
+// be called from another goroutine, e.g. the one of Defer.
+func (c *Controller) JsonRenderer() func(o interface{}) Result {
+	options := c.jsonOptions()
+	return func(o interface{}) Result {
+		return RenderJsonResult{o, options}
+	}
+}
+
+// marshalJson encodes the value as JSON, with the options.
+func marshalJson(value interface{}, options JsonOptions) ([]byte, error) {
+	b, err := marshalJsonKeys(value, options.FieldCase)
+	if err != nil {
+		return nil, err
+	}
+	if options.Envelope != "" {
+		var envelope bytes.Buffer
+		key, _ := json.Marshal(options.Envelope)
+		envelope.WriteString("{")
+		envelope.Write(key)
+		envelope.WriteString(":")
+		envelope.Write(b)
+		if options.Meta != nil {
+			meta, err := marshalJsonKeys(options.Meta, options.FieldCase)
+			if err != nil {
+				return nil, err
+			}
+			envelope.WriteString(`,"meta":`)
+			envelope.Write(meta)
+		}
+		envelope.WriteString("}")
+		b = envelope.Bytes()
+	}
+	if options.Pretty {
+		var indented bytes.Buffer
+		if err = json.Indent(&indented, b, "", "  "); err != nil {
+			return nil, err
+		}
+		b = indented.Bytes()
+	}
+	return b, nil
+}
+
+// marshalJsonKeys encodes the value as JSON, with the keys of its objects
+// transformed, if transform is set.
+func marshalJsonKeys(value interface{}, transform func(string) string) ([]byte, error) {
+	b, err := json.Marshal(value)
+	if err != nil || transform == nil {
+		return b, err
+	}
+	return transformJsonKeys(b, transform)
+}
+
+// transformJsonKeys transforms the keys of the objects of the JSON, keeping
+// their order.
+func transformJsonKeys(data []byte, transform func(string) string) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var out bytes.Buffer
+	// The objects and arrays being written, and the tokens written in each.
+	type level struct {
+		object bool
+		tokens int
+	}
+	var levels []level
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return out.Bytes(), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if delim, ok := token.(json.Delim); ok && (delim == '}' || delim == ']') {
+			levels = levels[:len(levels)-1]
+			out.WriteRune(rune(delim))
+			continue
+		}
+
+		isKey := false
+		if len(levels) > 0 {
+			top := &levels[len(levels)-1]
+			isKey = top.object && top.tokens%2 == 0
+			switch {
+			case top.tokens == 0:
+			case isKey:
+				out.WriteByte(',')
+			case top.object:
+				out.WriteByte(':')
+			default:
+				out.WriteByte(',')
+			}
+			top.tokens++
+		}
+
+		switch t := token.(type) {
+		case json.Delim:
+			out.WriteRune(rune(t))
+			levels = append(levels, level{object: t == '{'})
+			continue
+		case string:
+			if isKey {
+				token = transform(t)
+			}
+		}
+		b, err := json.Marshal(token)
+		if err != nil {
+			return nil, err
+		}
+		out.Write(b)
+	}
+}
+
+// jsonWords splits a name into its lower-case words, e.g. "UserID" into
+// "user" and "id", and "HTTPServer" into "http" and "server".
+func jsonWords(name string) []string {
+	var words []string
+	var word []rune
+	runes := []rune(name)
+	for i, r := range runes {
+		if r == '_' || r == '-' || r == ' ' {
+			if len(word) > 0 {
+				words, word = append(words, string(word)), nil
+			}
+			continue
+		}
+		if unicode.IsUpper(r) && len(word) > 0 {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				words, word = append(words, string(word)), nil
+			}
+		}
+		word = append(word, unicode.ToLower(r))
+	}
+	if len(word) > 0 {
+		words = append(words, string(word))
+	}
+	return words
+}
+
+// e.g. "UserID" => "user_id"
+func snakeCase(name string) string {
+	return strings.Join(jsonWords(name), "_")
+}
+
+// e.g. "UserID" => "user-id"
+func kebabCase(name string) string {
+	return strings.Join(jsonWords(name), "-")
+}
+
+// e.g. "UserID" => "userId", "user_name" => "userName"
+func camelCase(name string) string {
+	words := jsonWords(name)
+	for i := 1; i < len(words); i++ {
+		runes := []rune(words[i])
+		runes[0] = unicode.ToUpper(runes[0])
+		words[i] = string(runes)
+	}
+	return strings.Join(words, "")
+}
@@ -0,0 +1,62 @@
+package revel
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestJsonCases(t *testing.T) {
+	for name, expected := range map[string][3]string{
+		"UserID":     {"user_id", "user-id", "userId"},
+		"HTTPServer": {"http_server", "http-server", "httpServer"},
+		"userName":   {"user_name", "user-name", "userName"},
+		"user_name":  {"user_name", "user-name", "userName"},
+		"Address2":   {"address2", "address2", "address2"},
+		"name":       {"name", "name", "name"},
+	} {
+		eq(t, "Snake case of "+name, snakeCase(name), expected[0])
+		eq(t, "Kebab case of "+name, kebabCase(name), expected[1])
+		eq(t, "Camel case of "+name, camelCase(name), expected[2])
+	}
+}
+
+func TestJsonOptions(t *testing.T) {
+	type address struct{ ZipCode string }
+	type user struct {
+		UserID    int
+		FirstName string
+		Addresses []address
+		Extra     map[string]interface{}
+	}
+	value := []user{{1, "Bob <b>", []address{{"10001"}}, map[string]interface{}{"LastSeen": nil}}}
+	defer func(options JsonOptions) { DefaultJsonOptions = options }(DefaultJsonOptions)
+
+	for _, test := range []struct {
+		name     string
+		defaults JsonOptions
+		override *JsonOptions
+		expected string
+	}{
+		{"Plain", JsonOptions{}, nil,
+			`[{"UserID":1,"FirstName":"Bob \u003cb\u003e","Addresses":[{"ZipCode":"10001"}],"Extra":{"LastSeen":null}}]`},
+		{"Snake case", JsonOptions{FieldCase: snakeCase}, nil,
+			`[{"user_id":1,"first_name":"Bob \u003cb\u003e","addresses":[{"zip_code":"10001"}],"extra":{"last_seen":null}}]`},
+		{"Envelope", JsonOptions{Envelope: "data", FieldCase: camelCase}, nil,
+			`{"data":[{"userId":1,"firstName":"Bob \u003cb\u003e","addresses":[{"zipCode":"10001"}],"extra":{"lastSeen":null}}]}`},
+		{"Override", JsonOptions{Envelope: "data"}, &JsonOptions{Envelope: "users", Meta: map[string]interface{}{"TotalCount": 1}, FieldCase: snakeCase},
+			`{"users":[{"user_id":1,"first_name":"Bob \u003cb\u003e","addresses":[{"zip_code":"10001"}],"extra":{"last_seen":null}}],"meta":{"total_count":1}}`},
+		{"Pretty", JsonOptions{Pretty: true}, &JsonOptions{Pretty: true, Envelope: "data"},
+			"{\n  \"data\": [\n    {\n      \"UserID\": 1,\n      \"FirstName\": \"Bob \\u003cb\\u003e\",\n" +
+				"      \"Addresses\": [\n        {\n          \"ZipCode\": \"10001\"\n        }\n      ],\n" +
+				"      \"Extra\": {\n        \"LastSeen\": null\n      }\n    }\n  ]\n}"},
+	} {
+		DefaultJsonOptions = test.defaults
+		req, _ := http.NewRequest("GET", "/users", nil)
+		resp := httptest.NewRecorder()
+		c := NewController(NewRequest(req), NewResponse(resp))
+		c.JsonOptions = test.override
+		c.RenderJson(value).Apply(c.Request, c.Response)
+		eq(t, "JSON: "+test.name, resp.Body.String(), test.expected)
+	}
+}
@@ -0,0 +1,167 @@
+package revel
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"reflect"
+)
+
+// JsonStreamFlush is the number of items of a streamed JSON response (see
+// RenderJsonArray) that are sent at a time, from "results.jsonStreamFlush".
+var JsonStreamFlush = 100
+
+func init() {
+	OnAppStart(func() {
+		JsonStreamFlush = Config.IntDefault("results.jsonStreamFlush", JsonStreamFlush)
+	})
+}
+
+// RenderJsonArray streams a JSON array of the items, without holding them all
+// in memory, e.g. to export millions of rows.  The items are given by a
+// channel, which is received from only as fast as the client reads, until it
+// is closed:
+//
+//     rows := make(chan *models.Order)
+//     go func() {
+//     	defer close(rows)
+//     	for _, order := range ... {
+//     		select {
+//     		case rows <- order:
+//     		case <-c.Request.Context().Done():
+//     			return
+//     		}
+//     	}
+//     }()
+//     return c.RenderJsonArray(rows)
+//
+// or by a function that writes them to the stream, e.g. as it scans a query:
+//
+//     return c.RenderJsonArray(func(stream *revel.JsonStream) error {
+//     	for rows.Next() {
+//     		...
+//     		if err := stream.Write(order); err != nil {
+//     			return err
+//     		}
+//     	}
+//     	return rows.Err()
+//     })
+//
+// The status has been sent by then, so an error from the function (or in
+// encoding an item) is logged, and the array is left unclosed, so that the
+// client sees that it is incomplete.  The stream is flushed every
+// JsonStreamFlush items, and by JsonStream.Flush.
+func (c *Controller) RenderJsonArray(items interface{}) Result {
+	return newJsonStreamResult(items, false)
+}
+
+// RenderNDJson streams the items as newline-delimited JSON
+// (application/x-ndjson), one per line, as RenderJsonArray streams them.
+func (c *Controller) RenderNDJson(items interface{}) Result {
+	return newJsonStreamResult(items, true)
+}
+
+type JsonStreamResult struct {
+	items  reflect.Value           // the channel of the items, or
+	write  func(*JsonStream) error // the function that writes them
+	ndjson bool
+}
+
+func newJsonStreamResult(items interface{}, ndjson bool) *JsonStreamResult {
+	if write, ok := items.(func(*JsonStream) error); ok {
+		return &JsonStreamResult{write: write, ndjson: ndjson}
+	}
+	value := reflect.ValueOf(items)
+	if value.Kind() != reflect.Chan || value.Type().ChanDir()&reflect.RecvDir == 0 {
+		panic("revel: the items of a JSON stream must be a channel or a func(*revel.JsonStream) error")
+	}
+	return &JsonStreamResult{items: value, ndjson: ndjson}
+}
+
+func (r *JsonStreamResult) Apply(req *Request, resp *Response) {
+	contentType := "application/json"
+	if r.ndjson {
+		contentType = "application/x-ndjson"
+	}
+	resp.WriteHeader(http.StatusOK, contentType)
+	stream := &JsonStream{w: resp.Out, ndjson: r.ndjson}
+	stream.flusher, _ = resp.Out.(http.Flusher)
+	if !r.ndjson {
+		io.WriteString(resp.Out, "[")
+	}
+
+	var err error
+	if r.write != nil {
+		err = r.write(stream)
+	} else {
+		err = stream.writeItems(req, r.items)
+	}
+	if err != nil {
+		ERROR.Println("Error streaming JSON:", err)
+		return
+	}
+
+	if !r.ndjson {
+		io.WriteString(resp.Out, "]")
+	}
+	stream.Flush()
+}
+
+// JsonStream writes the items of a streamed JSON response.
+type JsonStream struct {
+	w       io.Writer
+	flusher http.Flusher
+	ndjson  bool
+	written int
+}
+
+// Write writes the item to the stream, returning the error in encoding it, or
+// in writing it (e.g. if the client has gone).
+func (s *JsonStream) Write(item interface{}) error {
+	b, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+	if s.written > 0 && !s.ndjson {
+		b = append([]byte{','}, b...)
+	}
+	if s.ndjson {
+		b = append(b, '\n')
+	}
+	if _, err = s.w.Write(b); err != nil {
+		return err
+	}
+	s.written++
+	if JsonStreamFlush > 0 && s.written%JsonStreamFlush == 0 {
+		s.Flush()
+	}
+	return nil
+}
+
+// Flush sends the items written so far to the client.
+func (s *JsonStream) Flush() {
+	if s.flusher != nil {
+		s.flusher.Flush()
+	}
+}
+
+// writeItems writes the items received from the channel until it is closed,
+// or the request is done.
+func (s *JsonStream) writeItems(req *Request, items reflect.Value) error {
+	cases := []reflect.SelectCase{
+		{Dir: reflect.SelectRecv, Chan: items},
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(req.Context().Done())},
+	}
+	for {
+		chosen, item, ok := reflect.Select(cases)
+		if chosen == 1 {
+			return req.Context().Err()
+		}
+		if !ok {
+			return nil
+		}
+		if err := s.Write(item.Interface()); err != nil {
+			return err
+		}
+	}
+}
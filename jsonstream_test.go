@@ -0,0 +1,76 @@
+package revel
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestJsonStreams(t *testing.T) {
+	type row struct {
+		Id   int
+		Name string
+	}
+	rows := func() <-chan row {
+		items := make(chan row)
+		go func() {
+			defer close(items)
+			for i, name := range []string{"a", "b", "c"} {
+				items <- row{i + 1, name}
+			}
+		}()
+		return items
+	}
+
+	for _, test := range []struct {
+		name        string
+		render      func(c *Controller) Result
+		contentType string
+		expected    string
+	}{
+		{"Array", func(c *Controller) Result { return c.RenderJsonArray(rows()) },
+			"application/json", `[{"Id":1,"Name":"a"},{"Id":2,"Name":"b"},{"Id":3,"Name":"c"}]`},
+		{"Empty array", func(c *Controller) Result {
+			items := make(chan int)
+			close(items)
+			return c.RenderJsonArray(items)
+		}, "application/json", `[]`},
+		{"NDJSON", func(c *Controller) Result { return c.RenderNDJson(rows()) },
+			"application/x-ndjson", "{\"Id\":1,\"Name\":\"a\"}\n{\"Id\":2,\"Name\":\"b\"}\n{\"Id\":3,\"Name\":\"c\"}\n"},
+		{"Writer", func(c *Controller) Result {
+			return c.RenderJsonArray(func(stream *JsonStream) error {
+				for i := 0; i < 3; i++ {
+					if err := stream.Write(i); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+		}, "application/json", `[0,1,2]`},
+		{"Failed writer", func(c *Controller) Result {
+			return c.RenderJsonArray(func(stream *JsonStream) error {
+				stream.Write(1)
+				return errors.New("the query failed")
+			})
+		}, "application/json", `[1`},
+	} {
+		req, _ := http.NewRequest("GET", "/orders/export", nil)
+		resp := httptest.NewRecorder()
+		c := NewController(NewRequest(req), NewResponse(resp))
+		test.render(c).Apply(c.Request, c.Response)
+
+		eq(t, "Content-Type of "+test.name, resp.Header().Get("Content-Type"), test.contentType)
+		eq(t, "Body of "+test.name, resp.Body.String(), test.expected)
+	}
+
+	// The stream ends when the client goes, though the channel is open.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req, _ := http.NewRequest("GET", "/orders/export", nil)
+	resp := httptest.NewRecorder()
+	c := NewController(NewRequest(req.WithContext(ctx)), NewResponse(resp))
+	c.RenderJsonArray(make(chan int)).Apply(c.Request, c.Response)
+	eq(t, "Body when the client has gone", resp.Body.String(), "[")
+}
@@ -0,0 +1,101 @@
+package revel
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// RequestLogger writes structured log lines, of a message followed by
+// key=value pairs, to revel's loggers (TRACE, INFO, WARN and ERROR).  The
+// request logger of a Controller (c.Log) adds the request's ID, action and
+// remote IP to each line, e.g.
+//
+//     c.Log.Info("Booked hotel", "hotel", hotel.Id, "nights", booking.Nights())
+//
+// logs
+//
+//     INFO  2013/06/01 12:00:00 hotels.go:140: Booked hotel hotel=3 nights=2 requestId=4f1c... action=Hotels.ConfirmBooking ip=10.0.0.5
+type RequestLogger struct {
+	c       *Controller
+	keyvals []interface{}
+}
+
+// With returns a logger that adds the key=value pairs to each line, after
+// those of the call.
+func (l RequestLogger) With(keyvals ...interface{}) RequestLogger {
+	l.keyvals = append(l.keyvals[:len(l.keyvals):len(l.keyvals)], keyvals...)
+	return l
+}
+
+func (l RequestLogger) Trace(msg string, keyvals ...interface{}) { l.output(TRACE, msg, keyvals) }
+func (l RequestLogger) Info(msg string, keyvals ...interface{})  { l.output(INFO, msg, keyvals) }
+func (l RequestLogger) Warn(msg string, keyvals ...interface{})  { l.output(WARN, msg, keyvals) }
+func (l RequestLogger) Error(msg string, keyvals ...interface{}) { l.output(ERROR, msg, keyvals) }
+
+// Unbound returns a logger that adds the request's ID, action and remote IP
+// as they are now, without keeping the controller, so that it may be used
+// after the request has been handled (e.g. by a goroutine that outlives it).
+func (l RequestLogger) Unbound() RequestLogger {
+	l.keyvals = append(l.keyvals[:len(l.keyvals):len(l.keyvals)], l.requestKeyvals()...)
+	l.c = nil
+	return l
+}
+
+// output writes the line to the logger, attributed to the caller of the
+// logging method.
+func (l RequestLogger) output(logger *log.Logger, msg string, keyvals []interface{}) {
+	var line strings.Builder
+	line.WriteString(msg)
+	appendKeyvals(&line, keyvals)
+	appendKeyvals(&line, l.keyvals)
+	appendKeyvals(&line, l.requestKeyvals())
+	logger.Output(3, line.String())
+}
+
+// requestKeyvals returns the key=value pairs of the controller's request.
+func (l RequestLogger) requestKeyvals() []interface{} {
+	c := l.c
+	if c == nil {
+		return nil
+	}
+	var keyvals []interface{}
+	if c.RequestId != "" {
+		keyvals = append(keyvals, "requestId", c.RequestId)
+	}
+	if c.Action != "" {
+		keyvals = append(keyvals, "action", c.Action)
+	}
+	if c.Request != nil && c.Request.Request != nil {
+		keyvals = append(keyvals, "ip", remoteIp(c.Request.Request))
+	}
+	return keyvals
+}
+
+// appendKeyvals appends the key=value pairs to the line.  Values are quoted if
+// they are empty or contain spaces, quotes or equals signs.  A key without a
+// value is given the value "MISSING".
+func appendKeyvals(line *strings.Builder, keyvals []interface{}) {
+	for i := 0; i < len(keyvals); i += 2 {
+		var value interface{} = "MISSING"
+		if i+1 < len(keyvals) {
+			value = keyvals[i+1]
+		}
+		s := fmt.Sprint(value)
+		if s == "" || strings.ContainsAny(s, " \t\r\n\"=") {
+			s = fmt.Sprintf("%q", s)
+		}
+		fmt.Fprintf(line, " %v=%s", keyvals[i], s)
+	}
+}
+
+// remoteIp returns the IP address of the client making the request.
+func remoteIp(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,39 @@
+package revel
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"testing"
+)
+
+func TestRequestLogger(t *testing.T) {
+	defer func(logger *log.Logger) { INFO = logger }(INFO)
+	var buf bytes.Buffer
+	INFO = log.New(&buf, "INFO  ", 0)
+
+	req, _ := http.NewRequest("GET", "/hotels/3", nil)
+	req.RemoteAddr = "10.0.0.5:41234"
+	c := NewController(NewRequest(req), nil)
+	c.RequestId = "abc"
+	c.Action = "Hotels.Show"
+
+	c.Log.Info("Showing hotel", "id", 3, "name", "The Ritz", "empty", "")
+	eq(t, "Line", buf.String(),
+		`INFO  Showing hotel id=3 name="The Ritz" empty="" requestId=abc action=Hotels.Show ip=10.0.0.5`+"\n")
+
+	buf.Reset()
+	c.Log.With("user", "bob").Info("Booked", "nights")
+	eq(t, "Line with context", buf.String(),
+		"INFO  Booked nights=MISSING user=bob requestId=abc action=Hotels.Show ip=10.0.0.5\n")
+
+	buf.Reset()
+	unbound := c.Log.Unbound()
+	c.RequestId, c.Action = "", ""
+	unbound.Info("Later")
+	eq(t, "Unbound line", buf.String(), "INFO  Later requestId=abc action=Hotels.Show ip=10.0.0.5\n")
+
+	buf.Reset()
+	RequestLogger{}.Info("No request")
+	eq(t, "Line without a controller", buf.String(), "INFO  No request\n")
+}
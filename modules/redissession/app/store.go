@@ -0,0 +1,222 @@
+// This module stores sessions in Redis, by github.com/redis/go-redis, so that
+// the instances of an app share them, and they may be ended on the server.
+//
+// Developers use this module by importing it, for it to register the "redis"
+// session store, and setting it in app.conf:
+//
+//   session.store = redis
+//   session.redis.addr = localhost:6379
+//
+// or, for Sentinel:
+//
+//   session.redis.sentinels = 10.0.0.1:26379,10.0.0.2:26379,10.0.0.3:26379
+//   session.redis.master = mymaster
+//
+// or, for Cluster:
+//
+//   session.redis.cluster = 10.0.0.1:6379,10.0.0.2:6379,10.0.0.3:6379
+//
+// Other options are session.redis.password, session.redis.db,
+// session.redis.timeout (e.g. "1s"), session.redis.pool (the idle
+// connections kept for each server), session.redis.prefix (of the keys, by
+// default "revel:session:"), session.redis.ttl (the life of sessions, by
+// default session.expires), and session.redis.codec (the name of one of
+// Codecs, by default "json").
+//
+// Of a Sentinel deployment, each connection is checked to be to the master,
+// so that sessions are not written to a replica that it has been demoted to.
+package redissession
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"github.com/redis/go-redis/v9"
+	"github.com/robfig/revel"
+	"strings"
+	"time"
+)
+
+// Codec serializes sessions, to store them.
+type Codec struct {
+	Marshal   func(session revel.Session) ([]byte, error)
+	Unmarshal func(data []byte) (revel.Session, error)
+}
+
+// Codecs are the serializations of sessions, by name, to set
+// "session.redis.codec" to.  Apps may add their own.
+var Codecs = map[string]Codec{
+	"json": {
+		Marshal: func(session revel.Session) ([]byte, error) {
+			return json.Marshal(session)
+		},
+		Unmarshal: func(data []byte) (revel.Session, error) {
+			session := revel.Session{}
+			err := json.Unmarshal(data, &session)
+			return session, err
+		},
+	},
+	"gob": {
+		Marshal: func(session revel.Session) ([]byte, error) {
+			var b bytes.Buffer
+			err := gob.NewEncoder(&b).Encode(map[string]string(session))
+			return b.Bytes(), err
+		},
+		Unmarshal: func(data []byte) (revel.Session, error) {
+			session := revel.Session{}
+			err := gob.NewDecoder(bytes.NewReader(data)).Decode((*map[string]string)(&session))
+			return session, err
+		},
+	},
+}
+
+func init() {
+	revel.RegisterSessionStore("redis", open)
+}
+
+// Store is a revel.SessionStore that keeps sessions in Redis.
+type Store struct {
+	Prefix string        // of the keys of sessions, e.g. "revel:session:"
+	TTL    time.Duration // the life of sessions, or 0 for that of the SessionFilter
+	Codec  Codec
+	Client redis.UniversalClient
+}
+
+// NewStore returns a store of sessions in the Redis of the client, with the
+// default prefix and codec.
+func NewStore(client redis.UniversalClient) *Store {
+	return &Store{Prefix: "revel:session:", Codec: Codecs["json"], Client: client}
+}
+
+// open opens the store configured in app.conf.
+func open() (revel.SessionStore, error) {
+	config := revel.Config
+	options := &redis.UniversalOptions{
+		MasterName: config.StringDefault("session.redis.master", ""),
+		Password:   config.StringDefault("session.redis.password", ""),
+		DB:         config.IntDefault("session.redis.db", 0),
+		PoolSize:   config.IntDefault("session.redis.pool", 10),
+	}
+	timeout, err := configDuration("session.redis.timeout")
+	if err != nil {
+		return nil, err
+	}
+	options.DialTimeout, options.ReadTimeout, options.WriteTimeout = timeout, timeout, timeout
+
+	var client redis.UniversalClient
+	sentinels := splitAddrs(config.StringDefault("session.redis.sentinels", ""))
+	cluster := splitAddrs(config.StringDefault("session.redis.cluster", ""))
+	switch {
+	case cluster != nil:
+		options.Addrs = cluster
+		client = redis.NewClusterClient(options.Cluster())
+	case options.MasterName != "":
+		if sentinels == nil {
+			return nil, errors.New("no session.redis.sentinels of session.redis.master found")
+		}
+		options.Addrs = sentinels
+		failover := options.Failover()
+		failover.OnConnect = checkMaster
+		client = redis.NewFailoverClient(failover)
+	default:
+		options.Addrs = []string{config.StringDefault("session.redis.addr", "localhost:6379")}
+		client = redis.NewClient(options.Simple())
+	}
+
+	store := NewStore(client)
+	store.Prefix = config.StringDefault("session.redis.prefix", store.Prefix)
+	if store.TTL, err = configDuration("session.redis.ttl"); err != nil {
+		return nil, err
+	}
+	name := config.StringDefault("session.redis.codec", "json")
+	codec, ok := Codecs[name]
+	if !ok {
+		return nil, errors.New("invalid session.redis.codec: " + name)
+	}
+	store.Codec = codec
+	return store, nil
+}
+
+// checkMaster refuses connections to replicas, by their ROLE, so that a
+// master that the sentinels report after it has been demoted is not written
+// to.  (The connections to the sentinels themselves are let be.)
+func checkMaster(ctx context.Context, cn *redis.Conn) error {
+	cmd := redis.NewSliceCmd(ctx, "ROLE")
+	if err := cn.Process(ctx, cmd); err != nil {
+		return err
+	}
+	reply := cmd.Val()
+	if len(reply) == 0 {
+		return errors.New("redis: empty ROLE reply")
+	}
+	if role, _ := reply[0].(string); role == "slave" || role == "replica" {
+		return errors.New("redis: " + cn.String() + " is a replica, not the master")
+	}
+	return nil
+}
+
+func (s *Store) Get(id string) (revel.Session, error) {
+	data, err := s.Client.Get(context.Background(), s.Prefix+id).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.Codec.Unmarshal(data)
+}
+
+func (s *Store) Set(id string, session revel.Session, expires time.Duration) error {
+	data, err := s.Codec.Marshal(session)
+	if err != nil {
+		return err
+	}
+	return s.Client.Set(context.Background(), s.Prefix+id, data, s.ttl(expires)).Err()
+}
+
+func (s *Store) Destroy(id string) error {
+	return s.Client.Del(context.Background(), s.Prefix+id).Err()
+}
+
+func (s *Store) Touch(id string, expires time.Duration) error {
+	return s.Client.PExpire(context.Background(), s.Prefix+id, s.ttl(expires)).Err()
+}
+
+// ttl returns the life of a session: the store's TTL, or else that given.
+func (s *Store) ttl(expires time.Duration) time.Duration {
+	if s.TTL > 0 {
+		expires = s.TTL
+	}
+	if expires < time.Millisecond {
+		expires = time.Millisecond
+	}
+	return expires
+}
+
+// splitAddrs splits a list of addresses separated by commas, or returns nil
+// if it is empty.
+func splitAddrs(list string) []string {
+	var addrs []string
+	for _, addr := range strings.Split(list, ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs
+}
+
+// configDuration returns the duration of the config key, or 0 if it is not
+// set.
+func configDuration(key string) (time.Duration, error) {
+	value, ok := revel.Config.String(key)
+	if !ok {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, errors.New("invalid " + key + ": " + value)
+	}
+	return d, nil
+}
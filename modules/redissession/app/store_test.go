@@ -0,0 +1,92 @@
+package redissession
+
+import (
+	"context"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/alicebob/miniredis/v2/server"
+	"github.com/redis/go-redis/v9"
+	"github.com/robfig/revel"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestStore(t *testing.T) {
+	m := miniredis.RunT(t)
+	m.RequireAuth("secret")
+	store := NewStore(redis.NewClient(&redis.Options{Addr: m.Addr(), Password: "secret", DB: 2}))
+
+	session, err := store.Get("a")
+	if session != nil || err != nil {
+		t.Errorf("Expected no session, got %v, %v", session, err)
+	}
+	if err = store.Set("a", revel.Session{"user": "bob"}, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	m.Select(2)
+	if data, _ := m.Get("revel:session:a"); data != `{"user":"bob"}` || m.TTL("revel:session:a") != time.Hour {
+		t.Errorf("Unexpected stored session: %s %s", data, m.TTL("revel:session:a"))
+	}
+	if session, err = store.Get("a"); err != nil || session["user"] != "bob" {
+		t.Errorf("Expected the session, got %v, %v", session, err)
+	}
+
+	store.TTL = time.Minute
+	store.Touch("a", time.Hour)
+	if m.TTL("revel:session:a") != time.Minute {
+		t.Errorf("Expected the store's TTL, got %s", m.TTL("revel:session:a"))
+	}
+	store.Destroy("a")
+	if session, _ = store.Get("a"); session != nil {
+		t.Errorf("Expected the session to be destroyed, got %v", session)
+	}
+
+	store.Codec = Codecs["gob"]
+	store.Set("b", revel.Session{"user": "alice"}, time.Hour)
+	if session, err = store.Get("b"); err != nil || session["user"] != "alice" {
+		t.Errorf("Expected the gob session, got %v, %v", session, err)
+	}
+}
+
+func TestCheckMaster(t *testing.T) {
+	m := miniredis.RunT(t)
+	role := "slave"
+	m.Server().Register("ROLE", func(c *server.Peer, cmd string, args []string) {
+		c.WriteLen(1)
+		c.WriteBulk(role)
+	})
+
+	// Connections to a replica are refused.
+	client := redis.NewClient(&redis.Options{Addr: m.Addr(), OnConnect: checkMaster, MaxRetries: -1})
+	defer client.Close()
+	store := NewStore(client)
+	if err := store.Set("a", revel.Session{"user": "bob"}, time.Hour); err == nil {
+		t.Errorf("Expected the replica to be refused")
+	}
+	if m.Exists("revel:session:a") {
+		t.Errorf("Expected no session written to the replica")
+	}
+
+	role = "master"
+	if err := store.Set("a", revel.Session{"user": "bob"}, time.Hour); err != nil {
+		t.Errorf("Expected the master to be used, got %v", err)
+	}
+}
+
+func TestClusterStore(t *testing.T) {
+	m := miniredis.RunT(t)
+	store := NewStore(redis.NewClusterClient(&redis.ClusterOptions{Addrs: []string{m.Addr()}}))
+	for i := 0; i < 10; i++ {
+		id := strconv.Itoa(i)
+		if err := store.Set(id, revel.Session{"n": id}, time.Hour); err != nil {
+			t.Fatal(err)
+		}
+		session, err := store.Get(id)
+		if err != nil || session["n"] != id {
+			t.Errorf("Expected session %s, got %v, %v", id, session, err)
+		}
+	}
+	if keys := store.Client.Keys(context.Background(), "revel:session:*").Val(); len(keys) != 10 {
+		t.Errorf("Expected the sessions stored, got %v", keys)
+	}
+}
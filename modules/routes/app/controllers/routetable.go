@@ -0,0 +1,82 @@
+package controllers
+
+import (
+	"github.com/robfig/revel"
+	"net/http"
+)
+
+// RouteTable shows the app's routing table, and which route a given request
+// would match.  It is only available in dev mode.
+type RouteTable struct {
+	*revel.Controller
+}
+
+// RouteTest describes the route matched by a test request.
+type RouteTest struct {
+	Method, Url string
+	Index       int    // index of the matched route in the table, or -1
+	Result      string // e.g. "Users.Show", "404", "405"
+	Params      map[string][]string
+	Error       string
+}
+
+// Index renders the routing table.  If a url is given, the route that it
+// matches is highlighted.
+func (c RouteTable) Index(method, url string) revel.Result {
+	if !revel.DevMode {
+		return c.Forbidden("The route table is only available in dev mode")
+	}
+	routes := revel.MainRouter.List()
+	var test *RouteTest
+	if url != "" {
+		test = testRoute(method, url)
+	}
+	return c.Render(routes, test)
+}
+
+// List returns the routing table as JSON.  If a url is given, the route that
+// it matches is included.
+func (c RouteTable) List(method, url string) revel.Result {
+	if !revel.DevMode {
+		return c.Forbidden("The route table is only available in dev mode")
+	}
+	result := map[string]interface{}{
+		"routes": revel.MainRouter.List(),
+	}
+	if url != "" {
+		result["test"] = testRoute(method, url)
+	}
+	return c.RenderJson(result)
+}
+
+// testRoute finds the route that a request for the given method and url
+// would match.
+func testRoute(method, url string) *RouteTest {
+	if method == "" {
+		method = "GET"
+	}
+	test := &RouteTest{Method: method, Url: url, Index: -1}
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		test.Error = err.Error()
+		return test
+	}
+
+	match := revel.MainRouter.Route(req)
+	switch {
+	case match == nil:
+		test.Result = "404"
+	case match.Route == nil:
+		test.Result = match.Action
+	default:
+		test.Result = match.Route.Action
+		test.Params = match.Params
+		for i, route := range revel.MainRouter.Table() {
+			if route == match.Route {
+				test.Index = i
+				break
+			}
+		}
+	}
+	return test
+}
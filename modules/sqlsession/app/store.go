@@ -0,0 +1,319 @@
+// This module stores sessions in a SQL database, by database/sql, so that the
+// instances of an app share them, and they may be ended on the server.
+//
+// Developers use this module by importing it (and the database's driver), for
+// it to register the "sql" session store, and setting it in app.conf:
+//
+//   session.store = sql
+//   session.sql.driver = postgres
+//   session.sql.spec = postgres://localhost/app?sslmode=disable
+//
+// The driver and spec default to those of the db module (db.driver and
+// db.spec).  Other options are session.sql.table (by default
+// "revel_sessions"), session.sql.numbered (true to write the parameters of
+// statements as $1, $2, ..., which it is by default for the "postgres" and
+// "pgx" drivers), and session.sql.gc (how often expired sessions are deleted,
+// by default "10m", or 0 for never).
+//
+// The table is created when the app starts, and migrated as the module
+// changes, as recorded in the table named after it with "_schema".
+//
+// Each session has a version, so that of concurrent requests with the same
+// session, one does not overwrite the changes of another (see
+// revel.VersionedSessionStore).
+package sqlsession
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/robfig/revel"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// migrations change the schema of the store's table, in order.  Each may be
+// run again, should it fail to be recorded, so must be idempotent.
+var migrations = []func(s *Store, tx *sql.Tx) error{
+	func(s *Store, tx *sql.Tx) error {
+		_, err := tx.Exec("CREATE TABLE IF NOT EXISTS " + s.Table +
+			" (id VARCHAR(64) PRIMARY KEY, data TEXT NOT NULL, expires BIGINT NOT NULL, version BIGINT NOT NULL)")
+		return err
+	},
+	func(s *Store, tx *sql.Tx) error {
+		return s.createIndex(tx, s.Table+"_expires", "expires")
+	},
+}
+
+func init() {
+	revel.RegisterSessionStore("sql", open)
+}
+
+// Store is a revel.SessionStore that keeps sessions in a table of a SQL
+// database.
+type Store struct {
+	DB     *sql.DB
+	Driver string // the name of the database's driver, e.g. "postgres"
+	Table  string
+
+	// NumberedParams, if set, writes the parameters of statements as $1, $2,
+	// ... (as PostgreSQL wants), rather than ?.
+	NumberedParams bool
+}
+
+// open opens the store configured in app.conf, migrating its table.
+func open() (revel.SessionStore, error) {
+	config := revel.Config
+	driver := config.StringDefault("session.sql.driver", config.StringDefault("db.driver", ""))
+	spec := config.StringDefault("session.sql.spec", config.StringDefault("db.spec", ""))
+	if driver == "" {
+		return nil, errors.New("no session.sql.driver (or db.driver) found")
+	}
+	db, err := sql.Open(driver, spec)
+	if err != nil {
+		return nil, err
+	}
+	store := &Store{
+		DB:             db,
+		Driver:         driver,
+		Table:          config.StringDefault("session.sql.table", "revel_sessions"),
+		NumberedParams: config.BoolDefault("session.sql.numbered", driver == "postgres" || driver == "pgx"),
+	}
+	if err = store.Migrate(); err != nil {
+		return nil, err
+	}
+
+	gc := config.StringDefault("session.sql.gc", "10m")
+	interval, err := time.ParseDuration(gc)
+	if err != nil || interval < 0 {
+		return nil, errors.New("invalid session.sql.gc: " + gc)
+	}
+	if interval > 0 {
+		store.StartGC(interval)
+	}
+	return store, nil
+}
+
+// Migrate creates the store's table, or brings its schema up to date.  A
+// migration that fails because another instance of the app has just applied
+// it is passed over.
+func (s *Store) Migrate() error {
+	schema := s.Table + "_schema"
+	if _, err := s.DB.Exec("CREATE TABLE IF NOT EXISTS " + schema + " (version BIGINT NOT NULL)"); err != nil {
+		return err
+	}
+	version, err := s.schemaVersion()
+	if err != nil {
+		return err
+	}
+	for ; version < len(migrations); version++ {
+		err := s.migrate(version)
+		if err == nil {
+			continue
+		}
+		if applied, _ := s.schemaVersion(); applied > version {
+			version = applied - 1
+			continue
+		}
+		return fmt.Errorf("sqlsession: failed to migrate %s to version %d: %s", s.Table, version+1, err)
+	}
+	return nil
+}
+
+// schemaVersion returns the number of migrations applied to the table.
+func (s *Store) schemaVersion() (int, error) {
+	var version int
+	err := s.DB.QueryRow("SELECT COALESCE(MAX(version), 0) FROM " + s.Table + "_schema").Scan(&version)
+	return version, err
+}
+
+// migrate applies the migration after the version, and records it.
+func (s *Store) migrate(version int) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	if err = migrations[version](s, tx); err == nil {
+		_, err = tx.Exec(s.rebind("INSERT INTO "+s.Table+"_schema (version) VALUES (?)"), version+1)
+	}
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// createIndex creates the index of the column, if it does not exist.  MySQL
+// has no CREATE INDEX IF NOT EXISTS, so its catalog is checked first.
+func (s *Store) createIndex(tx *sql.Tx, name, column string) error {
+	if s.Driver == "mysql" {
+		var n int
+		err := tx.QueryRow(s.rebind("SELECT COUNT(*) FROM information_schema.statistics"+
+			" WHERE table_schema = DATABASE() AND table_name = ? AND index_name = ?"), s.Table, name).Scan(&n)
+		if err != nil || n > 0 {
+			return err
+		}
+		_, err = tx.Exec("CREATE INDEX " + name + " ON " + s.Table + " (" + column + ")")
+		return err
+	}
+	_, err := tx.Exec("CREATE INDEX IF NOT EXISTS " + name + " ON " + s.Table + " (" + column + ")")
+	return err
+}
+
+// StartGC deletes the expired sessions every interval, until stop is called.
+func (s *Store) StartGC(interval time.Duration) (stop func()) {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				if _, err := s.GC(); err != nil {
+					revel.ERROR.Println("Failed to delete the expired sessions:", err)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		ticker.Stop()
+		close(done)
+	}
+}
+
+// GC deletes the expired sessions, returning how many there were.
+func (s *Store) GC() (int64, error) {
+	result, err := s.DB.Exec(s.rebind("DELETE FROM "+s.Table+" WHERE expires < ?"), unixMilli(time.Now()))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (s *Store) Get(id string) (revel.Session, error) {
+	session, _, err := s.GetVersion(id)
+	return session, err
+}
+
+func (s *Store) GetVersion(id string) (revel.Session, int64, error) {
+	var (
+		data             string
+		expires, version int64
+	)
+	err := s.DB.QueryRow(s.rebind("SELECT data, expires, version FROM "+s.Table+" WHERE id = ?"), id).
+		Scan(&data, &expires, &version)
+	if err == sql.ErrNoRows || err == nil && expires < unixMilli(time.Now()) {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	session := revel.Session{}
+	if err = json.Unmarshal([]byte(data), &session); err != nil {
+		return nil, 0, err
+	}
+	return session, version, nil
+}
+
+// Set stores the session, whatever its stored version.  A session inserted by
+// a concurrent request between the UPDATE and the INSERT is updated.
+func (s *Store) Set(id string, session revel.Session, expires time.Duration) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	expiresAt := unixMilli(time.Now().Add(expires))
+	if err = s.update("id = ?", string(data), expiresAt, id); err != revel.ErrSessionConflict {
+		return err
+	}
+	if err = s.insert(id, string(data), expiresAt); err == revel.ErrSessionConflict {
+		err = s.update("id = ?", string(data), expiresAt, id)
+	}
+	return err
+}
+
+// SetVersion stores the session if its stored version is the given one, 0 for
+// none (or an expired one, which is got as none).
+func (s *Store) SetVersion(id string, session revel.Session, version int64, expires time.Duration) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	expiresAt := unixMilli(time.Now().Add(expires))
+	if version != 0 {
+		return s.update("id = ? AND version = ?", string(data), expiresAt, id, version)
+	}
+	if err = s.insert(id, string(data), expiresAt); err == revel.ErrSessionConflict {
+		err = s.update("id = ? AND expires < ?", string(data), expiresAt, id, unixMilli(time.Now()))
+	}
+	return err
+}
+
+// insert inserts the session at version 1.  If it exists (e.g. having just been
+// inserted by a concurrent request), it returns revel.ErrSessionConflict rather
+// than the driver's error for the duplicate key, which differs by database.
+func (s *Store) insert(id, data string, expiresAt int64) error {
+	_, err := s.DB.Exec(s.rebind("INSERT INTO "+s.Table+" (id, data, expires, version) VALUES (?, ?, ?, 1)"),
+		id, data, expiresAt)
+	if err == nil {
+		return nil
+	}
+	var n int
+	if s.DB.QueryRow(s.rebind("SELECT COUNT(*) FROM "+s.Table+" WHERE id = ?"), id).Scan(&n) == nil && n > 0 {
+		return revel.ErrSessionConflict
+	}
+	return err
+}
+
+// update updates the data and expiry of the session where the condition holds,
+// incrementing its version.  It returns revel.ErrSessionConflict if no session
+// was updated.
+func (s *Store) update(where string, args ...interface{}) error {
+	result, err := s.DB.Exec(s.rebind("UPDATE "+s.Table+" SET data = ?, expires = ?, version = version + 1 WHERE "+where),
+		args...)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err == nil && rows == 0 {
+		err = revel.ErrSessionConflict
+	}
+	return err
+}
+
+func (s *Store) Destroy(id string) error {
+	_, err := s.DB.Exec(s.rebind("DELETE FROM "+s.Table+" WHERE id = ?"), id)
+	return err
+}
+
+func (s *Store) Touch(id string, expires time.Duration) error {
+	_, err := s.DB.Exec(s.rebind("UPDATE "+s.Table+" SET expires = ? WHERE id = ?"),
+		unixMilli(time.Now().Add(expires)), id)
+	return err
+}
+
+// rebind writes the parameters (?) of the statement as $1, $2, ..., if the
+// store's NumberedParams is set.
+func (s *Store) rebind(query string) string {
+	if !s.NumberedParams {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func unixMilli(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
@@ -0,0 +1,326 @@
+package sqlsession
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"github.com/robfig/revel"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeDB is a database of the statements of the store, for the fake driver.
+type fakeDB struct {
+	mu         sync.Mutex
+	statements []string
+	schema     []int64
+	rows       map[string][]driver.Value // by ID: data, expires, version
+	indexes    map[string]bool
+	fail       func(query string) error // fails the statement, if set
+}
+
+var testDB = &fakeDB{rows: map[string][]driver.Value{}, indexes: map[string]bool{}}
+
+func init() {
+	sql.Register("sqlsessiontest", fakeDriver{})
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) { return fakeConn{}, nil }
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(query string) (driver.Stmt, error) { return fakeStmt(query), nil }
+func (fakeConn) Close() error                              { return nil }
+func (fakeConn) Begin() (driver.Tx, error)                 { return fakeTx{}, nil }
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+type fakeStmt string
+
+func (s fakeStmt) Close() error  { return nil }
+func (s fakeStmt) NumInput() int { return -1 }
+
+func (s fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	db := testDB
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	query := string(s)
+	db.statements = append(db.statements, query)
+	if db.fail != nil {
+		if err := db.fail(query); err != nil {
+			return nil, err
+		}
+	}
+	switch {
+	case strings.HasPrefix(query, "CREATE INDEX"):
+		name := strings.Fields(strings.TrimPrefix(query, "CREATE INDEX IF NOT EXISTS"))[0]
+		if db.indexes[name] && !strings.Contains(query, "IF NOT EXISTS") {
+			return nil, errors.New("index exists")
+		}
+		db.indexes[name] = true
+	case strings.HasPrefix(query, "INSERT INTO revel_sessions_schema"):
+		db.schema = append(db.schema, args[0].(int64))
+	case strings.HasPrefix(query, "INSERT INTO revel_sessions "):
+		id := args[0].(string)
+		if _, ok := db.rows[id]; ok {
+			return nil, errors.New("duplicate key")
+		}
+		db.rows[id] = []driver.Value{args[1], args[2], int64(1)}
+	case strings.HasPrefix(query, "UPDATE revel_sessions SET data"):
+		row, ok := db.rows[args[2].(string)]
+		if !ok || strings.Contains(query, "version = ?") && row[2] != args[3] ||
+			strings.Contains(query, "expires < ?") && row[1].(int64) >= args[3].(int64) {
+			return driver.RowsAffected(0), nil
+		}
+		row[0], row[1], row[2] = args[0], args[1], row[2].(int64)+1
+	case strings.HasPrefix(query, "UPDATE revel_sessions SET expires"):
+		if row, ok := db.rows[args[1].(string)]; ok {
+			row[1] = args[0]
+		}
+	case strings.HasPrefix(query, "DELETE FROM revel_sessions WHERE id"):
+		delete(db.rows, args[0].(string))
+	case strings.HasPrefix(query, "DELETE FROM revel_sessions WHERE expires"):
+		deleted := 0
+		for id, row := range db.rows {
+			if row[1].(int64) < args[0].(int64) {
+				delete(db.rows, id)
+				deleted++
+			}
+		}
+		return driver.RowsAffected(deleted), nil
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (s fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	db := testDB
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	query := string(s)
+	db.statements = append(db.statements, query)
+	switch {
+	case strings.HasPrefix(query, "SELECT COALESCE(MAX(version), 0)"):
+		var version int64
+		for _, v := range db.schema {
+			version = max(version, v)
+		}
+		return &fakeRows{columns: []string{"version"}, rows: [][]driver.Value{{version}}}, nil
+	case strings.HasPrefix(query, "SELECT COUNT(*) FROM information_schema.statistics"):
+		var n int64
+		if db.indexes[args[1].(string)] {
+			n = 1
+		}
+		return &fakeRows{columns: []string{"count"}, rows: [][]driver.Value{{n}}}, nil
+	case strings.HasPrefix(query, "SELECT COUNT(*) FROM revel_sessions WHERE id"):
+		_, ok := db.rows[args[0].(string)]
+		n := int64(0)
+		if ok {
+			n = 1
+		}
+		return &fakeRows{columns: []string{"count"}, rows: [][]driver.Value{{n}}}, nil
+	case strings.HasPrefix(query, "SELECT data, expires, version"):
+		rows := &fakeRows{columns: []string{"data", "expires", "version"}}
+		if row, ok := db.rows[args[0].(string)]; ok {
+			rows.rows = [][]driver.Value{append([]driver.Value(nil), row...)}
+		}
+		return rows, nil
+	}
+	return nil, errors.New("unexpected query: " + query)
+}
+
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+func TestStore(t *testing.T) {
+	db, err := sql.Open("sqlsessiontest", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := &Store{DB: db, Table: "revel_sessions"}
+	for i := 0; i < 2; i++ {
+		if err = store.Migrate(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(testDB.schema) != len(migrations) ||
+		testDB.statements[2] != "CREATE TABLE IF NOT EXISTS revel_sessions (id VARCHAR(64) PRIMARY KEY, data TEXT NOT NULL, expires BIGINT NOT NULL, version BIGINT NOT NULL)" ||
+		testDB.statements[4] != "CREATE INDEX IF NOT EXISTS revel_sessions_expires ON revel_sessions (expires)" {
+		t.Errorf("Unexpected migration: %v %q", testDB.schema, testDB.statements)
+	}
+
+	session, err := store.Get("a")
+	if session != nil || err != nil {
+		t.Errorf("Expected no session, got %v, %v", session, err)
+	}
+	if err = store.Set("a", revel.Session{"user": "bob"}, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	session, version, err := store.GetVersion("a")
+	if err != nil || len(session) != 1 || session["user"] != "bob" || version != 1 {
+		t.Fatalf("Expected the session, got %v, %d, %v", session, version, err)
+	}
+
+	// Of two requests that got the session, the second to store it fails.
+	other, otherVersion, _ := store.GetVersion("a")
+	session["user"] = "alice"
+	if err = store.SetVersion("a", session, version, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	other["user"] = "carol"
+	if err = store.SetVersion("a", other, otherVersion, time.Hour); err != revel.ErrSessionConflict {
+		t.Errorf("Expected a conflict, got %v", err)
+	}
+	if session, version, _ = store.GetVersion("a"); session["user"] != "alice" || version != 2 {
+		t.Errorf("Expected the first request's session, got %v, %d", session, version)
+	}
+
+	// New sessions are inserted, unless they exist.
+	if err = store.SetVersion("b", session, 0, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if moved, version, _ := store.GetVersion("b"); moved["user"] != "alice" || version != 1 {
+		t.Errorf("Expected the new session, got %v, %d", moved, version)
+	}
+	if err = store.SetVersion("b", session, 0, time.Hour); err != revel.ErrSessionConflict {
+		t.Errorf("Expected an existing session not to be inserted, got %v", err)
+	}
+
+	// Expired sessions are not got, and are collected.
+	store.Touch("a", -time.Minute)
+	if session, _ = store.Get("a"); session != nil {
+		t.Errorf("Expected the session to have expired, got %v", session)
+	}
+	if deleted, err := store.GC(); deleted != 1 || err != nil {
+		t.Errorf("Expected one session collected, got %d, %v", deleted, err)
+	}
+
+	store.Destroy("b")
+	if session, _ = store.Get("b"); session != nil {
+		t.Errorf("Expected the session to be destroyed, got %v", session)
+	}
+}
+
+// Test that of concurrent requests storing a new session, one stores it and
+// the others conflict, or, with Set, overwrite it.
+func TestConcurrentFirstWrites(t *testing.T) {
+	db, err := sql.Open("sqlsessiontest", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := &Store{DB: db, Table: "revel_sessions"}
+	defer func() { testDB.fail = nil }()
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		stored    int
+		conflicts int
+	)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := store.SetVersion("new", revel.Session{"user": "bob"}, 0, time.Hour)
+			mu.Lock()
+			defer mu.Unlock()
+			switch err {
+			case nil:
+				stored++
+			case revel.ErrSessionConflict:
+				conflicts++
+			default:
+				t.Errorf("Expected a conflict, got %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if stored != 1 || conflicts != 9 {
+		t.Errorf("Expected one session stored and 9 conflicts, got %d and %d", stored, conflicts)
+	}
+
+	// An expired session is replaced.
+	store.Touch("new", -time.Minute)
+	if err = store.SetVersion("new", revel.Session{"user": "alice"}, 0, time.Hour); err != nil {
+		t.Errorf("Expected the expired session to be replaced, got %v", err)
+	}
+	if session, _ := store.Get("new"); session["user"] != "alice" {
+		t.Errorf("Expected the new session, got %v", session)
+	}
+
+	// Another request inserts the session between Set's UPDATE and INSERT.
+	testDB.fail = func(query string) error {
+		if strings.HasPrefix(query, "INSERT INTO revel_sessions ") && testDB.rows["set"] == nil {
+			testDB.rows["set"] = []driver.Value{`{"user":"carol"}`, int64(0), int64(1)}
+		}
+		return nil
+	}
+	if err = store.Set("set", revel.Session{"user": "bob"}, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if session, version, _ := store.GetVersion("set"); session["user"] != "bob" || version != 2 {
+		t.Errorf("Expected the session to be overwritten, got %v, %d", session, version)
+	}
+}
+
+func TestConcurrentMigrations(t *testing.T) {
+	db, err := sql.Open("sqlsessiontest", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { testDB.fail = nil }()
+
+	// Another instance applies the migration that this one is applying.
+	testDB.schema = []int64{1}
+	testDB.fail = func(query string) error {
+		if strings.HasPrefix(query, "CREATE INDEX") {
+			testDB.schema = append(testDB.schema, 2)
+			return errors.New("deadlock")
+		}
+		return nil
+	}
+	store := &Store{DB: db, Table: "revel_sessions"}
+	if err = store.Migrate(); err != nil {
+		t.Errorf("Expected the migration to be passed over, got %v", err)
+	}
+
+	// MySQL's catalog is checked for the index.
+	testDB.schema, testDB.fail = []int64{1}, nil
+	testDB.indexes["revel_sessions_expires"] = true
+	store.Driver = "mysql"
+	if err = store.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+	last := testDB.statements[len(testDB.statements)-2]
+	if !strings.HasPrefix(last, "SELECT COUNT(*) FROM information_schema.statistics") {
+		t.Errorf("Expected the catalog to be checked, got %s", last)
+	}
+}
+
+func TestRebind(t *testing.T) {
+	store := &Store{NumberedParams: true}
+	if query := store.rebind("UPDATE t SET a = ? WHERE id = ? AND v = ?"); query != "UPDATE t SET a = $1 WHERE id = $2 AND v = $3" {
+		t.Errorf("Unexpected query: %s", query)
+	}
+}
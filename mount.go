@@ -0,0 +1,73 @@
+package revel
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// mountHandlers maps names to the handlers registered with RegisterHandler.
+var mountHandlers = map[string]http.Handler{}
+
+// RegisterHandler registers a net/http handler that MOUNT routes may name, so
+// that it serves the requests under their path prefix, e.g.
+//   revel.RegisterHandler("metrics", promhttp.Handler())
+// allows:
+//   MOUNT  /metrics  metrics
+//
+// The handler is called without an action, or the filters after RouterFilter,
+// with the prefix stripped from the request's path.  e.g. a request for
+// "/metrics/go" is passed to the handler as "/go", and "/metrics" as "/".
+//
+// Handlers are looked up when the routes are loaded, so they should be
+// registered on initialization.
+func RegisterHandler(name string, handler http.Handler) {
+	mountHandlers[name] = handler
+}
+
+// setHandler looks up and sets the handler of a MOUNT route by name.
+func (r *Route) setHandler(name string) error {
+	handler, ok := mountHandlers[name]
+	if !ok {
+		return fmt.Errorf("Unknown handler: %s", name)
+	}
+	r.Mount, r.handler = name, handler
+	return nil
+}
+
+// Mount adds a route that passes the requests under the path prefix to the
+// handler, as a MOUNT route does, and rebuilds the routing table.  e.g.
+//   revel.MainRouter.Mount("/metrics", promhttp.Handler())
+// Returns nil if the prefix is invalid.
+func (router *Router) Mount(prefix string, handler http.Handler) *Route {
+	return newRouteGroup(router, nil, "", nil).Mount(prefix, handler)
+}
+
+// Mount adds a route to the group that passes the requests under the path
+// prefix to the handler.  See Router.Mount.
+func (g *RouteGroup) Mount(prefix string, handler http.Handler) *Route {
+	decl := routeDecl{
+		method: "*",
+		path:   mountPath(prefix),
+		action: "MOUNT",
+	}
+	route, err := g.newRoute(decl, "", 0)
+	if err != nil {
+		ERROR.Println("revel/router: invalid mount:", prefix, err)
+		return nil
+	}
+	route.Mount, route.handler = fmt.Sprintf("%T", handler), handler
+	g.addRoutes([]*Route{route})
+	return route
+}
+
+// mountResult passes the request to a mounted handler, with the given path.
+type mountResult struct {
+	handler http.Handler
+	path    string
+}
+
+func (r *mountResult) Apply(req *Request, resp *Response) {
+	mounted := req.Request.Clone(req.Context())
+	mounted.URL.Path, mounted.URL.RawPath = r.path, ""
+	r.handler.ServeHTTP(resp.Out, mounted)
+}
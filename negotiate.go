@@ -0,0 +1,205 @@
+package revel
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A Renderer renders a value (see RenderAuto) in its media type, or returns nil
+// if it can not, e.g. as HTML if the action has no template.
+type Renderer func(c *Controller, value interface{}) Result
+
+// Renderers maps media types to the renderers that RenderAuto chooses from.
+// Modules add renderers for other types, e.g. on initialization:
+//
+//     revel.Renderers["text/csv"] = func(c *revel.Controller, value interface{}) revel.Result {
+//     	if rows, ok := value.([][]string); ok {
+//     		return CsvResult(rows)
+//     	}
+//     	return nil
+//     }
+var Renderers = map[string]Renderer{
+	"application/json":       renderJson,
+	"application/xml":        renderXml,
+	"text/xml":               renderXml,
+	"text/html":              renderHtml,
+	"text/plain":             renderText,
+	"application/x-protobuf": renderProto,
+	"application/yaml":       renderYaml,
+	"application/x-yaml":     renderYaml,
+	"text/yaml":              renderYaml,
+}
+
+// AutoRenderType is the media type that RenderAuto renders in, when neither
+// the route nor the request's Accept header asks for one that it can.  It is
+// set by "results.autoType", a media type or a format (e.g. "xml"), which
+// defaults to "application/json".
+var AutoRenderType = "application/json"
+
+// formatTypes are the media types of the formats that routes may render in.
+var formatTypes = map[string]string{
+	"json":  "application/json",
+	"xml":   "application/xml",
+	"html":  "text/html",
+	"txt":   "text/plain",
+	"proto": "application/x-protobuf",
+	"yaml":  "application/yaml",
+}
+
+func init() {
+	OnAppStart(func() {
+		autoType := Config.StringDefault("results.autoType", AutoRenderType)
+		if mediaType, ok := formatTypes[autoType]; ok {
+			autoType = mediaType
+		}
+		if Renderers[autoType] == nil {
+			ERROR.Fatalln("Invalid results.autoType:", autoType)
+		}
+		AutoRenderType = autoType
+	})
+}
+
+// RenderAuto renders the value in the media type that the route declares
+// (e.g. {render=xml} or {render=text/csv}), or else in the one that the
+// request's Accept header prefers among those of Renderers, or else in
+// AutoRenderType, so that one action serves both pages and API clients:
+//
+//     func (c Users) Show(id int) revel.Result {
+//     	return c.RenderAuto(models.FindUser(id))
+//     }
+//
+// As HTML, the value is rendered by the action's template as "value", e.g.
+// "Users/Show.html".  Types that can not render the value (e.g. HTML, for an
+// action without a template) are passed over, and JSON is the last resort.
+func (c *Controller) RenderAuto(value interface{}) Result {
+	if c.Route != nil && c.Route.Route != nil && c.Route.Route.render != "" {
+		if result := renderAs(c, routeRenderType(c.Route.Route.render), value); result != nil {
+			return result
+		}
+	} else if c.Request != nil && c.Request.Request != nil {
+		if c.Response != nil && c.Response.Out != nil {
+			c.Response.Out.Header().Add("Vary", "Accept")
+		}
+		for _, mediaType := range acceptedTypes(c.Request.Header.Get("Accept")) {
+			if result := renderAs(c, mediaType, value); result != nil {
+				return result
+			}
+		}
+	}
+	if result := renderAs(c, AutoRenderType, value); result != nil {
+		return result
+	}
+	return c.RenderJson(value)
+}
+
+// routeRenderType returns the media type of a route's render option, which is
+// either a format or a media type.
+func routeRenderType(render string) string {
+	if mediaType, ok := formatTypes[render]; ok {
+		return mediaType
+	}
+	return render
+}
+
+// renderAs renders the value by the renderer of the media type, which may be a
+// pattern such as "text/*", or returns nil if none of them can.  Of the
+// renderers that a pattern matches, AutoRenderType's is tried first.
+func renderAs(c *Controller, mediaType string, value interface{}) Result {
+	if mediaType == "*/*" {
+		mediaType = AutoRenderType
+	}
+	if !strings.HasSuffix(mediaType, "/*") {
+		if renderer := Renderers[mediaType]; renderer != nil {
+			return renderer(c, value)
+		}
+		return nil
+	}
+
+	prefix := strings.TrimSuffix(mediaType, "*")
+	var matches []string
+	for renderType := range Renderers {
+		if strings.HasPrefix(renderType, prefix) && renderType != AutoRenderType {
+			matches = append(matches, renderType)
+		}
+	}
+	sort.Strings(matches)
+	if strings.HasPrefix(AutoRenderType, prefix) {
+		matches = append([]string{AutoRenderType}, matches...)
+	}
+	for _, renderType := range matches {
+		if result := Renderers[renderType](c, value); result != nil {
+			return result
+		}
+	}
+	return nil
+}
+
+// acceptedTypes returns the media types of an Accept header, most preferred
+// first, leaving out those that are not acceptable (q=0).
+func acceptedTypes(accept string) []string {
+	type acceptedType struct {
+		mediaType string
+		quality   float64
+	}
+	var accepted []acceptedType
+	for _, part := range strings.Split(accept, ",") {
+		fields := strings.Split(part, ";")
+		mediaType := strings.ToLower(strings.TrimSpace(fields[0]))
+		if mediaType == "" {
+			continue
+		}
+		quality := 1.0
+		for _, param := range fields[1:] {
+			if name, value, ok := strings.Cut(strings.TrimSpace(param), "="); ok && strings.TrimSpace(name) == "q" {
+				if q, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+					quality = q
+				}
+			}
+		}
+		if quality > 0 {
+			accepted = append(accepted, acceptedType{mediaType, quality})
+		}
+	}
+	sort.SliceStable(accepted, func(i, j int) bool {
+		return accepted[i].quality > accepted[j].quality
+	})
+	mediaTypes := make([]string, len(accepted))
+	for i, a := range accepted {
+		mediaTypes[i] = a.mediaType
+	}
+	return mediaTypes
+}
+
+func renderJson(c *Controller, value interface{}) Result {
+	return c.RenderJson(value)
+}
+
+func renderXml(c *Controller, value interface{}) Result {
+	return c.RenderXml(value)
+}
+
+func renderText(c *Controller, value interface{}) Result {
+	return c.RenderText("%v", value)
+}
+
+func renderProto(c *Controller, value interface{}) Result {
+	if value != nil && isProtoMessage(reflect.TypeOf(value)) {
+		return c.RenderProto(value)
+	}
+	return nil
+}
+
+// renderHtml renders the value by the action's template, if it has one.
+func renderHtml(c *Controller, value interface{}) Result {
+	if c.MethodType == nil || MainTemplateLoader == nil {
+		return nil
+	}
+	templatePath := c.Name + "/" + c.MethodType.Name + ".html"
+	if _, err := MainTemplateLoader.Template(templatePath); err != nil {
+		return nil
+	}
+	c.RenderArgs["value"] = value
+	return c.RenderTemplate(templatePath)
+}
@@ -0,0 +1,67 @@
+package revel
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type csvResult struct{ rows [][]string }
+
+func (r csvResult) Apply(req *Request, resp *Response) {}
+
+// Test that RenderAuto renders in the route's type, or else the most preferred
+// type that it can, or else AutoRenderType.
+func TestRenderAuto(t *testing.T) {
+	startFakeBookingApp()
+	Renderers["text/csv"] = func(c *Controller, value interface{}) Result {
+		if rows, ok := value.([][]string); ok {
+			return csvResult{rows}
+		}
+		return nil
+	}
+	defer delete(Renderers, "text/csv")
+
+	hotel := &Hotel{HotelId: 1, Name: "A Hotel"}
+	rows := [][]string{{"1", "A Hotel"}}
+	for _, test := range []struct {
+		action, accept, render string
+		value                  interface{}
+		expected               string
+	}{
+		{"Hotels.Show", "application/json", "", hotel, "revel.RenderJsonResult"},
+		{"Hotels.Show", "application/xml", "", hotel, "revel.RenderXmlResult"},
+		{"Hotels.Show", "text/html", "", hotel, "*revel.RenderTemplateResult"},
+		{"Static.Serve", "text/html", "", hotel, "revel.RenderJsonResult"}, // No template
+		{"Static.Serve", "text/html, text/plain;q=0.5", "", hotel, "*revel.RenderTextResult"},
+		{"Hotels.Show", "text/html;q=0.5, application/xml", "", hotel, "revel.RenderXmlResult"},
+		{"Hotels.Show", "application/xml;q=0, */*", "", hotel, "revel.RenderJsonResult"},
+		{"Hotels.Show", "", "", hotel, "revel.RenderJsonResult"},
+		{"Hotels.Show", "image/png", "", hotel, "revel.RenderJsonResult"},
+		{"Hotels.Show", "text/*", "", rows, "revel.csvResult"},
+		{"Hotels.Show", "text/csv", "", hotel, "revel.RenderJsonResult"},
+		{"Hotels.Show", "application/json", "xml", hotel, "revel.RenderXmlResult"},
+		{"Hotels.Show", "application/json", "text/csv", rows, "revel.csvResult"},
+	} {
+		req, _ := http.NewRequest("GET", "/hotels/1", nil)
+		req.Header.Set("Accept", test.accept)
+		c := NewController(NewRequest(req), NewResponse(httptest.NewRecorder()))
+		if test.render != "" {
+			c.Route = &RouteMatch{Route: &Route{render: test.render}}
+		}
+		action := strings.Split(test.action, ".")
+		if err := c.SetAction(action[0], action[1]); err != nil {
+			t.Fatal(err)
+		}
+		eq(t, fmt.Sprintf("Result of %v", test), fmt.Sprintf("%T", c.RenderAuto(test.value)), test.expected)
+	}
+
+	routes, err := parseRoutes("", "GET /hotels.csv Hotels.Index {render=text/csv}", false)
+	if eq(t, "Route error", err == nil, true) {
+		eq(t, "Render type", routes[0].render, "text/csv")
+	}
+	_, err = parseRoutes("", "GET /hotels.pdf Hotels.Index {render=application/pdf}", false)
+	eq(t, "Invalid render type", err != nil, true)
+}
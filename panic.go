@@ -1,9 +1,63 @@
 package revel
 
 import (
+	"fmt"
+	"net/http"
 	"runtime/debug"
+	"strings"
+	"time"
 )
 
+// PanicHandler returns the result to render for a panic in an action, given
+// the value passed to panic and the stack trace.  If it returns nil, the
+// default error page is rendered.
+type PanicHandler func(c *Controller, err interface{}, stack []byte) Result
+
+// PanicReporter is sent a report of each panic in an action, e.g. to ship it to
+// an error tracker.  The report is shared by all reporters, so must not be
+// modified.
+type PanicReporter func(report *PanicReport)
+
+// PanicReport describes a panic in an action, and the request it was serving.
+// It is copied from the request, so that it may be used after the request has
+// been handled.
+type PanicReport struct {
+	Err    interface{} // The value passed to panic.
+	Stack  []byte      // The stack trace of the panic.
+	Time   time.Time
+	Action string // e.g. "Hotels.Show"
+
+	RequestId  string // The request's ID, if the RequestIdFilter assigned one.
+	Method     string
+	URL        string
+	Header     http.Header
+	RemoteAddr string
+}
+
+// PanicRedactedHeaders are the request headers whose values are replaced with
+// "[REDACTED]" in panic reports, so that credentials are not sent to error
+// trackers.  Apps may add their own, e.g. "X-Api-Key".  Should Cookie be
+// removed, the session cookie in it is still redacted.
+var PanicRedactedHeaders = []string{"Cookie", "Authorization", "Proxy-Authorization"}
+
+var (
+	panicHandler   PanicHandler
+	panicReporters []PanicReporter
+)
+
+// RegisterPanicHandler sets the handler that renders the response to a panic
+// in an action, in place of the default error page.
+func RegisterPanicHandler(handler PanicHandler) {
+	panicHandler = handler
+}
+
+// RegisterPanicReporter adds a reporter to be sent each panic in an action.
+// Reporters are called in their own goroutines, so they do not delay the
+// response, and a panic in a reporter is logged rather than propagated.
+func RegisterPanicReporter(reporter PanicReporter) {
+	panicReporters = append(panicReporters, reporter)
+}
+
 // PanicFilter wraps the action invocation in a protective defer blanket that
 // converts panics into 500 error pages.
 func PanicFilter(c *Controller, fc []Filter) {
@@ -16,16 +70,107 @@ func PanicFilter(c *Controller, fc []Filter) {
 }
 
 // This function handles a panic in an action invocation.
-// It cleans up the stack trace, logs it, and displays an error page.
+// It cleans up the stack trace, logs it, reports it, and displays an error page.
 func handleInvocationPanic(c *Controller, err interface{}) {
+	stack := debug.Stack()
+	reportPanic(c, err, stack)
+
+	if result := handlePanic(c, err, stack); result != nil {
+		c.Log.Error(fmt.Sprint(err, "\n", string(stack)))
+		c.Result = result
+		return
+	}
+
+	// Panics with mapped errors (see MapError) render their mapped result.
+	if e, ok := err.(error); ok {
+		if result := mappedErrorResult(c, e); result != nil {
+			c.Log.Error(fmt.Sprint(err, "\n", string(stack)))
+			c.Result = result
+			return
+		}
+	}
+
 	error := NewErrorFromPanic(err)
 	if error == nil {
-		ERROR.Print(err, "\n", string(debug.Stack()))
+		c.Log.Error(fmt.Sprint(err, "\n", string(stack)))
 		c.Response.Out.WriteHeader(500)
-		c.Response.Out.Write(debug.Stack())
+		c.Response.Out.Write(stack)
 		return
 	}
 
-	ERROR.Print(err, "\n", error.Stack)
+	c.Log.Error(fmt.Sprint(err, "\n", error.Stack))
 	c.Result = c.RenderError(error)
 }
+
+// handlePanic returns the result of the registered panic handler, if any.  If
+// the handler itself panics, that is logged and nil is returned.
+func handlePanic(c *Controller, err interface{}, stack []byte) (result Result) {
+	if panicHandler == nil {
+		return nil
+	}
+	defer func() {
+		if handlerErr := recover(); handlerErr != nil {
+			ERROR.Print("Panic handler failed: ", handlerErr, "\n", string(debug.Stack()))
+			result = nil
+		}
+	}()
+	return panicHandler(c, err, stack)
+}
+
+// reportPanic sends a report of the panic to each of the reporters.
+func reportPanic(c *Controller, err interface{}, stack []byte) {
+	if len(panicReporters) == 0 {
+		return
+	}
+
+	report := &PanicReport{
+		Err:       err,
+		Stack:     stack,
+		Time:      time.Now(),
+		Action:    c.Action,
+		RequestId: c.RequestId,
+	}
+	if c.Request != nil && c.Request.Request != nil {
+		report.Method = c.Request.Method
+		report.URL = c.Request.URL.String()
+		report.Header = redactHeader(c.Request.Header)
+		report.RemoteAddr = c.Request.RemoteAddr
+	}
+
+	for _, reporter := range panicReporters {
+		go func(reporter PanicReporter) {
+			defer func() {
+				if reporterErr := recover(); reporterErr != nil {
+					ERROR.Print("Panic reporter failed: ", reporterErr)
+				}
+			}()
+			reporter(report)
+		}(reporter)
+	}
+}
+
+// redactHeader returns a copy of the header with the values of the
+// PanicRedactedHeaders, and of the session cookie, redacted.
+func redactHeader(header http.Header) http.Header {
+	header = header.Clone()
+	for _, name := range PanicRedactedHeaders {
+		if values, ok := header[http.CanonicalHeaderKey(name)]; ok {
+			for i := range values {
+				values[i] = "[REDACTED]"
+			}
+		}
+	}
+	if values, ok := header["Cookie"]; ok {
+		sessionCookie := CookiePrefix + "_SESSION="
+		for i, value := range values {
+			cookies := strings.Split(value, ";")
+			for j, cookie := range cookies {
+				if strings.HasPrefix(strings.TrimSpace(cookie), sessionCookie) {
+					cookies[j] = " " + sessionCookie + "[REDACTED]"
+				}
+			}
+			values[i] = strings.TrimPrefix(strings.Join(cookies, ";"), " ")
+		}
+	}
+	return header
+}
@@ -0,0 +1,74 @@
+package revel
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPanicHandler(t *testing.T) {
+	startFakeBookingApp()
+	defer func(handler PanicHandler, reporters []PanicReporter) {
+		panicHandler, panicReporters = handler, reporters
+	}(panicHandler, panicReporters)
+
+	reports := make(chan *PanicReport, 1)
+	RegisterPanicReporter(func(report *PanicReport) { reports <- report })
+	RegisterPanicHandler(func(c *Controller, err interface{}, stack []byte) Result {
+		c.Response.Status = http.StatusInternalServerError
+		return c.RenderText("Sorry: %v", err)
+	})
+
+	panicking := func(c *Controller, _ []Filter) { panic("out of rooms") }
+	req, _ := http.NewRequest("GET", "/hotels/3/book", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set("Cookie", CookiePrefix+"_SESSION=secret; theme=dark")
+	req.Header.Set("Accept", "text/html")
+	c := NewController(NewRequest(req), NewResponse(httptest.NewRecorder()))
+	c.Action = "Hotels.Book"
+	PanicFilter(c, []Filter{panicking})
+
+	result, ok := c.Result.(*RenderTextResult)
+	if eq(t, "Handler result", ok, true) {
+		eq(t, "Text", result.text, "Sorry: out of rooms")
+	}
+	select {
+	case report := <-reports:
+		eq(t, "Err", report.Err, "out of rooms")
+		eq(t, "Action", report.Action, "Hotels.Book")
+		eq(t, "URL", report.URL, "/hotels/3/book")
+		eq(t, "Stack", len(report.Stack) > 0, true)
+		eq(t, "Authorization", report.Header.Get("Authorization"), "[REDACTED]")
+		eq(t, "Cookie", report.Header.Get("Cookie"), "[REDACTED]")
+		eq(t, "Accept", report.Header.Get("Accept"), "text/html")
+		eq(t, "Request's Authorization", req.Header.Get("Authorization"), "Bearer secret")
+	case <-time.After(time.Second):
+		t.Error("Expected the panic to be reported")
+	}
+
+	// A panicking handler falls back to the default error response.
+	RegisterPanicHandler(func(c *Controller, err interface{}, stack []byte) Result {
+		panic("handler failed")
+	})
+	resp := httptest.NewRecorder()
+	c = NewController(NewRequest(req), NewResponse(resp))
+	PanicFilter(c, []Filter{panicking})
+	_, isError := c.Result.(ErrorResult)
+	eq(t, "Default response", isError || resp.Code == http.StatusInternalServerError, true)
+	<-reports
+}
+
+// Test that the session cookie is redacted even if other cookies are not.
+func TestRedactHeader(t *testing.T) {
+	defer func(headers []string) { PanicRedactedHeaders = headers }(PanicRedactedHeaders)
+	PanicRedactedHeaders = []string{"x-api-key"}
+
+	header := http.Header{}
+	header.Set("X-Api-Key", "secret")
+	header.Set("Cookie", "theme=dark; "+CookiePrefix+"_SESSION=secret; lang=en")
+	redacted := redactHeader(header)
+	eq(t, "X-Api-Key", redacted.Get("X-Api-Key"), "[REDACTED]")
+	eq(t, "Cookie", redacted.Get("Cookie"), "theme=dark; "+CookiePrefix+"_SESSION=[REDACTED]; lang=en")
+	eq(t, "Original cookie", header.Get("Cookie"), "theme=dark; "+CookiePrefix+"_SESSION=secret; lang=en")
+}
@@ -1,10 +1,19 @@
 package revel
 
 import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
 	"mime/multipart"
+	"net/http"
 	"net/url"
 	"os"
 	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // Params provides a unified view of the request params.
@@ -25,35 +34,70 @@ type Params struct {
 	Query url.Values // Parameters from the query string, e.g. /index?limit=10
 	Form  url.Values // Parameters from the request body.
 
-	Files    map[string][]*multipart.FileHeader // Files uploaded in a multipart form
-	tmpFiles []*os.File                         // Temp files used during the request.
+	Uploads  map[string][]*Upload // Files uploaded in a multipart form
+	JSON     []byte               // The request body, if it is JSON.
+	Proto    []byte               // The request body, if it is a Protocol Buffers message.
+	tmpFiles []*os.File           // Temp files used during the request.
+	opened   []io.Closer          // Uploads opened during the request.
+
+	// Files uploaded in a multipart form, copied from Uploads, unless
+	// "upload.files" is false (see UploadFiles).
+	//
+	// Deprecated: use Uploads, which are not copied.
+	Files     map[string][]*multipart.FileHeader
+	filesForm *multipart.Form // the form that holds the Files
+
+	body       interface{}           // the decoded JSON (or XML) body
+	bodyKinds  map[string]string     // the kinds of the body's values, e.g. {"user": "an object"}
+	bodyErrors []*ValidationError    // the errors in decoding the body, for the Validation
+	bindErrors []*ValidationError    // the errors in binding the params, for the Validation
+	badValues  []*ValidationError    // the params invalid for their type, which fail the request
+	transforms []func(string) string // the transformations of the strings bound, if not paramTransforms
+	validation *Validation           // the request's Validation, for the Must accessors (e.g. MustInt)
+	separator  string                // the separator of lists, if not ListSeparator, or "-" for none
 }
 
 func ParseParams(params *Params, req *Request) {
-	params.Query = req.URL.Query()
+	if err := parseParams(params, req); err != nil {
+		WARN.Println("Error parsing request body:", err)
+	}
+}
 
-	// Parse the body depending on the content type.
-	switch req.ContentType {
-	case "application/x-www-form-urlencoded":
-		// Typical form.
-		if err := req.ParseForm(); err != nil {
-			WARN.Println("Error parsing request body:", err)
-		} else {
-			params.Form = req.Form
-		}
+// parseParams parses the params, returning the error in parsing the body, if
+// any.  The params are set even if it fails, without those of the body.
+func parseParams(params *Params, req *Request) (err error) {
+	params.Query = req.URL.Query()
 
-	case "multipart/form-data":
-		// Multipart form.
-		// TODO: Extract the multipart form param so app can set it.
-		if err := req.ParseMultipartForm(32 << 20 /* 32 MB */); err != nil {
-			WARN.Println("Error parsing request body:", err)
-		} else {
-			params.Form = req.MultipartForm.Value
-			params.Files = req.MultipartForm.File
-		}
+	// Parse the body with the parser for its content type, if any.
+	if parser := bodyParser(req.ContentType); parser != nil {
+		err = parser(params, req)
 	}
 
 	params.Values = params.calcValues()
+	return err
+}
+
+// bindBodyToSoleStruct binds the fields of a JSON object (or XML) body to the
+// action's argument, if it is the only one and is a struct, and the body does
+// not name it.  e.g. {"name": "Bob"} to the argument "user" of
+// Users.Create(user *User) as "user.name=Bob".
+func bindBodyToSoleStruct(c *Controller) {
+	object, ok := c.Params.body.(map[string]interface{})
+	if !ok || c.MethodType == nil || len(c.MethodType.Args) != 1 {
+		return
+	}
+	arg := c.MethodType.Args[0]
+	typ := arg.Type
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if _, named := object[arg.Name]; named || typ.Kind() != reflect.Struct || TypeBinders[typ].Bind != nil {
+		return
+	}
+	c.Params.Form = make(url.Values)
+	c.Params.bodyKinds = make(map[string]string)
+	c.Params.flattenBody(arg.Name, object)
+	c.Params.Values = c.Params.calcValues()
 }
 
 // Bind looks for the named parameter, converts it to the requested type, and
@@ -71,6 +115,135 @@ func (p *Params) Bind(dest interface{}, name string) {
 	value.Set(Bind(p, name, value.Type()))
 }
 
+// ErrMissingParam is the error of the typed accessors of Params (e.g. Int)
+// for a param that is not given, or is empty.
+var ErrMissingParam = errors.New("missing param")
+
+// Int returns the param as an int, e.g. for /hotels?page=2:
+//   page, err := c.Params.Int("page")
+// The error is ErrMissingParam if it is not given, or else one saying that it
+// is not valid, e.g. `"two" is not a valid int`.  MustInt records the error in
+// the Validation instead.
+func (p *Params) Int(name string) (int, error) {
+	return typedParam(p, name, "int", strconv.Atoi)
+}
+
+// Int64 returns the param as an int64, as Int does.
+func (p *Params) Int64(name string) (int64, error) {
+	return typedParam(p, name, "int64", func(val string) (int64, error) {
+		return strconv.ParseInt(val, 10, 64)
+	})
+}
+
+// Bool returns the param as a bool, in the formats of the BoolBinder, e.g.
+// "true" or "on".  An unchecked checkbox is not given, so its error is
+// ErrMissingParam.
+func (p *Params) Bool(name string) (bool, error) {
+	return typedParam(p, name, "bool", parseBool)
+}
+
+// Time returns the param as a time, in the layout (as for time.Parse, or
+// "unix" or "unixmilli"), or in the TimeFormats if it is "".
+func (p *Params) Time(name, layout string) (time.Time, error) {
+	return typedParam(p, name, "time", func(val string) (time.Time, error) {
+		if layout == "" {
+			return parseTime(val, TimeFormats)
+		}
+		return parseTime(val, []string{layout})
+	})
+}
+
+// UUID returns the param as the 16 bytes of a UUID, given in the canonical
+// form (e.g. "123e4567-e89b-12d3-a456-426614174000") or as 32 hex digits.
+// The bytes convert to the UUID types of other packages, e.g.
+//   id, err := c.Params.UUID("id")
+//   user := models.FindUser(uuid.UUID(id))
+func (p *Params) UUID(name string) ([16]byte, error) {
+	return typedParam(p, name, "UUID", func(val string) (uuid [16]byte, err error) {
+		if len(val) == 36 && val[8] == '-' && val[13] == '-' && val[18] == '-' && val[23] == '-' {
+			val = val[:8] + val[9:13] + val[14:18] + val[19:23] + val[24:]
+		}
+		if len(val) != 32 {
+			return uuid, fmt.Errorf("not a UUID")
+		}
+		_, err = hex.Decode(uuid[:], []byte(val))
+		return uuid, err
+	})
+}
+
+// MustInt returns the param as an int, or 0, with a validation error for the
+// param (keyed by its name) if it is not given ("Required") or not valid.
+// For example:
+//   id := c.Params.MustInt("id")
+//   if c.Validation.HasErrors() {
+//   	return c.Redirect(Hotels.Index)
+//   }
+func (p *Params) MustInt(name string) int {
+	n, err := p.Int(name)
+	p.mustError(name, err)
+	return n
+}
+
+// MustInt64 returns the param as an int64, as MustInt does.
+func (p *Params) MustInt64(name string) int64 {
+	n, err := p.Int64(name)
+	p.mustError(name, err)
+	return n
+}
+
+// MustBool returns the param as a bool, as MustInt does.
+func (p *Params) MustBool(name string) bool {
+	b, err := p.Bool(name)
+	p.mustError(name, err)
+	return b
+}
+
+// MustTime returns the param as a time, as MustInt does.
+func (p *Params) MustTime(name, layout string) time.Time {
+	t, err := p.Time(name, layout)
+	p.mustError(name, err)
+	return t
+}
+
+// MustUUID returns the param as a UUID, as MustInt does.
+func (p *Params) MustUUID(name string) [16]byte {
+	uuid, err := p.UUID(name)
+	p.mustError(name, err)
+	return uuid
+}
+
+// typedParam parses the param, returning ErrMissingParam if it is empty, or an
+// error naming the type if it is not valid.
+func typedParam[T any](p *Params, name, typeName string, parse func(string) (T, error)) (T, error) {
+	var zero T
+	val := p.Get(name)
+	if val == "" {
+		return zero, ErrMissingParam
+	}
+	value, err := parse(val)
+	if err != nil {
+		return zero, fmt.Errorf("%q is not a valid %s", val, typeName)
+	}
+	return value, nil
+}
+
+// mustError records the error of a Must accessor, if any, in the request's
+// Validation.
+func (p *Params) mustError(name string, err error) {
+	if err == nil {
+		return
+	}
+	message := err.Error()
+	if err == ErrMissingParam {
+		message = Required{}.DefaultMessage()
+	}
+	if p.validation == nil {
+		WARN.Printf("revel/params: %s: %s (no Validation to record it in)", name, message)
+		return
+	}
+	p.validation.Errors = append(p.validation.Errors, &ValidationError{Key: name, Message: message})
+}
+
 // calcValues returns a unified view of the component param maps.
 func (p *Params) calcValues() url.Values {
 	numParams := len(p.Query) + len(p.Fixed) + len(p.Route) + len(p.Form)
@@ -92,43 +265,290 @@ func (p *Params) calcValues() url.Values {
 		return p.Form
 	}
 
-	// Copy everything into the same map.
+	// Copy everything into the same map, in the order of ParamsPrecedence.
 	values := make(url.Values, numParams)
-	for k, v := range p.Query {
-		values[k] = append(values[k], v...)
-	}
-	for k, v := range p.Fixed {
-		values[k] = append(values[k], v...)
+	for _, source := range p.sources() {
+		for k, v := range source {
+			values[k] = append(values[k], v...)
+		}
 	}
-	for k, v := range p.Route {
-		values[k] = append(values[k], v...)
+	return values
+}
+
+// ParamsPrecedence is the order in which the param maps are merged into
+// Values, from "params.precedence", e.g. "route, fixed, query, form".  A
+// param given by more than one has the values of each in turn, so that it is
+// bound from (and Get returns) the first.  By default, the query comes first,
+// and may override the route's params; apps that must not let the request
+// override them put "route" (and "fixed") first.  Maps that it leaves out
+// follow in the default order.
+var ParamsPrecedence = defaultParamsPrecedence
+
+var defaultParamsPrecedence = []string{"query", "fixed", "route", "form"}
+
+func init() {
+	OnAppStart(func() {
+		if order := Config.StringDefault("params.precedence", ""); order != "" {
+			precedence, err := parseParamsPrecedence(order)
+			if err != nil {
+				ERROR.Fatalln("Invalid params.precedence:", err)
+			}
+			ParamsPrecedence = precedence
+		}
+	})
+}
+
+// parseParamsPrecedence parses a list of param maps, separated by commas.
+func parseParamsPrecedence(order string) ([]string, error) {
+	var precedence []string
+	for _, source := range strings.Split(order, ",") {
+		source = strings.TrimSpace(source)
+		if !containsString(defaultParamsPrecedence, source) {
+			return nil, fmt.Errorf("unknown param source: %q", source)
+		}
+		if containsString(precedence, source) {
+			return nil, fmt.Errorf("param source given twice: %q", source)
+		}
+		precedence = append(precedence, source)
 	}
-	for k, v := range p.Form {
-		values[k] = append(values[k], v...)
+	return precedence, nil
+}
+
+// sources returns the param maps in the order of ParamsPrecedence.
+func (p *Params) sources() []url.Values {
+	byName := map[string]url.Values{"query": p.Query, "fixed": p.Fixed, "route": p.Route, "form": p.Form}
+	sources := make([]url.Values, 0, len(byName))
+	for _, precedence := range [][]string{ParamsPrecedence, defaultParamsPrecedence} {
+		for _, name := range precedence {
+			if source, ok := byName[name]; ok {
+				sources = append(sources, source)
+				delete(byName, name)
+			}
+		}
 	}
-	return values
+	return sources
+}
+
+// FromRoute returns the first value of the param extracted from the route's
+// path, or "" if it has none, whatever the other maps give.
+// e.g. for /users/:id, c.Params.FromRoute("id")
+func (p *Params) FromRoute(name string) string {
+	return p.Route.Get(name)
+}
+
+// FromQuery returns the first value of the param in the query string, or "".
+func (p *Params) FromQuery(name string) string {
+	return p.Query.Get(name)
+}
+
+// FromForm returns the first value of the param in the request body (a form
+// or JSON), or "".
+func (p *Params) FromForm(name string) string {
+	return p.Form.Get(name)
+}
+
+// FromFixed returns the first value of the param fixed by the route, or "".
+func (p *Params) FromFixed(name string) string {
+	return p.Fixed.Get(name)
 }
 
 func ParamsFilter(c *Controller, fc []Filter) {
-	ParseParams(c.Params, c.Request)
+	// The body of a route with {body=stream} is left for the action to read.
+	if c.Route != nil && c.Route.Route != nil && c.Route.Route.stream {
+		c.Params.Query = c.Request.URL.Query()
+		c.Params.Values = c.Params.calcValues()
+		if checkStrictParams(c) && checkRequiredParams(c) {
+			fc[0](c, fc[1:])
+		}
+		return
+	}
 
-	// Clean up from the request.
-	defer func() {
-		// Delete temp files.
-		if c.Request.MultipartForm != nil {
-			err := c.Request.MultipartForm.RemoveAll()
-			if err != nil {
-				WARN.Println("Error removing temporary files:", err)
+	// The uploads, and the temp files that they are bound to, are removed once
+	// the result has been applied (e.g. after a RenderFile of one).
+	limitBody(c)
+	err := parseParams(c.Params, c.Request)
+	c.Cleanup(c.Params.removeUploads)
+
+	// Errors in the body that are mapped (see MapError), e.g. one over the
+	// route's maxbody or MaxBodySize, are rendered rather than passed over.
+	if err != nil {
+		if result := mappedErrorResult(c, err); result != nil {
+			c.Result = result
+			return
+		}
+		WARN.Println("Error parsing request body:", err)
+	}
+	bindBodyToSoleStruct(c)
+
+	if checkStrictParams(c) && checkRequiredParams(c) {
+		fc[0](c, fc[1:])
+	}
+}
+
+// controllerStrictParams maps the (lower-case) names of the controllers
+// declared with StrictParams to the names of the params they allow.
+var controllerStrictParams = map[string][]string{}
+
+// StrictParams makes requests to the controller's actions that have query or
+// form params that the action does not take (and that are not allowed) fail
+// with 400 Bad Request, rather than ignoring the params.  For example:
+//   var _ = revel.StrictParams(Users{}, "page")
+// makes
+//   func (c Users) Update(id int, user *models.User) revel.Result
+// accept the params "id", "user.Name", etc, and "page", but not "admin".
+// Routes may also be made strict with {params=strict, allowParams=page sort},
+// or lax, despite their controller, with {params=any}.
+// It returns the allowed params, so that it may be declared as above.
+func StrictParams(controllerInstance interface{}, allowed ...string) []string {
+	t := reflect.TypeOf(controllerInstance)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	controllerStrictParams[strings.ToLower(t.Name())] = allowed
+	return allowed
+}
+
+// checkStrictParams returns true if the request's params are acceptable to
+// the action.  If it is strict, and given params that it does not take, the
+// result is set to a 400 Bad Request listing them, and false is returned.
+func checkStrictParams(c *Controller) bool {
+	if c.MethodType == nil {
+		return true
+	}
+	allowed, strict := controllerStrictParams[strings.ToLower(c.Name)]
+	if c.Route != nil && c.Route.Route != nil {
+		switch c.Route.Route.params {
+		case "strict":
+			strict = true
+			allowed = append(allowed[:len(allowed):len(allowed)], c.Route.Route.allowed...)
+		case "any":
+			strict = false
+		}
+	}
+	if !strict {
+		return true
+	}
+
+	var unknown []string
+	for _, params := range []url.Values{c.Params.Query, c.Params.Form} {
+		for name := range params {
+			if !c.takesParam(name, allowed) && !containsString(unknown, name) {
+				unknown = append(unknown, name)
 			}
 		}
+	}
+	for name := range c.Params.Uploads {
+		if !c.takesParam(name, allowed) && !containsString(unknown, name) {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) == 0 {
+		return true
+	}
 
-		for _, tmpFile := range c.Params.tmpFiles {
-			err := os.Remove(tmpFile.Name())
-			if err != nil {
-				WARN.Println("Could not remove upload temp file:", err)
+	sort.Strings(unknown)
+	c.Response.Status = http.StatusBadRequest
+	c.Result = c.RenderError(&Error{
+		Title:       "Bad Request",
+		Description: "Unknown parameters: " + strings.Join(unknown, ", "),
+	})
+	return false
+}
+
+// actionRequiredParams maps the (lower-case) names of the actions declared
+// with RequireParams to the names of the params they require.
+var actionRequiredParams = map[string][]string{}
+
+// RequireParams makes requests to the action that lack any of the params fail
+// with 400 Bad Request, listing the missing params, before the action is
+// called.  For example:
+//   var _ = revel.RequireParams("Users.Update", "id", "user.Name")
+// A param is given by a non-empty value of it, or of one of its fields or
+// elements (e.g. "user.Name" for "user"), in the query, the route, a form or a
+// JSON body, or by an uploaded file.  Routes may also require params, with
+// {required=id user.Name}.
+// It returns the required params, so that it may be declared as above.
+func RequireParams(action string, required ...string) []string {
+	actionRequiredParams[strings.ToLower(action)] = required
+	return required
+}
+
+// checkRequiredParams returns true if the request has the params that the
+// action (and its route) requires.  If not, the result is set to a 400 Bad
+// Request listing the missing params, and false is returned.
+func checkRequiredParams(c *Controller) bool {
+	required := actionRequiredParams[strings.ToLower(c.Action)]
+	if c.Route != nil && c.Route.Route != nil {
+		required = append(required[:len(required):len(required)], c.Route.Route.required...)
+	}
+
+	var missing []string
+	for _, name := range required {
+		if !c.Params.given(name) && !containsString(missing, name) {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) == 0 {
+		return true
+	}
+
+	c.Response.Status = http.StatusBadRequest
+	c.Result = c.RenderError(&Error{
+		Title:       "Bad Request",
+		Description: "Missing parameters: " + strings.Join(missing, ", "),
+	})
+	return false
+}
+
+// given returns true if the named param, or one of its fields or elements, has
+// a non-empty value or an upload.
+func (p *Params) given(name string) bool {
+	for param, values := range p.Values {
+		if !paramOf(param, name) {
+			continue
+		}
+		for _, value := range values {
+			if value != "" {
+				return true
 			}
 		}
-	}()
+	}
+	for param := range p.Uploads {
+		if paramOf(param, name) {
+			return true
+		}
+	}
+	return false
+}
 
-	fc[0](c, fc[1:])
+// takesParam returns true if the param is bound to one of the action's
+// arguments, or is allowed.  e.g. "user.Name" and "ids[]" are bound to the
+// arguments "user" and "ids".  The "_method" param, used to override the
+// request's method, is always allowed.
+func (c *Controller) takesParam(name string, allowed []string) bool {
+	if name == "_method" {
+		return true
+	}
+	for _, arg := range c.MethodType.Args {
+		if paramOf(name, arg.Name) {
+			return true
+		}
+	}
+	for _, allowedName := range allowed {
+		if paramOf(name, allowedName) {
+			return true
+		}
+	}
+	return false
+}
+
+// paramOf returns true if the param binds the named value, or one of its
+// fields, elements or keys.
+// e.g. ("user.Name", "user"), ("ids[0]", "ids") => true
+func paramOf(param, name string) bool {
+	if !strings.HasPrefix(param, name) {
+		return false
+	}
+	rest := param[len(name):]
+	return rest == "" || rest[0] == '.' || rest[0] == '['
 }
@@ -5,8 +5,11 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"os"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -91,6 +94,7 @@ func BenchmarkParams(b *testing.B) {
 	}
 	for i := 0; i < b.N; i++ {
 		ParamsFilter(&c, NilChain)
+		c.cleanup()
 	}
 }
 
@@ -120,6 +124,196 @@ func TestMultipartForm(t *testing.T) {
 	}
 }
 
+func TestMultipartUploads(t *testing.T) {
+	c := NewController(NewRequest(getMultipartRequest()), nil)
+	ParamsFilter(c, NilChain)
+
+	actualFiles := make(map[string][]fh)
+	for key, uploads := range c.Params.Uploads {
+		for _, upload := range uploads {
+			file, _ := upload.Open()
+			content, _ := ioutil.ReadAll(file)
+			file.Close()
+			actualFiles[key] = append(actualFiles[key], fh{upload.Filename, content})
+			eq(t, "Size of "+key, upload.Size, int64(len(content)))
+		}
+	}
+	if !reflect.DeepEqual(expectedFiles, actualFiles) {
+		t.Errorf("Param uploads: (expected) %v != %v (actual)", expectedFiles, actualFiles)
+	}
+
+	// The deprecated Files bind as they did.
+	file := BindFile(c.Params.Files["file1"][0], reflect.TypeOf((*os.File)(nil))).Interface().(*os.File)
+	defer os.Remove(file.Name())
+	content, _ := ioutil.ReadAll(file)
+	eq(t, "Bound file", string(content), string(expectedFiles["file1"][0].content))
+
+	// The uploads are removed once the result has been applied.
+	c.cleanup()
+	for key, uploads := range c.Params.Uploads {
+		for _, upload := range uploads {
+			if _, err := os.Stat(upload.Location); !os.IsNotExist(err) {
+				t.Errorf("Expected the upload %s to be removed, got %v", key, err)
+			}
+		}
+	}
+
+	// Apps that read only the uploads need not have them copied.
+	defer func(files bool) { UploadFiles = files }(UploadFiles)
+	UploadFiles = false
+	c = NewController(NewRequest(getMultipartRequest()), nil)
+	ParamsFilter(c, NilChain)
+	eq(t, "Uploads", len(c.Params.Uploads), len(expectedFiles))
+	eq(t, "Files not copied", c.Params.Files == nil, true)
+	c.cleanup()
+}
+
+func TestStreamBody(t *testing.T) {
+	routes, _ := parseRoutes("", "POST /upload Files.Upload {body=stream}", false)
+	req := getMultipartRequest()
+	req.URL.RawQuery = "name=backup"
+	c := Controller{
+		Request: NewRequest(req),
+		Params:  &Params{},
+		Route:   &RouteMatch{Route: routes[0]},
+	}
+	ParamsFilter(&c, NilChain)
+
+	eq(t, "Query", c.Params.Get("name"), "backup")
+	eq(t, "Form", c.Params.Form == nil && c.Params.Uploads == nil, true)
+	body, err := ioutil.ReadAll(c.Request.BodyReader())
+	eq(t, "Read error", err, nil)
+	eq(t, "Body unread", len(body) > 0, true)
+
+	if _, err := parseRoutes("", "POST /upload Files.Upload {body=buffer}", false); err == nil {
+		t.Error("Expected an error for an invalid body policy")
+	}
+}
+
+func TestStrictParams(t *testing.T) {
+	startFakeBookingApp()
+	StrictParams(Hotels{}, "page")
+	defer delete(controllerStrictParams, "hotels")
+
+	for _, test := range []struct {
+		route, query string
+		ok           bool
+	}{
+		{"GET /hotels/:id Hotels.Show", "page=2", true},
+		{"GET /hotels/:id Hotels.Show", "id=3&page=2", true},
+		{"GET /hotels/:id Hotels.Show", "admin=1&page=2&x[0]=1", false},
+		{"GET /hotels/:id Hotels.Show {params=strict, allowParams=sort}", "sort=name", true},
+		{"GET /hotels/:id Hotels.Show {params=any}", "admin=1", true},
+	} {
+		routes, err := parseRoutes("", test.route, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, _ := http.NewRequest("GET", "/hotels/3?"+test.query, nil)
+		c := NewController(NewRequest(req), NewResponse(httptest.NewRecorder()))
+		c.Route = &RouteMatch{Route: routes[0]}
+		if err := c.SetAction("Hotels", "Show"); err != nil {
+			t.Fatal(err)
+		}
+
+		called := false
+		ParamsFilter(c, []Filter{func(*Controller, []Filter) { called = true }})
+		name := test.route + "?" + test.query
+		eq(t, "Called action for "+name, called, test.ok)
+		if !test.ok {
+			eq(t, "Status for "+name, c.Response.Status, http.StatusBadRequest)
+			eq(t, "Unknown params for "+name,
+				c.Result.(ErrorResult).Error.(*Error).Description, "Unknown parameters: admin, x[0]")
+		}
+	}
+
+	if _, err := parseRoutes("", "GET /hotels/:id Hotels.Show {params=loose}", false); err == nil {
+		t.Error("Expected an error for an invalid params policy")
+	}
+}
+
+func TestRequiredParams(t *testing.T) {
+	startFakeBookingApp()
+	RequireParams("Hotels.Show", "id", "hotel.Name")
+	defer delete(actionRequiredParams, "hotels.show")
+
+	for _, test := range []struct {
+		route, query, contentType, body string
+		missing                         string
+	}{
+		{"POST /hotels Hotels.Show", "id=3", "application/x-www-form-urlencoded", "hotel.Name=Ritz", ""},
+		{"POST /hotels Hotels.Show", "", "application/x-www-form-urlencoded", "id=&hotel.Name=Ritz", "id"},
+		{"POST /hotels Hotels.Show", "", "application/x-www-form-urlencoded", "hotel.City=Paris", "id, hotel.Name"},
+		{"POST /hotels Hotels.Show", "id=3", "application/json", `{"hotel": {"Name": "Ritz"}}`, ""},
+		{"POST /hotels Hotels.Show", "id=3", "application/json", `{"hotel": {"Name": null}}`, "hotel.Name"},
+		{"POST /hotels Hotels.Show {required=page}", "", "application/json", `{"id": 3, "hotel": {"Name": "Ritz"}, "page": 2}`, ""},
+		{"POST /hotels Hotels.Show {required=page hotel}", "id=3", "application/json", `{"hotel": {"Name": "Ritz"}}`, "page"},
+	} {
+		routes, err := parseRoutes("", test.route, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, _ := http.NewRequest("POST", "/hotels?"+test.query, strings.NewReader(test.body))
+		req.Header.Set("Content-Type", test.contentType)
+		c := NewController(NewRequest(req), NewResponse(httptest.NewRecorder()))
+		c.Route = &RouteMatch{Route: routes[0]}
+		if err := c.SetAction("Hotels", "Show"); err != nil {
+			t.Fatal(err)
+		}
+
+		called := false
+		ParamsFilter(c, []Filter{func(*Controller, []Filter) { called = true }})
+		name := test.route + "?" + test.query + " " + test.body
+		eq(t, "Called action for "+name, called, test.missing == "")
+		if test.missing != "" {
+			eq(t, "Status for "+name, c.Response.Status, http.StatusBadRequest)
+			eq(t, "Missing params for "+name,
+				c.Result.(ErrorResult).Error.(*Error).Description, "Missing parameters: "+test.missing)
+		}
+	}
+}
+
+type jsonUser struct {
+	Name    string `json:"name"`
+	Age     int
+	Tags    []string
+	Address struct{ City string }
+}
+
+func TestJsonParams(t *testing.T) {
+	userArg := &MethodArg{"user", reflect.TypeOf((*jsonUser)(nil))}
+	idArg := &MethodArg{"id", reflect.TypeOf(0)}
+	for _, test := range []struct {
+		body string
+		args []*MethodArg
+	}{
+		// The body names the arguments.
+		{`{"id": 3, "user": {"name": "Bob", "age": 30, "tags": ["a", "b"], "address": {"city": "NYC"}}}`,
+			[]*MethodArg{idArg, userArg}},
+		// The body is the only argument.
+		{`{"name": "Bob", "Age": 30, "Tags": ["a", "b"], "Address": {"City": "NYC"}, "Extra": null}`,
+			[]*MethodArg{userArg}},
+	} {
+		req, _ := http.NewRequest("POST", "/users", strings.NewReader(test.body))
+		req.Header.Set("Content-Type", "application/json")
+		c := NewController(NewRequest(req), nil)
+		c.MethodType = &MethodType{Name: "Create", Args: test.args}
+		ParamsFilter(c, NilChain)
+
+		user := Bind(c.Params, "user", userArg.Type).Interface().(*jsonUser)
+		eq(t, "User", fmt.Sprintf("%+v", *user), "{Name:Bob Age:30 Tags:[a b] Address:{City:NYC}}")
+		eq(t, "Body", string(c.Params.JSON), test.body)
+	}
+
+	req, _ := http.NewRequest("POST", "/users", strings.NewReader(`{"name": `))
+	req.Header.Set("Content-Type", "application/json")
+	c := NewController(NewRequest(req), NewResponse(httptest.NewRecorder()))
+	ParamsFilter(c, []Filter{ValidationFilter, NilFilter})
+	if eq(t, "Validation errors", len(c.Validation.Errors), 1) {
+		eq(t, "Validation key", c.Validation.Errors[0].Key, "body")
+	}
+}
+
 func TestBind(t *testing.T) {
 	params := Params{
 		Values: url.Values{
@@ -133,6 +327,92 @@ func TestBind(t *testing.T) {
 	}
 }
 
+func TestTypedParams(t *testing.T) {
+	params := &Params{Values: url.Values{
+		"id":    {"42"},
+		"big":   {"9007199254740993"},
+		"bad":   {"two"},
+		"on":    {"on"},
+		"at":    {"2013-06-01"},
+		"ts":    {"1370044800"},
+		"uuid":  {"123e4567-e89b-12d3-a456-426614174000"},
+		"uuid2": {"123E4567E89B12D3A456426614174000"},
+		"empty": {""},
+	}}
+
+	id, err := params.Int("id")
+	eq(t, "Int", fmt.Sprint(id, err), "42 <nil>")
+	big, err := params.Int64("big")
+	eq(t, "Int64", fmt.Sprint(big, err), "9007199254740993 <nil>")
+	_, err = params.Int("bad")
+	eq(t, "Invalid int", fmt.Sprint(err), `"two" is not a valid int`)
+	_, err = params.Int("empty")
+	eq(t, "Missing int", err, ErrMissingParam)
+	on, err := params.Bool("on")
+	eq(t, "Bool", fmt.Sprint(on, err), "true <nil>")
+	_, err = params.Bool("bad")
+	eq(t, "Invalid bool", fmt.Sprint(err), `"two" is not a valid bool`)
+	at, err := params.Time("at", "2006-01-02")
+	eq(t, "Time", fmt.Sprint(at, err), "2013-06-01 00:00:00 +0000 UTC <nil>")
+	ts, err := params.Time("ts", "unix")
+	eq(t, "Unix time", fmt.Sprint(ts, err), "2013-06-01 00:00:00 +0000 UTC <nil>")
+	for _, name := range []string{"uuid", "uuid2"} {
+		uuid, err := params.UUID(name)
+		eq(t, "UUID "+name, fmt.Sprintf("%x %v", uuid, err), "123e4567e89b12d3a456426614174000 <nil>")
+	}
+	_, err = params.UUID("id")
+	eq(t, "Invalid UUID", fmt.Sprint(err), `"42" is not a valid UUID`)
+
+	params.validation = &Validation{}
+	eq(t, "MustInt", params.MustInt("id"), 42)
+	eq(t, "MustInt invalid", params.MustInt("bad"), 0)
+	eq(t, "MustBool missing", params.MustBool("missing"), false)
+	eq(t, "MustTime", params.MustTime("ts", "unix").Unix(), int64(1370044800))
+	var errors []string
+	for _, err := range params.validation.Errors {
+		errors = append(errors, err.Key+": "+err.Message)
+	}
+	eq(t, "Validation errors", fmt.Sprint(errors), `[bad: "two" is not a valid int missing: Required]`)
+}
+
+func TestParamsPrecedence(t *testing.T) {
+	defer func(precedence []string) { ParamsPrecedence = precedence }(ParamsPrecedence)
+	params := &Params{
+		Route: url.Values{"id": {"route"}},
+		Query: url.Values{"id": {"query"}, "page": {"2"}},
+		Form:  url.Values{"id": {"form"}},
+	}
+
+	for _, test := range []struct {
+		precedence, expected string
+	}{
+		{"", "[query route form]"},
+		{"route, form, query", "[route form query]"},
+		{"form", "[form query route]"},
+	} {
+		ParamsPrecedence = defaultParamsPrecedence
+		if test.precedence != "" {
+			var err error
+			if ParamsPrecedence, err = parseParamsPrecedence(test.precedence); err != nil {
+				t.Fatal(err)
+			}
+		}
+		values := params.calcValues()
+		eq(t, "Values of "+test.precedence, fmt.Sprint(values["id"]), test.expected)
+		eq(t, "Page for "+test.precedence, values.Get("page"), "2")
+	}
+	eq(t, "From route", params.FromRoute("id"), "route")
+	eq(t, "From query", params.FromQuery("id"), "query")
+	eq(t, "From form", params.FromForm("id"), "form")
+	eq(t, "From fixed", params.FromFixed("id"), "")
+
+	for _, precedence := range []string{"route, body", "route, route"} {
+		if _, err := parseParamsPrecedence(precedence); err == nil {
+			t.Errorf("Expected an error for %q", precedence)
+		}
+	}
+}
+
 func TestResolveAcceptLanguage(t *testing.T) {
 	request := buildHttpRequestWithAcceptLanguage("")
 	if result := ResolveAcceptLanguage(request); result != nil {
@@ -0,0 +1,166 @@
+package revel
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// ProblemErrors, if set, renders errors as problem details (see
+// ProblemDetails) to requests that accept JSON, rather than error pages.  It
+// is set by "errors.problems", which defaults to false.  Routes may choose
+// either, e.g. {errors=problem} for an API, or {errors=page}, and requests
+// that accept application/problem+json always get problem details.
+var ProblemErrors bool
+
+func init() {
+	OnAppStart(func() {
+		ProblemErrors = Config.BoolDefault("errors.problems", false)
+	})
+}
+
+// ProblemDetails describes an error to API clients, as RFC 7807 defines
+// (application/problem+json).  It is a Result, and an error, that an action
+// may return (or wrap in the error it returns) to respond with it, e.g.
+//
+//     return nil, &revel.ProblemDetails{
+//     	Type:       "https://example.com/probs/out-of-credit",
+//     	Title:      "You do not have enough credit.",
+//     	Status:     http.StatusForbidden,
+//     	Detail:     "Your current balance is 30, but that costs 50.",
+//     	Extensions: map[string]interface{}{"balance": 30},
+//     }
+//
+// Errors are rendered as problem details by RenderError (and so by the
+// mappings of MapError) where ProblemErrors (or the route) says so.
+type ProblemDetails struct {
+	Type     string // A URI of the type of problem, or "" for "about:blank".
+	Title    string // A summary of the type of problem.
+	Status   int    // The status, or 0 for the response's.
+	Detail   string // An explanation of this occurrence of the problem.
+	Instance string // A URI of this occurrence of the problem.
+
+	// Extensions are members beside the others, e.g. "errors", the
+	// validation errors.
+	Extensions map[string]interface{}
+}
+
+func (p *ProblemDetails) Error() string {
+	if p.Detail == "" {
+		return p.Title
+	}
+	return p.Title + ": " + p.Detail
+}
+
+func (p *ProblemDetails) MarshalJSON() ([]byte, error) {
+	object := make(map[string]interface{}, len(p.Extensions)+5)
+	for key, value := range p.Extensions {
+		object[key] = value
+	}
+	object["type"] = p.Type
+	if p.Type == "" {
+		object["type"] = "about:blank"
+	}
+	object["status"] = p.Status
+	for key, value := range map[string]string{"title": p.Title, "detail": p.Detail, "instance": p.Instance} {
+		if value != "" {
+			object[key] = value
+		}
+	}
+	return json.Marshal(object)
+}
+
+func (p *ProblemDetails) Apply(req *Request, resp *Response) {
+	problem := *p
+	if problem.Status == 0 {
+		problem.Status = resp.Status
+	}
+	if problem.Status == 0 {
+		problem.Status = http.StatusInternalServerError
+	}
+	if problem.Title == "" && (problem.Type == "" || problem.Type == "about:blank") {
+		problem.Title = http.StatusText(problem.Status)
+	}
+	b, err := json.Marshal(&problem)
+	if err != nil {
+		ErrorResult{Error: err}.Apply(req, resp)
+		return
+	}
+	resp.Status = problem.Status
+	resp.WriteHeader(problem.Status, "application/problem+json")
+	resp.Out.Write(b)
+}
+
+// problemErrors returns true if errors are rendered to the request as problem
+// details.
+func (c *Controller) problemErrors() bool {
+	if c.Route != nil && c.Route.Route != nil && c.Route.Route.errors != "" {
+		return c.Route.Route.errors == "problem"
+	}
+	if c.Request == nil || c.Request.Request == nil {
+		return false
+	}
+	if strings.Contains(c.Request.Header.Get("Accept"), "application/problem+json") {
+		return true
+	}
+	return ProblemErrors && c.Request.Format == "json"
+}
+
+// problemDetails returns the problem details of an error rendered with the
+// response's status: the error's own, if it is (or wraps) *ProblemDetails, or
+// else its title and description.  Validation errors are listed as the
+// extension "errors", and the details of server errors are only given in dev
+// mode.
+func (c *Controller) problemDetails(err error) *ProblemDetails {
+	var problem *ProblemDetails
+	if errors.As(err, &problem) {
+		return problem
+	}
+	status := c.Response.Status
+	if status == 0 {
+		status = http.StatusInternalServerError
+	}
+	problem = &ProblemDetails{Title: http.StatusText(status), Status: status}
+	if c.Request != nil && c.Request.Request != nil && c.Request.URL != nil {
+		problem.Instance = c.Request.URL.Path
+	}
+	var revelError *Error
+	if errors.As(err, &revelError) {
+		if revelError.Title != "" {
+			problem.Title = revelError.Title
+		}
+		problem.Detail = revelError.Description
+	} else {
+		problem.Detail = err.Error()
+	}
+	if status >= http.StatusInternalServerError && !DevMode {
+		problem.Detail = ""
+	}
+
+	var validationErrors ValidationErrors
+	var validationError *ValidationError
+	if errors.As(err, &validationError) {
+		validationErrors = ValidationErrors{validationError}
+	} else {
+		errors.As(err, &validationErrors)
+	}
+	if len(validationErrors) > 0 {
+		type invalidParam struct {
+			Key     string `json:"key"`
+			Message string `json:"message"`
+		}
+		params := make([]invalidParam, len(validationErrors))
+		for i, e := range validationErrors {
+			params[i] = invalidParam{e.Key, e.Message}
+		}
+		problem.Extensions = map[string]interface{}{"errors": params}
+	}
+	if c.RequestId != "" {
+		if problem.Extensions == nil {
+			problem.Extensions = map[string]interface{}{}
+		}
+		problem.Extensions["requestId"] = c.RequestId
+	}
+	return problem
+}
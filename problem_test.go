@@ -0,0 +1,67 @@
+package revel
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestProblemDetails(t *testing.T) {
+	startFakeBookingApp()
+	defer func(problems bool) { ProblemErrors = problems }(ProblemErrors)
+
+	outOfCredit := &ProblemDetails{
+		Type:       "https://example.com/probs/out-of-credit",
+		Title:      "You do not have enough credit.",
+		Status:     http.StatusForbidden,
+		Extensions: map[string]interface{}{"balance": 30},
+	}
+	for _, test := range []struct {
+		name, accept, route string
+		problems            bool
+		err                 error
+		status              int
+		expected            string
+	}{
+		{"Validation", "application/json", "problem", false,
+			ValidationErrors{{Key: "user.name", Message: "Required"}}, http.StatusUnprocessableEntity,
+			`{"detail":"Required","errors":[{"key":"user.name","message":"Required"}],"instance":"/hotels/3","status":422,"title":"Unprocessable Entity","type":"about:blank"}`},
+		{"Not found", "application/problem+json", "", false,
+			fmt.Errorf("hotel 3: %w", ErrNotFound), http.StatusNotFound,
+			`{"detail":"hotel 3: not found","instance":"/hotels/3","status":404,"title":"Not Found","type":"about:blank"}`},
+		{"Own details", "text/html", "", false,
+			fmt.Errorf("booking: %w", outOfCredit), http.StatusForbidden,
+			`{"balance":30,"status":403,"title":"You do not have enough credit.","type":"https://example.com/probs/out-of-credit"}`},
+		{"Server error", "application/json", "", true,
+			errors.New("the database is down"), http.StatusInternalServerError,
+			`{"instance":"/hotels/3","status":500,"title":"Internal Server Error","type":"about:blank"}`},
+		{"HTML", "text/html", "", true, ErrNotFound, http.StatusNotFound, ""},
+		{"Page route", "application/problem+json", "page", false, ErrNotFound, http.StatusNotFound, ""},
+	} {
+		ProblemErrors = test.problems
+		req, _ := http.NewRequest("GET", "/hotels/3", nil)
+		req.Header.Set("Accept", test.accept)
+		resp := httptest.NewRecorder()
+		c := NewController(NewRequest(req), NewResponse(resp))
+		c.Route = &RouteMatch{Route: &Route{errors: test.route}}
+		ActionResult(c, nil, test.err).Apply(c.Request, c.Response)
+
+		eq(t, "Status: "+test.name, resp.Code, test.status)
+		if test.expected == "" {
+			eq(t, "Page: "+test.name, resp.Header().Get("Content-Type") != "application/problem+json", true)
+			continue
+		}
+		eq(t, "Content-Type: "+test.name, resp.Header().Get("Content-Type"), "application/problem+json")
+		eq(t, "Body: "+test.name, resp.Body.String(), test.expected)
+		var problem map[string]interface{}
+		eq(t, "Valid JSON: "+test.name, json.Unmarshal(resp.Body.Bytes(), &problem), nil)
+	}
+
+	_, err := parseRoutes("", "GET /api/hotels Hotels.Index {errors=problem}", false)
+	eq(t, "Route error", err == nil, true)
+	_, err = parseRoutes("", "GET /api/hotels Hotels.Index {errors=json}", false)
+	eq(t, "Invalid route option", err != nil, true)
+}
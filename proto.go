@@ -0,0 +1,101 @@
+package revel
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+)
+
+// ProtoMarshal and ProtoUnmarshal encode and decode Protocol Buffers messages,
+// for RenderProto and for binding request bodies of the type
+// application/x-protobuf to the actions' arguments.  By default, they use the
+// messages' own Marshal and Unmarshal methods, which the messages generated by
+// github.com/gogo/protobuf have.  Apps using other generated code replace them
+// on initialization, e.g. with google.golang.org/protobuf/proto:
+//
+//     revel.ProtoMarshal = func(msg interface{}) ([]byte, error) {
+//     	return proto.Marshal(msg.(proto.Message))
+//     }
+//     revel.ProtoUnmarshal = func(b []byte, msg interface{}) error {
+//     	return proto.Unmarshal(b, msg.(proto.Message))
+//     }
+var (
+	ProtoMarshal = func(msg interface{}) ([]byte, error) {
+		if m, ok := msg.(interface{ Marshal() ([]byte, error) }); ok {
+			return m.Marshal()
+		}
+		return nil, fmt.Errorf("revel: can not marshal %T; set revel.ProtoMarshal", msg)
+	}
+	ProtoUnmarshal = func(b []byte, msg interface{}) error {
+		if m, ok := msg.(interface{ Unmarshal([]byte) error }); ok {
+			return m.Unmarshal(b)
+		}
+		return fmt.Errorf("revel: can not unmarshal %T; set revel.ProtoUnmarshal", msg)
+	}
+)
+
+// protoMessageType is implemented by (pointers to) all generated messages.
+var protoMessageType = reflect.TypeOf((*interface{ ProtoMessage() })(nil)).Elem()
+
+// isProtoMessage returns true if the type is a message, or a pointer to one.
+func isProtoMessage(typ reflect.Type) bool {
+	if typ.Kind() != reflect.Ptr {
+		typ = reflect.PtrTo(typ)
+	}
+	return typ.Implements(protoMessageType)
+}
+
+// RenderProto renders a Protocol Buffers message, as application/x-protobuf.
+// Actions that return values render them so for requests that accept
+// application/x-protobuf (or routes with {render=proto}).
+func (c *Controller) RenderProto(msg interface{}) Result {
+	return RenderProtoResult{msg}
+}
+
+type RenderProtoResult struct {
+	msg interface{}
+}
+
+func (r RenderProtoResult) Apply(req *Request, resp *Response) {
+	b, err := ProtoMarshal(r.msg)
+	if err != nil {
+		ErrorResult{Error: err}.Apply(req, resp)
+		return
+	}
+
+	resp.WriteHeader(http.StatusOK, "application/x-protobuf")
+	resp.Out.Write(b)
+}
+
+// protoArg returns the argument of the action that a Protocol Buffers body is
+// bound to: the first that is a message, or nil if none is.
+func protoArg(c *Controller) *MethodArg {
+	for _, arg := range c.MethodType.Args {
+		if isProtoMessage(arg.Type) {
+			return arg
+		}
+	}
+	return nil
+}
+
+// bindProto binds the Protocol Buffers body of the request to the argument.  A
+// body that is not a valid message fails the request with 400 Bad Request (see
+// ActionInvoker).
+func bindProto(params *Params, name string, typ reflect.Type) reflect.Value {
+	msgType := typ
+	if typ.Kind() == reflect.Ptr {
+		msgType = typ.Elem()
+	}
+	msg := reflect.New(msgType)
+	if err := ProtoUnmarshal(params.Proto, msg.Interface()); err != nil {
+		params.badValues = append(params.badValues, &ValidationError{
+			Key:     name,
+			Message: "Invalid Protocol Buffers message: " + err.Error(),
+		})
+		return reflect.Zero(typ)
+	}
+	if typ.Kind() == reflect.Ptr {
+		return msg
+	}
+	return msg.Elem()
+}
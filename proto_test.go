@@ -0,0 +1,69 @@
+package revel
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// A message with a single string field, as generated by gogo/protobuf.
+type protoGreeting struct {
+	Name string
+}
+
+func (*protoGreeting) ProtoMessage() {}
+
+func (m *protoGreeting) Marshal() ([]byte, error) {
+	return append([]byte{0x0a, byte(len(m.Name))}, m.Name...), nil
+}
+
+func (m *protoGreeting) Unmarshal(b []byte) error {
+	if len(b) < 2 || b[0] != 0x0a || int(b[1]) != len(b)-2 {
+		return errors.New("malformed greeting")
+	}
+	m.Name = string(b[2:])
+	return nil
+}
+
+func (c Greeter) Echo(greeting *protoGreeting) (*protoGreeting, error) {
+	return &protoGreeting{Name: "Hello " + greeting.Name}, nil
+}
+
+func TestProto(t *testing.T) {
+	startFakeBookingApp()
+	RegisterController((*Greeter)(nil), []*MethodType{{
+		Name: "Echo",
+		Args: []*MethodArg{{"greeting", reflect.TypeOf((**protoGreeting)(nil))}},
+	}})
+
+	for _, test := range []struct {
+		body, accept string
+		status       int
+		expected     string
+	}{
+		{"\x0a\x03Bob", "application/x-protobuf", http.StatusOK, "\x0a\x09Hello Bob"},
+		{"\x0a\x03Bob", "application/json", http.StatusOK, `{"Name":"Hello Bob"}`},
+		{"\x0a\x07Bob", "application/x-protobuf", http.StatusBadRequest, "Invalid parameters: greeting"},
+	} {
+		req, _ := http.NewRequest("POST", "/greeter/echo", bytes.NewBufferString(test.body))
+		req.Header.Set("Content-Type", "application/x-protobuf")
+		req.Header.Set("Accept", test.accept)
+		resp := httptest.NewRecorder()
+		c := NewController(NewRequest(req), NewResponse(resp))
+		if err := c.SetAction("Greeter", "Echo"); err != nil {
+			t.Fatal(err)
+		}
+		ParamsFilter(c, []Filter{ActionInvoker})
+		c.Result.Apply(c.Request, c.Response)
+
+		name := test.accept + " " + test.expected
+		eq(t, "Status of "+name, resp.Code, test.status)
+		eq(t, "Body of "+name, bytes.Contains(resp.Body.Bytes(), []byte(test.expected)), true)
+		if test.status == http.StatusOK {
+			eq(t, "Content-Type of "+name, resp.Header().Get("Content-Type"), test.accept)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package revel
+
+import (
+	"html"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+)
+
+var (
+	// EarlyHints, if set, sends the resources given to PushResources to
+	// clients that can not be pushed them in a 103 Early Hints response,
+	// for them to preload while the page is rendered.  It is set by
+	// "results.earlyHints", which defaults to false, as some old clients and
+	// proxies do not understand informational responses.
+	EarlyHints bool
+
+	// AutoPreload, if set, pushes (or hints) the resources that rendered pages
+	// preload, e.g. <link rel="preload" href="/public/css/app.css" as="style">,
+	// as PushResources does, before the page is sent.  Pages that are
+	// rendered as they are sent ("results.chunked" in prod mode) are not.
+	// It is set by "results.preload", which defaults to false.
+	AutoPreload bool
+)
+
+func init() {
+	OnAppStart(func() {
+		EarlyHints = Config.BoolDefault("results.earlyHints", false)
+		AutoPreload = Config.BoolDefault("results.preload", false)
+	})
+}
+
+// preload is a resource for the client to load before it is asked for.
+type preload struct {
+	href string // its URL, e.g. "/public/css/app.css"
+	as   string // its destination, e.g. "style", or "" if unknown
+}
+
+// PushResources has the client load the resources (given by their paths,
+// e.g. "/public/css/app.css") that the response will ask for, while it is
+// rendered, to speed up the first paint of the page.  It should be called
+// before the response is written.  The resources are:
+//   - pushed to clients of HTTP/2 that allow it (see http.Pusher);
+//   - else, sent to the client in a 103 Early Hints response, if EarlyHints
+//     is set;
+//   - and given in the Link header of the response, for proxies (e.g. CDNs)
+//     to hint or push, in any case.
+func (c *Controller) PushResources(paths ...string) {
+	preloads := make([]preload, len(paths))
+	for i, p := range paths {
+		preloads[i] = preload{href: p, as: preloadDestination(p)}
+	}
+	pushPreloads(c.Request, c.Response, preloads)
+}
+
+// pushPreloads pushes (or hints) the resources, and gives them in the Link
+// header of the response.
+func pushPreloads(req *Request, resp *Response, preloads []preload) {
+	if len(preloads) == 0 {
+		return
+	}
+	header := resp.Out.Header()
+	for _, p := range preloads {
+		header.Add("Link", p.link())
+	}
+
+	if pusher := responsePusher(resp.Out); pusher != nil {
+		options := &http.PushOptions{Header: http.Header{}}
+		if acceptEncoding := req.Header.Get("Accept-Encoding"); acceptEncoding != "" {
+			options.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		for _, p := range preloads {
+			// Only the app's own resources may be pushed.
+			if !strings.HasPrefix(p.href, "/") || strings.HasPrefix(p.href, "//") {
+				continue
+			}
+			if err := pusher.Push(p.href, options); err != nil {
+				if err != http.ErrNotSupported {
+					WARN.Println("Failed to push", p.href+":", err)
+				}
+				break
+			}
+		}
+		return
+	}
+	if EarlyHints && req.ProtoAtLeast(1, 1) {
+		resp.Out.WriteHeader(http.StatusEarlyHints)
+	}
+}
+
+// responsePusher returns the http.Pusher of the response, unwrapping the
+// writers of filters (e.g. the CompressFilter), or nil if it has none.
+func responsePusher(w http.ResponseWriter) http.Pusher {
+	for {
+		if pusher, ok := w.(http.Pusher); ok {
+			return pusher
+		}
+		unwrapper, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return nil
+		}
+		w = unwrapper.Unwrap()
+	}
+}
+
+// link returns the value of a Link header that preloads the resource.
+// e.g. </public/css/app.css>; rel=preload; as=style
+func (p preload) link() string {
+	link := "<" + p.href + ">; rel=preload"
+	if p.as != "" {
+		link += "; as=" + p.as
+	}
+	if p.as == "font" {
+		// Fonts are fetched in CORS mode, so they must be preloaded so too.
+		link += "; crossorigin"
+	}
+	return link
+}
+
+// preloadDestination returns the destination ("as") of a resource to preload
+// by its extension, or "" if it is not known.
+// e.g. "/public/js/app.js?v=2" => "script"
+func preloadDestination(href string) string {
+	if i := strings.IndexAny(href, "?#"); i != -1 {
+		href = href[:i]
+	}
+	switch strings.ToLower(path.Ext(href)) {
+	case ".css":
+		return "style"
+	case ".js", ".mjs":
+		return "script"
+	case ".woff", ".woff2", ".ttf", ".otf", ".eot":
+		return "font"
+	case ".png", ".jpg", ".jpeg", ".gif", ".svg", ".webp", ".avif", ".ico":
+		return "image"
+	}
+	return ""
+}
+
+var (
+	linkTagPattern  = regexp.MustCompile(`(?i)<link\s[^>]*>`)
+	linkAttrPattern = regexp.MustCompile(`(?i)([a-z-]+)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+)
+
+// pagePreloads returns the resources that the <link> tags of a page preload.
+// e.g. <link rel="preload" href="/public/css/app.css" as="style">
+func pagePreloads(page []byte) []preload {
+	var preloads []preload
+	for _, tag := range linkTagPattern.FindAll(page, -1) {
+		attrs := map[string]string{}
+		for _, match := range linkAttrPattern.FindAllSubmatch(tag, -1) {
+			value := string(match[2]) + string(match[3]) + string(match[4])
+			attrs[strings.ToLower(string(match[1]))] = html.UnescapeString(value)
+		}
+		isPreload := false
+		for _, rel := range strings.Fields(attrs["rel"]) {
+			isPreload = isPreload || strings.EqualFold(rel, "preload")
+		}
+		if isPreload && attrs["href"] != "" {
+			preloads = append(preloads, preload{href: attrs["href"], as: strings.ToLower(attrs["as"])})
+		}
+	}
+	return preloads
+}
+
+// isInformational returns true if the status is that of an informational
+// response, e.g. 103 Early Hints, which precedes the response.
+func isInformational(status int) bool {
+	return status >= 100 && status < 200
+}
@@ -0,0 +1,93 @@
+package revel
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"net/textproto"
+	"testing"
+	"time"
+)
+
+func TestPagePreloads(t *testing.T) {
+	page := []byte(`<html><head>
+<link rel="stylesheet" href="/public/css/app.css">
+<link rel="preload" href="/public/css/app.css" as="style">
+<LINK REL='Preload' AS='font' HREF='/public/fonts/a.woff2' crossorigin>
+<link href=/public/js/app.js?a=1&amp;b=2 rel=preload as=script />
+<link rel="preload">
+</head></html>`)
+	eq(t, "Preloads", fmt.Sprint(pagePreloads(page)),
+		"[{/public/css/app.css style} {/public/fonts/a.woff2 font} {/public/js/app.js?a=1&b=2 script}]")
+}
+
+func TestPushResources(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/hotels", nil)
+	resp := httptest.NewRecorder()
+	c := NewController(NewRequest(req), NewResponse(resp))
+	c.PushResources("/public/css/app.css", "/public/fonts/a.woff2?v=2", "/public/data")
+	eq(t, "Link", fmt.Sprint(resp.Header()["Link"]), "[</public/css/app.css>; rel=preload; as=style "+
+		"</public/fonts/a.woff2?v=2>; rel=preload; as=font; crossorigin </public/data>; rel=preload]")
+	// Without EarlyHints, nothing is sent yet.
+	eq(t, "Written", resp.Flushed || resp.Body.Len() > 0, false)
+}
+
+// pageTemplate is a Template of a fixed page.
+type pageTemplate string
+
+func (t pageTemplate) Name() string      { return "page.html" }
+func (t pageTemplate) Content() []string { return []string{string(t)} }
+func (t pageTemplate) Render(wr io.Writer, arg interface{}) error {
+	_, err := io.WriteString(wr, string(t))
+	return err
+}
+
+func TestEarlyHints(t *testing.T) {
+	startFakeBookingApp()
+	defer func(filters []Filter) { Filters = filters }(Filters)
+	defer func(hints, auto bool) { EarlyHints, AutoPreload = hints, auto }(EarlyHints, AutoPreload)
+	defer func(deadlines map[string]time.Duration) { actionDeadlines = deadlines }(actionDeadlines)
+	EarlyHints, AutoPreload = true, true
+	actionDeadlines = map[string]time.Duration{"": time.Minute}
+
+	page := `<link rel="preload" href="/public/js/app.js" as="script"><p>Hello</p>`
+	Filters = []Filter{CompressFilter, ETagFilter, DeadlineFilter, func(c *Controller, fc []Filter) {
+		if c.Request.URL.Path == "/pushed" {
+			c.PushResources("/public/css/app.css")
+			c.Result = c.RenderText("Hello")
+			return
+		}
+		c.Result = &RenderTemplateResult{Template: pageTemplate(page)}
+	}}
+	server := httptest.NewServer(http.HandlerFunc(handle))
+	defer server.Close()
+
+	for _, test := range []struct {
+		path, link, body string
+	}{
+		{"/pushed", "</public/css/app.css>; rel=preload; as=style", "Hello"},
+		{"/page", "</public/js/app.js>; rel=preload; as=script", page},
+	} {
+		var hints []string
+		trace := &httptrace.ClientTrace{
+			Got1xxResponse: func(code int, header textproto.MIMEHeader) error {
+				hints = append(hints, fmt.Sprint(code, " ", header.Get("Link")))
+				return nil
+			},
+		}
+		req, _ := http.NewRequest("GET", server.URL+test.path, nil)
+		resp, err := http.DefaultClient.Do(req.WithContext(httptrace.WithClientTrace(req.Context(), trace)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		eq(t, "Hints of "+test.path, fmt.Sprint(hints), "[103 "+test.link+"]")
+		eq(t, "Status of "+test.path, resp.StatusCode, http.StatusOK)
+		eq(t, "Link of "+test.path, resp.Header.Get("Link"), test.link)
+		eq(t, "Body of "+test.path, string(body), test.body)
+	}
+}
@@ -0,0 +1,146 @@
+package revel
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rateLimit limits the rate of requests to a route, as declared by its
+// options.  For example:
+//   POST  /login  Auth.Login  {rate=10/min, burst=5, key=ip}
+//
+// Each client, identified by the key, may make up to "burst" requests at once,
+// and after that, requests at the given rate.  The key is one of:
+//   "ip"          - the client's IP address (the default)
+//   "header:Name" - the value of a request header, e.g. "header:X-Api-Key"
+//   "route"       - all clients share a single limit
+type rateLimit struct {
+	rate  float64 // requests allowed per second
+	burst int     // requests allowed at once
+	key   string  // e.g. "ip"
+
+	mutex     sync.Mutex
+	buckets   map[string]*tokenBucket // key value => the client's bucket
+	lastPrune time.Time
+}
+
+// tokenBucket holds the requests that a client may make.  It is refilled
+// continuously at the limit's rate.
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// rateUnits are the units of time that a rate may be given per.
+var rateUnits = map[string]time.Duration{
+	"s":      time.Second,
+	"sec":    time.Second,
+	"second": time.Second,
+	"m":      time.Minute,
+	"min":    time.Minute,
+	"minute": time.Minute,
+	"h":      time.Hour,
+	"hour":   time.Hour,
+	"d":      24 * time.Hour,
+	"day":    24 * time.Hour,
+}
+
+// newRateLimit parses the rate limit options of a route.
+// e.g. ("10/min", "5", "ip").  The burst defaults to the number of requests
+// in the rate.
+func newRateLimit(rate, burst, key string) (*rateLimit, error) {
+	parts := strings.SplitN(rate, "/", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("Invalid rate: %s", rate)
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	unit, ok := rateUnits[strings.ToLower(strings.TrimSpace(parts[1]))]
+	if err != nil || count <= 0 || !ok {
+		return nil, fmt.Errorf("Invalid rate: %s", rate)
+	}
+
+	limit := &rateLimit{
+		rate:    float64(count) / unit.Seconds(),
+		burst:   count,
+		key:     key,
+		buckets: make(map[string]*tokenBucket),
+	}
+	if burst != "" {
+		if limit.burst, err = strconv.Atoi(burst); err != nil || limit.burst <= 0 {
+			return nil, fmt.Errorf("Invalid burst: %s", burst)
+		}
+	}
+	switch {
+	case key == "":
+		limit.key = "ip"
+	case key == "ip", key == "route":
+	case strings.HasPrefix(key, "header:") && len(key) > len("header:"):
+	default:
+		return nil, fmt.Errorf("Invalid rate limit key: %s", key)
+	}
+	return limit, nil
+}
+
+// keyFor returns the value identifying the client making the request.
+func (l *rateLimit) keyFor(req *http.Request) string {
+	switch {
+	case l.key == "route":
+		return ""
+	case strings.HasPrefix(l.key, "header:"):
+		return req.Header.Get(l.key[len("header:"):])
+	}
+	return remoteIp(req)
+}
+
+// allow takes one of the requests of the client making the request, returning
+// 0 if it was allowed, or else how long the client must wait.
+func (l *rateLimit) allow(req *http.Request) time.Duration {
+	return l.take(l.keyFor(req), time.Now())
+}
+
+// take uses one of the client's requests, returning 0 if it was allowed, or
+// else how long the client must wait before the next is.
+func (l *rateLimit) take(key string, now time.Time) time.Duration {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	// Forget the clients whose buckets have refilled, once a minute.
+	if now.Sub(l.lastPrune) > time.Minute {
+		for k, b := range l.buckets {
+			if b.refill(l, now) >= float64(l.burst) {
+				delete(l.buckets, k)
+			}
+		}
+		l.lastPrune = now
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: float64(l.burst), last: now}
+		l.buckets[key] = b
+	}
+	if b.refill(l, now) >= 1 {
+		b.tokens--
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+}
+
+// refill adds the tokens accrued since the bucket was last used, and returns
+// the number available.
+func (b *tokenBucket) refill(l *rateLimit, now time.Time) float64 {
+	b.tokens = math.Min(float64(l.burst), b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+	return b.tokens
+}
+
+// retryAfter formats a wait as the value of a Retry-After header, in whole
+// seconds.
+func retryAfter(wait time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(wait.Seconds())))
+}
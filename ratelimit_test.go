@@ -0,0 +1,71 @@
+package revel
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestNewRateLimit(t *testing.T) {
+	limit, err := newRateLimit("10/min", "5", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	eq(t, "rate", limit.rate, 10.0/60)
+	eq(t, "burst", limit.burst, 5)
+	eq(t, "key", limit.key, "ip")
+
+	limit, err = newRateLimit("2/s", "", "header:X-Api-Key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	eq(t, "default burst", limit.burst, 2)
+
+	for _, args := range [][3]string{
+		{"10", "", ""},
+		{"ten/min", "", ""},
+		{"10/fortnight", "", ""},
+		{"10/min", "0", ""},
+		{"10/min", "", "cookie"},
+	} {
+		if _, err := newRateLimit(args[0], args[1], args[2]); err == nil {
+			t.Errorf("Expected an error for %q", args)
+		}
+	}
+}
+
+func TestRateLimitTake(t *testing.T) {
+	limit, _ := newRateLimit("1/s", "2", "ip")
+	now := time.Now()
+
+	eq(t, "first request", limit.take("a", now), time.Duration(0))
+	eq(t, "second request", limit.take("a", now), time.Duration(0))
+	eq(t, "third request", limit.take("a", now), time.Second)
+	eq(t, "other client", limit.take("b", now), time.Duration(0))
+	eq(t, "after waiting", limit.take("a", now.Add(time.Second)), time.Duration(0))
+	eq(t, "retry after", retryAfter(limit.take("a", now.Add(time.Second+time.Second/2))), "1")
+}
+
+func TestRateLimitKey(t *testing.T) {
+	req, _ := http.NewRequest("POST", "/login", nil)
+	req.RemoteAddr = "10.0.0.1:5000"
+	req.Header.Set("X-Api-Key", "secret")
+
+	for key, expected := range map[string]string{
+		"ip":               "10.0.0.1",
+		"header:X-Api-Key": "secret",
+		"route":            "",
+	} {
+		limit, _ := newRateLimit("1/s", "", key)
+		eq(t, "key "+key, limit.keyFor(req), expected)
+	}
+
+	routes, err := parseRoutes("", "POST /login Auth.Login {rate=5/min, burst=2}", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	eq(t, "route limit", routes[0].limit != nil, true)
+	if _, err := parseRoutes("", "POST /login Auth.Login {rate=fast}", false); err == nil {
+		t.Error("Expected an error for an invalid rate")
+	}
+}
@@ -0,0 +1,77 @@
+package revel
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// RequestIdHeader is the header that gives the ID of a request, in both the
+// request (if the client, e.g. another service, assigned one) and the response.
+// It is set by "requestid.header", which defaults to "X-Request-ID".
+var RequestIdHeader = "X-Request-ID"
+
+// maxRequestIdLength is the length of the longest request ID accepted from a
+// client.  Longer IDs are replaced.
+const maxRequestIdLength = 128
+
+// requestIdKey is the key of the request ID in the request's context.
+type requestIdKey struct{}
+
+func init() {
+	OnAppStart(func() {
+		RequestIdHeader = Config.StringDefault("requestid.header", "X-Request-ID")
+	})
+}
+
+// RequestIdFilter assigns each request an ID, to correlate the logs of the
+// request (see RequestLogger), including those of other services that it
+// calls.  The ID is taken from the request's RequestIdHeader, if it has a valid
+// one, or else generated.  It is set on the Controller (c.RequestId), in the
+// request's context (see RequestIdFromContext), in the response's
+// RequestIdHeader, and in the template's "requestId".
+func RequestIdFilter(c *Controller, fc []Filter) {
+	id := c.Request.Header.Get(RequestIdHeader)
+	if !validRequestId(id) {
+		id = newRequestId()
+	}
+
+	c.RequestId = id
+	c.Request.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), requestIdKey{}, id))
+	c.Response.Out.Header().Set(RequestIdHeader, id)
+	c.RenderArgs["requestId"] = id
+
+	fc[0](c, fc[1:])
+}
+
+// RequestIdFromContext returns the ID of the request that the context is of,
+// or "" if it has none.  To propagate the ID to another service, set it as the
+// RequestIdHeader of the request to the service.
+func RequestIdFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIdKey{}).(string)
+	return id
+}
+
+// validRequestId returns true if the ID given by a client may be used: if it is
+// not too long, and is made only of printable ASCII characters, so that it is
+// safe to write to the logs and the response headers.
+func validRequestId(id string) bool {
+	if id == "" || len(id) > maxRequestIdLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestId returns a random request ID of 32 hex digits.
+func newRequestId() string {
+	var id [16]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(id[:])
+}
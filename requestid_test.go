@@ -0,0 +1,44 @@
+package revel
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequestIdFilter(t *testing.T) {
+	tests := []struct {
+		header   string
+		expected string // "" for a generated ID
+	}{
+		{"", ""},
+		{"abc-123", "abc-123"},
+		{"has space", ""},
+		{strings.Repeat("a", maxRequestIdLength+1), ""},
+	}
+
+	for _, test := range tests {
+		req, _ := http.NewRequest("GET", "/hotels", nil)
+		if test.header != "" {
+			req.Header.Set(RequestIdHeader, test.header)
+		}
+		resp := httptest.NewRecorder()
+		c := NewController(NewRequest(req), NewResponse(resp))
+
+		var contextId string
+		RequestIdFilter(c, []Filter{func(c *Controller, _ []Filter) {
+			contextId = RequestIdFromContext(c.Context())
+		}})
+
+		id := c.RequestId
+		if test.expected != "" {
+			eq(t, "Request ID of "+test.header, id, test.expected)
+		} else if len(id) != 32 || id == test.header {
+			t.Errorf("Expected a generated request ID for %q, got %q", test.header, id)
+		}
+		eq(t, "Context request ID", contextId, id)
+		eq(t, "Response request ID", resp.Header().Get(RequestIdHeader), id)
+		eq(t, "Template request ID", c.RenderArgs["requestId"], id)
+	}
+}
@@ -1,15 +1,18 @@
 package revel
 
 import (
+	"bufio"
 	"bytes"
-	"encoding/json"
+	"context"
 	"encoding/xml"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"reflect"
+	"runtime/debug"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -17,6 +20,36 @@ type Result interface {
 	Apply(req *Request, resp *Response)
 }
 
+var resultHooks []func(c *Controller, result Result) Result
+
+// OnResult registers a hook called with the result of each request, after the
+// filters (and action) and before the result is applied.  The hook returns the
+// result to apply, which may be the one given, or one that wraps or replaces
+// it, e.g. to add an envelope, to set headers, or to record metrics by the type
+// of result.  Hooks are called in the order registered, each given the result
+// of the one before, and are not called for requests without a result.
+func OnResult(hook func(c *Controller, result Result) Result) {
+	resultHooks = append(resultHooks, hook)
+}
+
+// runResultHooks passes the controller's result through the result hooks.  A
+// hook that panics is logged and skipped.
+func runResultHooks(c *Controller) {
+	for _, hook := range resultHooks {
+		if c.Result == nil {
+			return
+		}
+		func() {
+			defer func() {
+				if err := recover(); err != nil {
+					ERROR.Print("Result hook failed: ", err, "\n", string(debug.Stack()))
+				}
+			}()
+			c.Result = hook(c, c.Result)
+		}()
+	}
+}
+
 // This result handles all kinds of error codes (500, 404, ..).
 // It renders the relevant error page (errors/CODE.format, e.g. errors/500.json).
 // If RunMode is "dev", this results in a friendly error page.
@@ -27,6 +60,10 @@ type ErrorResult struct {
 
 func (r ErrorResult) Apply(req *Request, resp *Response) {
 	format := req.Format
+	if format == "proto" {
+		// Errors are rendered as text to Protocol Buffers clients.
+		format = "txt"
+	}
 	status := resp.Status
 	if status == 0 {
 		status = http.StatusInternalServerError
@@ -41,6 +78,13 @@ func (r ErrorResult) Apply(req *Request, resp *Response) {
 	var err error
 	templatePath := fmt.Sprintf("errors/%d.%s", status, format)
 	tmpl, err := MainTemplateLoader.Template(templatePath)
+	if tmpl == nil {
+		// Statuses without a template of their own (e.g. those given by
+		// MapError) share a generic one.
+		if generic, _ := MainTemplateLoader.Template("errors/error." + format); generic != nil {
+			tmpl, err = generic, nil
+		}
+	}
 
 	// This func shows a plaintext error message, in case the template rendering
 	// doesn't work.
@@ -138,6 +182,13 @@ func (r *RenderTemplateResult) Apply(req *Request, resp *Response) {
 	// would carry a 200 status code)
 	var b bytes.Buffer
 	r.render(req, resp, &b)
+	if TemplateETags && (resp.Status == 0 || resp.Status == http.StatusOK) && contentETag(req, resp, b.Bytes()) {
+		NotModifiedResult{}.Apply(req, resp)
+		return
+	}
+	if AutoPreload && (resp.Status == 0 || resp.Status == http.StatusOK) {
+		pushPreloads(req, resp, pagePreloads(b.Bytes()))
+	}
 	if !chunked {
 		resp.Out.Header().Set("Content-Length", strconv.Itoa(b.Len()))
 	}
@@ -151,25 +202,9 @@ func (r *RenderTemplateResult) render(req *Request, resp *Response, wr io.Writer
 		return
 	}
 
-	var templateContent []string
-	templateName, line, description := parseTemplateError(err)
-	if templateName == "" {
-		templateName = r.Template.Name()
-		templateContent = r.Template.Content()
-	} else {
-		if tmpl, err := MainTemplateLoader.Template(templateName); err == nil {
-			templateContent = tmpl.Content()
-		}
-	}
-	compileError := &Error{
-		Title:       "Template Execution Error",
-		Path:        templateName,
-		Description: description,
-		Line:        line,
-		SourceLines: templateContent,
-	}
+	compileError := templateExecutionError(r.Template, err)
 	resp.Status = 500
-	ERROR.Printf("Template Execution Error (in %s): %s", templateName, description)
+	ERROR.Printf("Template Execution Error (in %s): %s", compileError.Path, compileError.Description)
 	ErrorResult{r.RenderArgs, compileError}.Apply(req, resp)
 }
 
@@ -183,18 +218,12 @@ func (r RenderHtmlResult) Apply(req *Request, resp *Response) {
 }
 
 type RenderJsonResult struct {
-	obj interface{}
+	obj     interface{}
+	options JsonOptions
 }
 
 func (r RenderJsonResult) Apply(req *Request, resp *Response) {
-	var b []byte
-	var err error
-	if Config.BoolDefault("results.pretty", false) {
-		b, err = json.MarshalIndent(r.obj, "", "  ")
-	} else {
-		b, err = json.Marshal(r.obj)
-	}
-
+	b, err := marshalJson(r.obj, r.options)
 	if err != nil {
 		ErrorResult{Error: err}.Apply(req, resp)
 		return
@@ -242,31 +271,57 @@ var (
 	Inline     ContentDisposition = "inline"
 )
 
+// BinaryResult sends the content of a reader, e.g. a file.  Readers that can
+// seek (or read at an offset, given the Length) answer Range requests with 206
+// Partial Content, so that clients may resume downloads or seek in videos.  A
+// range is sent only if the content is unchanged since the client got the rest
+// of it, as told by an If-Range of the ETag or ModTime.
 type BinaryResult struct {
 	Reader   io.Reader
 	Name     string
-	Length   int64
+	Length   int64 // the length of the content, or -1 if unknown
 	Delivery ContentDisposition
 	ModTime  time.Time
+	ETag     string // the ETag of the content, if any, e.g. its hash
 }
 
 func (r *BinaryResult) Apply(req *Request, resp *Response) {
-	disposition := string(r.Delivery)
-	if r.Name != "" {
-		disposition += fmt.Sprintf("; filename=%s", r.Name)
+	header := resp.Out.Header()
+	header.Set("Content-Disposition", contentDisposition(r.Delivery, r.Name))
+	if r.ETag != "" {
+		header.Set("ETag", quoteETag(r.ETag))
 	}
-	resp.Out.Header().Set("Content-Disposition", disposition)
 
-	// If we have a ReadSeeker, delegate to http.ServeContent
-	if rs, ok := r.Reader.(io.ReadSeeker); ok {
+	// If we can seek, delegate to http.ServeContent, which handles ranges and
+	// conditional requests.
+	rs, ok := r.Reader.(io.ReadSeeker)
+	if ra, isReaderAt := r.Reader.(io.ReaderAt); !ok && isReaderAt && r.Length >= 0 {
+		rs, ok = io.NewSectionReader(ra, 0, r.Length), true
+	}
+	contentType := resp.ContentType
+	if contentType == "" {
+		contentType = ContentTypeByFilename(r.Name)
+	}
+	if ok {
+		// Unknown types are sniffed from the content.
+		if contentType != DefaultFileContentType {
+			header.Set("Content-Type", contentType)
+		}
 		http.ServeContent(resp.Out, req.Request, r.Name, r.ModTime, rs)
 	} else {
 		// Else, do a simple io.Copy.
+		reader := r.Reader
+		if contentType == DefaultFileContentType {
+			buffered := bufio.NewReader(reader)
+			head, _ := buffered.Peek(512)
+			contentType, reader = http.DetectContentType(head), buffered
+		}
 		if r.Length != -1 {
-			resp.Out.Header().Set("Content-Length", strconv.FormatInt(r.Length, 10))
+			header.Set("Content-Length", strconv.FormatInt(r.Length, 10))
 		}
-		resp.WriteHeader(http.StatusOK, ContentTypeByFilename(r.Name))
-		io.Copy(resp.Out, r.Reader)
+		header.Set("Accept-Ranges", "none")
+		resp.WriteHeader(http.StatusOK, contentType)
+		io.Copy(resp.Out, reader)
 	}
 
 	// Close the Reader if we can
@@ -275,17 +330,114 @@ func (r *BinaryResult) Apply(req *Request, resp *Response) {
 	}
 }
 
+// contentDisposition returns the Content-Disposition of content delivered by
+// the name, which is encoded as RFC 6266 says, for names that are not ASCII
+// (e.g. "Résumé.pdf") or that have quotes, with an ASCII fallback for old
+// clients.  The delivery defaults to Attachment.
+// e.g. (Inline, "a.pdf") => `inline; filename="a.pdf"`
+func contentDisposition(delivery ContentDisposition, name string) string {
+	if delivery == "" {
+		delivery = Attachment
+	}
+	if name == "" {
+		return string(delivery)
+	}
+	var fallback, encoded strings.Builder
+	plain := true
+	for _, r := range name {
+		if r < ' ' || r > '~' || r == '"' || r == '\\' || r == '%' {
+			plain = false
+			fallback.WriteByte('_')
+		} else {
+			fallback.WriteRune(r)
+		}
+	}
+	disposition := fmt.Sprintf(`%s; filename="%s"`, delivery, fallback.String())
+	if plain {
+		return disposition
+	}
+	for _, b := range []byte(name) {
+		if 'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z' || '0' <= b && b <= '9' || strings.IndexByte("!#$&+-.^_`|~", b) >= 0 {
+			encoded.WriteByte(b)
+		} else {
+			fmt.Fprintf(&encoded, "%%%02X", b)
+		}
+	}
+	return disposition + "; filename*=UTF-8''" + encoded.String()
+}
+
+// DeferredResult is a result produced by a goroutine started by
+// Controller.Defer, while the rest of the filters run.  Applying it waits for
+// the goroutine, and then applies its result, which the goroutine builds
+// without the controller (see Controller.JsonRenderer).
+type DeferredResult struct {
+	ctx        context.Context
+	renderArgs map[string]interface{}
+	done       chan struct{}
+	result     Result
+	panicErr   interface{}
+	stack      []byte
+}
+
+func newDeferredResult(ctx context.Context, renderArgs map[string]interface{}, produce func(context.Context) Result) *DeferredResult {
+	r := &DeferredResult{
+		ctx:        ctx,
+		renderArgs: renderArgs,
+		done:       make(chan struct{}),
+	}
+	go func() {
+		defer close(r.done)
+		defer func() {
+			if err := recover(); err != nil {
+				r.panicErr, r.stack = err, debug.Stack()
+			}
+		}()
+		r.result = produce(ctx)
+	}()
+	return r
+}
+
+func (r *DeferredResult) Apply(req *Request, resp *Response) {
+	<-r.done
+
+	switch {
+	case r.panicErr != nil:
+		ERROR.Print(r.panicErr, "\n", string(r.stack))
+		resp.Status = http.StatusInternalServerError
+		ErrorResult{r.renderArgs, &Error{
+			Title:       "Panic",
+			Description: fmt.Sprint(r.panicErr),
+			Stack:       string(r.stack),
+		}}.Apply(req, resp)
+	case r.result != nil:
+		r.result.Apply(req, resp)
+	case r.ctx.Err() == context.DeadlineExceeded:
+		resp.Status = http.StatusServiceUnavailable
+		ErrorResult{r.renderArgs, &Error{
+			Title:       "Service Unavailable",
+			Description: "The request timed out",
+		}}.Apply(req, resp)
+	}
+	// Otherwise the client has gone, or there is nothing to send.
+}
+
 type RedirectToUrlResult struct {
-	url string
+	url    string
+	status int // defaults to 302 Found
 }
 
 func (r *RedirectToUrlResult) Apply(req *Request, resp *Response) {
+	status := r.status
+	if status == 0 {
+		status = http.StatusFound
+	}
 	resp.Out.Header().Set("Location", r.url)
-	resp.WriteHeader(http.StatusFound, "")
+	resp.WriteHeader(status, "")
 }
 
 type RedirectToActionResult struct {
-	val interface{}
+	val    interface{}
+	status int // defaults to 302 Found
 }
 
 func (r *RedirectToActionResult) Apply(req *Request, resp *Response) {
@@ -295,8 +447,12 @@ func (r *RedirectToActionResult) Apply(req *Request, resp *Response) {
 		ErrorResult{Error: err}.Apply(req, resp)
 		return
 	}
+	status := r.status
+	if status == 0 {
+		status = http.StatusFound
+	}
 	resp.Out.Header().Set("Location", url)
-	resp.WriteHeader(http.StatusFound, "")
+	resp.WriteHeader(status, "")
 }
 
 func getRedirectUrl(item interface{}) (string, error) {
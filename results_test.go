@@ -1,9 +1,16 @@
 package revel
 
 import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
 	"net/http/httptest"
+	"os"
 	"strings"
 	"testing"
+	"time"
 )
 
 // Test that the render response is as expected.
@@ -19,6 +26,46 @@ func TestBenchmarkRender(t *testing.T) {
 	}
 }
 
+func TestDeferredResult(t *testing.T) {
+	startFakeBookingApp()
+	newController := func() (*Controller, *httptest.ResponseRecorder) {
+		resp := httptest.NewRecorder()
+		return NewController(NewRequest(plaintextRequest), NewResponse(resp)), resp
+	}
+
+	// The result is produced while the caller continues, without the
+	// controller, with the JSON options it had when the action deferred.
+	c, resp := newController()
+	c.JsonOptions = &JsonOptions{Envelope: "data"}
+	renderJson := c.JsonRenderer()
+	release := make(chan struct{})
+	result := c.Defer(func(ctx context.Context) Result {
+		<-release
+		return renderJson(map[string]string{"greeting": "Hello"})
+	})
+	c.JsonOptions = nil
+	close(release)
+	result.Apply(c.Request, c.Response)
+	eq(t, "Body", resp.Body.String(), `{"data":{"greeting":"Hello"}}`)
+
+	// A producer that times out without a result gives a 503.
+	c, resp = newController()
+	_, cancel := c.WithTimeout(time.Millisecond)
+	defer cancel()
+	c.Defer(func(ctx context.Context) Result {
+		<-ctx.Done()
+		return nil
+	}).Apply(c.Request, c.Response)
+	eq(t, "Timeout status", resp.Code, http.StatusServiceUnavailable)
+
+	// A panic gives a 500.
+	c, resp = newController()
+	c.Defer(func(ctx context.Context) Result {
+		panic("backend failed")
+	}).Apply(c.Request, c.Response)
+	eq(t, "Panic status", resp.Code, http.StatusInternalServerError)
+}
+
 func BenchmarkRenderChunked(b *testing.B) {
 	startFakeBookingApp()
 	resp := httptest.NewRecorder()
@@ -48,3 +95,121 @@ func BenchmarkRenderNotChunked(b *testing.B) {
 		hotels.Show(3).Apply(c.Request, c.Response)
 	}
 }
+
+// readerAt is a reader that can only be read at an offset.
+type readerAt struct{ io.ReaderAt }
+
+func (r readerAt) Read(p []byte) (int, error) { panic("not read from the start") }
+
+func TestBinaryResultRanges(t *testing.T) {
+	startFakeBookingApp()
+	content := []byte("0123456789")
+	modtime := time.Date(2013, 6, 1, 12, 0, 0, 0, time.UTC)
+	for _, test := range []struct {
+		name, rangeHeader, ifRange string
+		reader                     io.Reader
+		length                     int64
+		status                     int
+		contentRange, body         string
+	}{
+		{"Whole", "", "", bytes.NewReader(content), -1, http.StatusOK, "", "0123456789"},
+		{"Range", "bytes=2-4", "", bytes.NewReader(content), -1, http.StatusPartialContent, "bytes 2-4/10", "234"},
+		{"Suffix", "bytes=-3", "", bytes.NewReader(content), -1, http.StatusPartialContent, "bytes 7-9/10", "789"},
+		{"Unsatisfiable", "bytes=20-", "", bytes.NewReader(content), -1, http.StatusRequestedRangeNotSatisfiable, "bytes */10", ""},
+		{"If-Range ETag", "bytes=2-4", `"v1"`, bytes.NewReader(content), -1, http.StatusPartialContent, "bytes 2-4/10", "234"},
+		{"If-Range changed", "bytes=2-4", `"v0"`, bytes.NewReader(content), -1, http.StatusOK, "", "0123456789"},
+		{"ReaderAt", "bytes=5-", "", readerAt{bytes.NewReader(content)}, 10, http.StatusPartialContent, "bytes 5-9/10", "56789"},
+		{"Not seekable", "bytes=2-4", "", struct{ io.Reader }{bytes.NewReader(content)}, -1, http.StatusOK, "", "0123456789"},
+	} {
+		req, _ := http.NewRequest("GET", "/files/digits.txt", nil)
+		req.Header.Set("Range", test.rangeHeader)
+		req.Header.Set("If-Range", test.ifRange)
+		resp := httptest.NewRecorder()
+		c := NewController(NewRequest(req), NewResponse(resp))
+
+		result := &BinaryResult{Reader: test.reader, Name: "digits.txt", Length: test.length, Delivery: Inline, ModTime: modtime, ETag: "v1"}
+		result.Apply(c.Request, c.Response)
+		eq(t, "Status: "+test.name, resp.Code, test.status)
+		eq(t, "Content-Range: "+test.name, resp.Header().Get("Content-Range"), test.contentRange)
+		if test.status != http.StatusRequestedRangeNotSatisfiable {
+			eq(t, "Body: "+test.name, resp.Body.String(), test.body)
+		}
+	}
+}
+
+func TestRenderTemplateToString(t *testing.T) {
+	startFakeBookingApp()
+	hotel := &Hotel{3, "A Hotel", "300 Main St.", "New York", "NY", "10010", "USA", 300}
+	html, err := RenderTemplateToString("Hotels/Show.html", map[string]interface{}{"title": "View Hotel", "hotel": hotel})
+	eq(t, "Error", err, nil)
+	eq(t, "Has address", strings.Contains(html, "300 Main St."), true)
+
+	c := NewController(NewRequest(showRequest), NewResponse(httptest.NewRecorder()))
+	c.RenderArgs["title"], c.RenderArgs["hotel"] = "View Hotel", hotel
+	fromController, err := c.RenderToString("Hotels/Show.html")
+	eq(t, "Error from controller", err, nil)
+	eq(t, "From controller", fromController, html)
+
+	_, err = RenderTemplateToString("Hotels/Show.html", map[string]interface{}{"hotel": 3})
+	if executionError, ok := err.(*Error); eq(t, "Execution error", ok, true) {
+		eq(t, "Execution error path", executionError.Path, "Hotels/Show.html")
+	}
+	_, err = RenderTemplateToString("Hotels/Missing.html", nil)
+	eq(t, "Missing template", err != nil, true)
+}
+
+func TestContentDisposition(t *testing.T) {
+	for _, test := range []struct {
+		delivery ContentDisposition
+		name     string
+		expected string
+	}{
+		{Inline, "", "inline"},
+		{"", "report.pdf", `attachment; filename="report.pdf"`},
+		{Inline, "my report.pdf", `inline; filename="my report.pdf"`},
+		{Attachment, "Résumé.pdf", `attachment; filename="R_sum_.pdf"; filename*=UTF-8''R%C3%A9sum%C3%A9.pdf`},
+		{Attachment, `a "b".txt`, `attachment; filename="a _b_.txt"; filename*=UTF-8''a%20%22b%22.txt`},
+		{Attachment, "100%.txt", `attachment; filename="100_.txt"; filename*=UTF-8''100%25.txt`},
+		{Attachment, "a\r\nb.txt", `attachment; filename="a__b.txt"; filename*=UTF-8''a%0D%0Ab.txt`},
+	} {
+		eq(t, "Disposition of "+test.name, contentDisposition(test.delivery, test.name), test.expected)
+	}
+}
+
+func TestRenderDownload(t *testing.T) {
+	startFakeBookingApp()
+	png := []byte("\x89PNG\r\n\x1a\n" + strings.Repeat("\x00", 16))
+	for _, test := range []struct {
+		name, filename string
+		content        io.Reader
+		contentType    string
+		length         string
+		acceptRanges   string
+	}{
+		{"Bytes", "Résumé.txt", bytes.NewReader([]byte("Bob")), "text/plain; charset=utf-8", "3", "bytes"},
+		{"Sniffed", "photo", bytes.NewReader(png), "image/png", "24", "bytes"},
+		{"Sniffed stream", "photo", struct{ io.Reader }{bytes.NewReader(png)}, "image/png", "", "none"},
+	} {
+		req, _ := http.NewRequest("GET", "/download", nil)
+		resp := httptest.NewRecorder()
+		c := NewController(NewRequest(req), NewResponse(resp))
+		c.RenderDownload(test.content, test.filename).Apply(c.Request, c.Response)
+
+		eq(t, "Status: "+test.name, resp.Code, http.StatusOK)
+		eq(t, "Content-Type: "+test.name, resp.Header().Get("Content-Type"), test.contentType)
+		eq(t, "Content-Length: "+test.name, resp.Header().Get("Content-Length"), test.length)
+		eq(t, "Accept-Ranges: "+test.name, resp.Header().Get("Accept-Ranges"), test.acceptRanges)
+		eq(t, "Attachment: "+test.name, strings.HasPrefix(resp.Header().Get("Content-Disposition"), "attachment; "), true)
+	}
+
+	file, err := ioutil.TempFile("", "download")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	file.WriteString("Bob")
+	file.Seek(0, io.SeekStart)
+	result := NewController(nil, nil).RenderDownload(file, "bob.txt")
+	eq(t, "File length", result.Length, int64(3))
+	eq(t, "File modified", result.ModTime.IsZero(), false)
+}
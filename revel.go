@@ -49,6 +49,10 @@ var (
 	HttpPort int    // e.g. 9000
 	HttpAddr string // e.g. "", "127.0.0.1"
 
+	// The public address of the app, used to generate absolute URLs.
+	HttpHost   string // e.g. "example.com", "localhost"
+	HttpScheme string // e.g. "http", "https"
+
 	// All cookies dropped by the framework begin with this prefix.
 	CookiePrefix string
 
@@ -133,6 +137,11 @@ func Init(mode, importPath, srcPath string) {
 	DevMode = Config.BoolDefault("mode.dev", false)
 	HttpPort = Config.IntDefault("http.port", 9000)
 	HttpAddr = Config.StringDefault("http.addr", "")
+	HttpScheme = Config.StringDefault("http.scheme", "http")
+	HttpHost = Config.StringDefault("http.host", HttpAddr)
+	if HttpHost == "" {
+		HttpHost = "localhost"
+	}
 	AppName = Config.StringDefault("app.name", "(not set)")
 	CookiePrefix = Config.StringDefault("cookie.prefix", "REVEL")
 	TemplateDelims = Config.StringDefault("template.delimiters", "")
@@ -0,0 +1,69 @@
+package revel
+
+import (
+	"container/list"
+	"sync"
+)
+
+// routeCache is a least-recently-used cache of the matches of requests for
+// routes without parameters, keyed by their tree path, e.g. "/GET/users".
+// It allows repeated requests for the same paths to skip the pathtree.
+type routeCache struct {
+	size    int
+	mutex   sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List // of *routeCacheEntry, the most recently used first
+}
+
+type routeCacheEntry struct {
+	key   string
+	match *RouteMatch
+}
+
+func newRouteCache(size int) *routeCache {
+	return &routeCache{
+		size:    size,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// get returns a copy of the cached match for the key, or nil if there is none.
+func (c *routeCache) get(key string) *RouteMatch {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	element, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	c.order.MoveToFront(element)
+	match := *element.Value.(*routeCacheEntry).match
+	match.Params = make(map[string][]string)
+	return &match
+}
+
+// add caches a copy of the match for the key, evicting the least recently used
+// match if the cache is full.
+func (c *routeCache) add(key string, match *RouteMatch) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if element, ok := c.entries[key]; ok {
+		c.order.MoveToFront(element)
+		return
+	}
+	if c.order.Len() >= c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*routeCacheEntry).key)
+	}
+	cached := *match
+	cached.pooled, cached.Params = false, nil
+	c.entries[key] = c.order.PushFront(&routeCacheEntry{key, &cached})
+}
+
+// len returns the number of cached matches.
+func (c *routeCache) len() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.order.Len()
+}
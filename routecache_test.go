@@ -0,0 +1,51 @@
+package revel
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestRouteCache(t *testing.T) {
+	cache := newRouteCache(2)
+	cache.add("/GET/a", &RouteMatch{MethodName: "A"})
+	cache.add("/GET/b", &RouteMatch{MethodName: "B"})
+	cache.get("/GET/a")
+	cache.add("/GET/c", &RouteMatch{MethodName: "C"})
+
+	eq(t, "len", cache.len(), 2)
+	eq(t, "evicted", cache.get("/GET/b") == nil, true)
+	if match := cache.get("/GET/a"); eq(t, "kept", match != nil, true) {
+		eq(t, "MethodName", match.MethodName, "A")
+	}
+}
+
+func TestRouterMatchCache(t *testing.T) {
+	router := NewRouter("")
+	router.CacheSize = 10
+	router.Routes, _ = parseRoutes("", `
+GET  /users       Users.List
+GET  /users/:id   Users.Show
+`, false)
+	router.updateTree()
+	if !eq(t, "cache", router.load().cache != nil, true) {
+		return
+	}
+
+	for i := 0; i < 2; i++ {
+		match := router.Route(&http.Request{Method: "GET", URL: &url.URL{Path: "/users"}})
+		if eq(t, "Found route", match != nil, true) {
+			eq(t, "MethodName", match.MethodName, "List")
+		}
+		match = router.Route(&http.Request{Method: "GET", URL: &url.URL{Path: "/users/1"}})
+		if eq(t, "Found route", match != nil, true) {
+			eq(t, "Param", match.Params["id"][0], "1")
+		}
+	}
+	eq(t, "cached", router.load().cache.len(), 1)
+
+	// Routes that depend on more than the path disable the cache.
+	router.Routes, _ = parseRoutes("", "GET /users [json] Api.Users", false)
+	router.updateTree()
+	eq(t, "cache with formats", router.load().cache == nil, true)
+}
@@ -0,0 +1,292 @@
+package revel
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// RouteGroup collects routes under a shared path prefix.  Routes added to a
+// group (and any groups nested within it) inherit its settings.
+//
+// In the routes file, a group is opened with a "group" line and closed by a
+// line containing only "}":
+//
+//   group /api/v1 {
+//     GET  /users      Users.List
+//     GET  /users/:id  Users.Show
+//   }
+//
+// A group may also declare fixed parameters, which are prepended to those of
+// each route within it:
+//
+//   group /admin ("admin") {
+//     GET  /users      Users.List     # => Users.List("admin")
+//   }
+//
+// and filters, which are applied before those of each route within it:
+//
+//   group /admin [auth] {
+//     GET  /users      Users.List     [csrf]   # => [auth,csrf]
+//   }
+//
+// The prefix may begin with a host pattern, which applies to each route within
+// the group that does not declare its own:
+//
+//   group :tenant.example.com/ /admin {
+//     GET  /users      Users.List
+//   }
+//
+// A version block is a group for the routes of an API version.  A request for
+// a version falls back to the latest earlier version of a route that is not
+// defined in its own:
+//
+//   version 1 {
+//     GET  /users      ApiV1.Users
+//     GET  /users/:id  ApiV1.User
+//   }
+//   version 2 {
+//     GET  /users      ApiV2.Users     # GET /v2/users/1 => ApiV1.User
+//   }
+type RouteGroup struct {
+	Prefix      string   // e.g. "/api/v1", including the prefixes of enclosing groups
+	Host        string   // e.g. ":tenant.example.com", or "" for any
+	FixedParams []string // e.g. "admin", including those of enclosing groups
+	Filters     []string // e.g. "auth", including those of enclosing groups
+	Version     int      // e.g. 2, or 0 if unversioned
+
+	router *Router     // router to add routes to, if the group was created in code
+	parent *RouteGroup // enclosing group, or nil
+}
+
+func newRouteGroup(router *Router, parent *RouteGroup, prefix string, fixedParams []string) *RouteGroup {
+	host, prefix := splitRouteHost(prefix)
+	g := &RouteGroup{
+		Prefix:      joinRoutePath("", prefix),
+		Host:        host,
+		FixedParams: fixedParams,
+		router:      router,
+		parent:      parent,
+	}
+	if parent != nil {
+		g.Prefix = joinRoutePath(parent.Prefix, prefix)
+		g.FixedParams = append(append([]string{}, parent.FixedParams...), fixedParams...)
+		g.Filters = append([]string{}, parent.Filters...)
+		if host == "" {
+			g.Host = parent.Host
+		}
+		g.Version = parent.Version
+	}
+	return g
+}
+
+// Group returns a group for adding routes under the given path prefix in code.
+// For example:
+//   api := revel.MainRouter.Group("/api/v1")
+//   api.Add("GET", "/users", "Users.List")
+//   api.Add("GET", "/users/:id", "Users.Show")
+//
+// The prefix may begin with a host pattern, e.g. "admin.example.com/ /".
+//
+// Routes added in code are kept when the routes file is reloaded, and are
+// matched after all of the routes in the file.
+func (router *Router) Group(prefix string, fixedParams ...string) *RouteGroup {
+	return newRouteGroup(router, nil, prefix, fixedParams)
+}
+
+// Versioned returns a group for routes of the given API version.
+//
+// For example:
+//
+//   v2 := revel.MainRouter.Versioned(2)
+//   v2.Add("GET", "/users", "ApiV2.Users")
+//
+// See Router.Versioning for how requests give their version.
+func (router *Router) Versioned(version int) *RouteGroup {
+	return newRouteGroup(router, nil, "", nil).Versioned(version)
+}
+
+// Group returns a group nested within this one.
+func (g *RouteGroup) Group(prefix string, fixedParams ...string) *RouteGroup {
+	return newRouteGroup(g.router, g, prefix, fixedParams)
+}
+
+// Versioned returns a group nested within this one, for routes of the given
+// API version.  See Router.Versioning.
+func (g *RouteGroup) Versioned(version int) *RouteGroup {
+	v := newRouteGroup(g.router, g, "", nil)
+	v.Version = version
+	return v
+}
+
+// Add a route to the group, and rebuild the routing table.
+// The action may include fixed parameters, a name, filters, and options, as in
+// the routes file, e.g. `Static.Serve("public") as static [auth] {priority=1}`.
+// If the method lists several, e.g. "GET|POST", a route is added for each, and
+// the first is returned.
+// Returns nil if the route could not be parsed.
+func (g *RouteGroup) Add(method, path, action string) *Route {
+	line := method + " " + path + " " + action
+	decl, found := parseRouteDecl(line)
+	if !found {
+		ERROR.Println("revel/router: invalid route:", line)
+		return nil
+	}
+
+	routes, err := g.newRoutes(decl, "", 0)
+	if err != nil {
+		ERROR.Println("revel/router: invalid route:", line, err)
+		return nil
+	}
+	g.addRoutes(routes)
+	return routes[0]
+}
+
+// addRoutes adds routes created in code to the group's router, if any, and
+// rebuilds the routing table.
+func (g *RouteGroup) addRoutes(routes []*Route) {
+	if g.router == nil {
+		return
+	}
+	g.router.mutex.Lock()
+	defer g.router.mutex.Unlock()
+	g.router.added = append(g.router.added, routes...)
+	g.router.Routes = append(g.router.Routes, routes...)
+	if err := g.router.updateTree(); err != nil {
+		ERROR.Println("revel/router: failed to add route:", err)
+	}
+}
+
+// newRoutes prepares the routes declared by a line, one for each of its
+// methods, in the order listed.  e.g. "GET|POST /login Auth.Login"
+func (g *RouteGroup) newRoutes(decl routeDecl, routesPath string, line int) ([]*Route, error) {
+	methods := decl.methods
+	if len(methods) == 0 {
+		methods = []string{decl.method}
+	}
+	routes := make([]*Route, 0, len(methods))
+	for _, method := range methods {
+		decl.method = method
+		route, err := g.newRoute(decl, routesPath, line)
+		if err != nil {
+			return nil, err
+		}
+		routes = append(routes, route)
+	}
+	return routes, nil
+}
+
+// newRoute prepares a route declared within this group.
+// It may be called on a nil group, for routes that are not in a group.
+func (g *RouteGroup) newRoute(decl routeDecl, routesPath string, line int) (*Route, error) {
+	path, filters := decl.path, decl.filters
+	if prefix := controllerPrefix(decl.action); prefix != "" && decl.method != "ERROR" {
+		host, controllerPath := splitRouteHost(path)
+		path = joinRoutePath(prefix, controllerPath)
+		if host != "" {
+			path = host + "/ " + path
+		}
+	}
+	if g != nil {
+		host, groupPath := splitRouteHost(path)
+		if host == "" {
+			host = g.Host
+		}
+		path = joinRoutePath(g.Prefix, groupPath)
+		if host != "" {
+			path = host + "/ " + path
+		}
+		filters = append(append([]string{}, g.Filters...), filters...)
+	}
+
+	route, err := newRoute(decl.method, path, decl.action, decl.fixedArgs, routesPath, line)
+	if err != nil {
+		return route, err
+	}
+	if g != nil && len(g.FixedParams) > 0 {
+		route.FixedParams = append(append([]string{}, g.FixedParams...), route.FixedParams...)
+	}
+	route.Name = decl.name
+	route.Formats = decl.formats
+	route.Redirect = decl.redirect
+	route.Static = decl.static
+	route.ErrorStatus = decl.status
+	if decl.mount != "" {
+		if err = route.setHandler(decl.mount); err != nil {
+			return route, err
+		}
+	}
+	if g != nil {
+		route.Version = g.Version
+	}
+	if err = route.setFilters(filters); err != nil {
+		return route, err
+	}
+	return route, route.setOptions(decl.options)
+}
+
+// controllerPrefixes maps lower-case controller names to the path prefixes
+// declared with Prefix.
+var controllerPrefixes = map[string]string{}
+
+// Prefix declares a path prefix for the routes to all of the controller's
+// actions, whether declared in the routes file or in the actions' comments.
+// For example:
+//   var _ = revel.Prefix(ApiV2{}, "/api/v2")
+// makes
+//   GET  /users  ApiV2.Users
+// match "/api/v2/users".  Within a group, the prefix follows the group's.
+// It returns the prefix, so that it may be declared as above, before the
+// routes are loaded.
+func Prefix(controllerInstance interface{}, prefix string) string {
+	t := reflect.TypeOf(controllerInstance)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	controllerPrefixes[strings.ToLower(t.Name())] = prefix
+	return prefix
+}
+
+// controllerPrefix returns the prefix declared for the action's controller, if
+// any.  e.g. "ApiV2.Users" => "/api/v2"
+func controllerPrefix(action string) string {
+	i := strings.Index(action, ".")
+	if i <= 0 {
+		return ""
+	}
+	return controllerPrefixes[strings.ToLower(action[:i])]
+}
+
+// joinRoutePath appends a route path to a group prefix.
+// e.g. ("/api/", "/users") => "/api/users", ("/api", "/") => "/api"
+func joinRoutePath(prefix, path string) string {
+	prefix = strings.TrimRight(prefix, "/")
+	if path == "" || (path == "/" && prefix != "") {
+		return prefix
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return prefix + path
+}
+
+// versionPattern matches the line opening a block of versioned routes.
+// e.g. "version 2 {"
+var versionPattern = regexp.MustCompile(`(?i)^version[ \t]+([0-9]+)[ \t]*\{$`)
+
+// Groups:
+// 1: prefix, including any host
+// 2: fixedargs
+// 3: filters
+var groupPattern = regexp.MustCompile(
+	`(?i)^group[ \t]+((?:[^ \t/(\[]+/?[ \t]+)?/[^ \t(\[]*)[ \t]*(?:\(([^)]*)\))?[ \t]*(?:\[([^\]]*)\])?[ \t]*\{$`)
+
+// parseGroupLine parses the line opening a route group.
+// e.g. `group /admin ("admin") [auth] {`
+func parseGroupLine(line string) (prefix string, fixedParams, filters []string, found bool) {
+	matches := groupPattern.FindStringSubmatch(line)
+	if matches == nil {
+		return
+	}
+	return matches[1], parseFixedArgs(matches[2]), splitNames(matches[3]), true
+}
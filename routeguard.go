@@ -0,0 +1,59 @@
+package revel
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// RouteGuard decides whether a route may match a request.
+type RouteGuard func(req *http.Request) bool
+
+// routeGuards maps names to the guards registered with RegisterRouteGuard.
+var routeGuards = map[string]RouteGuard{}
+
+// RegisterRouteGuard registers a guard that routes may name in their options,
+// so that they match only the requests that it passes.  Otherwise, matching
+// continues with the next route.  For example:
+//   revel.RegisterRouteGuard("beta", func(req *http.Request) bool {
+//   	cookie, err := req.Cookie("beta")
+//   	return err == nil && cookie.Value == "1"
+//   })
+// allows:
+//   GET  /dashboard  Beta.Dashboard  {guard=beta}
+//   GET  /dashboard  App.Dashboard
+// A route may name several guards, separated by spaces, all of which must
+// pass, e.g. {guard=beta staff}.
+//
+// Guards are looked up when the routes are loaded, so they should be
+// registered on initialization.  They may be called more than once for a
+// request, and concurrently, so they should be fast and free of side effects.
+func RegisterRouteGuard(name string, guard func(req *http.Request) bool) {
+	routeGuards[name] = guard
+}
+
+// setGuards looks up and sets the route's guards by name.
+func (r *Route) setGuards(names string) error {
+	r.guards = nil
+	for _, name := range strings.Fields(names) {
+		guard, ok := routeGuards[name]
+		if !ok {
+			return fmt.Errorf("Unknown route guard: %s", name)
+		}
+		r.guards = append(r.guards, guard)
+	}
+	if len(r.guards) == 0 {
+		return fmt.Errorf("Invalid route guard: %q", names)
+	}
+	return nil
+}
+
+// passesGuards returns true if the route's guards all pass the request.
+func (r *Route) passesGuards(req *http.Request) bool {
+	for _, guard := range r.guards {
+		if !guard(req) {
+			return false
+		}
+	}
+	return true
+}
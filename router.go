@@ -1,27 +1,69 @@
 package revel
 
 import (
+	"bytes"
 	"encoding/csv"
+	"errors"
 	"fmt"
 	"github.com/robfig/pathtree"
 	"io"
 	"io/ioutil"
+	"math"
 	"net/http"
 	"net/url"
+	"os"
 	"path"
+	"path/filepath"
 	"regexp"
+	"runtime/debug"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 type Route struct {
-	Method         string         // e.g. GET
-	Path           string         // e.g. /app/:id
-	Action         string         // e.g. "Application.ShowApp", "404"
-	ControllerName string         // e.g. "Application", ""
-	MethodName     string         // e.g. "ShowApp", ""
-	FixedParams    []string       // e.g. "arg1","arg2","arg3" (CSV formatting)
-	TreePath       string         // e.g. "/GET/app/:id"
-	leaf           *pathtree.Leaf // leaf in the tree used for reverse routing
+	Method         string            // e.g. GET
+	Path           string            // e.g. /app/:id
+	Host           string            // e.g. "admin.example.com", ":tenant.example.com", "" for any
+	Action         string            // e.g. "Application.ShowApp", "404", "301", "STATIC", "MOUNT"
+	ControllerName string            // e.g. "Application", ""
+	MethodName     string            // e.g. "ShowApp", ""
+	FixedParams    []string          // e.g. "arg1","arg2","arg3" (CSV formatting)
+	TreePath       string            // e.g. "/GET/app/:id"
+	Filters        []string          // e.g. "auth", "csrf" (names in NamedFilters)
+	Name           string            // e.g. "users.show", or "" if unnamed
+	Priority       int               // routes with higher priority are matched first
+	TrailingSlash  string            // overrides Router.TrailingSlash, if set
+	Case           string            // overrides Router.Case, if set
+	Meta           map[string]string // e.g. {auth: none}, from the route's options
+	Query          url.Values        // e.g. {format: json}, required query parameters
+	Formats        []string          // e.g. "json", "application/vnd.api+json", or empty for any
+	Version        int               // e.g. 2, or 0 if unversioned
+	Redirect       string            // e.g. "/new-path", "Users.Show", the target of a redirect route
+	Static         string            // e.g. "public/", the directory served by a static route
+	ErrorStatus    int               // e.g. 404, the status of the errors handled by an ERROR route
+	Mount          string            // e.g. "metrics", the name of the handler of a MOUNT route
+
+	args     []*arg        // parameters captured from the path, in order
+	forms    []*routeForm  // the paths matched, the full path first
+	maxAge   int           // seconds that static files may be cached, or 0
+	limit    *rateLimit    // the limit on the rate of requests, or nil
+	timeout  time.Duration // the deadline of the request's context, or 0
+	maxBody  int64         // the largest request body allowed, in bytes, or 0
+	stream   bool          // true if the request body is not parsed into the Params
+	render   string        // the format (or media type) to render values returned by the action in, e.g. "json"
+	errors   string        // "problem" to render errors as problem details, "page" as error pages, or "" for either
+	params   string        // "strict" to reject params the action does not take, "any" to accept them
+	allowed  []string      // the params that a strict route accepts besides the action's
+	required []string      // the params that must be given, or the request fails with 400
+	host     []string      // the labels of the Host, e.g. ":tenant", "example", "com"
+	filters  []Filter      // the Filters, looked up by name
+	guards   []RouteGuard  // the guards that must pass a request for the route to match
+	elements []string      // the elements of the TreePath, e.g. "GET", "app", ":id"
+	handler  http.Handler  // the handler of a MOUNT route
 
 	routesPath string // e.g. /Users/robfig/gocode/src/myapp/conf/routes
 	line       int    // e.g. 3
@@ -33,29 +75,101 @@ type RouteMatch struct {
 	MethodName     string // e.g. ShowApp
 	FixedParams    []string
 	Params         map[string][]string // e.g. {id: 123}
+	Filters        []Filter            // filters declared on the route
+	Allowed        []string            // methods allowed for the path, for "405" and "OPTIONS"
+	Location       string              // the URL to redirect to, for "301" and redirect routes
+	Meta           map[string]string   // the route's options, e.g. {auth: none}
+	Locale         string              // the locale given by the path's prefix, e.g. "fr", if any
+	Route          *Route              // the matched route, or nil for "405", "OPTIONS" and "301"
+
+	pooled bool // true if the match was taken from matchPool
+}
+
+// matchPool holds RouteMatches, with their Params maps, for reuse, so that
+// routing a request need not allocate them.  Matches are taken by
+// Router.Route, and returned once their request has been handled, so a match
+// (and its Params) must not be kept after then.
+var matchPool = sync.Pool{
+	New: func() interface{} {
+		return &RouteMatch{Params: make(map[string][]string)}
+	},
+}
+
+func acquireRouteMatch() *RouteMatch {
+	m := matchPool.Get().(*RouteMatch)
+	m.pooled = true
+	return m
+}
+
+// releaseRouteMatch returns a match to the pool, or, with PoolCheck set,
+// clears it.  Matches that were not taken from the pool are ignored.
+func releaseRouteMatch(m *RouteMatch) {
+	if m == nil || !m.pooled {
+		return
+	}
+	if PoolCheck {
+		*m = RouteMatch{}
+		return
+	}
+	params := m.Params
+	for name := range params {
+		delete(params, name)
+	}
+	*m = RouteMatch{Params: params}
+	matchPool.Put(m)
+}
+
+// setParams sets the parameters of the route from the values captured from
+// the path, reusing the match's Params map.  A nil match allocates a new map.
+func (m *RouteMatch) setParams(form *routeForm, expansions []string) url.Values {
+	if m == nil {
+		return form.params(expansions)
+	}
+	for name := range m.Params {
+		delete(m.Params, name)
+	}
+	for i := range expansions {
+		m.Params[form.args[i].name] = expansions[i : i+1 : i+1]
+	}
+	form.setDefaults(m.Params)
+	return m.Params
+}
+
+// routeForm is one of the paths matched by a route: its full path, or its path
+// without some of its optional segments or trailing parameters with defaults.
+type routeForm struct {
+	route    *Route
+	elements []string // e.g. "GET", "docs", "intro"
+	args     []*arg   // the parameters captured by the elements, in order
+	omitted  []*arg   // the parameters omitted, which take their defaults
 }
 
 type arg struct {
-	name       string
-	index      int
-	constraint *regexp.Regexp
+	name         string
+	index        int
+	constraint   *regexp.Regexp
+	defaultValue string // the value if the path omits the parameter, e.g. "1" for ":page=1"
 }
 
 // Prepares the route to be used in matching.
 func NewRoute(method, path, action, fixedArgs, routesPath string, line int) (r *Route) {
-	// Handle fixed arguments
-	argsReader := strings.NewReader(fixedArgs)
-	csv := csv.NewReader(argsReader)
-	fargs, err := csv.Read()
-	if err != nil && err != io.EOF {
-		ERROR.Printf("Invalid fixed parameters (%v): for string '%v'", err.Error(), fixedArgs)
+	r, err := newRoute(method, path, action, fixedArgs, routesPath, line)
+	if err != nil {
+		ERROR.Print(err)
 	}
+	return r
+}
 
+// newRoute prepares the route to be used in matching, returning an error if
+// the path could not be parsed.
+func newRoute(method, path, action, fixedArgs, routesPath string, line int) (r *Route, err error) {
+	host, path := splitRouteHost(path)
 	r = &Route{
 		Method:      strings.ToUpper(method),
 		Path:        path,
 		Action:      action,
-		FixedParams: fargs,
+		Host:        host,
+		FixedParams: parseFixedArgs(fixedArgs),
 		TreePath:    treePath(strings.ToUpper(method), path),
 		routesPath:  routesPath,
 		line:        line,
@@ -63,8 +177,87 @@ func NewRoute(method, path, action, fixedArgs, routesPath string, line int) (r *
 
 	// URL pattern
 	if !strings.HasPrefix(r.Path, "/") {
-		ERROR.Print("Absolute URL required.")
-		return
+		return r, errors.New("Absolute URL required.")
+	}
+
+	// Host pattern
+	if host != "" {
+		r.host = strings.Split(strings.ToLower(host), ".")
+		for _, label := range r.host {
+			if label == "" || label == ":" {
+				return r, fmt.Errorf("Invalid host: %s", host)
+			}
+		}
+	}
+
+	// Separate any parameter constraints from the path used in the tree.
+	var constraints map[string]*regexp.Regexp
+	if path, constraints, err = parseConstraints(path); err != nil {
+		return r, err
+	}
+
+	// Separate any required query parameters.
+	// e.g. "/search?format=json" => "/search", {format: json}
+	if i := strings.Index(path, "?"); i != -1 {
+		rawQuery := path[i+1:]
+		if r.Query, err = url.ParseQuery(rawQuery); err != nil {
+			return r, fmt.Errorf("Invalid query: %s", err)
+		}
+		path, r.Path = path[:i], strings.TrimSuffix(r.Path, "?"+rawQuery)
+	}
+
+	// Separate any optional segments.
+	// e.g. "/docs(/:lang)/intro" => "/docs/:lang/intro", with ":lang" optional
+	var optional [][2]int
+	if path, optional, err = parseOptional(path); err != nil {
+		return r, err
+	}
+
+	// Separate any default values of the parameters.
+	// e.g. "/posts/:page=1" => "/posts/:page", {page: 1}
+	defaults := make(map[string]string)
+	path = parseDefaults(path, defaults)
+
+	r.TreePath = treePath(r.Method, path)
+	r.elements = splitTreePath(r.TreePath)
+	required := len(r.elements) // the elements before any trailing parameters with defaults
+	for i, el := range r.elements {
+		if el[0] == '*' && i != len(r.elements)-1 {
+			return r, fmt.Errorf("Catch-all parameter %s must be at the end of the path", el)
+		}
+		if isWildcard(el) {
+			a := &arg{
+				name:         el[1:],
+				index:        len(r.args),
+				constraint:   constraints[el[1:]],
+				defaultValue: defaults[el[1:]],
+			}
+			if a.defaultValue != "" && a.constraint != nil && !a.constraint.MatchString(a.defaultValue) {
+				return r, fmt.Errorf("Default value of %s does not match its constraint: %s", el, a.defaultValue)
+			}
+			r.args = append(r.args, a)
+		}
+
+		// Parameters with defaults may be omitted from the end of the path,
+		// unless they are in an optional segment.
+		switch {
+		case !isWildcard(el) || inOptional(optional, i):
+			if required != len(r.elements) {
+				return r, fmt.Errorf("Parameters with defaults must be at the end of the path")
+			}
+		case defaults[el[1:]] != "":
+			if required == len(r.elements) {
+				required = i
+			}
+		case required != len(r.elements):
+			return r, fmt.Errorf("Parameter %s must have a default, as it follows one that does", el)
+		}
+	}
+
+	// Error routes handle the errors of requests for their paths, rather than
+	// matching requests themselves.
+	if r.Method != "ERROR" {
+		r.forms = r.newForms(optional, required)
 	}
 
 	actionSplit := strings.Split(action, ".")
@@ -84,6 +277,351 @@ func NewRoute(method, path, action, fixedArgs, routesPath string, line int) (r *
 	return
 }
 
+// RouteConstraints are the named parameter types that may be used to
+// constrain route parameters, e.g. "/posts/:id<int>".  Each maps to a regular
+// expression that the entire parameter value must match.
+//
+// Applications may register their own types on initialization.
+var RouteConstraints = map[string]string{
+	"int":     `-?[0-9]+`,
+	"uint":    `[0-9]+`,
+	"alpha":   `[a-zA-Z]+`,
+	"alnum":   `[a-zA-Z0-9]+`,
+	"hex":     `[0-9a-fA-F]+`,
+	"slug":    `[a-z0-9]+(?:-[a-z0-9]+)*`,
+	"uuid":    `[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`,
+	"decimal": `-?[0-9]+(?:\.[0-9]+)?`,
+}
+
+// parseConstraints removes the constraints from the parameters in a route
+// path.  A constraint is either a regular expression or a named type from
+// RouteConstraints.  For example:
+//   /users/:id([0-9]+)  =>  /users/:id, {id: ^(?:[0-9]+)$}
+//   /users/:id<int>     =>  /users/:id, {id: ^(?:-?[0-9]+)$}
+// A regular expression may contain any characters (including slashes, though
+// not at its start), as long as its parentheses are balanced.
+func parseConstraints(path string) (string, map[string]*regexp.Regexp, error) {
+	if !strings.ContainsAny(path, "(<") {
+		return path, nil, nil
+	}
+
+	var (
+		stripped    bytes.Buffer
+		constraints = make(map[string]*regexp.Regexp)
+		name        string // name of the current parameter, if in one
+	)
+	for i := 0; i < len(path); i++ {
+		switch c := path[i]; {
+		case (c == ':' || c == '*') && (i == 0 || path[i-1] == '/'):
+			j := i + 1
+			for j < len(path) && path[j] != '/' && path[j] != '(' && path[j] != '<' && path[j] != '=' {
+				j++
+			}
+			name = path[i+1 : j]
+			stripped.WriteString(path[i:j])
+			i = j - 1
+		case c == '<' && name != "":
+			j := strings.IndexByte(path[i:], '>')
+			if j == -1 {
+				return "", nil, fmt.Errorf("Unterminated type for :%s", name)
+			}
+			typ := path[i+1 : i+j]
+			pattern, ok := RouteConstraints[typ]
+			if !ok {
+				return "", nil, fmt.Errorf("Unknown type for :%s: %s", name, typ)
+			}
+			re, err := regexp.Compile("^(?:" + pattern + ")$")
+			if err != nil {
+				return "", nil, fmt.Errorf("Invalid pattern for type %s: %s", typ, err)
+			}
+			constraints[name] = re
+			name = ""
+			i += j
+		case c == '(' && name != "" && !strings.HasPrefix(path[i+1:], "/"):
+			depth, j := 0, i
+			for ; j < len(path); j++ {
+				if path[j] == '\\' {
+					j++
+				} else if path[j] == '(' {
+					depth++
+				} else if path[j] == ')' {
+					if depth--; depth == 0 {
+						break
+					}
+				}
+			}
+			if j >= len(path) {
+				return "", nil, fmt.Errorf("Unbalanced parentheses in constraint for :%s", name)
+			}
+			re, err := regexp.Compile("^(?:" + path[i+1:j] + ")$")
+			if err != nil {
+				return "", nil, fmt.Errorf("Invalid constraint for :%s: %s", name, err)
+			}
+			constraints[name] = re
+			name = ""
+			i = j
+		default:
+			if c == '/' {
+				name = ""
+			}
+			stripped.WriteByte(c)
+		}
+	}
+	return stripped.String(), constraints, nil
+}
+
+// parseOptional removes the parentheses around the optional segments of a
+// route path, returning the range of tree path elements in each.
+// e.g. "/docs(/:lang)/intro"  =>  "/docs/:lang/intro", [[2, 3]]
+func parseOptional(path string) (string, [][2]int, error) {
+	if !strings.ContainsAny(path, "()") {
+		return path, nil, nil
+	}
+
+	var (
+		stripped bytes.Buffer
+		optional [][2]int
+		start    = -1 // the first element of the current optional segment, if in one
+		slashes  = 0
+	)
+	for i := 0; i < len(path); i++ {
+		switch c := path[i]; c {
+		case '(':
+			if start != -1 {
+				return "", nil, errors.New("Optional segments may not be nested")
+			}
+			if i+2 >= len(path) || path[i+1] != '/' || path[i+2] == ')' {
+				return "", nil, errors.New("Optional segments must contain a slash and a segment, e.g. (/:lang)")
+			}
+			start = slashes + 1
+		case ')':
+			if start == -1 {
+				return "", nil, errors.New("Unbalanced parentheses in optional segment")
+			}
+			if i+1 < len(path) && path[i+1] != '/' && path[i+1] != '(' {
+				return "", nil, errors.New("Optional segments must end with a complete segment")
+			}
+			optional = append(optional, [2]int{start, slashes + 1})
+			start = -1
+		default:
+			if c == '/' {
+				slashes++
+			}
+			stripped.WriteByte(c)
+		}
+	}
+	if start != -1 {
+		return "", nil, errors.New("Unbalanced parentheses in optional segment")
+	}
+	return stripped.String(), optional, nil
+}
+
+// inOptional returns true if the element is in one of the optional segments.
+func inOptional(optional [][2]int, element int) bool {
+	for _, segment := range optional {
+		if element >= segment[0] && element < segment[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// newForms returns the paths matched by the route: its full path, and its
+// path with each combination of its optional segments omitted, and with each
+// number of its trailing parameters with defaults omitted.  Later segments are
+// omitted before earlier ones, so that they take precedence when two forms
+// have the same shape.
+func (r *Route) newForms(optional [][2]int, required int) []*routeForm {
+	var forms []*routeForm
+	for mask := 0; mask < 1<<uint(len(optional)); mask++ {
+		omit := make([]bool, len(r.elements))
+		for j, segment := range optional {
+			if mask&(1<<uint(len(optional)-1-j)) != 0 {
+				for i := segment[0]; i < segment[1]; i++ {
+					omit[i] = true
+				}
+			}
+		}
+		for n := len(r.elements); n >= required; n-- {
+			form, args := &routeForm{route: r}, r.args
+			for i, el := range r.elements {
+				var a *arg
+				if isWildcard(el) {
+					a, args = args[0], args[1:]
+				}
+				switch {
+				case i >= n || omit[i]:
+					if a != nil {
+						form.omitted = append(form.omitted, a)
+					}
+				case a != nil:
+					form.args = append(form.args, a)
+					fallthrough
+				default:
+					form.elements = append(form.elements, el)
+				}
+			}
+			forms = append(forms, form)
+		}
+	}
+	return forms
+}
+
+// parseDefaults removes the default values from the parameters in a route
+// path, adding them to defaults.
+// e.g. "/posts/:page=1"  =>  "/posts/:page", {page: 1}
+func parseDefaults(path string, defaults map[string]string) string {
+	if !strings.Contains(path, "=") {
+		return path
+	}
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if j := strings.IndexByte(segment, '='); j != -1 && isWildcard(segment) {
+			defaults[segment[1:j]] = segment[j+1:]
+			segments[i] = segment[:j]
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// setOptions applies the options declared on the route.  Options other than
+// those known to the router are kept as metadata, for use by filters.
+func (r *Route) setOptions(options map[string]string) error {
+	r.Meta = options
+	for key, value := range options {
+		switch key {
+		case "priority":
+			priority, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("Invalid route priority: %s", value)
+			}
+			r.Priority = priority
+		case "case":
+			if !casePolicies[value] {
+				return fmt.Errorf("Invalid case policy: %s", value)
+			}
+			r.Case = value
+		case "trailingSlash":
+			if !trailingSlashPolicies[value] {
+				return fmt.Errorf("Invalid trailing slash policy: %s", value)
+			}
+			r.TrailingSlash = value
+		case "maxage":
+			maxAge, err := strconv.Atoi(value)
+			if err != nil || maxAge < 0 {
+				return fmt.Errorf("Invalid max age: %s", value)
+			}
+			r.maxAge = maxAge
+		case "timeout":
+			timeout, err := time.ParseDuration(value)
+			if err != nil || timeout <= 0 {
+				return fmt.Errorf("Invalid timeout: %s", value)
+			}
+			r.timeout = timeout
+		case "maxbody":
+			maxBody, err := parseByteSize(value)
+			if err != nil || maxBody <= 0 {
+				return fmt.Errorf("Invalid max body size: %s", value)
+			}
+			r.maxBody = maxBody
+		case "body":
+			if value != "parse" && value != "stream" {
+				return fmt.Errorf("Invalid body policy: %s", value)
+			}
+			r.stream = value == "stream"
+		case "params":
+			if value != "strict" && value != "any" {
+				return fmt.Errorf("Invalid params policy: %s", value)
+			}
+			r.params = value
+		case "allowParams":
+			r.allowed = strings.Fields(value)
+		case "required":
+			r.required = strings.Fields(value)
+		case "errors":
+			if value != "problem" && value != "page" {
+				return fmt.Errorf("Invalid errors format: %s", value)
+			}
+			r.errors = value
+		case "render":
+			if !valueFormats[value] && Renderers[value] == nil {
+				return fmt.Errorf("Invalid render format: %s", value)
+			}
+			r.render = value
+		case "guard":
+			if err := r.setGuards(value); err != nil {
+				return err
+			}
+		}
+	}
+	if rate, ok := options["rate"]; ok {
+		limit, err := newRateLimit(rate, options["burst"], options["key"])
+		if err != nil {
+			return err
+		}
+		r.limit = limit
+	}
+	return nil
+}
+
+// byteUnits are the units that a size may be given in.
+var byteUnits = map[string]int64{
+	"":   1,
+	"B":  1,
+	"KB": 1 << 10,
+	"MB": 1 << 20,
+	"GB": 1 << 30,
+}
+
+// parseByteSize parses a number of bytes, with an optional unit.
+// e.g. "2MB" => 2097152
+func parseByteSize(size string) (int64, error) {
+	size = strings.ToUpper(strings.TrimSpace(size))
+	i := strings.IndexFunc(size, func(r rune) bool { return r < '0' || r > '9' })
+	if i == -1 {
+		i = len(size)
+	}
+	unit, ok := byteUnits[strings.TrimSpace(size[i:])]
+	if !ok {
+		return 0, fmt.Errorf("Unknown unit: %s", size[i:])
+	}
+	if i == 0 {
+		return 0, fmt.Errorf("No number of bytes: %s", size)
+	}
+	n, err := strconv.ParseInt(size[:i], 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if n > math.MaxInt64/unit {
+		return 0, fmt.Errorf("Too many bytes: %s", size)
+	}
+	return n * unit, nil
+}
+
+// setFilters looks up and sets the route's filters by name.
+func (r *Route) setFilters(names []string) error {
+	r.Filters, r.filters = names, nil
+	for _, name := range names {
+		filter, ok := NamedFilters[name]
+		if !ok {
+			return fmt.Errorf("Unknown filter: %s", name)
+		}
+		r.filters = append(r.filters, filter)
+	}
+	return nil
+}
+
+// parseFixedArgs splits the CSV-formatted fixed arguments of a route.
+func parseFixedArgs(fixedArgs string) []string {
+	argsReader := strings.NewReader(fixedArgs)
+	csv := csv.NewReader(argsReader)
+	fargs, err := csv.Read()
+	if err != nil && err != io.EOF {
+		ERROR.Printf("Invalid fixed parameters (%v): for string '%v'", err.Error(), fixedArgs)
+	}
+	return fargs
+}
+
 func treePath(method, path string) string {
 	if method == "*" {
 		method = ":METHOD"
@@ -91,47 +629,482 @@ func treePath(method, path string) string {
 	return "/" + method + path
 }
 
-func untreePath(path string) (method string, url string) {
-	if len(path) == 0 {
-		return path, "/"
+// splitRouteHost separates the host pattern, if any, from a route's path.
+// e.g. "admin.example.com/ /dashboard" => "admin.example.com", "/dashboard"
+func splitRouteHost(path string) (host, routePath string) {
+	fields := strings.Fields(path)
+	if len(fields) != 2 {
+		return "", path
 	}
-	split := strings.Index(path[1:], "/")
+	return strings.TrimSuffix(fields[0], "/"), fields[1]
+}
 
-	if split == -1 {
-		return path, "/"
+// splitTreePath splits a tree path into its elements, ignoring leading and
+// trailing slashes, e.g. "/GET/app/:id/" => "GET", "app", ":id"
+func splitTreePath(path string) []string {
+	elements := strings.Split(path, "/")
+	if len(elements) > 0 && elements[0] == "" {
+		elements = elements[1:]
 	}
-	return path[:split], path[split+1:]
+	if len(elements) > 0 && elements[len(elements)-1] == "" {
+		elements = elements[:len(elements)-1]
+	}
+	return elements
+}
+
+// isWildcard returns true if the tree path element captures a parameter.
+func isWildcard(element string) bool {
+	return len(element) > 0 && (element[0] == ':' || element[0] == '*')
+}
+
+// treeShape returns the tree path with the parameter names removed, so that
+// routes that differ only in their parameter names share a leaf in the tree.
+// e.g. "/GET/app/:id" => "/GET/app/:"
+func treeShape(elements []string) string {
+	var shape bytes.Buffer
+	for _, el := range elements {
+		shape.WriteByte('/')
+		if isWildcard(el) {
+			shape.WriteByte(el[0])
+		} else {
+			shape.WriteString(el)
+		}
+	}
+	return shape.String()
 }
 
 type Router struct {
+	// Routes and Tree are the routing table as last loaded.  Requests are
+	// routed with a copy of them that is replaced atomically on Refresh, so
+	// while requests are being served, use Table to read the routes instead.
 	Routes []*Route
 	Tree   *pathtree.Node
-	path   string // path to the routes file
+
+	// TrailingSlash is the policy for requests whose paths differ from the
+	// matching route's only by a trailing slash, e.g. "/users/" for "/users":
+	//   "strip"    - ignore the trailing slash (the default)
+	//   "redirect" - redirect GET and HEAD requests to the route's path
+	//   "strict"   - do not match
+	// It is configured by "routes.trailingSlash" in app.conf.
+	TrailingSlash string
+
+	// Case is the policy for matching the case of paths:
+	//   "sensitive"   - match the case exactly (the default)
+	//   "insensitive" - match any case
+	//   "redirect"    - match any case, redirecting GET and HEAD requests to
+	//                   the route's case
+	// It is configured by "routes.case" in app.conf.
+	Case string
+
+	// MethodOverride allows POST requests to be routed as PUT, PATCH, or
+	// DELETE requests, as given by the X-HTTP-Method-Override header or the
+	// "_method" form field.  This allows HTML forms to reach those routes.
+	// It is configured by "routes.methodOverride" in app.conf.
+	MethodOverride bool
+
+	// Versioning is how the API version of a request is given, for matching
+	// versioned routes:
+	//   "path"   - a path prefix, e.g. "/v2/users" (the default)
+	//   "header" - the Accept header, e.g. "application/vnd.app.v2+json"
+	//   "query"  - the "v" query parameter, e.g. "/users?v=2"
+	// Requests without a version match the latest version, except with "path",
+	// where they match only unversioned routes.
+	// It is configured by "routes.versioning" in app.conf.
+	Versioning string
+
+	// ValidateReferences checks, on Refresh, that every action referenced by
+	// the templates (e.g. {{url "Users.Show" .id}}) and by the controllers'
+	// redirects (e.g. Redirect(Users.Show)) has a route, failing otherwise.
+	// It is configured by "routes.validateReferences" in app.conf.
+	ValidateReferences bool
+
+	// StrictConflicts makes a route that can never match, because an earlier
+	// route matches all of the same requests, an error rather than a warning.
+	// It is configured by "routes.strictConflicts" in app.conf.
+	StrictConflicts bool
+
+	// Locales are the locales that may prefix the path of any route, e.g. "fr"
+	// for "/fr/users/1".  The prefix sets the request's locale, and is added
+	// by Reverse when given a "locale" argument.
+	// It is configured by "routes.locales" in app.conf, e.g. "en,fr,de".
+	Locales []string
+
+	// CacheSize is the number of matches of routes without parameters to
+	// cache, so that requests for the most used paths skip the tree.  The
+	// cache is not used if any route depends on more of the request than its
+	// method and path, e.g. its host or format.  It is off if 0 (the default).
+	// It is configured by "routes.cacheSize" in app.conf.
+	CacheSize int
+
+	// EncodedSlashes is how an encoded slash ("%2F") in a request's path is
+	// matched:
+	//   "decode" - as a slash, separating path segments (the default)
+	//   "keep"   - within its segment, so that a parameter may contain a
+	//              slash, e.g. "/files/a%2Fb.txt" binds name to "a/b.txt" for
+	//              the route "/files/:name"
+	// It is configured by "routes.encodedSlashes" in app.conf.
+	EncodedSlashes string
+
+	path  string       // path to the routes file
+	added []*Route     // routes added in code, preserved across Refresh
+	table atomic.Value // *routeTable, the routing table in use
+	mutex sync.Mutex   // held while the routing table is rebuilt
+
+	refreshHooks []func(old, new []*Route) // called after each successful Refresh
+}
+
+// routeTable is the routing table built from a list of routes.  It is not
+// modified once built, so requests may be routed with it while a new table is
+// built to replace it.
+type routeTable struct {
+	routes      []*Route // in the order they are matched
+	tree        *pathtree.Node
+	foldCase    bool        // true if any route matches case-insensitively
+	latest      int         // the latest version of any route, or 0 if none are versioned
+	cache       *routeCache // matches of routes without parameters, or nil
+	errorRoutes []*Route    // the ERROR routes, in order
+
+	// The routes to consider when reversing.
+	actions   map[string][]*Route // "Controller.Method" => its routes and the wildcards, in order
+	wildcards []*Route            // routes with a wildcard action, e.g. ":controller.:action"
+	names     map[string]*Route   // route name => the route
+}
+
+// emptyRouteTable is in use until the routes are first loaded.
+var emptyRouteTable = &routeTable{tree: pathtree.New()}
+
+// trailingSlashPolicies are the valid values of Router.TrailingSlash.
+var trailingSlashPolicies = map[string]bool{
+	"strip":    true,
+	"redirect": true,
+	"strict":   true,
+}
+
+// overrideMethods are the methods that a POST may be overridden to use.
+var overrideMethods = map[string]bool{
+	"PUT":    true,
+	"PATCH":  true,
+	"DELETE": true,
+}
+
+// versioningPolicies are the valid values of Router.Versioning.
+var versioningPolicies = map[string]bool{
+	"path":   true,
+	"header": true,
+	"query":  true,
+}
+
+var (
+	versionPathPattern   = regexp.MustCompile(`^/v([0-9]+)(/.*)?$`)
+	versionAcceptPattern = regexp.MustCompile(`vnd\.[^;,]*\.v([0-9]+)`)
+)
+
+// encodedSlashPolicies are the valid values of Router.EncodedSlashes.
+var encodedSlashPolicies = map[string]bool{
+	"decode": true,
+	"keep":   true,
+}
+
+// casePolicies are the valid values of Router.Case.
+var casePolicies = map[string]bool{
+	"sensitive":   true,
+	"insensitive": true,
+	"redirect":    true,
 }
 
 var notFound = &RouteMatch{Action: "404"}
 
+// redirectCodes are the status codes that a route may redirect with, in place
+// of an action.
+var redirectCodes = map[string]int{
+	"301": http.StatusMovedPermanently,
+	"302": http.StatusFound,
+	"303": http.StatusSeeOther,
+	"307": http.StatusTemporaryRedirect,
+	"308": http.StatusPermanentRedirect,
+}
+
+// Route finds the route matching the request.
+//
+// If method overrides are enabled, the method of a POST request is first
+// replaced by its override, if any.
+//
+// If encoded slashes are kept, a parameter may capture an encoded slash, and
+// its value is decoded, e.g. "a%2Fb" => "a/b".
+//
+// If no route matches, but the path matches routes for other methods, it
+// returns a RouteMatch for the special action "405" (Method Not Allowed), or
+// "OPTIONS" for an OPTIONS request, with the methods that are allowed.
+// Otherwise, it returns nil.
+//
+// The server reuses the match once the request has been handled, so it must
+// not be kept after then.
 func (router *Router) Route(req *http.Request) *RouteMatch {
-	leaf, expansions := router.Tree.Find(treePath(req.Method, req.URL.Path))
-	if leaf == nil {
+	if router.MethodOverride && req.Method == "POST" {
+		overrideMethod(req)
+	}
+
+	// Route with the same table throughout, even if it is replaced meanwhile.
+	t := router.load()
+
+	// Match the path with its encoded slashes kept within their segments, and
+	// decode the parameters captured.
+	if path, ok := router.encodedPath(req); ok {
+		encoded, u := *req, *req.URL
+		u.Path, encoded.URL = path, &u
+		match := router.routeLocale(t, &encoded)
+		if match != nil {
+			decodeParams(match.Params)
+		}
+		return match
+	}
+	return router.routeLocale(t, req)
+}
+
+// routeLocale returns the match for the request, removing any locale prefix
+// from its path.
+func (router *Router) routeLocale(t *routeTable, req *http.Request) *RouteMatch {
+	// Remove any locale prefix from the path, and match the rest.
+	if locale, path := router.requestLocale(req.URL.Path); locale != "" {
+		localized, u := *req, *req.URL
+		u.Path, localized.URL = path, &u
+		match := router.route(t, &localized)
+		if match == nil {
+			return nil
+		}
+		if !match.pooled {
+			// Matches not taken from the pool (e.g. notFound) are shared.
+			shared := *match
+			match = &shared
+		}
+		match.Locale = locale
+		if match.Action == "301" && match.Route == nil {
+			match.Location = joinRoutePath("/"+locale, match.Location)
+		}
+		return match
+	}
+	return router.route(t, req)
+}
+
+// route returns the match for the request, or if no route matches, a match
+// giving the methods allowed for its path.
+func (router *Router) route(t *routeTable, req *http.Request) *RouteMatch {
+	if match := router.find(t, req); match != nil {
+		return match
+	}
+
+	allowed := router.allowedMethods(t, req)
+	if len(allowed) == 0 {
 		return nil
 	}
-	route := leaf.Value.(*Route)
+	if req.Method == "OPTIONS" {
+		return &RouteMatch{Action: "OPTIONS", Allowed: allowed}
+	}
 
-	// Create a map of the route parameters.
-	var params url.Values
-	if len(expansions) > 0 {
-		params = make(url.Values)
-		for i, v := range expansions {
-			params[leaf.Wildcards[i].Name] = []string{v}
+	// The method is allowed, but the request was rejected for another reason,
+	// e.g. its format or query.
+	if containsString(allowed, req.Method) {
+		return nil
+	}
+	return &RouteMatch{Action: "405", Allowed: allowed}
+}
+
+// encodedPath returns the request's path with its encoded slashes kept
+// encoded, e.g. "/files/a%2Fb.txt", if they are to be kept and it has any.
+// Any "%" is also kept encoded, so that the parameters are decoded just once.
+func (router *Router) encodedPath(req *http.Request) (string, bool) {
+	if router.EncodedSlashes != "keep" {
+		return "", false
+	}
+	escaped := req.URL.EscapedPath()
+	if !strings.Contains(strings.ToUpper(escaped), "%2F") {
+		return "", false
+	}
+	segments := strings.Split(escaped, "/")
+	for i, segment := range segments {
+		unescaped, err := url.PathUnescape(segment)
+		if err != nil {
+			return "", false
+		}
+		segments[i] = strings.Replace(strings.Replace(unescaped, "%", "%25", -1), "/", "%2F", -1)
+	}
+	return strings.Join(segments, "/"), true
+}
+
+// decodeParams decodes the parameters captured from a path returned by
+// encodedPath.
+func decodeParams(params map[string][]string) {
+	for _, values := range params {
+		for i, value := range values {
+			if decoded, err := url.PathUnescape(value); err == nil {
+				values[i] = decoded
+			}
+		}
+	}
+}
+
+// requestLocale returns the locale that prefixes the path, if any, and the
+// path without it.  e.g. "/fr/users/1" => "fr", "/users/1"
+func (router *Router) requestLocale(path string) (string, string) {
+	for _, locale := range router.Locales {
+		prefix := "/" + locale
+		if path == prefix {
+			return locale, "/"
+		}
+		if strings.HasPrefix(path, prefix+"/") {
+			return locale, path[len(prefix):]
+		}
+	}
+	return "", path
+}
+
+// overrideMethod replaces the method of the request with the one given by its
+// X-HTTP-Method-Override header, or its "_method" form field.
+func overrideMethod(req *http.Request) {
+	method := req.Header.Get("X-HTTP-Method-Override")
+	if method == "" {
+		method = req.PostFormValue("_method")
+	}
+	if method = strings.ToUpper(method); overrideMethods[method] {
+		req.Method = method
+	} else if method != "" {
+		WARN.Println("revel/router: ignoring method override:", method)
+	}
+}
+
+// allowedMethods returns the methods of the routes that match the request's
+// host and path, in sorted order.
+func (router *Router) allowedMethods(t *routeTable, req *http.Request) []string {
+	var (
+		elements = splitTreePath(treePath("OPTIONS", req.URL.Path))
+		found    = make(map[string]bool)
+	)
+	for _, route := range t.routes {
+		// Websocket routes are not reachable by ordinary requests.
+		if route.Method == "WS" || len(route.elements) == 0 {
+			continue
+		}
+		elements[0] = route.elements[0]
+		for _, form := range route.forms {
+			if expansions, ok := form.match(elements, router.caseFor(route) != "sensitive"); ok {
+				if params, ok := route.matchHost(req.Host, form.params(expansions)); ok && route.accepts(params) && route.passesGuards(req) {
+					found[route.Method] = true
+				}
+			}
+		}
+	}
+	if len(found) == 0 {
+		return nil
+	}
+
+	// GET routes also respond to HEAD, and OPTIONS is answered automatically.
+	if found["GET"] {
+		found["HEAD"] = true
+	}
+	found["OPTIONS"] = true
+
+	allowed := make([]string, 0, len(found))
+	for method := range found {
+		allowed = append(allowed, method)
+	}
+	sort.Strings(allowed)
+	return allowed
+}
+
+// find returns the first route matching the request, or nil.
+func (router *Router) find(t *routeTable, req *http.Request) (result *RouteMatch) {
+	var (
+		reqTreePath = treePath(req.Method, req.URL.Path)
+		form        *routeForm
+		params      url.Values
+		ok          bool
+		versioned   bool // true if the version was removed from the path
+	)
+
+	if t.cache != nil {
+		if match := t.cache.get(reqTreePath); match != nil {
+			return match
+		}
+	}
+
+	// The match and its parameters are taken from the pool, and returned to
+	// it unless they are the result.
+	match := acquireRouteMatch()
+	defer func() {
+		if result != match {
+			releaseRouteMatch(match)
+		}
+	}()
+
+	// Versioned routes are matched by scanning for the latest version of the
+	// route that is no later than the request's.
+	// The tree matches case-sensitively, so if any routes do not, scan the
+	// routes in order instead.
+	if t.latest > 0 {
+		version, path := router.requestVersion(t, req)
+		form, params = router.scanVersions(t, req, version, splitTreePath(treePath(req.Method, path)))
+		if form == nil {
+			return nil
+		}
+		ok, versioned = true, path != req.URL.Path
+	} else if !t.foldCase && router.Case != "insensitive" && router.Case != "redirect" {
+		leaf, expansions := t.tree.Find(reqTreePath)
+		if leaf == nil {
+			return nil
+		}
+		form = leaf.Value.(*routeForm)
+		params, ok = router.matchRoute(form, req, expansions, match)
+	}
+
+	// If the route's host or constraints reject the request, fall through to
+	// the next matching route.  Only the first route of each shape is in the
+	// tree, so scan the routes in order to find it.
+	if !ok {
+		if form, params = router.scan(t, req, splitTreePath(reqTreePath), match); form == nil {
+			return nil
+		}
+	}
+	route := form.route
+
+	// Redirect to the route's path if it differs by a trailing slash or case,
+	// according to the route's policies.
+	if (req.Method == "GET" || req.Method == "HEAD") && !versioned {
+		if location := router.canonicalPath(form, req.URL.Path); location != req.URL.Path {
+			return &RouteMatch{
+				Action:   "301",
+				Location: (&url.URL{Path: location, RawQuery: req.URL.RawQuery}).String(),
+			}
 		}
 	}
 
+	// A catch-all parameter captures the rest of the path, including any
+	// trailing slash.
+	if name := splat(form.elements); name != "" && strings.HasSuffix(req.URL.Path, "/") {
+		params[name][0] += "/"
+	}
+
 	// Special handling for explicit 404's.
 	if route.Action == "404" {
 		return notFound
 	}
 
+	// Redirect routes send the client on to their target.
+	if route.Redirect != "" {
+		return router.redirect(route, req, params)
+	}
+
+	// Static routes serve files without an action.
+	if route.Static != "" {
+		match.Action, match.Params, match.Meta, match.Route = "STATIC", params, route.Meta, route
+		return match
+	}
+
+	// Mount routes pass the request to their handler without an action.
+	if route.handler != nil {
+		match.Action, match.Params, match.Meta, match.Route = "MOUNT", params, route.Meta, route
+		return match
+	}
+
 	// If the action is variablized, replace into it with the captured args.
 	controllerName, methodName := route.ControllerName, route.MethodName
 	if pos := strings.LastIndex(controllerName, ":"); pos != -1 {
@@ -149,46 +1122,832 @@ func (router *Router) Route(req *http.Request) *RouteMatch {
 		}
 	}
 
+	match.ControllerName = controllerName
+	match.MethodName = methodName
+	match.Params = params
+	match.FixedParams = route.FixedParams
+	match.Filters = route.filters
+	match.Meta = route.Meta
+	match.Route = route
+	if t.cache != nil && len(route.args) == 0 {
+		t.cache.add(reqTreePath, match)
+	}
+	return match
+}
+
+// scan returns the first route (in order) that matches the given request tree
+// path elements, along with its parameters.
+func (router *Router) scan(t *routeTable, req *http.Request, elements []string, match *RouteMatch) (*routeForm, url.Values) {
+	for _, route := range t.routes {
+		for _, form := range route.forms {
+			if expansions, ok := form.match(elements, router.caseFor(route) != "sensitive"); ok {
+				if params, ok := router.matchRoute(form, req, expansions, match); ok {
+					return form, params
+				}
+			}
+		}
+	}
+	return nil, nil
+}
+
+// redirect returns the match for a redirect route.  Its target is either a
+// path, into which the route's parameters are substituted, or an action, which
+// is reversed with them.  The request's query is kept, unless the target has
+// its own.
+func (router *Router) redirect(route *Route, req *http.Request, params url.Values) *RouteMatch {
+	var location string
+	if strings.Contains(route.Redirect, "/") {
+		location = expandRedirect(route.Redirect, params)
+	} else {
+		argValues := make(map[string]string)
+		for name, values := range params {
+			argValues[name] = values[0]
+		}
+		actionDef := router.Reverse(route.Redirect, argValues)
+		if actionDef == nil {
+			ERROR.Println("revel/router: failed to reverse redirect target:", route.Redirect)
+			return notFound
+		}
+
+		// Stay on the request's host, unless the action is on a host of its own.
+		location = actionDef.Url
+		if actionDef.Host != hostWithPort(HttpHost, actionDef.Scheme) {
+			location = actionDef.AbsoluteURL()
+		}
+	}
+
+	if req.URL.RawQuery != "" && !strings.Contains(location, "?") {
+		location += "?" + req.URL.RawQuery
+	}
 	return &RouteMatch{
-		ControllerName: controllerName,
-		MethodName:     methodName,
-		Params:         params,
-		FixedParams:    route.FixedParams,
+		Action:   route.Action,
+		Location: location,
+		Meta:     route.Meta,
+		Route:    route,
+	}
+}
+
+// expandRedirect substitutes the parameters into a redirect target path.
+// e.g. ("/posts/:id", {id: 5}) => "/posts/5"
+func expandRedirect(target string, params url.Values) string {
+	segments := strings.Split(target, "/")
+	for i, segment := range segments {
+		if !isWildcard(segment) {
+			continue
+		}
+		if values, ok := params[segment[1:]]; ok {
+			segments[i] = values[0]
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// scanVersions returns the latest version of the first route matching the
+// elements that is no later than the given version.  Unversioned routes match
+// any version, but versioned routes are preferred.
+func (router *Router) scanVersions(t *routeTable, req *http.Request, version int, elements []string) (*routeForm, url.Values) {
+	var (
+		best       *routeForm
+		bestParams url.Values
+	)
+	for _, route := range t.routes {
+		if route.Version > version || (best != nil && route.Version <= best.route.Version) {
+			continue
+		}
+		for _, form := range route.forms {
+			if expansions, ok := form.match(elements, router.caseFor(route) != "sensitive"); ok {
+				if params, ok := router.matchRoute(form, req, expansions, nil); ok {
+					best, bestParams = form, params
+					break
+				}
+			}
+		}
+	}
+	return best, bestParams
+}
+
+// requestVersion returns the API version requested, and the request's path
+// without any version prefix.
+func (router *Router) requestVersion(t *routeTable, req *http.Request) (int, string) {
+	var version string
+	switch router.Versioning {
+	case "header":
+		if matches := versionAcceptPattern.FindStringSubmatch(req.Header.Get("Accept")); matches != nil {
+			version = matches[1]
+		}
+	case "query":
+		version = req.URL.Query().Get("v")
+	default:
+		matches := versionPathPattern.FindStringSubmatch(req.URL.Path)
+		if matches == nil {
+			return 0, req.URL.Path
+		}
+		path := matches[2]
+		if path == "" {
+			path = "/"
+		}
+		n, _ := strconv.Atoi(matches[1])
+		return n, path
+	}
+
+	if n, err := strconv.Atoi(version); err == nil {
+		return n, req.URL.Path
+	}
+	return t.latest, req.URL.Path
+}
+
+// matchRoute checks the request against the route's host, constraints,
+// trailing slash, and guards, given the expansions of the path parameters of one of its
+// forms.  It returns the route's parameters if they all match.
+func (router *Router) matchRoute(form *routeForm, req *http.Request, expansions []string, match *RouteMatch) (url.Values, bool) {
+	route := form.route
+	params, ok := route.matchHost(req.Host, match.setParams(form, expansions))
+	if !ok || !route.accepts(params) || (route.Query != nil && !route.matchQuery(req.URL.Query())) || !route.matchFormat(req) {
+		return nil, false
+	}
+	if router.trailingSlash(route) == "strict" && !route.matchesSlash(req.URL.Path) {
+		return nil, false
+	}
+	if !route.passesGuards(req) {
+		return nil, false
+	}
+	return params, true
+}
+
+// matchQuery returns true if the query has the route's required parameters.
+// A required parameter without a value only needs to be present.
+// e.g. "/search?format=json&debug" requires format=json, and debug with any value.
+func (route *Route) matchQuery(query url.Values) bool {
+	for key, values := range route.Query {
+		actual, ok := query[key]
+		if !ok {
+			return false
+		}
+		for _, value := range values {
+			if value != "" && !containsString(actual, value) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// matchFormat returns true if the request is in one of the route's formats.
+// A format name (e.g. "json") is matched against the format resolved from the
+// Accept header, or that of the request body, while a media type (e.g.
+// "application/vnd.api+json") is matched against the Accept and Content-Type
+// headers.
+func (route *Route) matchFormat(req *http.Request) bool {
+	if len(route.Formats) == 0 {
+		return true
+	}
+	var contentType, bodyFormat string
+	if req.Header.Get("Content-Type") != "" {
+		contentType = ResolveContentType(req)
+		bodyFormat = contentTypeFormats[contentType]
+	}
+	for _, format := range route.Formats {
+		if !strings.Contains(format, "/") {
+			if format == ResolveFormat(req) || format == bodyFormat {
+				return true
+			}
+			continue
+		}
+		if format == contentType ||
+			strings.Contains(strings.ToLower(req.Header.Get("Accept")), format) {
+			return true
+		}
 	}
+	return false
 }
 
-// Refresh re-reads the routes file and re-calculates the routing table.
-// Returns an error if a specified action could not be found.
-func (router *Router) Refresh() (err *Error) {
-	router.Routes, err = parseRoutesFile(router.path, true)
+// contentTypeFormats maps the content types of request bodies to formats.
+var contentTypeFormats = map[string]string{
+	"application/json": "json",
+	"text/javascript":  "json",
+	"application/xml":  "xml",
+	"text/xml":         "xml",
+	"text/plain":       "txt",
+	"text/html":        "html",
+}
+
+// containsString returns true if the value is in the list.
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// trailingSlash returns the trailing slash policy in effect for the route.
+func (router *Router) trailingSlash(route *Route) string {
+	if route.TrailingSlash != "" {
+		return route.TrailingSlash
+	}
+	if router.TrailingSlash != "" {
+		return router.TrailingSlash
+	}
+	return "strip"
+}
+
+// caseFor returns the case matching policy in effect for the route.
+func (router *Router) caseFor(route *Route) string {
+	if route.Case != "" {
+		return route.Case
+	}
+	if router.Case != "" {
+		return router.Case
+	}
+	return "sensitive"
+}
+
+// canonicalPath returns the path that the route redirects the request's path
+// to, according to its trailing slash and case policies.  This is the same
+// path, unless a redirect is required.
+func (router *Router) canonicalPath(form *routeForm, path string) string {
+	var (
+		route    = form.route
+		fixSlash = router.trailingSlash(route) == "redirect"
+		fixCase  = router.caseFor(route) == "redirect"
+	)
+	if !fixSlash && !fixCase {
+		return path
+	}
+
+	var buf bytes.Buffer
+	elements := splitTreePath(path)
+	for i, el := range form.elements[1:] {
+		if i == len(elements) {
+			break
+		}
+		buf.WriteByte('/')
+		switch {
+		case el[0] == '*':
+			buf.WriteString(strings.Join(elements[i:], "/"))
+		case el[0] == ':' || !fixCase:
+			buf.WriteString(elements[i])
+		default:
+			buf.WriteString(el)
+		}
+	}
+
+	slash := len(path) > 1 && strings.HasSuffix(path, "/")
+	if fixSlash && !route.matchesSlash(path) {
+		slash = !slash
+	}
+	if buf.Len() == 0 || slash {
+		buf.WriteByte('/')
+	}
+	return buf.String()
+}
+
+// matchesSlash returns true if the path ends in a slash exactly when the
+// route's path does.  Routes with a catch-all parameter match either way.
+func (route *Route) matchesSlash(path string) bool {
+	if splat(route.elements) != "" {
+		return true
+	}
+	return (len(path) > 1 && strings.HasSuffix(path, "/")) ==
+		(len(route.Path) > 1 && strings.HasSuffix(route.Path, "/"))
+}
+
+// hasArg returns true if the route captures the named parameter.
+func (r *Route) hasArg(name string) bool {
+	for _, arg := range r.args {
+		if arg.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// splat returns the name of the catch-all parameter of the tree path
+// elements, e.g. "filepath" for "/public/*filepath", or "" if it has none.
+func splat(elements []string) string {
+	if n := len(elements); n > 0 && elements[n-1][0] == '*' {
+		return elements[n-1][1:]
+	}
+	return ""
+}
+
+// matchHost checks the request's host against the route's host pattern, and
+// adds any parameters captured from it to params.
+// Routes without a host pattern match any host.
+func (route *Route) matchHost(host string, params url.Values) (url.Values, bool) {
+	if route.host == nil {
+		return params, true
+	}
+
+	// Remove the port, if any.
+	if i := strings.LastIndex(host, ":"); i != -1 && !strings.Contains(host[i:], "]") {
+		host = host[:i]
+	}
+
+	labels := strings.Split(strings.ToLower(host), ".")
+	if len(labels) != len(route.host) {
+		return nil, false
+	}
+	for i, label := range route.host {
+		if label[0] != ':' {
+			if label != labels[i] {
+				return nil, false
+			}
+			continue
+		}
+		if params == nil {
+			params = make(url.Values)
+		}
+		params[label[1:]] = []string{labels[i]}
+	}
+	return params, true
+}
+
+// match checks the elements of a request tree path against each of the
+// route's forms, returning the first that matches, with the values of its
+// parameters.
+func (route *Route) match(elements []string, fold bool) (*routeForm, []string, bool) {
+	for _, form := range route.forms {
+		if expansions, ok := form.match(elements, fold); ok {
+			return form, expansions, true
+		}
+	}
+	return nil, nil, false
+}
+
+// match checks the elements of a request tree path against the form's,
+// returning the values of its parameters if they match.
+func (form *routeForm) match(elements []string, fold bool) (expansions []string, ok bool) {
+	if len(form.elements) == 0 {
+		return nil, false
+	}
+	for i, el := range form.elements {
+		switch {
+		case i == len(elements):
+			return nil, false
+		case el[0] == '*':
+			return append(expansions, strings.Join(elements[i:], "/")), true
+		case el[0] == ':':
+			expansions = append(expansions, elements[i])
+		case el == elements[i], fold && strings.EqualFold(el, elements[i]):
+		case i == 0 && el == "GET" && elements[i] == "HEAD":
+			// Allow GETs to respond to HEAD requests.
+		default:
+			return nil, false
+		}
+	}
+	return expansions, len(elements) == len(form.elements)
+}
+
+// params returns a map of the route parameters, given the expansions of the
+// form's wildcards.
+func (form *routeForm) params(expansions []string) url.Values {
+	var params url.Values
+	if len(form.route.args) > 0 {
+		params = make(url.Values, len(form.route.args))
+		for i := range expansions {
+			params[form.args[i].name] = expansions[i : i+1 : i+1]
+		}
+		form.setDefaults(params)
+	}
+	return params
+}
+
+// setDefaults sets the parameters omitted from the form to their defaults, if
+// they have them.
+func (form *routeForm) setDefaults(params url.Values) {
+	for _, arg := range form.omitted {
+		if arg.defaultValue != "" {
+			params[arg.name] = []string{arg.defaultValue}
+		}
+	}
+}
+
+// accepts returns true if the route parameters satisfy the route's
+// constraints.
+func (route *Route) accepts(params url.Values) bool {
+	for _, arg := range route.args {
+		if values, ok := params[arg.name]; ok && arg.constraint != nil && !arg.constraint.MatchString(values[0]) {
+			return false
+		}
+	}
+	return true
+}
+
+// byPriority sorts routes from highest to lowest priority.  Routes of equal
+// priority keep their order.
+type byPriority []*Route
+
+func (r byPriority) Len() int           { return len(r) }
+func (r byPriority) Swap(i, j int)      { r[i], r[j] = r[j], r[i] }
+func (r byPriority) Less(i, j int) bool { return r[i].Priority > r[j].Priority }
+
+// Refresh reloads the routes file and the routes declared by the actions, and
+// puts the new routing table in use, along with the routes added in code.
+// Requests being routed meanwhile use the old table.  If the routes are
+// invalid, the old table stays in use.  The refresh hooks are then called,
+// without the router locked, so that they may add routes or hooks.
+func (router *Router) Refresh() *Error {
+	old, t, hooks, err := router.refresh()
+	if err != nil {
+		return err
+	}
+	for _, hook := range hooks {
+		hook(old.routes, t.routes)
+	}
+	return nil
+}
+
+// refresh puts the new routing table in use, returning the old one and the
+// refresh hooks to call.
+func (router *Router) refresh() (old, t *routeTable, hooks []func(old, new []*Route), err *Error) {
+	router.mutex.Lock()
+	defer router.mutex.Unlock()
+
+	routes, err := parseRoutesFile(router.path, nil, true)
 	if err != nil {
 		return
 	}
-	err = router.updateTree()
-	return
+	actionRoutes, actionErr := ActionRoutes()
+	if actionErr != nil {
+		return nil, nil, nil, &Error{
+			Title:       "Route validation error",
+			Description: actionErr.Error(),
+		}
+	}
+	routes = append(routes, actionRoutes...)
+	for _, route := range router.added {
+		if err := validateRoute(route); err != nil {
+			return nil, nil, nil, &Error{
+				Title:       "Route validation error",
+				Description: err.Error(),
+			}
+		}
+	}
+	routes = append(routes, router.added...)
+	if t, err = router.buildTable(routes); err != nil {
+		return nil, nil, nil, err
+	}
+	if router.ValidateReferences {
+		if err = t.checkReferences(); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	old = router.load()
+	router.use(t)
+	hooks = append(hooks, router.refreshHooks...)
+	return old, t, hooks, nil
+}
+
+// OnRefresh registers a function to be called each time the routes are
+// reloaded, e.g. by the watcher, with the routing table from before and after.
+// This allows caches keyed by route, metrics, and the like to be updated.
+func (router *Router) OnRefresh(f func(old, new []*Route)) {
+	router.mutex.Lock()
+	defer router.mutex.Unlock()
+	router.refreshHooks = append(router.refreshHooks, f)
+}
+
+// RouteInfo describes a route in the routing table, for debugging.
+type RouteInfo struct {
+	Method      string            // e.g. "GET"
+	Host        string            // e.g. ":tenant.example.com", or "" for any
+	Path        string            // e.g. "/users/:id<int>"
+	Query       string            // e.g. "format=json", the required query parameters
+	Formats     []string          // e.g. "json"
+	Version     int               // e.g. 2, or 0 if unversioned
+	Action      string            // e.g. "Users.Show", or "301" for a redirect
+	Redirect    string            // e.g. "/new-path", the target of a redirect
+	Static      string            // e.g. "public/", the directory served by a static route
+	ErrorStatus int               // e.g. 404, the status of the errors handled by an ERROR route
+	Mount       string            // e.g. "metrics", the name of the handler of a MOUNT route
+	Name        string            // e.g. "users.show"
+	Constraints map[string]string // e.g. {id: "^(?:-?[0-9]+)$"}
+	Filters     []string          // e.g. "auth"
+	Priority    int
+	Meta        map[string]string
+	File        string // e.g. "/Users/robfig/gocode/src/myapp/conf/routes", or "" if added in code
+	Line        int    // e.g. 12, or 0 if added in code
+}
+
+// List describes the routes in the routing table, in the order they are
+// matched.
+func (router *Router) List() []RouteInfo {
+	routes := router.Table()
+	infos := make([]RouteInfo, len(routes))
+	for i, route := range routes {
+		infos[i] = route.Info()
+	}
+	return infos
+}
+
+// Info describes the route.
+func (r *Route) Info() RouteInfo {
+	info := RouteInfo{
+		Method:      r.Method,
+		Host:        r.Host,
+		Path:        r.Path,
+		Query:       r.Query.Encode(),
+		Formats:     r.Formats,
+		Version:     r.Version,
+		Action:      r.Action,
+		Redirect:    r.Redirect,
+		Static:      r.Static,
+		ErrorStatus: r.ErrorStatus,
+		Mount:       r.Mount,
+		Name:        r.Name,
+		Filters:     r.Filters,
+		Priority:    r.Priority,
+		Meta:        r.Meta,
+		File:        r.routesPath,
+	}
+	if r.routesPath != "" {
+		info.Line = r.line + 1
+	}
+	for _, arg := range r.args {
+		if arg.constraint != nil {
+			if info.Constraints == nil {
+				info.Constraints = make(map[string]string)
+			}
+			info.Constraints[arg.name] = arg.constraint.String()
+		}
+	}
+	return info
 }
 
+// ActionRoutes returns the routes declared in the comments of the registered
+// controllers' actions.  For example:
+//   // @Route GET /users/:id as users.show
+//   func (c Users) Show(id int) revel.Result {
+//
+// The comments are gathered from the app source by the harness.  The routes
+// are matched after those in the routes file, and may declare a name,
+// filters, and options, but not fixed parameters.
+func ActionRoutes() ([]*Route, error) {
+	var names []string
+	for name := range controllers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var routes []*Route
+	for _, name := range names {
+		controllerType := controllers[name]
+		for _, method := range controllerType.Methods {
+			action := controllerType.Type.Name() + "." + method.Name
+			for _, declared := range method.Routes {
+				fields := strings.Fields(declared)
+				if len(fields) < 2 {
+					return nil, fmt.Errorf("%s: invalid route: @Route %s", action, declared)
+				}
+				line := strings.TrimSpace(fields[0] + " " + fields[1] + " " + action + " " +
+					strings.Join(fields[2:], " "))
+				decl, found := parseRouteDecl(line)
+				if !found {
+					return nil, fmt.Errorf("%s: invalid route: @Route %s", action, declared)
+				}
+				declRoutes, err := (*RouteGroup)(nil).newRoutes(decl, "", 0)
+				if err != nil {
+					return nil, fmt.Errorf("%s: invalid route: @Route %s: %s", action, declared, err)
+				}
+				routes = append(routes, declRoutes...)
+			}
+		}
+	}
+	return routes, nil
+}
+
+// checkConflicts reports routes that can never match, because an earlier
+// route matches all of the same requests: either a duplicate, or a route with
+// wildcards.  They are logged as warnings, or returned as an error if
+// StrictConflicts is set.
+func (router *Router) checkConflicts(routes []*Route) *Error {
+	for i, route := range routes {
+		for _, prev := range routes[:i] {
+			if !prev.shadows(route) {
+				continue
+			}
+			reason := "shadowed by"
+			if route.shadows(prev) {
+				reason = "a duplicate of"
+			}
+			msg := fmt.Sprintf("%s %s (%s) is unreachable: it is %s %s %s (%s)",
+				route.Method, route.Path, route.location(), reason, prev.Method, prev.Path, prev.location())
+			if !router.StrictConflicts {
+				WARN.Println("revel/router:", msg)
+				break
+			}
+			if route.routesPath == "" {
+				return &Error{Title: "Route validation error", Description: msg}
+			}
+			return routeError(errors.New(msg), route.routesPath, "", route.line)
+		}
+	}
+	return nil
+}
+
+// shadows returns true if every request matched by other is also matched by
+// this route, and this route has at least the same priority.
+func (r *Route) shadows(other *Route) bool {
+	if r.Priority < other.Priority || r.Host != other.Host || r.Version != other.Version ||
+		(r.Method != other.Method && r.Method != "*") {
+		return false
+	}
+	for _, arg := range r.args {
+		if arg.constraint != nil {
+			return false
+		}
+	}
+	if len(r.guards) > 0 {
+		return false
+	}
+	for _, format := range r.Formats {
+		if !containsString(other.Formats, format) {
+			return false
+		}
+	}
+	for key, values := range r.Query {
+		for _, value := range values {
+			if otherValues, ok := other.Query[key]; !ok || (value != "" && !containsString(otherValues, value)) {
+				return false
+			}
+		}
+	}
+	for _, otherForm := range other.forms {
+		covered := false
+		for _, form := range r.forms {
+			if form.covers(otherForm) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			return false
+		}
+	}
+	return len(other.forms) > 0
+}
+
+// covers returns true if every path matched by the other form is also matched
+// by this one, disregarding their methods.
+func (form *routeForm) covers(other *routeForm) bool {
+	for i := 1; i < len(form.elements); i++ {
+		if i == len(other.elements) {
+			return false
+		}
+		el, otherEl := form.elements[i], other.elements[i]
+		switch {
+		case el[0] == '*':
+			return true
+		case el[0] == ':':
+			if otherEl[0] == '*' {
+				return false
+			}
+		case el != otherEl:
+			return false
+		}
+	}
+	return len(form.elements) == len(other.elements)
+}
+
+// location describes where the route was declared, e.g. "conf/routes:12".
+func (r *Route) location() string {
+	if r.routesPath == "" {
+		return "added in code"
+	}
+	return fmt.Sprintf("%s:%d", r.routesPath, r.line+1)
+}
+
+// updateTree builds the routing table from Routes, and puts it in use.
+// Unless the router is not yet serving requests, the caller must hold the
+// router's mutex.
 func (router *Router) updateTree() *Error {
-	router.Tree = pathtree.New()
-	for _, route := range router.Routes {
+	t, err := router.buildTable(router.Routes)
+	if err != nil {
+		return err
+	}
+	router.use(t)
+	return nil
+}
+
+// Table returns the routes of the routing table in use, in the order they are
+// matched.  Unlike Routes, it is safe to call while the routes are reloaded.
+func (router *Router) Table() []*Route {
+	return router.load().routes
+}
+
+// load returns the routing table in use.
+func (router *Router) load() *routeTable {
+	if t, ok := router.table.Load().(*routeTable); ok {
+		return t
+	}
+	return emptyRouteTable
+}
+
+// use puts the routing table in use, replacing the previous one.
+func (router *Router) use(t *routeTable) {
+	router.table.Store(t)
+	router.Routes, router.Tree = t.routes, t.tree
+}
+
+// buildTable builds a routing table from the routes, sorted by priority.  The
+// given slice is not modified.
+func (router *Router) buildTable(routes []*Route) (*routeTable, *Error) {
+	t := &routeTable{
+		routes: append([]*Route(nil), routes...),
+		tree:   pathtree.New(),
+	}
+	sort.Stable(byPriority(t.routes))
+	cacheable := router.CacheSize > 0
+	for _, route := range t.routes {
+		if route.Host != "" || route.Query != nil || len(route.Formats) > 0 || route.Version > 0 || len(route.guards) > 0 {
+			cacheable = false
+		}
+		if route.Case == "insensitive" || route.Case == "redirect" {
+			t.foldCase = true
+		}
+		if route.Version > t.latest {
+			t.latest = route.Version
+		}
+		if route.Method == "ERROR" {
+			t.errorRoutes = append(t.errorRoutes, route)
+		}
+	}
+	if cacheable {
+		t.cache = newRouteCache(router.CacheSize)
+	}
+	t.indexActions()
+
+	shapes := make(map[string]bool)
+	for _, route := range t.routes {
 		var err error
-		route.leaf, err = router.Tree.Add(route.TreePath, route)
+		for _, form := range route.forms {
+			if err = t.addToTree(shapes, form, form.elements); err != nil {
+				break
+			}
+
+			// Allow GETs to respond to HEAD requests.
+			if route.Method == "GET" {
+				if err = t.addToTree(shapes, form, append([]string{"HEAD"}, form.elements[1:]...)); err != nil {
+					break
+				}
+			}
+		}
+
+		// Error adding a route to the pathtree.
+		if err != nil {
+			return nil, routeError(err, route.routesPath, "", route.line)
+		}
+	}
+	if err := router.checkConflicts(t.routes); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
 
-		// Allow GETs to respond to HEAD requests.
-		if err == nil && route.Method == "GET" {
-			_, err = router.Tree.Add(treePath("HEAD", route.Path), route)
+// indexActions indexes the routes by action and name, so that Reverse and
+// ReverseByName need not scan them.  Each action's routes include those with
+// wildcard actions, e.g. ":controller.Show", keeping the order of the routes.
+func (t *routeTable) indexActions() {
+	t.actions = make(map[string][]*Route)
+	t.names = make(map[string]*Route)
+	for _, route := range t.routes {
+		if _, ok := t.names[route.Name]; route.Name != "" && !ok {
+			t.names[route.Name] = route
 		}
-
-		// Error adding a route to the pathtree.
-		if err != nil {
-			return routeError(err, route.routesPath, "", route.line)
+		if route.ControllerName == "" || route.MethodName == "" || route.Method == "ERROR" {
+			continue
+		}
+		if strings.Contains(route.ControllerName, ":") || strings.Contains(route.MethodName, ":") {
+			t.wildcards = append(t.wildcards, route)
+			for action, routes := range t.actions {
+				t.actions[action] = append(routes, route)
+			}
+			continue
+		}
+		action := route.ControllerName + "." + route.MethodName
+		if _, ok := t.actions[action]; !ok {
+			t.actions[action] = append([]*Route{}, t.wildcards...)
 		}
+		t.actions[action] = append(t.actions[action], route)
 	}
-	return nil
 }
 
-// parseRoutesFile reads the given routes file and returns the contained routes.
-func parseRoutesFile(routesPath string, validate bool) ([]*Route, *Error) {
+// addToTree adds the route's form to the tree under the given path elements,
+// unless an earlier route has the same shape.  In that case, the route is only
+// reachable if the earlier route's constraints reject a request.
+func (t *routeTable) addToTree(shapes map[string]bool, form *routeForm, elements []string) error {
+	shape := treeShape(elements)
+	if shapes[shape] {
+		return nil
+	}
+	shapes[shape] = true
+	_, err := t.tree.Add("/"+strings.Join(elements, "/"), form)
+	return err
+}
+
+func parseRoutesFile(routesPath string, group *RouteGroup, validate bool) ([]*Route, *Error) {
 	contentBytes, err := ioutil.ReadFile(routesPath)
 	if err != nil {
 		return nil, &Error{
@@ -196,12 +1955,22 @@ func parseRoutesFile(routesPath string, validate bool) ([]*Route, *Error) {
 			Description: err.Error(),
 		}
 	}
-	return parseRoutes(routesPath, string(contentBytes), validate)
+	return parseGroupRoutes(routesPath, string(contentBytes), group, validate)
 }
 
 // parseRoutes reads the content of a routes file into the routing table.
 func parseRoutes(routesPath, content string, validate bool) ([]*Route, *Error) {
-	var routes []*Route
+	return parseGroupRoutes(routesPath, content, nil, validate)
+}
+
+// parseGroupRoutes reads the content of a routes file, with all of its routes
+// in the given group (e.g. the prefix that a module is mounted under).
+func parseGroupRoutes(routesPath, content string, base *RouteGroup, validate bool) ([]*Route, *Error) {
+	var (
+		routes []*Route
+		group  = base             // the innermost open group
+		names  = map[string]int{} // route name => line number
+	)
 
 	// For each line..
 	for n, line := range strings.Split(content, "\n") {
@@ -210,10 +1979,41 @@ func parseRoutes(routesPath, content string, validate bool) ([]*Route, *Error) {
 			continue
 		}
 
+		// Open and close route groups.
+		// e.g. "group /api/v1 {" ... "}"
+		if line == "}" {
+			if group == base {
+				return nil, routeError(errors.New("Unexpected '}' outside of a route group"),
+					routesPath, content, n)
+			}
+			group = group.parent
+			continue
+		}
+		if matches := versionPattern.FindStringSubmatch(line); matches != nil {
+			group = newRouteGroup(nil, group, "", nil)
+			group.Version, _ = strconv.Atoi(matches[1])
+			continue
+		}
+		if prefix, fixedArgs, filters, found := parseGroupLine(line); found {
+			group = newRouteGroup(nil, group, prefix, fixedArgs)
+			group.Filters = append(group.Filters, filters...)
+			continue
+		}
+
 		// Handle included routes from modules.
 		// e.g. "module:testrunner" imports all routes from that module.
+		// e.g. "module:cms /content" mounts them under the /content prefix.
 		if strings.HasPrefix(line, "module:") {
-			moduleRoutes, err := getModuleRoutes(line[len("module:"):], validate)
+			fields := strings.Fields(line[len("module:"):])
+			if len(fields) == 0 || len(fields) > 2 || (len(fields) == 2 && !strings.HasPrefix(fields[1], "/")) {
+				return nil, routeError(errors.New("Expected module:name or module:name /prefix"),
+					routesPath, content, n)
+			}
+			moduleGroup := group
+			if len(fields) == 2 {
+				moduleGroup = newRouteGroup(nil, group, fields[1], nil)
+			}
+			moduleRoutes, err := getModuleRoutes(fields[0], moduleGroup, validate)
 			if err != nil {
 				return nil, routeError(err, routesPath, content, n)
 			}
@@ -222,36 +2022,60 @@ func parseRoutes(routesPath, content string, validate bool) ([]*Route, *Error) {
 		}
 
 		// A single route
-		method, path, action, fixedArgs, found := parseRouteLine(line)
+		decl, found := parseRouteDecl(line)
 		if !found {
 			continue
 		}
 
-		route := NewRoute(method, path, action, fixedArgs, routesPath, n)
-		routes = append(routes, route)
+		lineRoutes, err := group.newRoutes(decl, routesPath, n)
+		if err != nil {
+			return nil, routeError(err, routesPath, content, n)
+		}
+		if name := lineRoutes[0].Name; name != "" {
+			if prev, ok := names[name]; ok {
+				return nil, routeError(fmt.Errorf("Duplicate route name %s (first used on line %d)", name, prev+1),
+					routesPath, content, n)
+			}
+			names[name] = n
+		}
+		routes = append(routes, lineRoutes...)
 
 		if validate {
-			if err := validateRoute(route); err != nil {
+			if err := validateRoute(lineRoutes[0]); err != nil {
 				return nil, routeError(err, routesPath, content, n)
 			}
 		}
 	}
 
+	if group != base {
+		return nil, routeError(fmt.Errorf("Route group %s is missing a closing '}'", group.Prefix),
+			routesPath, content, strings.Count(content, "\n"))
+	}
+
 	return routes, nil
 }
 
 // validateRoute checks that every specified action exists.
 func validateRoute(route *Route) error {
-	// Skip 404s
-	if route.Action == "404" {
+	// Skip 404s, static, and mount routes
+	if route.Action == "404" || route.Action == "STATIC" || route.Action == "MOUNT" {
 		return nil
 	}
 
+	// Redirects to a path are not checked, but redirects to an action are.
+	action := route.Action
+	if route.Redirect != "" {
+		if strings.Contains(route.Redirect, "/") {
+			return nil
+		}
+		action = route.Redirect
+	}
+
 	// We should be able to load the action.
-	parts := strings.Split(route.Action, ".")
+	parts := strings.Split(action, ".")
 	if len(parts) != 2 {
 		return fmt.Errorf("Expected two parts (Controller.Action), but got %d: %s",
-			len(parts), route.Action)
+			len(parts), action)
 	}
 
 	// Skip variable routes.
@@ -292,7 +2116,7 @@ func routeError(err error, routesPath, content string, n int) *Error {
 
 // getModuleRoutes loads the routes file for the given module and returns the
 // list of routes.
-func getModuleRoutes(moduleName string, validate bool) ([]*Route, *Error) {
+func getModuleRoutes(moduleName string, group *RouteGroup, validate bool) ([]*Route, *Error) {
 	// Look up the module.  It may be not found due to the common case of e.g. the
 	// testrunner module being active only in dev mode.
 	module, found := ModuleByName(moduleName)
@@ -300,7 +2124,7 @@ func getModuleRoutes(moduleName string, validate bool) ([]*Route, *Error) {
 		INFO.Println("Skipping routes for inactive module", moduleName)
 		return nil, nil
 	}
-	return parseRoutesFile(path.Join(module.Path, "conf", "routes"), validate)
+	return parseRoutesFile(path.Join(module.Path, "conf", "routes"), group, validate)
 }
 
 // Groups:
@@ -310,7 +2134,7 @@ func getModuleRoutes(moduleName string, validate bool) ([]*Route, *Error) {
 // 6: fixedargs
 var routePattern *regexp.Regexp = regexp.MustCompile(
 	"(?i)^(GET|POST|PUT|DELETE|PATCH|OPTIONS|HEAD|WS|\\*)" +
-		"[(]?([^)]*)(\\))?[ \t]+" +
+		"[(]?([^)]*?)(\\))?[ \t]+" +
 		"(.*/[^ \t]*)[ \t]+([^ \t(]+)" +
 		`\(?([^)]*)\)?[ \t]*$`)
 
@@ -324,6 +2148,162 @@ func parseRouteLine(line string) (method, path, action, fixedArgs string, found
 	return
 }
 
+// routeDecl is a route as declared in the routes file.  For example:
+//   GET  /users/new  [html]  Users.New  as users.new  [auth]  {priority=10, audit=false}
+//   GET  /old-users  301 /users
+//   STATIC  /assets  public/  {maxage=86400, index=index.html}
+//   MOUNT  /metrics  metrics
+//   GET|POST  /login  Auth.Login
+type routeDecl struct {
+	method, path, action, fixedArgs string
+	formats                         []string          // e.g. "html"
+	name                            string            // e.g. "users.new"
+	filters                         []string          // e.g. "auth"
+	options                         map[string]string // e.g. {priority: 10}
+	redirect                        string            // e.g. "/users", with a redirect status as the action
+	static                          string            // e.g. "public/", with "STATIC" as the action
+	mount                           string            // e.g. "metrics", with "MOUNT" as the action
+	status                          int               // e.g. 404, with "ERROR" as the method
+	methods                         []string          // e.g. "GET", "POST", if several are listed
+}
+
+// parseRouteDecl parses a route line, including the optional name, filters,
+// and options that follow the action.
+func parseRouteDecl(line string) (decl routeDecl, found bool) {
+	if rest, options, ok := parseRouteSuffix(line, '{', '}'); ok {
+		line, decl.options = rest, parseRouteOptions(options)
+	}
+	if rest, filters, ok := parseRouteSuffix(line, '[', ']'); ok {
+		line, decl.filters = rest, splitNames(filters)
+	}
+	line, decl.name = parseRouteName(line)
+	if matches := staticRoutePattern.FindStringSubmatch(line); matches != nil {
+		decl.method, decl.action, decl.static = "GET", "STATIC", matches[2]
+		decl.path = joinRoutePath(matches[1], "/*filepath")
+		return decl, true
+	}
+	if matches := mountRoutePattern.FindStringSubmatch(line); matches != nil {
+		decl.method, decl.action, decl.mount = "*", "MOUNT", matches[2]
+		decl.path = mountPath(matches[1])
+		return decl, true
+	}
+	if matches := errorRoutePattern.FindStringSubmatch(line); matches != nil {
+		decl.method, decl.path, decl.action = "ERROR", matches[2], matches[3]
+		decl.status, _ = strconv.Atoi(matches[1])
+		return decl, true
+	}
+	if matches := routeFormatsPattern.FindStringSubmatchIndex(line); matches != nil {
+		decl.formats = splitNames(strings.ToLower(line[matches[2]:matches[3]]))
+		line = line[:matches[0]] + " " + line[matches[1]:]
+	}
+	if matches := routeRedirectPattern.FindStringSubmatch(line); matches != nil {
+		line, decl.redirect = matches[1]+" "+matches[2], matches[3]
+	}
+	if matches := routeMethodsPattern.FindStringSubmatch(line); matches != nil {
+		decl.methods = strings.FieldsFunc(matches[1], func(r rune) bool { return r == '|' || r == ',' })
+		line = decl.methods[0] + matches[2]
+	}
+	decl.method, decl.path, decl.action, decl.fixedArgs, found = parseRouteLine(line)
+	return
+}
+
+// routeFormatsPattern matches the list of formats between a route's path and
+// action, e.g. " [json, xml] "
+var routeFormatsPattern = regexp.MustCompile(`[ \t]+\[([^\]]*)\][ \t]+`)
+
+// Groups:
+// 1: methods, separated by '|' or ','
+// 2: the rest of the route
+var routeMethodsPattern = regexp.MustCompile(
+	`(?i)^((?:GET|POST|PUT|DELETE|PATCH|OPTIONS|HEAD|WS)(?:[|,](?:GET|POST|PUT|DELETE|PATCH|OPTIONS|HEAD|WS))+)([ \t].*)$`)
+
+// Groups:
+// 1: path prefix
+// 2: directory
+var staticRoutePattern = regexp.MustCompile(`(?i)^STATIC[ \t]+(/[^ \t]*)[ \t]+([^ \t]+)$`)
+
+// Groups:
+// 1: path prefix
+// 2: handler name
+var mountRoutePattern = regexp.MustCompile(`(?i)^MOUNT[ \t]+(/[^ \t]*)[ \t]+([^ \t]+)$`)
+
+// mountPath returns the path of a mount route, which matches the prefix and
+// any path under it, given to the handler as "mountpath".
+// e.g. "/metrics" => "/metrics(/*mountpath)"
+func mountPath(prefix string) string {
+	prefix = strings.TrimRight(prefix, "/")
+	if prefix == "" {
+		return "/*mountpath"
+	}
+	return prefix + "(/*mountpath)"
+}
+
+// Groups:
+// 1: status code
+// 2: path, e.g. "/api/*" for any path under /api
+// 3: action
+var errorRoutePattern = regexp.MustCompile(`(?i)^ERROR[ \t]+([1-5][0-9][0-9])[ \t]+(/[^ \t]*)[ \t]+([^ \t]+)$`)
+
+// Groups:
+// 1: the route, without its redirect
+// 2: status code
+// 3: target path or action
+var routeRedirectPattern = regexp.MustCompile(`^(.*[^ \t])[ \t]+(30[12378])[ \t]+([^ \t]+)$`)
+
+// parseRouteSuffix separates a bracketed list at the end of a route line from
+// the rest of it.
+// e.g. ("GET / App.Index [auth,csrf]", '[', ']') => "GET / App.Index", "auth,csrf"
+func parseRouteSuffix(line string, open, close byte) (rest, list string, found bool) {
+	if len(line) == 0 || line[len(line)-1] != close {
+		return line, "", false
+	}
+	start := strings.LastIndex(line, string(open))
+	if start <= 0 || (line[start-1] != ' ' && line[start-1] != '\t') {
+		return line, "", false
+	}
+	return strings.TrimSpace(line[:start]), line[start+1 : len(line)-1], true
+}
+
+// parseRouteOptions parses a comma-separated list of route options.
+// e.g. "priority=10, trailingSlash=strip" => {priority: 10, trailingSlash: strip}
+func parseRouteOptions(list string) map[string]string {
+	options := make(map[string]string)
+	for _, option := range splitNames(list) {
+		pair := strings.SplitN(option, "=", 2)
+		if len(pair) == 1 {
+			pair = append(pair, "")
+		}
+		options[strings.TrimSpace(pair[0])] = strings.TrimSpace(pair[1])
+	}
+	return options
+}
+
+// Groups:
+// 1: the route, without its name
+// 2: name
+var routeNamePattern = regexp.MustCompile(`^(.*[^ \t])[ \t]+as[ \t]+([^ \t]+)$`)
+
+// parseRouteName separates the route's name from the rest of the line.
+// e.g. "GET /users/:id Users.Show as users.show" => "GET /users/:id Users.Show", "users.show"
+func parseRouteName(line string) (string, string) {
+	matches := routeNamePattern.FindStringSubmatch(line)
+	if matches == nil {
+		return line, ""
+	}
+	return matches[1], matches[2]
+}
+
+// splitNames splits a comma-separated list of names, ignoring whitespace.
+func splitNames(list string) []string {
+	var names []string
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
 func NewRouter(routesPath string) *Router {
 	return &Router{
 		Tree: pathtree.New(),
@@ -332,16 +2312,47 @@ func NewRouter(routesPath string) *Router {
 }
 
 type ActionDefinition struct {
-	Host, Method, Url, Action string
-	Star                      bool
-	Args                      map[string]string
+	Scheme, Host, Method, Url, Action string
+	Star                              bool
+	Args                              map[string]string
 }
 
 func (a *ActionDefinition) String() string {
 	return a.Url
 }
 
+// AbsoluteURL returns the full URL of the action, including the scheme and
+// host.  e.g. "https://example.com/users/1"
+func (a *ActionDefinition) AbsoluteURL() string {
+	if a.Host == "" {
+		return a.Url
+	}
+	scheme := a.Scheme
+	if scheme == "" {
+		scheme = "http"
+	}
+	return scheme + "://" + a.Host + a.Url
+}
+
 func (router *Router) Reverse(action string, argValues map[string]string) *ActionDefinition {
+	return router.reverse(action, argValues, "")
+}
+
+// ReverseWebSocket is like Reverse, but considers only the websocket (WS)
+// routes, whose URLs have the "ws" scheme, or "wss" if the app is served over
+// https.  Routes without a host are given the app's (http.host), so that
+// AbsoluteURL returns the URL for a client to connect to.
+func (router *Router) ReverseWebSocket(action string, argValues map[string]string) *ActionDefinition {
+	actionDef := router.reverse(action, argValues, "WS")
+	if actionDef != nil && actionDef.Host == "" {
+		actionDef.Host = hostWithPort(HttpHost, actionDef.Scheme)
+	}
+	return actionDef
+}
+
+// reverse returns the URL and method to reach the action with the arguments,
+// considering only the routes with the given method, if any.
+func (router *Router) reverse(action string, argValues map[string]string, method string) *ActionDefinition {
 	actionSplit := strings.Split(action, ".")
 	if len(actionSplit) != 2 {
 		ERROR.Print("revel/router: reverse router got invalid action ", action)
@@ -349,29 +2360,22 @@ func (router *Router) Reverse(action string, argValues map[string]string) *Actio
 	}
 	controllerName, methodName := actionSplit[0], actionSplit[1]
 
-	for _, route := range router.Routes {
-		// Skip routes without either a ControllerName or MethodName
-		if route.ControllerName == "" || route.MethodName == "" {
-			continue
-		}
-
-		// Check that the action matches or is a wildcard.
-		controllerWildcard := strings.LastIndex(route.ControllerName, ":")
-		methodWildcard := strings.LastIndex(route.MethodName, ":")
-		if (controllerWildcard == -1 && route.ControllerName != controllerName) ||
-			(methodWildcard == -1 && route.MethodName != methodName) {
-			continue
-		}
-		// Check prefix excists and matchs
-		if (controllerWildcard > 0 && len(route.ControllerName) <= controllerWildcard) ||
-			(methodWildcard > 0 && len(route.MethodName) <= methodWildcard) {
+	t := router.load()
+	candidates, ok := t.actions[controllerName+"."+methodName]
+	if !ok {
+		candidates = t.wildcards
+	}
+	for _, route := range candidates {
+		if !route.reverses(controllerName, methodName) || (method != "" && route.Method != method) {
 			continue
 		}
-		if (controllerWildcard > 0 && route.ControllerName[:controllerWildcard] != controllerName[:controllerWildcard]) ||
-			(methodWildcard > 0 && route.MethodName[:methodWildcard] != methodName[:methodWildcard]) {
+		// Skip routes whose constraints reject the given arguments.
+		if !route.acceptsArgs(argValues) {
 			continue
 		}
 		// Insert origional methods/function
+		controllerWildcard := strings.LastIndex(route.ControllerName, ":")
+		methodWildcard := strings.LastIndex(route.MethodName, ":")
 		if controllerWildcard != -1 {
 			argValues[route.ControllerName[controllerWildcard+1:]] = controllerName[controllerWildcard:]
 		}
@@ -379,48 +2383,356 @@ func (router *Router) Reverse(action string, argValues map[string]string) *Actio
 			argValues[route.MethodName[methodWildcard+1:]] = methodName[methodWildcard:]
 		}
 
-		// Get the path for the route and generate the url
-		queryValues := make(url.Values)
-		path, unusedValues, missing := router.Tree.Reverse(route.leaf, argValues)
-		_, url := untreePath(path)
+		return router.actionDefinition(route, action, argValues)
+	}
+	ERROR.Println("Failed to find reverse route:", action, argValues)
+	return nil
+}
+
+// reverses returns true if the route's action is the given one, or a
+// wildcard that matches it, e.g. ":controller.Show" for "Users.Show".
+func (route *Route) reverses(controllerName, methodName string) bool {
+	// Skip routes without either a ControllerName or MethodName
+	if route.ControllerName == "" || route.MethodName == "" {
+		return false
+	}
+
+	// Check that the action matches or is a wildcard.
+	controllerWildcard := strings.LastIndex(route.ControllerName, ":")
+	methodWildcard := strings.LastIndex(route.MethodName, ":")
+	if (controllerWildcard == -1 && route.ControllerName != controllerName) ||
+		(methodWildcard == -1 && route.MethodName != methodName) {
+		return false
+	}
+	// Check prefix excists and matchs
+	if (controllerWildcard > 0 && len(route.ControllerName) <= controllerWildcard) ||
+		(methodWildcard > 0 && len(route.MethodName) <= methodWildcard) {
+		return false
+	}
+	if (controllerWildcard > 0 && route.ControllerName[:controllerWildcard] != controllerName[:controllerWildcard]) ||
+		(methodWildcard > 0 && route.MethodName[:methodWildcard] != methodName[:methodWildcard]) {
+		return false
+	}
+	return true
+}
+
+// checkReferences returns an error for the first action referenced by the
+// templates or the controllers' redirects that has no route.
+func (t *routeTable) checkReferences() *Error {
+	for _, ref := range actionReferences() {
+		found := false
+		if actionSplit := strings.Split(ref.Action, "."); len(actionSplit) == 2 {
+			for _, route := range t.routes {
+				if route.reverses(actionSplit[0], actionSplit[1]) {
+					found = true
+					break
+				}
+			}
+		}
+		if !found {
+			return routeError(fmt.Errorf("No route for action %s", ref.Action), ref.File, "", ref.Line-1)
+		}
+	}
+	return nil
+}
+
+// templateUrlPattern matches the action given to the url and websocketUrl
+// template functions.
+// e.g. {{url "Users.Show" .user.Id}}
+var templateUrlPattern = regexp.MustCompile(`\b(?:url|websocketUrl)[ \t]+"([^"]+)"`)
+
+// actionReferences returns the actions referenced by the registered
+// controllers' redirects, and by the templates in TemplatePaths.
+func actionReferences() []ActionReference {
+	var names []string
+	for name := range controllers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var refs []ActionReference
+	for _, name := range names {
+		for _, method := range controllers[name].Methods {
+			refs = append(refs, method.Redirects...)
+		}
+	}
+
+	for _, basePath := range TemplatePaths {
+		filepath.Walk(basePath, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return nil
+			}
+			if strings.HasPrefix(info.Name(), ".") {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if info.IsDir() {
+				return nil
+			}
+			content, err := ioutil.ReadFile(path)
+			if err != nil {
+				ERROR.Println("Failed reading file:", path)
+				return nil
+			}
+			for n, line := range strings.Split(string(content), "\n") {
+				for _, matches := range templateUrlPattern.FindAllStringSubmatch(line, -1) {
+					refs = append(refs, ActionReference{Action: matches[1], File: path, Line: n + 1})
+				}
+			}
+			return nil
+		})
+	}
+	return refs
+}
+
+// ReverseByName returns the URL and method of the route with the given name,
+// e.g. "users.show" for the route:
+//   GET  /users/:id  Users.Show  as users.show
+// Returns nil if there is no route by that name.
+func (router *Router) ReverseByName(name string, argValues map[string]string) *ActionDefinition {
+	if route, ok := router.load().names[name]; ok {
+		return router.actionDefinition(route, route.Action, argValues)
+	}
+	ERROR.Println("Failed to find route named:", name, argValues)
+	return nil
+}
+
+// actionDefinition generates the URL and method to reach the route, given the
+// arguments.  Any arguments that do not appear in the path are added to the
+// query string.
+func (router *Router) actionDefinition(route *Route, action string, argValues map[string]string) *ActionDefinition {
+	// Get the path for the route and generate the url
+	queryValues := make(url.Values)
+	url, unusedValues, missing := route.reverse(argValues)
+
+	// Add the route's version.
+	if route.Version > 0 {
+		switch router.Versioning {
+		case "header":
+		case "query":
+			queryValues.Set("v", strconv.Itoa(route.Version))
+			delete(unusedValues, "v")
+		default:
+			url = "/v" + strconv.Itoa(route.Version) + strings.TrimSuffix(url, "/")
+			if strings.HasSuffix(route.Path, "/") && len(route.Path) > 1 {
+				url += "/"
+			}
+		}
+	}
+
+	// Add the locale prefix, if requested.
+	if locale := argValues["locale"]; locale != "" && containsString(router.Locales, locale) && !route.hasArg("locale") {
+		url = joinRoutePath("/"+locale, url)
+		delete(unusedValues, "locale")
+	}
 
-		if missing != nil {
-			ERROR.Print("revel/router: reverse route missing route args %+v", missing)
+	// Add the route's required query parameters.
+	for key, values := range route.Query {
+		for _, value := range values {
+			if value == "" {
+				value = unusedValues[key]
+			}
+			queryValues.Add(key, value)
 		}
+		delete(unusedValues, key)
+	}
+	host := HttpHost
+	if route.host != nil {
+		var missingHost []string
+		host, missingHost = route.reverseHost(argValues, unusedValues)
+		missing = append(missing, missingHost...)
+	}
+
+	if missing != nil {
+		ERROR.Print("revel/router: reverse route missing route args %+v", missing)
+	}
+
+	// Add any args that were not inserted into the path into the query string.
+	for k, v := range unusedValues {
+		queryValues.Set(k, v)
+	}
+
+	// Calculate the final URL and Method
+	if len(queryValues) > 0 {
+		url += "?" + queryValues.Encode()
+	}
+
+	method := route.Method
+	star := false
+	if route.Method == "*" {
+		method = "GET"
+		star = true
+	}
 
-		// Add any args that were not inserted into the path into the query string.
-		for k, v := range unusedValues {
-			queryValues.Set(k, v)
+	scheme := HttpScheme
+	if method == "WS" {
+		scheme = "ws"
+		if HttpScheme == "https" {
+			scheme = "wss"
 		}
+	}
 
-		// Calculate the final URL and Method
-		if len(queryValues) > 0 {
-			url += "?" + queryValues.Encode()
+	return &ActionDefinition{
+		Url:    url,
+		Method: method,
+		Star:   star,
+		Action: action,
+		Args:   argValues,
+		Scheme: scheme,
+		Host:   hostWithPort(host, scheme),
+	}
+}
+
+// hostWithPort adds the app's port to the host, unless it is the default port
+// for the scheme or the host already has one.
+func hostWithPort(host, scheme string) string {
+	if host == "" || HttpPort == 0 || strings.Contains(host, ":") {
+		return host
+	}
+	switch {
+	case HttpPort == 80 && (scheme == "http" || scheme == "ws"),
+		HttpPort == 443 && (scheme == "https" || scheme == "wss"):
+		return host
+	}
+	return fmt.Sprintf("%s:%d", host, HttpPort)
+}
+
+// reverseHost fills in the parameters of the route's host pattern, removing
+// those used from unused.
+func (route *Route) reverseHost(argValues, unused map[string]string) (host string, missing []string) {
+	labels := make([]string, len(route.host))
+	for i, label := range route.host {
+		if label[0] != ':' {
+			labels[i] = label
+			continue
 		}
+		value, ok := argValues[label[1:]]
+		if !ok {
+			missing = append(missing, label[1:])
+		}
+		delete(unused, label[1:])
+		labels[i] = value
+	}
+	return strings.Join(labels, "."), missing
+}
+
+// reverse returns the path of the route with the given arguments inserted,
+// along with the arguments that were not used and the names of any parameters
+// that were not provided.
+func (route *Route) reverse(argValues map[string]string) (path string, unused map[string]string, missing []string) {
+	unused = make(map[string]string, len(argValues))
+	for k, v := range argValues {
+		unused[k] = v
+	}
+
+	// Omit the optional segments and trailing parameters that have no
+	// values, or their default values.
+	form := route.reverseForm(argValues)
+	for _, arg := range form.omitted {
+		delete(unused, arg.name)
+	}
 
-		method := route.Method
-		star := false
-		if route.Method == "*" {
-			method = "GET"
-			star = true
+	var (
+		buf  bytes.Buffer
+		args = form.args
+	)
+	for i, el := range form.elements {
+		var a *arg
+		if isWildcard(el) {
+			a, args = args[0], args[1:]
+		}
+		// Skip the method.
+		if i == 0 {
+			continue
+		}
+		buf.WriteByte('/')
+		if a == nil {
+			buf.WriteString(el)
+			continue
+		}
+		value, ok := argValues[a.name]
+		if !ok && a.defaultValue != "" {
+			value, ok = a.defaultValue, true
 		}
+		if !ok {
+			missing = append(missing, el[1:])
+		}
+		delete(unused, el[1:])
+		if el[0] == '*' {
+			// The catch-all value is the rest of the path, e.g. "css/app.css".
+			value = strings.TrimPrefix(value, "/")
+		}
+		buf.WriteString(value)
+	}
+	if buf.Len() == 0 || (len(form.elements) == len(route.elements) && strings.HasSuffix(route.Path, "/")) {
+		buf.WriteByte('/')
+	}
+	return buf.String(), unused, missing
+}
 
-		return &ActionDefinition{
-			Url:    url,
-			Method: method,
-			Star:   star,
-			Action: action,
-			Args:   argValues,
-			Host:   "TODO",
+// reverseForm returns the form of the route to reverse to: the first, from the
+// last, that omits only parameters without values, or with their default
+// values.  The last forms omit the most.
+func (route *Route) reverseForm(argValues map[string]string) *routeForm {
+	if len(route.forms) == 0 {
+		return &routeForm{route: route}
+	}
+	for i := len(route.forms) - 1; i > 0; i-- {
+		form, ok := route.forms[i], true
+		for _, arg := range form.omitted {
+			if value, given := argValues[arg.name]; given && value != arg.defaultValue {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return form
 		}
 	}
-	ERROR.Println("Failed to find reverse route:", action, argValues)
-	return nil
+	return route.forms[0]
+}
+
+// acceptsArgs returns true if none of the given arguments are rejected by the
+// route's constraints.
+func (route *Route) acceptsArgs(argValues map[string]string) bool {
+	for _, arg := range route.args {
+		if value, ok := argValues[arg.name]; ok && arg.constraint != nil && !arg.constraint.MatchString(value) {
+			return false
+		}
+	}
+	return true
 }
 
 func init() {
 	OnAppStart(func() {
 		MainRouter = NewRouter(path.Join(BasePath, "conf", "routes"))
+		MainRouter.TrailingSlash = Config.StringDefault("routes.trailingSlash", "strip")
+		if !trailingSlashPolicies[MainRouter.TrailingSlash] {
+			ERROR.Println("revel/router: invalid routes.trailingSlash:", MainRouter.TrailingSlash)
+			MainRouter.TrailingSlash = "strip"
+		}
+		MainRouter.MethodOverride = Config.BoolDefault("routes.methodOverride", false)
+		MainRouter.Versioning = Config.StringDefault("routes.versioning", "path")
+		if !versioningPolicies[MainRouter.Versioning] {
+			ERROR.Println("revel/router: invalid routes.versioning:", MainRouter.Versioning)
+			MainRouter.Versioning = "path"
+		}
+		MainRouter.ValidateReferences = Config.BoolDefault("routes.validateReferences", false)
+		MainRouter.StrictConflicts = Config.BoolDefault("routes.strictConflicts", false)
+		MainRouter.Locales = splitNames(Config.StringDefault("routes.locales", ""))
+		MainRouter.CacheSize = Config.IntDefault("routes.cacheSize", 0)
+		MainRouter.EncodedSlashes = Config.StringDefault("routes.encodedSlashes", "decode")
+		if !encodedSlashPolicies[MainRouter.EncodedSlashes] {
+			ERROR.Println("revel/router: invalid routes.encodedSlashes:", MainRouter.EncodedSlashes)
+			MainRouter.EncodedSlashes = "decode"
+		}
+		MainRouter.Case = Config.StringDefault("routes.case", "sensitive")
+		if !casePolicies[MainRouter.Case] {
+			ERROR.Println("revel/router: invalid routes.case:", MainRouter.Case)
+			MainRouter.Case = "sensitive"
+		}
 		if MainWatcher != nil && Config.BoolDefault("watch.routes", true) {
 			MainWatcher.Listen(MainRouter, MainRouter.path)
 		} else {
@@ -429,6 +2741,75 @@ func init() {
 	})
 }
 
+// ErrorRoute returns the first ERROR route that handles errors with the status
+// for the path, or nil if there is none.  For example:
+//   ERROR  404  /api/*  Api.NotFound
+// handles 404s for "/api" and any path under it, while
+//   ERROR  500  /about  Pages.AboutError
+// handles 500s for "/about" only.
+func (router *Router) ErrorRoute(status int, path string) *Route {
+	for _, route := range router.load().errorRoutes {
+		if route.ErrorStatus == status && route.handlesErrorsFor(path) {
+			return route
+		}
+	}
+	return nil
+}
+
+// handlesErrorsFor returns true if the ERROR route's path covers the path.
+func (route *Route) handlesErrorsFor(path string) bool {
+	return pathCovers(route.Path, path)
+}
+
+// pathCovers returns true if the path is the pattern, or if the pattern ends
+// in "/*" and the path is under it.  e.g. "/admin/*" covers "/admin" and
+// "/admin/users/:id".
+func pathCovers(pattern, path string) bool {
+	if !strings.HasSuffix(pattern, "/*") {
+		return path == pattern
+	}
+	prefix := strings.TrimSuffix(pattern, "*")
+	return strings.HasPrefix(path, prefix) || path+"/" == prefix
+}
+
+// handleErrorRoute replaces an error page with the result of the action of the
+// ERROR route for its status and the request's path, if there is one.  The
+// action is invoked without the filters, with the error in RenderArgs["Error"]
+// and the status already set.  If it panics or returns no result, the error
+// page is rendered as usual.
+func handleErrorRoute(c *Controller) {
+	result, ok := c.Result.(ErrorResult)
+	if !ok || MainRouter == nil {
+		return
+	}
+	status := c.Response.Status
+	if status == 0 {
+		status = http.StatusInternalServerError
+	}
+	_, path := MainRouter.requestLocale(c.Request.URL.Path)
+	route := MainRouter.ErrorRoute(status, path)
+	if route == nil {
+		return
+	}
+	if err := c.SetAction(route.ControllerName, route.MethodName); err != nil {
+		ERROR.Println("revel/router: failed to find error action:", err)
+		return
+	}
+
+	defer func() {
+		if err := recover(); err != nil {
+			ERROR.Print("revel/router: error action ", route.Action, " panicked: ", err, "\n", string(debug.Stack()))
+			c.Response.Status, c.Result = status, result
+		}
+	}()
+	c.Response.Status, c.Result = status, nil
+	c.RenderArgs["Error"] = result.Error
+	ActionInvoker(c, nil)
+	if c.Result == nil {
+		c.Result = result
+	}
+}
+
 func RouterFilter(c *Controller, fc []Filter) {
 	// Figure out the Controller/Action
 	var route *RouteMatch = MainRouter.Route(c.Request.Request)
@@ -443,6 +2824,55 @@ func RouterFilter(c *Controller, fc []Filter) {
 		return
 	}
 
+	// The route redirects, or the path differs from the route's (e.g. by a
+	// trailing slash).
+	if status, ok := redirectCodes[route.Action]; ok {
+		c.Result = &RedirectToUrlResult{url: route.Location, status: status}
+		return
+	}
+
+	// Reject requests over the route's rate limit.
+	if route.Route != nil && route.Route.limit != nil {
+		if wait := route.Route.limit.allow(c.Request.Request); wait > 0 {
+			c.Response.Out.Header().Set("Retry-After", retryAfter(wait))
+			c.Response.Status = http.StatusTooManyRequests
+			c.Result = c.RenderError(&Error{
+				Title:       "Too Many Requests",
+				Description: "Too many requests for " + c.Request.URL.Path + ", retry after " + retryAfter(wait) + "s",
+			})
+			return
+		}
+	}
+
+	// Static files are served directly, without invoking an action.
+	if route.Action == "STATIC" {
+		c.Route = route
+		c.Result = serveStatic(c, route.Route, url.Values(route.Params).Get("filepath"))
+		return
+	}
+
+	// Mounted handlers serve the request directly, without invoking an action.
+	if route.Action == "MOUNT" {
+		c.Route = route
+		c.Result = &mountResult{route.Route.handler, "/" + url.Values(route.Params).Get("mountpath")}
+		return
+	}
+
+	// The path is routed, but not for this method.
+	if route.Action == "405" || route.Action == "OPTIONS" {
+		c.Response.Out.Header().Set("Allow", strings.Join(route.Allowed, ", "))
+		if route.Action == "OPTIONS" {
+			c.Result = c.RenderText("")
+			return
+		}
+		c.Response.Status = http.StatusMethodNotAllowed
+		c.Result = c.RenderError(&Error{
+			Title:       "Method Not Allowed",
+			Description: c.Request.Method + " is not allowed for " + c.Request.URL.Path,
+		})
+		return
+	}
+
 	// Set the action.
 	if err := c.SetAction(route.ControllerName, route.MethodName); err != nil {
 		c.Result = c.NotFound(err.Error())
@@ -450,8 +2880,28 @@ func RouterFilter(c *Controller, fc []Filter) {
 	}
 
 	// Add the route and fixed params to the Request Params.
+	c.Route = route
 	c.Params.Route = route.Params
 
+	// Apply the route's limits on the request's duration and body size.
+	if timeout := route.Route.timeout; timeout > 0 {
+		// The result (e.g. a stream, or a deferred result) is applied with
+		// the context, once the filters have returned.
+		_, cancel := c.WithTimeout(timeout)
+		defer c.releaseAfterApply(cancel)()
+	}
+	if maxBody := route.Route.maxBody; maxBody > 0 && c.Request.Body != nil {
+		if c.Request.ContentLength > maxBody {
+			c.Response.Status = http.StatusRequestEntityTooLarge
+			c.Result = c.RenderError(&Error{
+				Title:       "Request Entity Too Large",
+				Description: fmt.Sprintf("The request body may be at most %d bytes", maxBody),
+			})
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Response.Out, c.Request.Body, maxBody)
+	}
+
 	// Add the fixed parameters mapped by name.
 	// TODO: Pre-calculate this mapping.
 	for i, value := range route.FixedParams {
@@ -469,3 +2919,47 @@ func RouterFilter(c *Controller, fc []Filter) {
 
 	fc[0](c, fc[1:])
 }
+
+// serveStatic serves a file from the directory of a static route.  A relative
+// directory is taken to be within the application.  Requests for a directory
+// are served its index file, if the route has one, e.g. {index=index.html}.
+func serveStatic(c *Controller, route *Route, file string) Result {
+	dir := filepath.FromSlash(route.Static)
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(BasePath, dir)
+	}
+	dir = filepath.Clean(dir)
+	fname := filepath.Join(dir, filepath.FromSlash(file))
+	if fname != dir && !strings.HasPrefix(fname, dir+string(filepath.Separator)) {
+		WARN.Printf("Attempted to read file outside of static directory: %s", fname)
+		return c.NotFound("")
+	}
+
+	finfo, err := os.Stat(fname)
+	if err == nil && finfo.IsDir() {
+		index := route.Meta["index"]
+		if index == "" {
+			WARN.Printf("Attempted directory listing of %s", fname)
+			return c.Forbidden("Directory listing not allowed")
+		}
+		fname = filepath.Join(fname, index)
+		finfo, err = os.Stat(fname)
+	}
+	if err != nil {
+		if os.IsNotExist(err) {
+			return c.NotFound("File not found")
+		}
+		ERROR.Printf("Error trying to get fileinfo for '%s': %s", fname, err)
+		return c.RenderError(err)
+	}
+
+	f, err := os.Open(fname)
+	if err != nil {
+		ERROR.Printf("Error opening '%s': %s", fname, err)
+		return c.RenderError(err)
+	}
+	if route.maxAge > 0 {
+		c.Response.Out.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", route.maxAge))
+	}
+	return c.RenderFile(f, Inline)
+}
@@ -2,9 +2,15 @@ package revel
 
 import (
 	"fmt"
+	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 // Data-driven tests that check that a given routes-file line translates into
@@ -290,6 +296,1905 @@ func TestReverseRouting(t *testing.T) {
 	}
 }
 
+func TestReverseIndex(t *testing.T) {
+	router := NewRouter("")
+	router.Routes, _ = parseRoutes("", `
+GET   /app/:action     Application.:action
+GET   /show            Application.Show
+GET   /users/:id       Users.Show         as users.show
+`, false)
+	router.updateTree()
+
+	// The wildcard route is declared first, so it is preferred.
+	eq(t, "Wildcard first", router.Reverse("Application.Show", map[string]string{}).Url, "/app/Show")
+	eq(t, "Wildcard only", router.Reverse("Application.Index", map[string]string{}).Url, "/app/Index")
+	eq(t, "Exact", router.Reverse("Users.Show", map[string]string{"id": "1"}).Url, "/users/1")
+	eq(t, "Unknown", router.Reverse("Users.Delete", map[string]string{}) == nil, true)
+	eq(t, "By name", router.ReverseByName("users.show", map[string]string{"id": "1"}).Url, "/users/1")
+	eq(t, "Candidates", len(router.load().actions["Application.Show"]), 2)
+}
+
+func TestReverseWebSocket(t *testing.T) {
+	defer func(scheme, host string, port int) {
+		HttpScheme, HttpHost, HttpPort = scheme, host, port
+	}(HttpScheme, HttpHost, HttpPort)
+	HttpScheme, HttpHost, HttpPort = "http", "localhost", 9000
+
+	router := NewRouter("")
+	router.Routes, _ = parseRoutes("", `
+GET   /chat/:room      Chat.Room
+WS    /chat/:room/ws   Chat.Socket
+`, false)
+	router.updateTree()
+
+	actionDef := router.ReverseWebSocket("Chat.Socket", map[string]string{"room": "lobby"})
+	if eq(t, "Found websocket route", actionDef != nil, true) {
+		eq(t, "Method", actionDef.Method, "WS")
+		eq(t, "Url", actionDef.Url, "/chat/lobby/ws")
+		eq(t, "AbsoluteURL", actionDef.AbsoluteURL(), "ws://localhost:9000/chat/lobby/ws")
+	}
+	eq(t, "Only websocket routes", router.ReverseWebSocket("Chat.Room", map[string]string{"room": "lobby"}) == nil, true)
+
+	HttpScheme, HttpPort = "https", 443
+	eq(t, "Secure AbsoluteURL",
+		router.ReverseWebSocket("Chat.Socket", map[string]string{"room": "lobby"}).AbsoluteURL(),
+		"wss://localhost/chat/lobby/ws")
+}
+
+const TEST_GROUP_ROUTES = `
+GET   /                          Application.Index
+group /api/v1 {
+  GET   /users                   Users.List
+  group /admin ("admin") {
+    GET   /users/:id             Users.Show
+  }
+}
+`
+
+func TestControllerPrefix(t *testing.T) {
+	defer delete(controllerPrefixes, "fakecontroller")
+	eq(t, "Prefix", Prefix(&FakeController{}, "/api/v2"), "/api/v2")
+
+	router := NewRouter("")
+	router.Routes, _ = parseRoutes("", `
+GET    /                       FakeController.Index
+GET    /users/:id              FakeController.Show
+GET    admin.example.com/ /me  FakeController.Me
+ERROR  404 /api/*              FakeController.NotFound
+group /tenants/:tenant {
+  GET  /users                  FakeController.List
+}
+GET    /users                  Users.List
+`, false)
+	router.updateTree()
+	router.Group("/").Add("POST", "/users", "FakeController.Create")
+
+	for _, test := range []struct {
+		method, host, path, action string
+	}{
+		{"GET", "", "/api/v2", "FakeController.Index"},
+		{"GET", "", "/api/v2/users/1", "FakeController.Show"},
+		{"GET", "admin.example.com", "/api/v2/me", "FakeController.Me"},
+		{"GET", "", "/tenants/acme/api/v2/users", "FakeController.List"},
+		{"POST", "", "/api/v2/users", "FakeController.Create"},
+		{"GET", "", "/users", "Users.List"},
+	} {
+		req, _ := http.NewRequest(test.method, "http://"+test.host+test.path, nil)
+		match := router.Route(req)
+		if eq(t, "Found route for "+test.path, match != nil && match.Route != nil, true) {
+			eq(t, "Action for "+test.path, match.Route.Action, test.action)
+		}
+	}
+	eq(t, "Reverse", router.Reverse("FakeController.Show", map[string]string{"id": "1"}).Url, "/api/v2/users/1")
+	eq(t, "Error route", router.ErrorRoute(404, "/api/users") != nil, true)
+}
+
+func TestRouteGroups(t *testing.T) {
+	router := NewRouter("")
+	router.Routes, _ = parseRoutes("", TEST_GROUP_ROUTES, false)
+	router.updateTree()
+
+	api := router.Group("/api/v2/")
+	api.Add("GET", "/users", `Users.List("v2")`)
+
+	for _, test := range []struct {
+		path, methodName string
+		fixedParams      []string
+	}{
+		{"/", "Index", nil},
+		{"/api/v1/users", "List", nil},
+		{"/api/v1/admin/users/1", "Show", []string{"admin"}},
+		{"/api/v2/users", "List", []string{"v2"}},
+	} {
+		actual := router.Route(&http.Request{Method: "GET", URL: &url.URL{Path: test.path}})
+		if !eq(t, "Found route "+test.path, actual != nil, true) {
+			continue
+		}
+		eq(t, "MethodName", actual.MethodName, test.methodName)
+		if eq(t, "len(FixedParams)", len(actual.FixedParams), len(test.fixedParams)) {
+			for i, actualValue := range actual.FixedParams {
+				eq(t, "FixedParams", actualValue, test.fixedParams[i])
+			}
+		}
+	}
+
+	if actual := router.Reverse("Users.Show", map[string]string{"id": "1"}); actual != nil {
+		eq(t, "Url", actual.Url, "/api/v1/admin/users/1")
+	} else {
+		t.Error("Failed to reverse Users.Show")
+	}
+
+	// Added routes are kept across reloads of the routes file.
+	if len(router.added) != 1 {
+		t.Errorf("Expected 1 added route, got %d", len(router.added))
+	}
+
+	// Unbalanced braces are reported.
+	if _, err := parseRoutes("", "group /api {\nGET / A.B\n", false); err == nil {
+		t.Error("Expected an error for an unclosed group")
+	}
+	if _, err := parseRoutes("", "GET / A.B\n}\n", false); err == nil {
+		t.Error("Expected an error for an unopened group")
+	}
+}
+
+const TEST_CONSTRAINT_ROUTES = `
+GET   /users/:id([0-9]+)             Users.Show
+GET   /users/:name                   Users.ByName
+GET   /docs/:name([a-z]+(\.txt)?)    Docs.Show
+*     /:controller/:action           :controller.:action
+`
+
+func TestRouteConstraints(t *testing.T) {
+	router := NewRouter("")
+	router.Routes, _ = parseRoutes("", TEST_CONSTRAINT_ROUTES, false)
+	router.updateTree()
+
+	for _, test := range []struct {
+		path, methodName, param, value string
+	}{
+		{"/users/123", "Show", "id", "123"},
+		{"/users/bob", "ByName", "name", "bob"},
+		{"/docs/readme.txt", "Show", "name", "readme.txt"},
+		{"/docs/README", "README", "controller", "docs"},
+	} {
+		actual := router.Route(&http.Request{Method: "GET", URL: &url.URL{Path: test.path}})
+		if !eq(t, "Found route "+test.path, actual != nil, true) {
+			continue
+		}
+		eq(t, "MethodName", actual.MethodName, test.methodName)
+		eq(t, "Param "+test.param, url.Values(actual.Params).Get(test.param), test.value)
+	}
+
+	// Reverse routing skips routes whose constraints reject the arguments.
+	if actual := router.Reverse("Users.Show", map[string]string{"id": "123"}); actual != nil {
+		eq(t, "Url", actual.Url, "/users/123")
+	} else {
+		t.Error("Failed to reverse Users.Show")
+	}
+	if actual := router.Reverse("Users.Show", map[string]string{"id": "bob"}); actual != nil {
+		eq(t, "Url", actual.Url, "/Users/Show?id=bob")
+	} else {
+		t.Error("Failed to reverse Users.Show to the catch-all route")
+	}
+
+	// Invalid constraints are reported.
+	if _, err := parseRoutes("", "GET /users/:id([0-9+ Users.Show", false); err == nil {
+		t.Error("Expected an error for an unbalanced constraint")
+	}
+	if _, err := parseRoutes("", "GET /users/:id([0-9]++) Users.Show", false); err == nil {
+		t.Error("Expected an error for an invalid constraint")
+	}
+}
+
+const TEST_TYPED_ROUTES = `
+GET   /posts/:id<int>                Posts.Show
+GET   /posts/:uid<uuid>              Posts.ShowByUid
+GET   /posts/:slug<slug>             Posts.ShowBySlug
+GET   /posts/:other                  404
+`
+
+func TestTypedRouteConstraints(t *testing.T) {
+	router := NewRouter("")
+	var err *Error
+	router.Routes, err = parseRoutes("", TEST_TYPED_ROUTES, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	router.updateTree()
+
+	for _, test := range []struct {
+		path, methodName, param, value string
+	}{
+		{"/posts/42", "Show", "id", "42"},
+		{"/posts/-1", "Show", "id", "-1"},
+		{"/posts/1b4e28ba-2fa1-11d2-883f-0016d3cca427", "ShowByUid", "uid", "1b4e28ba-2fa1-11d2-883f-0016d3cca427"},
+		{"/posts/hello-world", "ShowBySlug", "slug", "hello-world"},
+		{"/posts/Hello_World", "", "", ""},
+	} {
+		actual := router.Route(&http.Request{Method: "GET", URL: &url.URL{Path: test.path}})
+		if !eq(t, "Found route "+test.path, actual != nil, true) {
+			continue
+		}
+		eq(t, "MethodName", actual.MethodName, test.methodName)
+		eq(t, "Param "+test.param, url.Values(actual.Params).Get(test.param), test.value)
+	}
+
+	if _, err := parseRoutes("", "GET /posts/:id<integer> Posts.Show", false); err == nil {
+		t.Error("Expected an error for an unknown type")
+	}
+}
+
+var TEST_FILTER_ROUTES = `
+GET  /              Application.Index
+GET  /admin         Admin.Index         [auth]
+group /api [auth] {
+  POST /users       Users.Create        [csrf, auth2]
+  GET  /users       Users.List
+}
+`
+
+func TestRouteFilters(t *testing.T) {
+	var calls []string
+	namedFilter := func(name string) Filter {
+		return func(c *Controller, fc []Filter) {
+			calls = append(calls, name)
+			if len(fc) > 0 {
+				fc[0](c, fc[1:])
+			}
+		}
+	}
+	for _, name := range []string{"auth", "auth2", "csrf"} {
+		NamedFilters[name] = namedFilter(name)
+		defer delete(NamedFilters, name)
+	}
+
+	router := NewRouter("")
+	var err *Error
+	router.Routes, err = parseRoutes("", TEST_FILTER_ROUTES, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	router.updateTree()
+
+	for _, test := range []struct {
+		method, path string
+		filters      []string
+	}{
+		{"GET", "/", nil},
+		{"GET", "/admin", []string{"auth"}},
+		{"POST", "/api/users", []string{"auth", "csrf", "auth2"}},
+		{"GET", "/api/users", []string{"auth"}},
+	} {
+		route := router.Route(&http.Request{Method: test.method, URL: &url.URL{Path: test.path}})
+		if !eq(t, "Found route "+test.path, route != nil, true) {
+			continue
+		}
+
+		calls = nil
+		c := &Controller{Route: route}
+		FilterConfiguringFilter(c, []Filter{namedFilter("invoker")})
+		eq(t, "Filters for "+test.method+" "+test.path,
+			strings.Join(calls, ","), strings.Join(append(test.filters, "invoker"), ","))
+	}
+
+	if _, err := parseRoutes("", "GET / Application.Index [nosuchfilter]", false); err == nil {
+		t.Error("Expected an error for an unknown filter")
+	}
+}
+
+var TEST_NAMED_ROUTES = `
+GET  /users/:id        Users.Show        as users.show
+GET  /admin/users/:id  Users.Show        as admin.users.show [auth]
+GET  /                 Application.Index
+`
+
+func TestNamedRoutes(t *testing.T) {
+	NamedFilters["auth"] = NilFilter
+	defer delete(NamedFilters, "auth")
+
+	router := NewRouter("")
+	var err *Error
+	router.Routes, err = parseRoutes("", TEST_NAMED_ROUTES, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	router.updateTree()
+
+	eq(t, "Route name", router.Routes[0].Name, "users.show")
+	eq(t, "Route name", router.Routes[1].Name, "admin.users.show")
+	eq(t, "Route name", router.Routes[2].Name, "")
+	eq(t, "Route filters", len(router.Routes[1].Filters), 1)
+
+	for name, expected := range map[string]string{
+		"users.show":       "/users/123?page=2",
+		"admin.users.show": "/admin/users/123?page=2",
+	} {
+		actual := router.ReverseByName(name, map[string]string{"id": "123", "page": "2"})
+		if !eq(t, "Found route "+name, actual != nil, true) {
+			continue
+		}
+		eq(t, "Url", actual.Url, expected)
+		eq(t, "Action", actual.Action, "Users.Show")
+	}
+
+	if actual := router.ReverseByName("users.missing", map[string]string{}); actual != nil {
+		t.Error("Expected no route for an unknown name, got", actual.Url)
+	}
+
+	if _, err := parseRoutes("", "GET /a A.B as x\nGET /b A.C as x", false); err == nil {
+		t.Error("Expected an error for a duplicate route name")
+	}
+}
+
+var TEST_HOST_ROUTES = `
+GET  admin.example.com/   /dashboard  Admin.Index
+GET  :tenant.example.com/ /dashboard  Tenants.Dashboard
+GET  /dashboard                       Application.Dashboard
+group :tenant.example.com/ /api {
+  GET  /users                         Users.List
+}
+`
+
+func TestHostRoutes(t *testing.T) {
+	router := NewRouter("")
+	var err *Error
+	router.Routes, err = parseRoutes("", TEST_HOST_ROUTES, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	router.updateTree()
+
+	eq(t, "Host", router.Routes[0].Host, "admin.example.com")
+	eq(t, "Path", router.Routes[0].Path, "/dashboard")
+
+	for _, test := range []struct {
+		host, path, action, tenant string
+	}{
+		{"admin.example.com", "/dashboard", "Admin.Index", ""},
+		{"Admin.Example.com:9000", "/dashboard", "Admin.Index", ""},
+		{"acme.example.com", "/dashboard", "Tenants.Dashboard", "acme"},
+		{"example.com", "/dashboard", "Application.Dashboard", ""},
+		{"localhost:9000", "/dashboard", "Application.Dashboard", ""},
+		{"acme.example.com", "/api/users", "Users.List", "acme"},
+		{"localhost", "/api/users", "", ""},
+	} {
+		req := &http.Request{Method: "GET", Host: test.host, URL: &url.URL{Path: test.path}}
+		actual := router.Route(req)
+		if test.action == "" {
+			if actual != nil {
+				t.Errorf("Expected no route for %s%s, got %s.%s",
+					test.host, test.path, actual.ControllerName, actual.MethodName)
+			}
+			continue
+		}
+		if !eq(t, "Found route "+test.host+test.path, actual != nil, true) {
+			continue
+		}
+		eq(t, "Action", actual.ControllerName+"."+actual.MethodName, test.action)
+		eq(t, "Param tenant", url.Values(actual.Params).Get("tenant"), test.tenant)
+	}
+
+	actual := router.Reverse("Users.List", map[string]string{"tenant": "acme"})
+	if eq(t, "Found reverse route", actual != nil, true) {
+		eq(t, "Host", actual.Host, hostWithPort("acme.example.com", actual.Scheme))
+		eq(t, "Url", actual.Url, "/api/users")
+	}
+}
+
+var TEST_METHOD_ROUTES = `
+GET     /users             Users.List
+POST    /users             Users.Create
+DELETE  /users/:id<int>    Users.Delete
+`
+
+func TestMethodNotAllowed(t *testing.T) {
+	router := NewRouter("")
+	var err *Error
+	router.Routes, err = parseRoutes("", TEST_METHOD_ROUTES, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	router.updateTree()
+
+	for _, test := range []struct {
+		method, path, action, allowed string
+	}{
+		{"PUT", "/users", "405", "GET, HEAD, OPTIONS, POST"},
+		{"OPTIONS", "/users", "OPTIONS", "GET, HEAD, OPTIONS, POST"},
+		{"GET", "/users/1", "405", "DELETE, OPTIONS"},
+		{"OPTIONS", "/users/1", "OPTIONS", "DELETE, OPTIONS"},
+	} {
+		actual := router.Route(&http.Request{Method: test.method, URL: &url.URL{Path: test.path}})
+		if !eq(t, "Found route "+test.method+" "+test.path, actual != nil, true) {
+			continue
+		}
+		eq(t, "Action", actual.Action, test.action)
+		eq(t, "Allowed", strings.Join(actual.Allowed, ", "), test.allowed)
+	}
+
+	// Paths that are not routed at all are still not found.
+	for _, path := range []string{"/posts", "/users/bob"} {
+		if actual := router.Route(&http.Request{Method: "GET", URL: &url.URL{Path: path}}); actual != nil {
+			t.Errorf("Expected no route for %s, got %s", path, actual.Action)
+		}
+	}
+}
+
+var TEST_PRIORITY_ROUTES = `
+GET  /users/:id   Users.Show
+GET  /users/new   Users.New      {priority=10}
+GET  /*path       Static.Serve   {priority=-1}
+GET  /about       Application.About
+`
+
+func TestRoutePriority(t *testing.T) {
+	router := NewRouter("")
+	var err *Error
+	router.Routes, err = parseRoutes("", TEST_PRIORITY_ROUTES, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	router.updateTree()
+
+	eq(t, "Priority", router.Routes[0].Priority, 10)
+	eq(t, "Lowest priority last", router.Routes[len(router.Routes)-1].Action, "Static.Serve")
+
+	for path, expected := range map[string]string{
+		"/users/new":  "New",
+		"/users/1":    "Show",
+		"/about":      "About",
+		"/robots.txt": "Serve",
+	} {
+		actual := router.Route(&http.Request{Method: "GET", URL: &url.URL{Path: path}})
+		if eq(t, "Found route "+path, actual != nil, true) {
+			eq(t, "MethodName for "+path, actual.MethodName, expected)
+		}
+	}
+
+	if _, err := parseRoutes("", "GET / A.B {priority=high}", false); err == nil {
+		t.Error("Expected an error for an invalid priority")
+	}
+}
+
+func TestRouteShadows(t *testing.T) {
+	for _, test := range []struct {
+		first, second string
+		shadows       bool
+	}{
+		{"GET /users/:id A.B", "GET /users/new A.C", true},
+		{"GET /users/new A.B", "GET /users/:id A.C", false},
+		{"GET /users/:id<int> A.B", "GET /users/new A.C", false},
+		{"GET /users/:id A.B", "GET /users/new A.C {priority=1}", false},
+		{"GET /users/:id A.B", "POST /users/new A.C", false},
+		{"* /users/:id A.B", "POST /users/new A.C", true},
+		{"GET /*path A.B", "GET /users/new A.C", true},
+	} {
+		routes, err := parseRoutes("", test.first+"\n"+test.second, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		eq(t, test.first+" shadows "+test.second, routes[0].shadows(routes[1]), test.shadows)
+	}
+}
+
+func TestRouteConflicts(t *testing.T) {
+	for _, test := range []struct {
+		routes, expected string
+	}{
+		{"GET /users/:id A.B\nGET /users/new A.C", "GET /users/new (routes:2) is unreachable: it is shadowed by GET /users/:id (routes:1)"},
+		{"GET /users/:id A.B\nGET /users/:name A.C", "GET /users/:name (routes:2) is unreachable: it is a duplicate of GET /users/:id (routes:1)"},
+		{"GET /users/new A.B\nGET /users/:id A.C", ""},
+		{"version 1 {\nGET /users A.B\n}\nversion 2 {\nGET /users A.C\n}", ""},
+	} {
+		router := NewRouter("")
+		router.StrictConflicts = true
+		router.Routes, _ = parseRoutes("routes", test.routes, false)
+		err := router.updateTree()
+		if test.expected == "" {
+			if err != nil {
+				t.Errorf("%q: unexpected error: %s", test.routes, err.Description)
+			}
+			continue
+		}
+		if eq(t, "Conflict for "+test.routes, err != nil, true) {
+			eq(t, "Description", err.Description, test.expected)
+		}
+	}
+}
+
+var TEST_SPLAT_ROUTES = `
+GET  /public/*filepath          Static.Serve("public")
+*    /proxy/:host/*path         Proxy.Forward
+`
+
+func TestCatchAllRoutes(t *testing.T) {
+	router := NewRouter("")
+	var err *Error
+	router.Routes, err = parseRoutes("", TEST_SPLAT_ROUTES, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	router.updateTree()
+
+	for _, test := range []struct {
+		method, path, param, value string
+	}{
+		{"GET", "/public/app.css", "filepath", "app.css"},
+		{"GET", "/public/css/vendor/app.css", "filepath", "css/vendor/app.css"},
+		{"GET", "/public/css/", "filepath", "css/"},
+		{"POST", "/proxy/example.com/api/v1/users", "path", "api/v1/users"},
+	} {
+		actual := router.Route(&http.Request{Method: test.method, URL: &url.URL{Path: test.path}})
+		if eq(t, "Found route "+test.path, actual != nil, true) {
+			eq(t, "Param "+test.param, url.Values(actual.Params).Get(test.param), test.value)
+		}
+	}
+
+	for _, test := range []struct {
+		action string
+		args   map[string]string
+		url    string
+	}{
+		{"Static.Serve", map[string]string{"filepath": "css/vendor/app.css"}, "/public/css/vendor/app.css"},
+		{"Static.Serve", map[string]string{"filepath": "/app.css"}, "/public/app.css"},
+		{"Proxy.Forward", map[string]string{"host": "example.com", "path": "api/v1/users"}, "/proxy/example.com/api/v1/users"},
+	} {
+		actual := router.Reverse(test.action, test.args)
+		if eq(t, "Found reverse route "+test.action, actual != nil, true) {
+			eq(t, "Url", actual.Url, test.url)
+		}
+	}
+
+	if _, err := parseRoutes("", "GET /public/*filepath/edit Static.Edit", false); err == nil {
+		t.Error("Expected an error for a catch-all parameter that is not at the end of the path")
+	}
+}
+
+var TEST_TRAILING_SLASH_ROUTES = `
+GET  /users          Users.List
+GET  /docs/          Docs.Index
+GET  /feed           Feed.Show     {trailingSlash=strict}
+POST /users          Users.Create
+`
+
+func TestTrailingSlash(t *testing.T) {
+	router := NewRouter("")
+	var err *Error
+	router.Routes, err = parseRoutes("", TEST_TRAILING_SLASH_ROUTES, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	router.updateTree()
+
+	for _, test := range []struct {
+		policy, method, path, action, location string
+	}{
+		{"", "GET", "/users/", "Users.List", ""},
+		{"strip", "GET", "/docs", "Docs.Index", ""},
+		{"strip", "GET", "/feed/", "", ""},
+		{"strict", "GET", "/users", "Users.List", ""},
+		{"strict", "GET", "/users/", "", ""},
+		{"strict", "GET", "/docs", "", ""},
+		{"redirect", "GET", "/users/?page=2", "301", "/users?page=2"},
+		{"redirect", "GET", "/docs", "301", "/docs/"},
+		{"redirect", "GET", "/docs/", "Docs.Index", ""},
+		{"redirect", "POST", "/users/", "Users.Create", ""},
+		{"redirect", "GET", "/feed/", "", ""},
+	} {
+		router.TrailingSlash = test.policy
+		reqUrl, _ := url.Parse(test.path)
+		actual := router.Route(&http.Request{Method: test.method, URL: reqUrl})
+		name := test.policy + " " + test.method + " " + test.path
+		if test.action == "" {
+			if actual != nil && actual.Action != "405" {
+				t.Errorf("%s: expected no route, got %s.%s", name, actual.ControllerName, actual.MethodName)
+			}
+			continue
+		}
+		if !eq(t, "Found route for "+name, actual != nil, true) {
+			continue
+		}
+		if test.action == "301" {
+			eq(t, "Action for "+name, actual.Action, "301")
+			eq(t, "Location for "+name, actual.Location, test.location)
+			continue
+		}
+		eq(t, "Action for "+name, actual.ControllerName+"."+actual.MethodName, test.action)
+	}
+
+	if _, err := parseRoutes("", "GET / A.B {trailingSlash=sometimes}", false); err == nil {
+		t.Error("Expected an error for an invalid trailing slash policy")
+	}
+}
+
+var TEST_CASE_ROUTES = `
+GET  /Spring-Sale          Promotions.Spring
+GET  /Products/:name       Products.Show
+GET  /About                Application.About   {case=redirect}
+GET  /Legal                Application.Legal   {case=sensitive}
+`
+
+func TestCaseInsensitiveRoutes(t *testing.T) {
+	router := NewRouter("")
+	var err *Error
+	router.Routes, err = parseRoutes("", TEST_CASE_ROUTES, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	router.updateTree()
+
+	for _, test := range []struct {
+		policy, path, action, param, location string
+	}{
+		{"", "/Spring-Sale", "Promotions.Spring", "", ""},
+		{"", "/spring-sale", "", "", ""},
+		{"", "/about", "301", "", "/About"},
+		{"", "/About", "Application.About", "", ""},
+		{"insensitive", "/SPRING-sale", "Promotions.Spring", "", ""},
+		{"insensitive", "/products/Gadget", "Products.Show", "Gadget", ""},
+		{"insensitive", "/legal", "", "", ""},
+		{"redirect", "/spring-sale", "301", "", "/Spring-Sale"},
+		{"redirect", "/products/Gadget/", "301", "", "/Products/Gadget/"},
+		{"redirect", "/Products/Gadget", "Products.Show", "Gadget", ""},
+	} {
+		router.Case = test.policy
+		actual := router.Route(&http.Request{Method: "GET", URL: &url.URL{Path: test.path}})
+		name := test.policy + " " + test.path
+		if test.action == "" {
+			if actual != nil {
+				t.Errorf("%s: expected no route, got %s", name, actual.Action)
+			}
+			continue
+		}
+		if !eq(t, "Found route for "+name, actual != nil, true) {
+			continue
+		}
+		if test.action == "301" {
+			eq(t, "Action for "+name, actual.Action, "301")
+			eq(t, "Location for "+name, actual.Location, test.location)
+			continue
+		}
+		eq(t, "Action for "+name, actual.ControllerName+"."+actual.MethodName, test.action)
+		eq(t, "Param for "+name, url.Values(actual.Params).Get("name"), test.param)
+	}
+
+	if _, err := parseRoutes("", "GET / A.B {case=upper}", false); err == nil {
+		t.Error("Expected an error for an invalid case policy")
+	}
+}
+
+var TEST_OVERRIDE_ROUTES = `
+GET     /users/:id     Users.Show
+POST    /users/:id     Users.Update
+PUT     /users/:id     Users.Replace
+DELETE  /users/:id     Users.Delete
+`
+
+func TestMethodOverride(t *testing.T) {
+	router := NewRouter("")
+	var err *Error
+	router.Routes, err = parseRoutes("", TEST_OVERRIDE_ROUTES, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	router.updateTree()
+
+	for _, test := range []struct {
+		enabled              bool
+		method, header, form string
+		expected             string
+	}{
+		{true, "POST", "", "", "Update"},
+		{true, "POST", "PUT", "", "Replace"},
+		{true, "POST", "", "delete", "Delete"},
+		{true, "POST", "DELETE", "PUT", "Delete"},
+		{true, "POST", "GET", "", "Update"},
+		{true, "GET", "DELETE", "", "Show"},
+		{false, "POST", "DELETE", "DELETE", "Update"},
+	} {
+		router.MethodOverride = test.enabled
+		req, _ := http.NewRequest(test.method, "/users/1",
+			strings.NewReader(url.Values{"_method": {test.form}}.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if test.header != "" {
+			req.Header.Set("X-HTTP-Method-Override", test.header)
+		}
+
+		actual := router.Route(req)
+		if eq(t, "Found route", actual != nil, true) {
+			eq(t, fmt.Sprintf("MethodName for %+v", test), actual.MethodName, test.expected)
+		}
+	}
+}
+
+func TestAbsoluteURL(t *testing.T) {
+	defer func(host, scheme string, port int) {
+		HttpHost, HttpScheme, HttpPort = host, scheme, port
+	}(HttpHost, HttpScheme, HttpPort)
+
+	router := NewRouter("")
+	var err *Error
+	router.Routes, err = parseRoutes("", `
+GET  /users/:id                 Users.Show
+WS   /chat                      Chat.Socket
+GET  :tenant.example.com/ /     Tenants.Index
+`, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	router.updateTree()
+
+	for _, test := range []struct {
+		scheme, host string
+		port         int
+		action       string
+		args         map[string]string
+		expected     string
+	}{
+		{"http", "example.com", 80, "Users.Show", map[string]string{"id": "1"}, "http://example.com/users/1"},
+		{"http", "localhost", 9000, "Users.Show", map[string]string{"id": "1"}, "http://localhost:9000/users/1"},
+		{"https", "example.com", 443, "Users.Show", map[string]string{"id": "1"}, "https://example.com/users/1"},
+		{"https", "example.com", 80, "Users.Show", map[string]string{"id": "1"}, "https://example.com:80/users/1"},
+		{"https", "example.com:8443", 9000, "Users.Show", map[string]string{"id": "1"}, "https://example.com:8443/users/1"},
+		{"https", "example.com", 443, "Chat.Socket", map[string]string{}, "wss://example.com/chat"},
+		{"http", "localhost", 80, "Tenants.Index", map[string]string{"tenant": "acme"}, "http://acme.example.com/"},
+	} {
+		HttpScheme, HttpHost, HttpPort = test.scheme, test.host, test.port
+		actual := router.Reverse(test.action, test.args)
+		if eq(t, "Found route "+test.action, actual != nil, true) {
+			eq(t, "AbsoluteURL", actual.AbsoluteURL(), test.expected)
+		}
+	}
+}
+
+func TestRouteMeta(t *testing.T) {
+	router := NewRouter("")
+	var err *Error
+	router.Routes, err = parseRoutes("", `
+GET  /health     Application.Health   {auth=none, audit=false, priority=1}
+GET  /flagged    Application.Flagged  {beta}
+GET  /           Application.Index
+`, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	router.updateTree()
+
+	for _, test := range []struct {
+		path string
+		meta map[string]string
+	}{
+		{"/health", map[string]string{"auth": "none", "audit": "false", "priority": "1"}},
+		{"/flagged", map[string]string{"beta": ""}},
+		{"/", map[string]string{}},
+	} {
+		actual := router.Route(&http.Request{Method: "GET", URL: &url.URL{Path: test.path}})
+		if !eq(t, "Found route "+test.path, actual != nil, true) {
+			continue
+		}
+		eq(t, "Meta length for "+test.path, len(actual.Meta), len(test.meta))
+		for key, value := range test.meta {
+			actualValue, ok := actual.Meta[key]
+			eq(t, "Has meta "+key, ok, true)
+			eq(t, "Meta "+key, actualValue, value)
+		}
+	}
+}
+
+func TestActionRoutes(t *testing.T) {
+	startFakeBookingApp()
+	show := controllers["hotels"].Method("Show")
+	book := controllers["hotels"].Method("Book")
+	defer func() { show.Routes, book.Routes = nil, nil }()
+	show.Routes = []string{"GET /hotels/:id<int> as hotels.show", "GET /h/:id"}
+	book.Routes = []string{"POST /hotels/:id/book {audit=true}"}
+
+	routes, err := ActionRoutes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !eq(t, "Number of routes", len(routes), 3) {
+		return
+	}
+
+	for i, expected := range []struct {
+		method, path, action, name string
+	}{
+		{"GET", "/hotels/:id<int>", "Hotels.Show", "hotels.show"},
+		{"GET", "/h/:id", "Hotels.Show", ""},
+		{"POST", "/hotels/:id/book", "Hotels.Book", ""},
+	} {
+		eq(t, "Method", routes[i].Method, expected.method)
+		eq(t, "Path", routes[i].Path, expected.path)
+		eq(t, "Action", routes[i].Action, expected.action)
+		eq(t, "Name", routes[i].Name, expected.name)
+	}
+	eq(t, "Meta", routes[2].Meta["audit"], "true")
+
+	book.Routes = []string{"/hotels/:id/book"}
+	if _, err := ActionRoutes(); err == nil {
+		t.Error("Expected an error for a route without a method")
+	}
+}
+
+func TestRouteList(t *testing.T) {
+	NamedFilters["auth"] = NilFilter
+	defer delete(NamedFilters, "auth")
+
+	router := NewRouter("")
+	var err *Error
+	router.Routes, err = parseRoutes("conf/routes", `
+GET  /users/:id<int>   Users.Show   as users.show [auth] {priority=1}
+GET  /                 Application.Index
+`, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	router.updateTree()
+	router.Group("/admin").Add("GET", "/", "Admin.Index")
+
+	list := router.List()
+	if !eq(t, "Number of routes", len(list), 3) {
+		return
+	}
+
+	show := list[0]
+	eq(t, "Method", show.Method, "GET")
+	eq(t, "Path", show.Path, "/users/:id<int>")
+	eq(t, "Action", show.Action, "Users.Show")
+	eq(t, "Name", show.Name, "users.show")
+	eq(t, "Constraint", show.Constraints["id"], "^(?:"+RouteConstraints["int"]+")$")
+	eq(t, "Filters", strings.Join(show.Filters, ","), "auth")
+	eq(t, "Priority", show.Priority, 1)
+	eq(t, "File", show.File, "conf/routes")
+	eq(t, "Line", show.Line, 2)
+
+	eq(t, "Constraints", len(list[1].Constraints), 0)
+	eq(t, "File", list[2].File, "")
+	eq(t, "Line", list[2].Line, 0)
+
+	match := router.Route(&http.Request{Method: "GET", URL: &url.URL{Path: "/users/1"}})
+	if eq(t, "Found route", match != nil, true) {
+		eq(t, "Matched route", match.Route, router.Routes[0])
+	}
+}
+
+func TestModuleMountPrefix(t *testing.T) {
+	dir, err := ioutil.TempDir("", "revel-module")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.Mkdir(filepath.Join(dir, "conf"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "conf", "routes"), []byte(`
+GET  /                 Pages.Index
+GET  /pages/:slug      Pages.Show    as cms.page
+`), 0644)
+
+	defer func(modules []Module) { Modules = modules }(Modules)
+	Modules = append(Modules, Module{Name: "cms", Path: dir})
+
+	router := NewRouter("")
+	var routeErr *Error
+	router.Routes, routeErr = parseRoutes("", `
+module:cms /content
+group /api {
+  module:cms /v1/cms
+}
+GET  /                 Application.Index
+`, false)
+	if routeErr != nil {
+		t.Fatal(routeErr)
+	}
+	router.updateTree()
+
+	for path, expected := range map[string]string{
+		"/content":                "Pages.Index",
+		"/content/pages/about":    "Pages.Show",
+		"/api/v1/cms/pages/about": "Pages.Show",
+		"/":                       "Application.Index",
+	} {
+		actual := router.Route(&http.Request{Method: "GET", URL: &url.URL{Path: path}})
+		if eq(t, "Found route "+path, actual != nil, true) {
+			eq(t, "Action for "+path, actual.ControllerName+"."+actual.MethodName, expected)
+		}
+	}
+
+	if actual := router.ReverseByName("cms.page", map[string]string{"slug": "about"}); eq(t, "Found cms.page", actual != nil, true) {
+		eq(t, "Url", actual.Url, "/content/pages/about")
+	}
+	if actual := router.Reverse("Pages.Index", map[string]string{}); eq(t, "Found Pages.Index", actual != nil, true) {
+		eq(t, "Url", actual.Url, "/content")
+	}
+
+	if _, err := parseRoutes("", "module:cms content", false); err == nil {
+		t.Error("Expected an error for a relative module prefix")
+	}
+}
+
+var TEST_QUERY_ROUTES = `
+GET  /search?format=json          Search.Json
+GET  /search?format=xml&debug     Search.XmlDebug
+GET  /search                      Search.Index
+`
+
+func TestQueryRoutes(t *testing.T) {
+	router := NewRouter("")
+	var err *Error
+	router.Routes, err = parseRoutes("", TEST_QUERY_ROUTES, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	router.updateTree()
+
+	eq(t, "Path", router.Routes[0].Path, "/search")
+	eq(t, "Query", router.Routes[0].Query.Get("format"), "json")
+
+	for rawurl, expected := range map[string]string{
+		"/search?format=json&q=revel":  "Json",
+		"/search?q=revel&format=json":  "Json",
+		"/search?format=xml":           "Index",
+		"/search?format=xml&debug":     "XmlDebug",
+		"/search?format=xml&debug=yes": "XmlDebug",
+		"/search?format=html":          "Index",
+		"/search":                      "Index",
+	} {
+		reqUrl, _ := url.Parse(rawurl)
+		actual := router.Route(&http.Request{Method: "GET", URL: reqUrl})
+		if eq(t, "Found route "+rawurl, actual != nil, true) {
+			eq(t, "MethodName for "+rawurl, actual.MethodName, expected)
+		}
+	}
+
+	for action, expected := range map[string]string{
+		"Search.Json":     "/search?format=json&q=revel",
+		"Search.XmlDebug": "/search?debug=&format=xml&q=revel",
+		"Search.Index":    "/search?q=revel",
+	} {
+		actual := router.Reverse(action, map[string]string{"q": "revel"})
+		if eq(t, "Found reverse route "+action, actual != nil, true) {
+			eq(t, "Url for "+action, actual.Url, expected)
+		}
+	}
+
+	// The unrestricted route shadows the others when declared first.
+	routes, _ := parseRoutes("", "GET /search Search.Index\nGET /search?format=json Search.Json", false)
+	eq(t, "Shadows", routes[0].shadows(routes[1]), true)
+	eq(t, "Shadows", routes[1].shadows(routes[0]), false)
+}
+
+var TEST_FORMAT_ROUTES = `
+GET   /users/:id    [json]                      Api.ShowUser
+GET   /users/:id    [application/vnd.app+json]  Api.ShowUserV2
+GET   /users/:id    [html, txt]                 Users.Show
+POST  /users        [json]                      Api.CreateUser   [auth]
+`
+
+func TestFormatRoutes(t *testing.T) {
+	NamedFilters["auth"] = NilFilter
+	defer delete(NamedFilters, "auth")
+
+	router := NewRouter("")
+	var err *Error
+	router.Routes, err = parseRoutes("", TEST_FORMAT_ROUTES, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	router.updateTree()
+
+	eq(t, "Formats", strings.Join(router.Routes[2].Formats, ","), "html,txt")
+	eq(t, "Action", router.Routes[3].Action, "Api.CreateUser")
+	eq(t, "Filters", strings.Join(router.Routes[3].Filters, ","), "auth")
+
+	for _, test := range []struct {
+		method, accept, contentType, expected string
+	}{
+		{"GET", "application/json", "", "ShowUser"},
+		{"GET", "text/html,application/xhtml+xml", "", "Show"},
+		{"GET", "", "", "Show"},
+		{"GET", "text/plain", "", "Show"},
+		{"GET", "application/vnd.app+json", "", "ShowUserV2"},
+		{"GET", "application/xml", "", ""},
+		{"POST", "application/json", "", "CreateUser"},
+		{"POST", "", "application/json; charset=utf-8", "CreateUser"},
+		{"POST", "", "application/x-www-form-urlencoded", ""},
+	} {
+		path := "/users/1"
+		if test.method == "POST" {
+			path = "/users"
+		}
+		req := &http.Request{Method: test.method, URL: &url.URL{Path: path}, Header: http.Header{}}
+		req.Header.Set("Accept", test.accept)
+		req.Header.Set("Content-Type", test.contentType)
+
+		actual := router.Route(req)
+		name := fmt.Sprintf("%s %q", test.method, test.accept)
+		if test.expected == "" {
+			if actual != nil {
+				t.Errorf("%s: expected no route, got %s %s", name, actual.Action, actual.MethodName)
+			}
+			continue
+		}
+		if eq(t, "Found route for "+name, actual != nil, true) {
+			eq(t, "MethodName for "+name, actual.MethodName, test.expected)
+		}
+	}
+}
+
+var TEST_VERSIONED_ROUTES = `
+GET   /status        App.Status
+
+version 1 {
+  GET   /users       ApiV1.Users
+  GET   /users/:id   ApiV1.User as user
+}
+
+version 2 {
+  GET   /users       ApiV2.Users
+}
+`
+
+func TestVersionedRoutes(t *testing.T) {
+	router := NewRouter("")
+	var err *Error
+	router.Routes, err = parseRoutes("", TEST_VERSIONED_ROUTES, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	router.updateTree()
+
+	eq(t, "Version", router.Routes[2].Version, 1)
+	eq(t, "Version", router.Routes[3].Version, 2)
+
+	for _, test := range []struct {
+		versioning, path, accept, expected string
+	}{
+		{"path", "/v1/users", "", "ApiV1.Users"},
+		{"path", "/v2/users", "", "ApiV2.Users"},
+		{"path", "/v3/users", "", "ApiV2.Users"},
+		{"path", "/v2/users/1", "", "ApiV1.User"},
+		{"path", "/v2/status", "", "App.Status"},
+		{"path", "/status", "", "App.Status"},
+		{"path", "/users", "", ""},
+		{"header", "/users", "application/vnd.app.v1+json", "ApiV1.Users"},
+		{"header", "/users", "application/json", "ApiV2.Users"},
+		{"query", "/users?v=1", "", "ApiV1.Users"},
+		{"query", "/users/1?v=2", "", "ApiV1.User"},
+		{"query", "/users", "", "ApiV2.Users"},
+	} {
+		router.Versioning = test.versioning
+		u, _ := url.Parse(test.path)
+		req := &http.Request{Method: "GET", URL: u, Header: http.Header{}}
+		req.Header.Set("Accept", test.accept)
+
+		actual := router.Route(req)
+		name := fmt.Sprintf("%s %s %q", test.versioning, test.path, test.accept)
+		if test.expected == "" {
+			if actual != nil {
+				t.Errorf("%s: expected no route, got %s", name, actual.Action)
+			}
+			continue
+		}
+		if eq(t, "Found route for "+name, actual != nil, true) {
+			eq(t, "Action for "+name, actual.Route.Action, test.expected)
+		}
+	}
+
+	router.Versioning = "path"
+	eq(t, "Reverse path", router.ReverseByName("user", map[string]string{"id": "1"}).Url, "/v1/users/1")
+	router.Versioning = "query"
+	eq(t, "Reverse query", router.ReverseByName("user", map[string]string{"id": "1"}).Url, "/users/1?v=1")
+}
+
+var TEST_REDIRECT_ROUTES = `
+GET   /users/:id         Users.Show
+GET   /old-users/:id     301 /users/:id
+GET   /people/:id        302 Users.Show
+GET   /files/*filepath   307 /assets/*filepath
+GET   /search            301 /find?q=all
+POST  /old-login         308 /login
+`
+
+func TestRedirectRoutes(t *testing.T) {
+	router := NewRouter("")
+	var err *Error
+	router.Routes, err = parseRoutes("", TEST_REDIRECT_ROUTES, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	router.updateTree()
+
+	eq(t, "Action", router.Routes[1].Action, "301")
+	eq(t, "Redirect", router.Routes[1].Redirect, "/users/:id")
+	eq(t, "Redirect", router.Routes[2].Redirect, "Users.Show")
+
+	for _, test := range []struct {
+		method, path, action, location string
+	}{
+		{"GET", "/old-users/5", "301", "/users/5"},
+		{"GET", "/old-users/5?tab=posts", "301", "/users/5?tab=posts"},
+		{"GET", "/people/7", "302", "/users/7"},
+		{"GET", "/files/css/app.css", "307", "/assets/css/app.css"},
+		{"GET", "/search?q=go", "301", "/find?q=all"},
+		{"POST", "/old-login", "308", "/login"},
+	} {
+		u, _ := url.Parse(test.path)
+		req := &http.Request{Method: test.method, URL: u}
+
+		actual := router.Route(req)
+		name := test.method + " " + test.path
+		if eq(t, "Found route for "+name, actual != nil, true) {
+			eq(t, "Action for "+name, actual.Action, test.action)
+			eq(t, "Location for "+name, actual.Location, test.location)
+		}
+	}
+}
+
+func TestStaticRoutes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "revel-static")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.MkdirAll(filepath.Join(dir, "css"), 0755)
+	os.MkdirAll(filepath.Join(dir, "docs"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "css", "app.css"), []byte("body {}"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "docs", "index.html"), []byte("<html>"), 0644)
+
+	router := NewRouter("")
+	var routeErr *Error
+	router.Routes, routeErr = parseRoutes("", "STATIC /assets "+dir+" {maxage=86400, index=index.html}", false)
+	if routeErr != nil {
+		t.Fatal(routeErr)
+	}
+	router.updateTree()
+
+	route := router.Routes[0]
+	eq(t, "Method", route.Method, "GET")
+	eq(t, "Path", route.Path, "/assets/*filepath")
+	eq(t, "Static", route.Static, dir)
+	eq(t, "maxAge", route.maxAge, 86400)
+
+	for _, test := range []struct {
+		path, body string
+	}{
+		{"/assets/css/app.css", "body {}"},
+		{"/assets/docs/", "<html>"},
+	} {
+		req, _ := http.NewRequest("GET", test.path, nil)
+		match := router.Route(req)
+		if !eq(t, "Found route for "+test.path, match != nil, true) {
+			continue
+		}
+		eq(t, "Action for "+test.path, match.Action, "STATIC")
+
+		resp := httptest.NewRecorder()
+		c := NewController(NewRequest(req), NewResponse(resp))
+		serveStatic(c, match.Route, url.Values(match.Params).Get("filepath")).Apply(c.Request, c.Response)
+		eq(t, "Body for "+test.path, resp.Body.String(), test.body)
+		eq(t, "Cache-Control for "+test.path, resp.Header().Get("Cache-Control"), "public, max-age=86400")
+	}
+
+	_, routeErr = parseRoutes("", "STATIC /assets public {maxage=forever}", false)
+	eq(t, "Invalid maxage", routeErr != nil, true)
+}
+
+func TestMountRoutes(t *testing.T) {
+	startFakeBookingApp()
+	defer func(router *Router) { MainRouter = router }(MainRouter)
+	defer delete(mountHandlers, "echo")
+
+	echo := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s %s", r.Method, r.URL.Path)
+	})
+	RegisterHandler("echo", echo)
+
+	MainRouter = NewRouter("")
+	var routeErr *Error
+	MainRouter.Routes, routeErr = parseRoutes("", `
+MOUNT  /debug   echo
+GET    /hotels  Hotels.Index
+`, false)
+	if routeErr != nil {
+		t.Fatal(routeErr)
+	}
+	MainRouter.updateTree()
+	MainRouter.Group("/api").Mount("/v1", echo)
+
+	route := MainRouter.Routes[0]
+	eq(t, "Method", route.Method, "*")
+	eq(t, "Mount", route.Mount, "echo")
+
+	for _, test := range []struct {
+		method, path, body string
+	}{
+		{"GET", "/debug", "GET /"},
+		{"GET", "/debug/", "GET /"},
+		{"POST", "/debug/vars/memstats", "POST /vars/memstats"},
+		{"GET", "/api/v1/users", "GET /users"},
+		{"GET", "/hotels", "Hello, World!"},
+	} {
+		req, _ := http.NewRequest(test.method, test.path, nil)
+		resp := httptest.NewRecorder()
+		handle(resp, req)
+		eq(t, "Body for "+test.method+" "+test.path, resp.Body.String(), test.body)
+	}
+
+	_, routeErr = parseRoutes("", "MOUNT /debug unknown", false)
+	eq(t, "Unknown handler", routeErr != nil, true)
+}
+
+var TEST_MULTI_METHOD_ROUTES = `
+GET|POST    /login        Auth.Login as login
+PUT,PATCH   /users/:id    Users.Update
+`
+
+func TestMultiMethodRoutes(t *testing.T) {
+	router := NewRouter("")
+	var err *Error
+	router.Routes, err = parseRoutes("", TEST_MULTI_METHOD_ROUTES, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	router.updateTree()
+
+	if !eq(t, "Routes", len(router.Routes), 4) {
+		return
+	}
+	for i, method := range []string{"GET", "POST", "PUT", "PATCH"} {
+		eq(t, "Method", router.Routes[i].Method, method)
+	}
+
+	for _, test := range []struct {
+		method, path, expected string
+	}{
+		{"GET", "/login", "Login"},
+		{"POST", "/login", "Login"},
+		{"PUT", "/users/1", "Update"},
+		{"PATCH", "/users/1", "Update"},
+		{"DELETE", "/login", ""},
+	} {
+		req := &http.Request{Method: test.method, URL: &url.URL{Path: test.path}}
+		actual := router.Route(req)
+		name := test.method + " " + test.path
+		if eq(t, "Found route for "+name, actual != nil, true) {
+			if test.expected == "" {
+				eq(t, "Action for "+name, actual.Action, "405")
+				eq(t, "Allowed for "+name, strings.Join(actual.Allowed, ","), "GET,HEAD,OPTIONS,POST")
+			} else {
+				eq(t, "MethodName for "+name, actual.MethodName, test.expected)
+			}
+		}
+	}
+
+	eq(t, "Reverse method", router.Reverse("Auth.Login", map[string]string{}).Method, "GET")
+	eq(t, "ReverseByName method", router.ReverseByName("login", map[string]string{}).Method, "GET")
+	eq(t, "Reverse method", router.Reverse("Users.Update", map[string]string{"id": "1"}).Method, "PUT")
+}
+
+func TestRefreshHooks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "revel-routes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	routesPath := filepath.Join(dir, "routes")
+	ioutil.WriteFile(routesPath, []byte("GET /a 404\n"), 0644)
+
+	var calls [][2]int
+	router := NewRouter(routesPath)
+	router.OnRefresh(func(old, new []*Route) {
+		calls = append(calls, [2]int{len(old), len(new)})
+		// Hooks may use the router.
+		if len(calls) == 1 {
+			router.OnRefresh(func(old, new []*Route) {})
+		}
+	})
+
+	if err := router.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	ioutil.WriteFile(routesPath, []byte("GET /a 404\nGET /b 404\n"), 0644)
+	if err := router.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	ioutil.WriteFile(routesPath, []byte("GET /a 404\n}\n"), 0644)
+	if err := router.Refresh(); err == nil {
+		t.Error("Expected an error refreshing an invalid routes file")
+	}
+
+	if eq(t, "Refresh hook calls", len(calls), 2) {
+		eq(t, "First refresh", calls[0], [2]int{0, 1})
+		eq(t, "Second refresh", calls[1], [2]int{1, 2})
+	}
+}
+
+func TestParamDefaults(t *testing.T) {
+	router := NewRouter("")
+	router.Routes, _ = parseRoutes("", `
+GET  /posts/:page<int>=1                 Posts.Index
+GET  /archive/:year=2024/:month<uint>=1  Posts.Archive
+*    /tags/:tag=all                      Posts.Tags
+`, false)
+	if !eq(t, "Routes", len(router.Routes), 3) {
+		return
+	}
+	router.updateTree()
+
+	for _, test := range []struct {
+		method, path, action string
+		params               map[string]string
+	}{
+		{"GET", "/posts", "Posts.Index", map[string]string{"page": "1"}},
+		{"GET", "/posts/", "Posts.Index", map[string]string{"page": "1"}},
+		{"GET", "/posts/3", "Posts.Index", map[string]string{"page": "3"}},
+		{"HEAD", "/posts", "Posts.Index", map[string]string{"page": "1"}},
+		{"GET", "/archive", "Posts.Archive", map[string]string{"year": "2024", "month": "1"}},
+		{"GET", "/archive/2020", "Posts.Archive", map[string]string{"year": "2020", "month": "1"}},
+		{"GET", "/archive/2020/6", "Posts.Archive", map[string]string{"year": "2020", "month": "6"}},
+		{"POST", "/tags", "Posts.Tags", map[string]string{"tag": "all"}},
+		{"GET", "/posts/x", "", nil},
+		{"GET", "/posts/3/4", "", nil},
+	} {
+		req, _ := http.NewRequest(test.method, test.path, nil)
+		match := router.Route(req)
+		if test.action == "" {
+			eq(t, "No match for "+test.path, match == nil || match.Route == nil, true)
+			continue
+		}
+		if !eq(t, "Match for "+test.method+" "+test.path, match != nil && match.Route != nil, true) {
+			continue
+		}
+		eq(t, "Action for "+test.path, match.Route.Action, test.action)
+		for name, value := range test.params {
+			eq(t, name+" for "+test.path, url.Values(match.Params).Get(name), value)
+		}
+	}
+
+	for _, test := range []struct {
+		action   string
+		args     map[string]string
+		expected string
+	}{
+		{"Posts.Index", map[string]string{}, "/posts"},
+		{"Posts.Index", map[string]string{"page": "1"}, "/posts"},
+		{"Posts.Index", map[string]string{"page": "2"}, "/posts/2"},
+		{"Posts.Archive", map[string]string{"year": "2024"}, "/archive"},
+		{"Posts.Archive", map[string]string{"year": "2020"}, "/archive/2020"},
+		{"Posts.Archive", map[string]string{"month": "6"}, "/archive/2024/6"},
+		{"Posts.Tags", map[string]string{"tag": "go"}, "/tags/go"},
+	} {
+		actionDef := router.Reverse(test.action, test.args)
+		if eq(t, "Reverse "+test.action, actionDef != nil, true) {
+			eq(t, "Url", actionDef.Url, test.expected)
+		}
+	}
+
+	for _, path := range []string{
+		"/posts/:page=1/comments",
+		"/posts/:page=1/:id",
+		"/posts/:page<int>=one",
+	} {
+		if _, err := newRoute("GET", path, "Posts.Index", "", "", 0); err == nil {
+			t.Errorf("Expected an error for %s", path)
+		}
+	}
+
+	// A route with defaults shadows a later route for the shorter path.
+	shadowing, _ := newRoute("GET", "/posts/:page=1", "A.B", "", "", 0)
+	shadowed, _ := newRoute("GET", "/posts", "A.C", "", "", 0)
+	eq(t, "Shadows", shadowing.shadows(shadowed), true)
+	eq(t, "Shadowed", shadowed.shadows(shadowing), false)
+}
+
+func TestOptionalSegments(t *testing.T) {
+	router := NewRouter("")
+	router.Routes, _ = parseRoutes("", `
+GET  /docs(/:lang<alpha>)/intro      Docs.Intro
+GET  /files/:id(/:name)(/download)   Files.Show
+GET  /shop(/:region=us)/cart         Shop.Cart
+`, false)
+	if !eq(t, "Routes", len(router.Routes), 3) {
+		return
+	}
+	router.updateTree()
+
+	for _, test := range []struct {
+		path, action string
+		params       map[string]string
+	}{
+		{"/docs/intro", "Docs.Intro", map[string]string{"lang": ""}},
+		{"/docs/fr/intro", "Docs.Intro", map[string]string{"lang": "fr"}},
+		{"/files/1", "Files.Show", map[string]string{"id": "1", "name": ""}},
+		{"/files/1/a.txt", "Files.Show", map[string]string{"id": "1", "name": "a.txt"}},
+		{"/files/1/download", "Files.Show", map[string]string{"id": "1", "name": "download"}},
+		{"/files/1/a.txt/download", "Files.Show", map[string]string{"id": "1", "name": "a.txt"}},
+		{"/shop/cart", "Shop.Cart", map[string]string{"region": "us"}},
+		{"/shop/eu/cart", "Shop.Cart", map[string]string{"region": "eu"}},
+		{"/docs/12/intro", "", nil},
+		{"/docs/fr", "", nil},
+	} {
+		req, _ := http.NewRequest("GET", test.path, nil)
+		match := router.Route(req)
+		if test.action == "" {
+			eq(t, "No match for "+test.path, match == nil, true)
+			continue
+		}
+		if !eq(t, "Match for "+test.path, match != nil && match.Route != nil, true) {
+			continue
+		}
+		eq(t, "Action for "+test.path, match.Route.Action, test.action)
+		for name, value := range test.params {
+			eq(t, name+" for "+test.path, url.Values(match.Params).Get(name), value)
+		}
+	}
+
+	for _, test := range []struct {
+		action   string
+		args     map[string]string
+		expected string
+	}{
+		{"Docs.Intro", map[string]string{}, "/docs/intro"},
+		{"Docs.Intro", map[string]string{"lang": "fr"}, "/docs/fr/intro"},
+		{"Files.Show", map[string]string{"id": "1"}, "/files/1"},
+		{"Files.Show", map[string]string{"id": "1", "name": "a.txt"}, "/files/1/a.txt"},
+		{"Shop.Cart", map[string]string{"region": "us"}, "/shop/cart"},
+		{"Shop.Cart", map[string]string{"region": "eu"}, "/shop/eu/cart"},
+	} {
+		actionDef := router.Reverse(test.action, test.args)
+		if eq(t, "Reverse "+test.action, actionDef != nil, true) {
+			eq(t, "Url", actionDef.Url, test.expected)
+		}
+	}
+
+	for _, path := range []string{
+		"/docs(/:lang",
+		"/docs/:lang)",
+		"/docs(/a(/b))",
+		"/docs(:lang)",
+		"/docs(/:lang)x",
+		"/docs()",
+	} {
+		if _, err := newRoute("GET", path, "Docs.Intro", "", "", 0); err == nil {
+			t.Errorf("Expected an error for %s", path)
+		}
+	}
+
+	// A constraint may still follow a parameter before an optional segment.
+	route, err := newRoute("GET", "/docs/:id([0-9]+)(/:lang)", "Docs.Show", "", "", 0)
+	if eq(t, "Constraint and optional segment", err, nil) {
+		eq(t, "Forms", len(route.forms), 2)
+		eq(t, "Args", len(route.args), 2)
+	}
+}
+
+func TestRouteGuards(t *testing.T) {
+	defer func() {
+		delete(routeGuards, "beta")
+		delete(routeGuards, "staff")
+	}()
+	RegisterRouteGuard("beta", func(req *http.Request) bool {
+		return req.Header.Get("X-Beta") == "1"
+	})
+	RegisterRouteGuard("staff", func(req *http.Request) bool {
+		return req.Header.Get("X-Staff") == "1"
+	})
+
+	router := NewRouter("")
+	router.CacheSize = 10
+	var routeErr *Error
+	router.Routes, routeErr = parseRoutes("", `
+GET   /dashboard       Beta.Dashboard   {guard=beta}
+GET   /dashboard       App.Dashboard
+GET   /admin           Admin.Index      {guard=beta staff}
+POST  /feedback        Beta.Feedback    {guard=beta}
+`, false)
+	if routeErr != nil {
+		t.Fatal(routeErr)
+	}
+	if err := router.updateTree(); err != nil {
+		t.Fatal(err)
+	}
+	eq(t, "Cache", router.load().cache == nil, true)
+
+	for _, test := range []struct {
+		method, path string
+		headers      map[string]string
+		action       string
+	}{
+		{"GET", "/dashboard", nil, "App.Dashboard"},
+		{"GET", "/dashboard", map[string]string{"X-Beta": "1"}, "Beta.Dashboard"},
+		{"GET", "/admin", map[string]string{"X-Beta": "1"}, ""},
+		{"GET", "/admin", map[string]string{"X-Beta": "1", "X-Staff": "1"}, "Admin.Index"},
+		{"POST", "/feedback", map[string]string{"X-Beta": "1"}, "Beta.Feedback"},
+		{"POST", "/feedback", nil, ""},
+	} {
+		req, _ := http.NewRequest(test.method, test.path, nil)
+		for key, value := range test.headers {
+			req.Header.Set(key, value)
+		}
+		match := router.Route(req)
+		name := fmt.Sprintf("%s %s %v", test.method, test.path, test.headers)
+		if test.action == "" {
+			eq(t, "No match for "+name, match == nil, true)
+			continue
+		}
+		if eq(t, "Match for "+name, match != nil && match.Route != nil, true) {
+			eq(t, "Action for "+name, match.Route.Action, test.action)
+		}
+	}
+
+	if _, err := parseRoutes("", "GET /x A.B {guard=unknown}", false); err == nil {
+		t.Error("Expected an error for an unknown guard")
+	}
+}
+
+func TestEncodedSlashes(t *testing.T) {
+	router := NewRouter("")
+	router.Routes, _ = parseRoutes("", `
+GET  /files/:name          Files.Show
+GET  /files/:name/raw      Files.Raw
+GET  /public/*filepath     Static.Serve
+`, false)
+	router.updateTree()
+
+	route := func(path string) *RouteMatch {
+		req, _ := http.NewRequest("GET", path, nil)
+		return router.Route(req)
+	}
+
+	// By default, an encoded slash separates segments.
+	eq(t, "Decoded", route("/files/a%2Fb.txt") == nil, true)
+	if match := route("/files/a%2Fraw"); eq(t, "Decoded match", match != nil, true) {
+		eq(t, "Action", match.Route.Action, "Files.Raw")
+	}
+
+	router.EncodedSlashes = "keep"
+	for _, test := range []struct {
+		path, action, param, value string
+	}{
+		{"/files/a%2Fb.txt", "Files.Show", "name", "a/b.txt"},
+		{"/files/a%2fb.txt", "Files.Show", "name", "a/b.txt"},
+		{"/files/a%2Fraw", "Files.Show", "name", "a/raw"},
+		{"/files/a%2Fb/raw", "Files.Raw", "name", "a/b"},
+		{"/files/100%25%2F2", "Files.Show", "name", "100%/2"},
+		{"/files/a%20b", "Files.Show", "name", "a b"},
+		{"/public/css%2Fx/site.css", "Static.Serve", "filepath", "css/x/site.css"},
+	} {
+		match := route(test.path)
+		if !eq(t, "Match "+test.path, match != nil, true) {
+			continue
+		}
+		eq(t, "Action "+test.path, match.Route.Action, test.action)
+		eq(t, "Param "+test.path, url.Values(match.Params).Get(test.param), test.value)
+	}
+}
+
+func TestRefreshConcurrent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "revel-routes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	routesPath := filepath.Join(dir, "routes")
+	tables := []string{
+		"GET /users/:id 301 /a/:id as users\n",
+		"GET /users/:id 301 /b/:id as users\nGET /extra 404\n",
+	}
+
+	router := NewRouter(routesPath)
+	ioutil.WriteFile(routesPath, []byte(tables[0]), 0644)
+	if err := router.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Route, reverse, and list while the routes are reloaded.
+	var (
+		done     = make(chan struct{})
+		failures = make(chan string, 3)
+	)
+	for i := 0; i < 3; i++ {
+		go func() {
+			for {
+				select {
+				case <-done:
+					failures <- ""
+					return
+				default:
+				}
+				req, _ := http.NewRequest("GET", "/users/1", nil)
+				match := router.Route(req)
+				if match == nil || (match.Location != "/a/1" && match.Location != "/b/1") {
+					failures <- fmt.Sprintf("unexpected match: %+v", match)
+					return
+				}
+				if action := router.ReverseByName("users", map[string]string{"id": "1"}); action == nil || action.Url != "/users/1" {
+					failures <- fmt.Sprintf("unexpected reverse: %+v", action)
+					return
+				}
+				if n := len(router.List()); n != 1 && n != 2 {
+					failures <- fmt.Sprintf("unexpected number of routes: %d", n)
+					return
+				}
+			}
+		}()
+	}
+	for i := 0; i < 50; i++ {
+		ioutil.WriteFile(routesPath, []byte(tables[i%2]), 0644)
+		if err := router.Refresh(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	close(done)
+	for i := 0; i < 3; i++ {
+		if msg := <-failures; msg != "" {
+			t.Error(msg)
+		}
+	}
+
+	// A failed refresh leaves the previous routes in use.
+	ioutil.WriteFile(routesPath, []byte("GET /a 404\n}\n"), 0644)
+	if err := router.Refresh(); err == nil {
+		t.Error("Expected an error refreshing an invalid routes file")
+	}
+	req, _ := http.NewRequest("GET", "/users/1", nil)
+	if match := router.Route(req); eq(t, "Match after failed refresh", match != nil, true) {
+		eq(t, "Location", match.Location, "/b/1")
+	}
+	eq(t, "Routes after failed refresh", len(router.Table()), 2)
+}
+
+func TestCheckReferences(t *testing.T) {
+	dir, err := ioutil.TempDir("", "revel-views")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(paths []string) { TemplatePaths = paths }(TemplatePaths)
+	TemplatePaths = []string{dir}
+
+	router := NewRouter("")
+	router.Routes, _ = parseRoutes("", "GET /users/:id Users.Show", false)
+	router.updateTree()
+
+	view := filepath.Join(dir, "index.html")
+	ioutil.WriteFile(view, []byte(`<a href="{{url "Users.Show" .id}}">`), 0644)
+	if err := router.load().checkReferences(); err != nil {
+		t.Error("Unexpected error:", err)
+	}
+
+	ioutil.WriteFile(view, []byte("<p>\n<a href=\"{{url \"Users.Edit\" .id}}\">"), 0644)
+	refErr := router.load().checkReferences()
+	if eq(t, "Found missing reference", refErr != nil, true) {
+		eq(t, "Description", refErr.Description, "No route for action Users.Edit")
+		eq(t, "Path", refErr.Path, view)
+		eq(t, "Line", refErr.Line, 2)
+	}
+}
+
+func TestErrorRoutes(t *testing.T) {
+	startFakeBookingApp()
+	defer func(router *Router) { MainRouter = router }(MainRouter)
+	MainRouter = NewRouter("")
+	var routeErr *Error
+	MainRouter.Routes, routeErr = parseRoutes("", `
+GET    /hotels/:id       Hotels.Show
+ERROR  404  /hotels/*    Hotels.Index
+ERROR  500  /about       Hotels.Index
+`, false)
+	if routeErr != nil {
+		t.Fatal(routeErr)
+	}
+	MainRouter.updateTree()
+	eq(t, "Method", MainRouter.Routes[1].Method, "ERROR")
+	eq(t, "ErrorStatus", MainRouter.Routes[1].ErrorStatus, 404)
+
+	for _, test := range []struct {
+		status   int
+		path     string
+		expected bool
+	}{
+		{404, "/hotels", true},
+		{404, "/hotels/", true},
+		{404, "/hotels/1/rooms", true},
+		{404, "/hotelsx", false},
+		{500, "/hotels/1", false},
+		{500, "/about", true},
+		{500, "/about/us", false},
+	} {
+		eq(t, fmt.Sprintf("Error route for %d %s", test.status, test.path),
+			MainRouter.ErrorRoute(test.status, test.path) != nil, test.expected)
+	}
+
+	// Error routes are not matched by requests, nor reversed to.
+	req, _ := http.NewRequest("ERROR", "/hotels/1/rooms", nil)
+	eq(t, "Match", MainRouter.Route(req) == nil, true)
+	eq(t, "Reverse", MainRouter.Reverse("Hotels.Index", map[string]string{}) == nil, true)
+
+	// The error route's action renders the error for its paths.
+	resp := httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/hotels/1/rooms", nil)
+	handle(resp, req)
+	eq(t, "Status", resp.Code, http.StatusNotFound)
+	eq(t, "Body", resp.Body.String(), "Hello, World!")
+
+	resp = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/missing", nil)
+	handle(resp, req)
+	eq(t, "Status", resp.Code, http.StatusNotFound)
+	eq(t, "Body", resp.Body.String() != "Hello, World!", true)
+}
+
+// Test that the context of a route with a timeout lasts until its result has
+// been applied, so that streams end at the deadline, not when the action
+// returns.
+func TestRouteTimeoutStream(t *testing.T) {
+	startFakeBookingApp()
+	defer func(router *Router, filters []Filter) {
+		MainRouter, Filters = router, filters
+	}(MainRouter, Filters)
+	MainRouter = NewRouter("")
+	MainRouter.Routes, _ = parseRoutes("", "GET /events Hotels.Index {timeout=50ms}", false)
+	MainRouter.updateTree()
+
+	doneOnSend := make(chan error, 1)
+	Filters = []Filter{RouterFilter, func(c *Controller, _ []Filter) {
+		ctx := c.Context()
+		events := make(chan Event)
+		go func() {
+			defer close(events)
+			select {
+			case events <- Event{Data: "hello"}:
+				doneOnSend <- ctx.Err()
+			case <-ctx.Done():
+				doneOnSend <- ctx.Err()
+				return
+			}
+			<-ctx.Done()
+		}()
+		c.Result = c.RenderSSE(events)
+	}}
+
+	req, _ := http.NewRequest("GET", "/events", nil)
+	resp := httptest.NewRecorder()
+	start := time.Now()
+	handle(resp, req)
+	eq(t, "Context done when streaming", <-doneOnSend, nil)
+	eq(t, "Streamed until the deadline", time.Since(start) >= 50*time.Millisecond, true)
+	eq(t, "Event", strings.Contains(resp.Body.String(), "data: hello"), true)
+}
+
+func TestRouteRequestLimits(t *testing.T) {
+	startFakeBookingApp()
+	defer func(router *Router) { MainRouter = router }(MainRouter)
+	MainRouter = NewRouter("")
+	MainRouter.Routes, _ = parseRoutes("", "POST /hotels/:id/book Hotels.Book {timeout=5s, maxbody=1KB}", false)
+	MainRouter.updateTree()
+	eq(t, "timeout", MainRouter.Routes[0].timeout, 5*time.Second)
+	eq(t, "maxBody", MainRouter.Routes[0].maxBody, int64(1024))
+
+	for _, test := range []struct {
+		body          string
+		contentLength int64
+		status        int
+		readErr       bool
+	}{
+		{"name=Bob", 8, 0, false},
+		{strings.Repeat("x", 2000), 2000, http.StatusRequestEntityTooLarge, false},
+		{strings.Repeat("x", 2000), -1, 0, true},
+	} {
+		req, _ := http.NewRequest("POST", "/hotels/1/book", strings.NewReader(test.body))
+		req.ContentLength = test.contentLength
+		c := NewController(NewRequest(req), NewResponse(httptest.NewRecorder()))
+
+		var (
+			called, hasDeadline bool
+			readErr             error
+		)
+		RouterFilter(c, []Filter{func(c *Controller, _ []Filter) {
+			called = true
+			_, hasDeadline = c.Context().Deadline()
+			_, readErr = ioutil.ReadAll(c.Request.Body)
+		}})
+
+		name := fmt.Sprintf("body of %d bytes", len(test.body))
+		eq(t, "Status for "+name, c.Response.Status, test.status)
+		eq(t, "Called action for "+name, called, test.status == 0)
+		if called {
+			eq(t, "Deadline for "+name, hasDeadline, true)
+			eq(t, "Read error for "+name, readErr != nil, test.readErr)
+		}
+	}
+
+	for _, option := range []string{"timeout=soon", "maxbody=2XB", "maxbody=-1", "maxbody=GB", "maxbody=99999999999GB"} {
+		if _, err := parseRoutes("", "POST /upload Files.Upload {"+option+"}", false); err == nil {
+			t.Errorf("Expected an error for {%s}", option)
+		}
+	}
+}
+
+func TestLocaleRoutes(t *testing.T) {
+	router := NewRouter("")
+	router.Locales = []string{"en", "fr"}
+	router.TrailingSlash = "redirect"
+	router.Routes, _ = parseRoutes("", `
+GET  /             App.Index
+GET  /users/:id    Users.Show
+GET  /hidden       404
+`, false)
+	router.updateTree()
+
+	for _, test := range []struct {
+		path, action, locale, location string
+	}{
+		{"/fr/users/1", "Users.Show", "fr", ""},
+		{"/fr/hidden", "404", "fr", ""},
+		{"/users/1", "Users.Show", "", ""},
+		{"/en", "App.Index", "en", ""},
+		{"/fr/users/1/", "301", "fr", "/fr/users/1"},
+		{"/de/users/1", "", "", ""},
+	} {
+		actual := router.Route(&http.Request{Method: "GET", URL: &url.URL{Path: test.path}})
+		if test.action == "" {
+			if actual != nil {
+				t.Errorf("%s: expected no route, got %s", test.path, actual.Action)
+			}
+			continue
+		}
+		if !eq(t, "Found route for "+test.path, actual != nil, true) {
+			continue
+		}
+		eq(t, "Locale for "+test.path, actual.Locale, test.locale)
+		if actual.Route != nil {
+			eq(t, "Action for "+test.path, actual.Route.Action, test.action)
+		} else {
+			eq(t, "Action for "+test.path, actual.Action, test.action)
+			eq(t, "Location for "+test.path, actual.Location, test.location)
+		}
+	}
+
+	eq(t, "Shared match unchanged", notFound.Locale, "")
+
+	eq(t, "Reverse in fr", router.Reverse("Users.Show", map[string]string{"id": "1", "locale": "fr"}).Url, "/fr/users/1")
+	eq(t, "Reverse index in fr", router.Reverse("App.Index", map[string]string{"locale": "fr"}).Url, "/fr")
+	eq(t, "Reverse in de", router.Reverse("Users.Show", map[string]string{"id": "1", "locale": "de"}).Url, "/users/1?locale=de")
+}
+
+func TestRouteMatchRelease(t *testing.T) {
+	router := NewRouter("")
+	router.Routes, _ = parseRoutes("", `
+GET  /users/:id              Users.Show
+GET  /posts/:year/:slug      Posts.Show
+GET  /about                  App.About
+`, false)
+	router.updateTree()
+
+	for i := 0; i < 3; i++ {
+		for _, test := range []struct {
+			path, method, params string
+		}{
+			{"/users/1", "Show", "id=1"},
+			{"/posts/2013/hello", "Show", "slug=hello&year=2013"},
+			{"/about", "About", ""},
+		} {
+			match := router.Route(&http.Request{Method: "GET", URL: &url.URL{Path: test.path}})
+			if !eq(t, "Found route for "+test.path, match != nil, true) {
+				continue
+			}
+			eq(t, "MethodName for "+test.path, match.MethodName, test.method)
+			eq(t, "Params for "+test.path, url.Values(match.Params).Encode(), test.params)
+			releaseRouteMatch(match)
+			eq(t, "Released "+test.path, match.Route == nil && len(match.Params) == 0, true)
+		}
+	}
+
+	// Matches that were not taken from the pool are left alone.
+	match := &RouteMatch{Action: "404"}
+	releaseRouteMatch(match)
+	eq(t, "Unpooled match", match.Action, "404")
+}
+
 func BenchmarkRouter(b *testing.B) {
 	router := NewRouter("")
 	router.Routes, _ = parseRoutes("", TEST_ROUTES, false)
@@ -301,6 +2206,7 @@ func BenchmarkRouter(b *testing.B) {
 			if r == nil {
 				b.Errorf("Request not found: %s", req.URL.Path)
 			}
+			releaseRouteMatch(r)
 		}
 	}
 }
@@ -352,10 +2258,25 @@ func BenchmarkLargeRouter(b *testing.B) {
 			if route == nil {
 				b.Errorf("Failed to route: %s", req.URL.Path)
 			}
+			releaseRouteMatch(route)
 		}
 	}
 }
 
+func BenchmarkReverse(b *testing.B) {
+	router := NewRouter("")
+	router.Routes, _ = parseRoutes("", TEST_ROUTES, false)
+	for i := 0; i < 500; i++ {
+		router.Routes = append(router.Routes, NewRoute("GET", fmt.Sprintf("/generated/%d", i),
+			fmt.Sprintf("Generated.Action%d", i), "", "", 0))
+	}
+	router.updateTree()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		router.Reverse("Application.Show", map[string]string{"id": "123"})
+	}
+}
+
 func BenchmarkRouterFilter(b *testing.B) {
 	startFakeBookingApp()
 	controllers := []*Controller{
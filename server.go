@@ -2,8 +2,10 @@ package revel
 
 import (
 	"code.google.com/p/go.net/websocket"
+	"context"
 	"fmt"
 	"net/http"
+	"sync/atomic"
 	"time"
 )
 
@@ -31,13 +33,61 @@ func handleInternal(w http.ResponseWriter, r *http.Request, ws *websocket.Conn)
 	var (
 		req  = NewRequest(r)
 		resp = NewResponse(w)
-		c    = NewController(req, resp)
+		c    = acquireController(req, resp)
 	)
 	req.Websocket = ws
 
-	Filters[0](c, Filters[1:])
-	if c.Result != nil {
-		c.Result.Apply(req, resp)
+	chain := Filters
+	if FilterTiming {
+		chain = timedFilters(chain)
+		c.timer.timed = true
+	}
+	chain[0](c, chain[1:])
+	if !resp.hijacked {
+		handleErrorRoute(c)
+		runResultHooks(c)
+		if c.Result != nil {
+			c.Result.Apply(req, resp)
+		}
+	}
+	c.cleanup()
+	if FilterTimingLog {
+		logFilterTimes(c)
+	}
+	if r.Context().Err() == context.Canceled {
+		requestAborted(c)
+	}
+
+	// The request has been handled, so its controller and route match may be
+	// reused.
+	releaseRouteMatch(c.Route)
+	releaseController(c)
+}
+
+var (
+	abortedRequests int64
+	abortHooks      []func(c *Controller)
+)
+
+// AbortedRequests returns the number of requests whose clients disconnected
+// before their responses were complete.
+func AbortedRequests() int64 {
+	return atomic.LoadInt64(&abortedRequests)
+}
+
+// OnAbort registers a hook called for each request whose client disconnected
+// before its response was complete, once the request has been handled, e.g.
+// to record a metric.
+func OnAbort(hook func(c *Controller)) {
+	abortHooks = append(abortHooks, hook)
+}
+
+// requestAborted records a request whose client disconnected.
+func requestAborted(c *Controller) {
+	atomic.AddInt64(&abortedRequests, 1)
+	c.Log.Info("Request aborted by the client", "method", c.Request.Method, "path", c.Request.URL.Path)
+	for _, hook := range abortHooks {
+		hook(c)
 	}
 }
 
@@ -86,6 +136,9 @@ func runStartupHooks() {
 	for _, hook := range startupHooks {
 		hook()
 	}
+
+	// The application's binders are all registered by now.
+	findDirectKinds()
 }
 
 var startupHooks []func()
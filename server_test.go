@@ -1,12 +1,16 @@
 package revel
 
 import (
+	"context"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"path"
 	"strings"
 	"testing"
+	"time"
 )
 
 // This tries to benchmark the usual request-serving pipeline to get an overall
@@ -86,6 +90,110 @@ func TestFakeServer(t *testing.T) {
 	resp.Body = nil
 }
 
+// Test that the controller's context is the request's, and that a timeout
+// set on it is seen by the later filters.
+func TestControllerContext(t *testing.T) {
+	parent, cancelParent := context.WithCancel(context.Background())
+	req, _ := http.NewRequest("GET", "/hotels", nil)
+	c := NewController(NewRequest(req.WithContext(parent)), NewResponse(httptest.NewRecorder()))
+	eq(t, "Request context", c.Context(), parent)
+
+	_, cancel := c.WithTimeout(time.Minute)
+	defer cancel()
+	_, hasDeadline := c.Context().Deadline()
+	eq(t, "Deadline", hasDeadline, true)
+
+	// Cancelling the request (e.g. by disconnecting) cancels the derived context.
+	cancelParent()
+	select {
+	case <-c.Context().Done():
+	case <-time.After(time.Second):
+		t.Error("Expected the context to be cancelled with the request's")
+	}
+
+	eq(t, "No request", NewController(nil, nil).Context(), context.Background())
+}
+
+func TestControllerPool(t *testing.T) {
+	defer func(check bool) { PoolCheck = check }(PoolCheck)
+	PoolCheck = false
+
+	c := acquireController(NewRequest(showRequest), NewResponse(httptest.NewRecorder()))
+	c.Args["user"] = "Bob"
+	c.RenderArgs["title"] = "Hotels"
+	c.Params.Route = url.Values{"id": {"3"}}
+	releaseController(c)
+	eq(t, "Args reset", len(c.Args), 0)
+	eq(t, "RenderArgs reset", len(c.RenderArgs), 0)
+	eq(t, "Params reset", c.Params.Route == nil, true)
+	eq(t, "Request reset", c.Request == nil, true)
+
+	// With PoolCheck, a released controller may not be used.
+	PoolCheck = true
+	c = acquireController(NewRequest(showRequest), NewResponse(httptest.NewRecorder()))
+	c.Action = "Hotels.Show"
+	releaseController(c)
+	defer func() {
+		err, _ := recover().(string)
+		eq(t, "Use after release", strings.Contains(err, "Hotels.Show"), true)
+	}()
+	c.Render()
+	t.Error("Expected a panic using a released controller")
+}
+
+func TestAbortedRequest(t *testing.T) {
+	startFakeBookingApp()
+	defer func(hooks []func(*Controller)) { abortHooks = hooks }(abortHooks)
+
+	var aborted []string
+	OnAbort(func(c *Controller) { aborted = append(aborted, c.Action) })
+	before := AbortedRequests()
+
+	// A request whose client is still connected is not aborted.
+	handle(httptest.NewRecorder(), plaintextRequest)
+	eq(t, "Not aborted", AbortedRequests(), before)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req := plaintextRequest.WithContext(ctx)
+	cancel()
+	c := NewController(NewRequest(req), nil)
+	eq(t, "Aborted", c.Aborted(), true)
+	handle(httptest.NewRecorder(), req)
+	eq(t, "Aborted requests", AbortedRequests(), before+1)
+	eq(t, "Hook", fmt.Sprint(aborted), "[Hotels.Index]")
+}
+
+// headerResult sets a header before applying the result it wraps.
+type headerResult struct {
+	Result
+	name, value string
+}
+
+func (r headerResult) Apply(req *Request, resp *Response) {
+	resp.Out.Header().Set(r.name, r.value)
+	r.Result.Apply(req, resp)
+}
+
+func TestResultHooks(t *testing.T) {
+	startFakeBookingApp()
+	defer func(hooks []func(*Controller, Result) Result) { resultHooks = hooks }(resultHooks)
+
+	var resultTypes []string
+	OnResult(func(c *Controller, result Result) Result {
+		resultTypes = append(resultTypes, fmt.Sprintf("%T", result))
+		return headerResult{result, "X-Action", c.Action}
+	})
+	OnResult(func(c *Controller, result Result) Result {
+		panic("broken hook")
+	})
+
+	resp := httptest.NewRecorder()
+	handle(resp, plaintextRequest)
+	eq(t, "Body", resp.Body.String(), "Hello, World!")
+	eq(t, "Header", resp.Header().Get("X-Action"), "Hotels.Index")
+	eq(t, "Result types", fmt.Sprint(resultTypes), "[*revel.RenderTextResult]")
+}
+
 func getFileSize(t *testing.T, name string) int64 {
 	fi, err := os.Stat(name)
 	if err != nil {
@@ -10,7 +10,8 @@ import (
 	"time"
 )
 
-// A signed cookie (and thus limited to 4kb in size).
+// A signed cookie (and thus limited to 4kb in size), or, if SessionStorage is
+// set, a session stored on the server, whose cookie has just its ID.
 // Restriction: Keys may not have a colon in them.
 type Session map[string]string
 
@@ -113,7 +114,24 @@ func getSessionFromCookie(cookie *http.Cookie) Session {
 	return session
 }
 
+// SessionFilter restores the request's session, and stores it once the action
+// has run: in its signed cookie, or in the SessionStorage, if it is set, with
+// just its ID in the cookie.  A stored session is destroyed once its keys
+// have all been deleted (e.g. on logout), and moved to a new ID if its ID has
+// been deleted (e.g. on login, so that an ID known before then is no use):
+//   delete(c.Session, revel.SESSION_ID_KEY)
 func SessionFilter(c *Controller, fc []Filter) {
+	if store := SessionStorage; store != nil {
+		var version int64
+		c.Session, version = restoreStoredSession(store, c.Request.Request)
+		restored := copySession(c.Session)
+
+		fc[0](c, fc[1:])
+
+		saveStoredSession(c, store, restored, version)
+		return
+	}
+
 	c.Session = restoreSession(c.Request.Request)
 
 	fc[0](c, fc[1:])
@@ -0,0 +1,309 @@
+package revel
+
+import (
+	"crypto/hmac"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SessionStore stores sessions on the server, by their IDs, so that the
+// session cookie holds just the (signed) ID.  Sessions stored so are not
+// limited to the 4kb of a cookie, and may be ended on the server, e.g. those
+// of a user whose password has changed.  Stores must be safe for concurrent
+// use.
+type SessionStore interface {
+	// Get returns the session with the ID, or nil if there is none, or it
+	// has expired.
+	Get(id string) (Session, error)
+
+	// Set stores the session with the ID, to expire after the duration.
+	Set(id string, session Session, expires time.Duration) error
+
+	// Destroy removes the session with the ID, if there is one.
+	Destroy(id string) error
+
+	// Touch extends the life of the session with the ID, unchanged, to
+	// expire after the duration.
+	Touch(id string, expires time.Duration) error
+}
+
+// ErrSessionConflict is the error of a VersionedSessionStore in storing a
+// session that has been stored (by another request) since it was got.
+var ErrSessionConflict = errors.New("the session was changed by another request")
+
+// VersionedSessionStore is a SessionStore that keeps a version of each
+// session, so that of concurrent requests with the same session, one does not
+// overwrite the changes of another.  The SessionFilter stores the sessions of
+// such stores from the version that they were got at, and if another request
+// has stored the session meanwhile, applies the request's changes to the
+// session as stored, and tries again.
+type VersionedSessionStore interface {
+	SessionStore
+
+	// GetVersion returns the session with the ID, and its version, or nil if
+	// there is none, or it has expired.
+	GetVersion(id string) (session Session, version int64, err error)
+
+	// SetVersion stores the session with the ID, if its stored version is
+	// that given, or if the version is 0 and there is none stored, or else
+	// returns ErrSessionConflict.
+	SetVersion(id string, session Session, version int64, expires time.Duration) error
+}
+
+// maxSessionMerges is the most times that a session is merged with that
+// stored by another request, and stored again.
+const maxSessionMerges = 3
+
+// SessionStorage, if set, stores the sessions of the SessionFilter, rather
+// than their cookies.  It is set by "session.store", the name of a store
+// registered with RegisterSessionStore, e.g. "memory", or "cookie" (the
+// default) for none.
+var SessionStorage SessionStore
+
+// sessionStores maps names to the openers of the stores registered with
+// RegisterSessionStore.
+var sessionStores = map[string]func() (SessionStore, error){
+	"memory": func() (SessionStore, error) { return NewMemorySessionStore(), nil },
+}
+
+// RegisterSessionStore registers a session store that "session.store" may
+// name, opened when the app starts, e.g. by a module that stores sessions in
+// a database.  Stores should be registered on initialization.
+func RegisterSessionStore(name string, open func() (SessionStore, error)) {
+	sessionStores[name] = open
+}
+
+func init() {
+	OnAppStart(func() {
+		SessionStorage = nil
+		name := Config.StringDefault("session.store", "cookie")
+		if name == "cookie" {
+			return
+		}
+		open, ok := sessionStores[name]
+		if !ok {
+			ERROR.Fatalln("Invalid session.store:", name)
+		}
+		var err error
+		if SessionStorage, err = open(); err != nil {
+			ERROR.Fatalln("Failed to open the session store", name+":", err)
+		}
+	})
+}
+
+// restoreStoredSession returns the session whose signed ID the request's
+// cookie has, from the store, and its version (if the store keeps them), or a
+// new session if there is none.  Unknown IDs are not reused, so that a
+// session's ID is always one that was given to its client.
+func restoreStoredSession(store SessionStore, req *http.Request) (Session, int64) {
+	cookie, err := req.Cookie(CookiePrefix + "_SESSION")
+	if err != nil {
+		return make(Session), 0
+	}
+	hyphen := strings.Index(cookie.Value, "-")
+	if hyphen == -1 || hyphen >= len(cookie.Value)-1 {
+		return make(Session), 0
+	}
+	sig, id := cookie.Value[:hyphen], cookie.Value[hyphen+1:]
+	if !hmac.Equal([]byte(Sign(id)), []byte(sig)) {
+		INFO.Println("Session cookie signature failed")
+		return make(Session), 0
+	}
+
+	var (
+		session Session
+		version int64
+	)
+	if versioned, ok := store.(VersionedSessionStore); ok {
+		session, version, err = versioned.GetVersion(id)
+	} else {
+		session, err = store.Get(id)
+	}
+	if err != nil {
+		ERROR.Println("Failed to get the session:", err)
+	}
+	if session == nil {
+		return make(Session), 0
+	}
+	session[SESSION_ID_KEY] = id
+	return session, version
+}
+
+// saveStoredSession stores the session, or destroys it if it has been emptied,
+// and sets its cookie.  If the session's ID has been deleted (e.g. to give
+// the session of a user that has just logged in a new ID), the session is
+// moved to a new ID.  A session that is unchanged is touched.
+func saveStoredSession(c *Controller, store SessionStore, restored Session, version int64) {
+	restoredId := restored[SESSION_ID_KEY]
+	session := make(Session, len(c.Session))
+	for key, value := range c.Session {
+		if key != SESSION_ID_KEY && key != TS_KEY {
+			session[key] = value
+		}
+	}
+
+	id := c.Session[SESSION_ID_KEY]
+	if restoredId != "" && (id != restoredId || len(session) == 0) {
+		if err := store.Destroy(restoredId); err != nil {
+			ERROR.Println("Failed to destroy the session:", err)
+		}
+	}
+	if len(session) == 0 {
+		if restoredId != "" {
+			c.SetCookie(&http.Cookie{Name: CookiePrefix + "_SESSION", Path: "/", MaxAge: -1})
+		}
+		return
+	}
+
+	id = c.Session.Id()
+	var err error
+	if id == restoredId && sameSession(session, restored) {
+		err = store.Touch(id, expireAfterDuration)
+	} else {
+		if id != restoredId {
+			version = 0
+		}
+		err = setStoredSession(store, id, session, restored, version)
+	}
+	if err != nil {
+		ERROR.Println("Failed to store the session:", err)
+		return
+	}
+	c.SetCookie(&http.Cookie{
+		Name:    CookiePrefix + "_SESSION",
+		Value:   Sign(id) + "-" + id,
+		Path:    "/",
+		Expires: getSessionExpiration().UTC(),
+	})
+}
+
+// setStoredSession stores the session.  If the store is versioned, the
+// session is stored from the version that it was restored at, or else the
+// request's changes to it (from restored) are applied to the session stored
+// since, which is stored in turn.  A session destroyed since is not stored.
+func setStoredSession(store SessionStore, id string, session, restored Session, version int64) error {
+	versioned, ok := store.(VersionedSessionStore)
+	if !ok {
+		return store.Set(id, session, expireAfterDuration)
+	}
+	for merges := 0; ; merges++ {
+		err := versioned.SetVersion(id, session, version, expireAfterDuration)
+		if err != ErrSessionConflict || merges == maxSessionMerges {
+			return err
+		}
+		var stored Session
+		if stored, version, err = versioned.GetVersion(id); err != nil {
+			return err
+		}
+		if stored == nil {
+			return ErrSessionConflict
+		}
+		session = mergeSession(stored, restored, session)
+	}
+}
+
+// mergeSession applies the changes made to the restored session (the keys
+// set and deleted) to the stored one, returning it.
+func mergeSession(stored, restored, session Session) Session {
+	for key, value := range session {
+		if restoredValue, ok := restored[key]; !ok || restoredValue != value {
+			stored[key] = value
+		}
+	}
+	for key := range restored {
+		if _, ok := session[key]; !ok && key != SESSION_ID_KEY {
+			delete(stored, key)
+		}
+	}
+	return stored
+}
+
+// sameSession returns true if the session has the values of the restored one
+// (other than its ID).
+func sameSession(session, restored Session) bool {
+	if _, ok := restored[SESSION_ID_KEY]; len(session) != len(restored)-1 || !ok {
+		return false
+	}
+	for key, value := range session {
+		if restoredValue, ok := restored[key]; !ok || restoredValue != value {
+			return false
+		}
+	}
+	return true
+}
+
+// MemorySessionStore is a SessionStore that keeps sessions in memory, e.g.
+// for an app run as a single process.  Its sessions are lost when the app
+// stops.
+type MemorySessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]memorySession
+	swept    time.Time
+}
+
+type memorySession struct {
+	session Session
+	expires time.Time
+}
+
+// memorySessionSweep is how often expired sessions are removed from a
+// MemorySessionStore.
+const memorySessionSweep = time.Minute
+
+func NewMemorySessionStore() *MemorySessionStore {
+	return &MemorySessionStore{sessions: map[string]memorySession{}, swept: time.Now()}
+}
+
+func (s *MemorySessionStore) Get(id string) (Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.sessions[id]
+	if !ok || time.Now().After(stored.expires) {
+		return nil, nil
+	}
+	return copySession(stored.session), nil
+}
+
+func (s *MemorySessionStore) Set(id string, session Session, expires time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if now.Sub(s.swept) >= memorySessionSweep {
+		for storedId, stored := range s.sessions {
+			if now.After(stored.expires) {
+				delete(s.sessions, storedId)
+			}
+		}
+		s.swept = now
+	}
+	s.sessions[id] = memorySession{copySession(session), now.Add(expires)}
+	return nil
+}
+
+func (s *MemorySessionStore) Destroy(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, id)
+	return nil
+}
+
+func (s *MemorySessionStore) Touch(id string, expires time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if stored, ok := s.sessions[id]; ok {
+		stored.expires = time.Now().Add(expires)
+		s.sessions[id] = stored
+	}
+	return nil
+}
+
+func copySession(session Session) Session {
+	copied := make(Session, len(session))
+	for key, value := range session {
+		copied[key] = value
+	}
+	return copied
+}
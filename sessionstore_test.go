@@ -0,0 +1,176 @@
+package revel
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMemorySessionStore(t *testing.T) {
+	store := NewMemorySessionStore()
+	session := Session{"user": "bob"}
+	store.Set("a", session, time.Hour)
+	session["user"] = "alice"
+	stored, _ := store.Get("a")
+	eq(t, "Stored", stored["user"], "bob")
+	stored["user"] = "alice"
+	stored, _ = store.Get("a")
+	eq(t, "Copied", stored["user"], "bob")
+
+	store.Set("b", Session{"user": "carol"}, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	stored, _ = store.Get("b")
+	eq(t, "Expired", stored == nil, true)
+	store.Touch("a", time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	stored, _ = store.Get("a")
+	eq(t, "Touched", stored == nil, true)
+
+	store.Set("c", Session{"user": "dave"}, time.Hour)
+	store.Destroy("c")
+	stored, _ = store.Get("c")
+	eq(t, "Destroyed", stored == nil, true)
+}
+
+func TestStoredSessions(t *testing.T) {
+	startFakeBookingApp()
+	defer func(store SessionStore) { SessionStorage = store }(SessionStorage)
+	store := NewMemorySessionStore()
+	SessionStorage = store
+
+	// request runs the SessionFilter with the cookie, returning the session
+	// that the action saw and the cookie set, if any.
+	request := func(cookie *http.Cookie, action func(s Session)) (Session, *http.Cookie) {
+		req, _ := http.NewRequest("GET", "/", nil)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		resp := httptest.NewRecorder()
+		c := NewController(NewRequest(req), NewResponse(resp))
+		var seen Session
+		SessionFilter(c, []Filter{func(c *Controller, fc []Filter) {
+			seen = copySession(c.Session)
+			action(c.Session)
+		}})
+		cookies := resp.Result().Cookies()
+		if len(cookies) == 0 {
+			return seen, nil
+		}
+		return seen, cookies[0]
+	}
+
+	// A session that is empty is not stored.
+	_, cookie := request(nil, func(s Session) {})
+	eq(t, "No cookie", cookie == nil, true)
+
+	large := strings.Repeat("x", 8<<10)
+	_, cookie = request(nil, func(s Session) {
+		s["user"] = "bob"
+		s["large"] = large
+	})
+	id := cookie.Value[strings.Index(cookie.Value, "-")+1:]
+	eq(t, "Signed ID", cookie.Value, Sign(id)+"-"+id)
+	stored, _ := store.Get(id)
+	eq(t, "Stored", stored["user"], "bob")
+	eq(t, "Stored large", stored["large"] == large, true)
+
+	seen, cookie := request(cookie, func(s Session) {})
+	eq(t, "Restored", seen["user"], "bob")
+	eq(t, "Restored ID", seen.Id(), id)
+	eq(t, "Cookie kept", cookie.Value, Sign(id)+"-"+id)
+
+	// Sessions whose IDs are deleted are moved to new IDs.
+	_, cookie = request(cookie, func(s Session) {
+		delete(s, SESSION_ID_KEY)
+		s["user"] = "alice"
+	})
+	newId := cookie.Value[strings.Index(cookie.Value, "-")+1:]
+	eq(t, "New ID", newId != id, true)
+	stored, _ = store.Get(id)
+	eq(t, "Old ID destroyed", stored == nil, true)
+	stored, _ = store.Get(newId)
+	eq(t, "Moved", stored["user"], "alice")
+
+	// Forged and unknown IDs are not used.
+	seen, _ = request(&http.Cookie{Name: cookie.Name, Value: "bad-" + newId}, func(s Session) {})
+	eq(t, "Forged", seen["user"], "")
+	seen, _ = request(&http.Cookie{Name: cookie.Name, Value: Sign("unknown") + "-unknown"}, func(s Session) {
+		s["user"] = "mallory"
+	})
+	stored, _ = store.Get("unknown")
+	eq(t, "Unknown", stored == nil, true)
+
+	// Sessions whose keys are all deleted are destroyed.
+	_, cookie = request(cookie, func(s Session) {
+		for key := range s {
+			delete(s, key)
+		}
+	})
+	eq(t, "Cookie deleted", cookie.MaxAge, -1)
+	stored, _ = store.Get(newId)
+	eq(t, "Destroyed", stored == nil, true)
+}
+
+// versionedStore is a VersionedSessionStore of sessions in memory.
+type versionedStore struct {
+	*MemorySessionStore
+	versions map[string]int64
+}
+
+func (s *versionedStore) GetVersion(id string) (Session, int64, error) {
+	session, err := s.Get(id)
+	return session, s.versions[id], err
+}
+
+func (s *versionedStore) SetVersion(id string, session Session, version int64, expires time.Duration) error {
+	if s.versions[id] != version {
+		return ErrSessionConflict
+	}
+	s.versions[id]++
+	return s.Set(id, session, expires)
+}
+
+func TestVersionedSessions(t *testing.T) {
+	startFakeBookingApp()
+	defer func(store SessionStore) { SessionStorage = store }(SessionStorage)
+	store := &versionedStore{NewMemorySessionStore(), map[string]int64{}}
+	SessionStorage = store
+
+	request := func(cookie *http.Cookie, action func(s Session)) *http.Cookie {
+		req, _ := http.NewRequest("GET", "/", nil)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		resp := httptest.NewRecorder()
+		c := NewController(NewRequest(req), NewResponse(resp))
+		SessionFilter(c, []Filter{func(c *Controller, fc []Filter) { action(c.Session) }})
+		if cookies := resp.Result().Cookies(); len(cookies) > 0 {
+			return cookies[0]
+		}
+		return nil
+	}
+
+	cookie := request(nil, func(s Session) {
+		s["user"] = "bob"
+		s["cart"] = "1"
+		s["theme"] = "dark"
+	})
+	id := cookie.Value[strings.Index(cookie.Value, "-")+1:]
+	eq(t, "Version", store.versions[id], int64(1))
+
+	// The changes of a request are merged with those of another request that
+	// stored the session meanwhile.
+	request(cookie, func(s Session) {
+		request(cookie, func(s Session) {
+			s["cart"] = "2"
+		})
+		s["user"] = "alice"
+		delete(s, "theme")
+	})
+	stored, _ := store.Get(id)
+	eq(t, "Merged", fmt.Sprint(stored), "map[cart:2 user:alice]")
+	eq(t, "Merged version", store.versions[id], int64(3))
+}
@@ -0,0 +1,112 @@
+package revel
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	errUnsignedURL = errors.New("The link is invalid")
+	errExpiredURL  = errors.New("The link has expired")
+)
+
+func init() {
+	NamedFilters["signed"] = SignedURLFilter
+}
+
+// SignedReverse returns the URL and method of the action, as Reverse does,
+// with the URL signed by the app's secret, so that it can not be forged or
+// altered, e.g. for download links, or the confirmation links of emails.  The
+// signature (and the time that the URL expires, ttl from now, unless ttl is
+// 0) are added to its query string, as "signature" (and "expires"), which the
+// action's arguments must not be named.  The routes of signed URLs should
+// have the "signed" filter (see SignedURLFilter), e.g.
+//   GET  /invoices/:id/download  Invoices.Download  [signed]
+//
+// It returns nil if the action has no route, or the app has no secret.
+func (router *Router) SignedReverse(action string, argValues map[string]string, ttl time.Duration) *ActionDefinition {
+	actionDef := router.Reverse(action, argValues)
+	if actionDef == nil {
+		return nil
+	}
+	if len(secretKey) == 0 {
+		ERROR.Println("revel/router: there is no app.secret to sign the URL of", action)
+		return nil
+	}
+	signedUrl := actionDef.Url
+	if ttl > 0 {
+		signedUrl = appendQuery(signedUrl, "expires="+strconv.FormatInt(time.Now().Add(ttl).Unix(), 10))
+	}
+	actionDef.Url = appendQuery(signedUrl, "signature="+urlSignature(signedUrl))
+	return actionDef
+}
+
+// SignedURLFilter answers requests whose URLs were not signed by
+// SignedReverse (or were altered since), or have expired, with 403 Forbidden.
+// It is the "signed" filter of routes, e.g.
+//   GET  /invoices/:id/download  Invoices.Download  [signed]
+func SignedURLFilter(c *Controller, fc []Filter) {
+	if err := verifySignedURL(c.Request.URL, time.Now()); err != nil {
+		c.Result = c.Forbidden("%s", err)
+		return
+	}
+	fc[0](c, fc[1:])
+}
+
+// verifySignedURL returns an error if the URL's signature (the last parameter
+// of its query string) is not that of the rest of it, or it expired before
+// now.
+func verifySignedURL(u *url.URL, now time.Time) error {
+	query := "&" + u.RawQuery
+	i := strings.LastIndex(query, "&signature=")
+	if i == -1 || len(secretKey) == 0 {
+		return errUnsignedURL
+	}
+	signature, unsigned := query[i+len("&signature="):], ""
+	signedUrl := u.EscapedPath()
+	if i > 0 {
+		unsigned = query[1:i]
+		signedUrl += "?" + unsigned
+	}
+	if !hmac.Equal([]byte(signature), []byte(urlSignature(signedUrl))) {
+		return errUnsignedURL
+	}
+
+	values, err := url.ParseQuery(unsigned)
+	if err != nil {
+		return errUnsignedURL
+	}
+	if expires := values.Get("expires"); expires != "" {
+		unix, err := strconv.ParseInt(expires, 10, 64)
+		if err != nil {
+			return errUnsignedURL
+		}
+		if now.Unix() > unix {
+			return errExpiredURL
+		}
+	}
+	return nil
+}
+
+// urlSignature returns the signature of the URL, in hex.
+func urlSignature(signedUrl string) string {
+	mac := hmac.New(sha256.New, secretKey)
+	io.WriteString(mac, signedUrl)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// appendQuery appends the parameter (e.g. "a=1") to the query string of the
+// URL.
+func appendQuery(u, param string) string {
+	if strings.Contains(u, "?") {
+		return u + "&" + param
+	}
+	return u + "?" + param
+}
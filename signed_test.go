@@ -0,0 +1,61 @@
+package revel
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignedReverse(t *testing.T) {
+	startFakeBookingApp()
+	defer func(router *Router) { MainRouter = router }(MainRouter)
+	MainRouter = NewRouter("")
+	var routeErr *Error
+	MainRouter.Routes, routeErr = parseRoutes("", `
+GET  /hotels/:id/download  Hotels.Book  [signed]
+GET  /hotels               Hotels.Index
+`, false)
+	if routeErr != nil {
+		t.Fatal(routeErr)
+	}
+	MainRouter.updateTree()
+
+	now := time.Now()
+	signed := MainRouter.SignedReverse("Hotels.Book", map[string]string{"id": "3", "file": "a b.pdf"}, time.Hour)
+	forever := MainRouter.SignedReverse("Hotels.Index", nil, 0)
+	eq(t, "Path", strings.HasPrefix(signed.Url, "/hotels/3/download?file=a+b.pdf&expires="), true)
+	eq(t, "No expiry", strings.HasPrefix(forever.Url, "/hotels?signature="), true)
+
+	verify := func(u string, now time.Time) error {
+		parsed, err := url.Parse(u)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return verifySignedURL(parsed, now)
+	}
+	eq(t, "Valid", verify(signed.Url, now), nil)
+	eq(t, "Valid forever", verify(forever.Url, now.Add(24*365*time.Hour)), nil)
+	eq(t, "Expired", verify(signed.Url, now.Add(2*time.Hour)), errExpiredURL)
+	eq(t, "Unsigned", verify("/hotels/3/download?file=a+b.pdf", now), errUnsignedURL)
+	eq(t, "Altered path", verify(strings.Replace(signed.Url, "/3/", "/4/", 1), now), errUnsignedURL)
+	eq(t, "Altered query", verify(strings.Replace(signed.Url, "a+b", "c", 1), now), errUnsignedURL)
+	eq(t, "Added param", verify(signed.Url+"&admin=1", now), errUnsignedURL)
+	eq(t, "Only signature", verify("/hotels?signature=", now), errUnsignedURL)
+
+	for _, test := range []struct {
+		url    string
+		status int
+	}{
+		{signed.Url, http.StatusOK},
+		{"/hotels/3/download", http.StatusForbidden},
+		{strings.Replace(signed.Url, "/3/", "/4/", 1), http.StatusForbidden},
+	} {
+		req, _ := http.NewRequest("GET", test.url, nil)
+		resp := httptest.NewRecorder()
+		handle(resp, req)
+		eq(t, "Status of "+test.url, resp.Code, test.status)
+	}
+}
@@ -0,0 +1,147 @@
+package revel
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// SSEHeartbeat is the interval of the comments sent on idle event streams (see
+// RenderSSE), to keep proxies from closing them, and to find clients that have
+// gone, from "results.sseHeartbeat", e.g. "15s".  0 sends none.
+var SSEHeartbeat = 15 * time.Second
+
+func init() {
+	OnAppStart(func() {
+		if heartbeat, ok := Config.String("results.sseHeartbeat"); ok {
+			var err error
+			if SSEHeartbeat, err = time.ParseDuration(heartbeat); err != nil {
+				ERROR.Fatalln("Invalid results.sseHeartbeat:", heartbeat)
+			}
+		}
+	})
+}
+
+// Event is a Server-Sent Event, sent by RenderSSE.
+type Event struct {
+	ID    string        // The event's ID, which a client that reconnects sends as Last-Event-ID.
+	Type  string        // The type of the event, or "" for "message".
+	Data  interface{}   // The data, sent as it is if a string or []byte, or else as JSON.
+	Retry time.Duration // How long a client waits to reconnect, or 0 for its default.
+}
+
+// RenderSSE streams the events to the client as Server-Sent Events
+// (text/event-stream), each as it is received, until the channel is closed or
+// the client goes.  The action sends the events from a goroutine, which should
+// stop once the request's context is done, e.g.
+//
+//     events := make(chan revel.Event)
+//     go func() {
+//     	defer close(events)
+//     	for {
+//     		select {
+//     		case stats := <-dashboard.Updates:
+//     			events <- revel.Event{Type: "stats", Data: stats}
+//     		case <-c.Request.Context().Done():
+//     			return
+//     		}
+//     	}
+//     }()
+//     return c.RenderSSE(events)
+//
+// Routes with a {timeout} end the stream at their deadline.
+func (c *Controller) RenderSSE(events <-chan Event) Result {
+	return &SSEResult{events: events, heartbeat: SSEHeartbeat}
+}
+
+type SSEResult struct {
+	events    <-chan Event
+	heartbeat time.Duration
+}
+
+func (r *SSEResult) Apply(req *Request, resp *Response) {
+	flusher, ok := resp.Out.(http.Flusher)
+	if !ok {
+		resp.Status = http.StatusInternalServerError
+		ErrorResult{Error: errors.New("The response can not stream events")}.Apply(req, resp)
+		return
+	}
+
+	header := resp.Out.Header()
+	header.Set("Cache-Control", "no-cache")
+	header.Set("X-Accel-Buffering", "no") // e.g. for nginx
+	resp.WriteHeader(http.StatusOK, "text/event-stream")
+	flusher.Flush()
+
+	var heartbeat <-chan time.Time
+	if r.heartbeat > 0 {
+		ticker := time.NewTicker(r.heartbeat)
+		defer ticker.Stop()
+		heartbeat = ticker.C
+	}
+	done := req.Context().Done()
+	for {
+		var err error
+		select {
+		case <-done:
+			return
+		case event, ok := <-r.events:
+			if !ok {
+				return
+			}
+			err = writeEvent(resp.Out, event)
+		case <-heartbeat:
+			_, err = io.WriteString(resp.Out, ":\n\n")
+		}
+		if err != nil {
+			// The client has gone.
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// writeEvent writes the event in the text/event-stream format.  Events whose
+// data can not be encoded are left out.
+func writeEvent(w io.Writer, event Event) error {
+	var data string
+	switch d := event.Data.(type) {
+	case nil:
+	case string:
+		data = d
+	case []byte:
+		data = string(d)
+	default:
+		b, err := json.Marshal(d)
+		if err != nil {
+			ERROR.Println("Could not encode the event:", err)
+			return nil
+		}
+		data = string(b)
+	}
+
+	// Newlines would end the fields (or the event) early.
+	oneLine := strings.NewReplacer("\r", "", "\n", "")
+	var b bytes.Buffer
+	if event.ID != "" {
+		fmt.Fprintf(&b, "id: %s\n", oneLine.Replace(event.ID))
+	}
+	if event.Type != "" {
+		fmt.Fprintf(&b, "event: %s\n", oneLine.Replace(event.Type))
+	}
+	if event.Retry > 0 {
+		fmt.Fprintf(&b, "retry: %d\n", event.Retry.Milliseconds())
+	}
+	data = strings.ReplaceAll(strings.ReplaceAll(data, "\r\n", "\n"), "\r", "\n")
+	for _, line := range strings.Split(data, "\n") {
+		fmt.Fprintf(&b, "data: %s\n", line)
+	}
+	b.WriteString("\n")
+	_, err := w.Write(b.Bytes())
+	return err
+}
@@ -0,0 +1,48 @@
+package revel
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderSSE(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/dashboard/events", nil)
+	resp := httptest.NewRecorder()
+	c := NewController(NewRequest(req), NewResponse(resp))
+
+	events := make(chan Event, 3)
+	events <- Event{ID: "1", Type: "stats", Data: map[string]int{"users": 3}}
+	events <- Event{Data: "line 1\nline 2", Retry: 5 * time.Second}
+	events <- Event{ID: "2\n", Data: []byte("bytes")}
+	close(events)
+	c.RenderSSE(events).Apply(c.Request, c.Response)
+
+	eq(t, "Status", resp.Code, http.StatusOK)
+	eq(t, "Content-Type", resp.Header().Get("Content-Type"), "text/event-stream")
+	eq(t, "Cache-Control", resp.Header().Get("Cache-Control"), "no-cache")
+	eq(t, "Body", resp.Body.String(), strings.Join([]string{
+		"id: 1\nevent: stats\ndata: {\"users\":3}\n\n",
+		"retry: 5000\ndata: line 1\ndata: line 2\n\n",
+		"id: 2\ndata: bytes\n\n",
+	}, ""))
+}
+
+func TestSSEHeartbeat(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequest("GET", "/dashboard/events", nil)
+	resp := httptest.NewRecorder()
+	c := NewController(NewRequest(req.WithContext(ctx)), NewResponse(resp))
+
+	// The stream ends when the client goes, though the channel is open.
+	result := c.RenderSSE(make(chan Event)).(*SSEResult)
+	result.heartbeat = 10 * time.Millisecond
+	result.Apply(c.Request, c.Response)
+
+	eq(t, "Heartbeats", strings.Count(resp.Body.String(), ":\n\n") >= 2, true)
+	eq(t, "Only heartbeats", strings.Trim(resp.Body.String(), ":\n"), "")
+}
@@ -0,0 +1,110 @@
+package revel
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+)
+
+// StreamFlushInterval is how often a streamed response (see Stream) is
+// flushed to the client, from "results.streamFlush", e.g. "100ms".  0 flushes
+// each chunk as it is read.
+var StreamFlushInterval time.Duration
+
+// streamChunkSize is the size of the chunks that streamed responses are read
+// in.
+const streamChunkSize = 32 << 10
+
+func init() {
+	OnAppStart(func() {
+		if interval, ok := Config.String("results.streamFlush"); ok {
+			var err error
+			if StreamFlushInterval, err = time.ParseDuration(interval); err != nil || StreamFlushInterval < 0 {
+				ERROR.Fatalln("Invalid results.streamFlush:", interval)
+			}
+		}
+	})
+}
+
+// Stream sends the content of the reader as it is read, in chunks, e.g. to
+// proxy an upstream response, or a CSV that is written as it is generated:
+//
+//     r, w := io.Pipe()
+//     go func() {
+//     	csvWriter := csv.NewWriter(w)
+//     	for rows.Next() {
+//     		...
+//     		csvWriter.Write(record)
+//     	}
+//     	csvWriter.Flush()
+//     	w.CloseWithError(rows.Err())
+//     }()
+//     return c.Stream(r, "text/csv")
+//
+// The content is flushed to the client as it is read (see
+// StreamFlushInterval).  Once the client has gone, or the request's context
+// is otherwise done, reading stops, and the reader is closed if it is an
+// io.Closer, as it is once it has been read.  The status has been sent by
+// then, so an error in reading is logged, and the response is cut short.
+func (c *Controller) Stream(reader io.Reader, contentType string) *StreamResult {
+	return &StreamResult{
+		Reader:        reader,
+		ContentType:   contentType,
+		FlushInterval: StreamFlushInterval,
+	}
+}
+
+type StreamResult struct {
+	Reader        io.Reader
+	ContentType   string        // the content type, or "" for application/octet-stream
+	FlushInterval time.Duration // how often to flush, or 0 to flush each chunk
+}
+
+func (r *StreamResult) Apply(req *Request, resp *Response) {
+	ctx := req.Context()
+	if closer, ok := r.Reader.(io.Closer); ok {
+		defer closer.Close()
+		// Close the reader once the request is done, to stop a blocked read.
+		stop := context.AfterFunc(ctx, func() { closer.Close() })
+		defer stop()
+	}
+
+	contentType := r.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	resp.WriteHeader(http.StatusOK, contentType)
+	flusher, _ := resp.Out.(http.Flusher)
+	var flushed time.Time
+	flush := func() {
+		if flusher != nil {
+			flusher.Flush()
+			flushed = time.Now()
+		}
+	}
+
+	buf := make([]byte, streamChunkSize)
+	for ctx.Err() == nil {
+		n, err := r.Reader.Read(buf)
+		if n > 0 {
+			if _, writeErr := resp.Out.Write(buf[:n]); writeErr != nil {
+				// The client has gone.
+				return
+			}
+			if r.FlushInterval <= 0 || time.Since(flushed) >= r.FlushInterval {
+				flush()
+			}
+		}
+		if err == io.EOF {
+			flush()
+			return
+		}
+		if err != nil {
+			if ctx.Err() == nil {
+				ERROR.Println("Error streaming the response:", err)
+			}
+			return
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package revel
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+// flushRecorder is a ResponseRecorder that counts its flushes.
+type flushRecorder struct {
+	*httptest.ResponseRecorder
+	flushes int
+}
+
+func (r *flushRecorder) Flush() {
+	r.flushes++
+	r.ResponseRecorder.Flush()
+}
+
+func TestStream(t *testing.T) {
+	for _, test := range []struct {
+		name          string
+		reader        io.Reader
+		flushInterval time.Duration
+		body          string
+		flushes       int
+	}{
+		{"Each chunk", iotest.OneByteReader(strings.NewReader("a,b\n")), 0, "a,b\n", 5},
+		{"Periodically", iotest.OneByteReader(strings.NewReader("a,b\n")), time.Hour, "a,b\n", 2},
+		{"Error", io.MultiReader(strings.NewReader("a,b\n"), iotest.ErrReader(io.ErrUnexpectedEOF)), 0, "a,b\n", 1},
+	} {
+		req, _ := http.NewRequest("GET", "/export.csv", nil)
+		resp := &flushRecorder{ResponseRecorder: httptest.NewRecorder()}
+		c := NewController(NewRequest(req), NewResponse(resp))
+
+		result := c.Stream(test.reader, "text/csv")
+		result.FlushInterval = test.flushInterval
+		result.Apply(c.Request, c.Response)
+		eq(t, "Content-Type: "+test.name, resp.Header().Get("Content-Type"), "text/csv")
+		eq(t, "Body: "+test.name, resp.Body.String(), test.body)
+		eq(t, "Flushes: "+test.name, resp.flushes, test.flushes)
+	}
+
+	// A blocked read is stopped once the client goes.
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequest("GET", "/export.csv", nil)
+	c := NewController(NewRequest(req.WithContext(ctx)), NewResponse(httptest.NewRecorder()))
+	r, w := io.Pipe()
+	c.Stream(r, "").Apply(c.Request, c.Response)
+	_, err := w.Write([]byte("a,b\n"))
+	eq(t, "Write after the client has gone", err, io.ErrClosedPipe)
+	eq(t, "Default Content-Type", c.Response.Out.Header().Get("Content-Type"), "application/octet-stream")
+}
@@ -0,0 +1,287 @@
+package revel
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+	"reflect"
+	"time"
+	"unicode/utf8"
+)
+
+var (
+	// CSVComma is the delimiter of the fields of rendered CSV (see RenderCSV),
+	// from "results.csvComma", e.g. ";" for locales that write decimal commas.
+	CSVComma = ','
+
+	// CSVBOM, if set, starts rendered CSV with a byte order mark, for Excel to
+	// read it as UTF-8.  It is set by "results.csvBOM", which defaults to
+	// false.
+	CSVBOM bool
+)
+
+// XLSXEncoder writes the rows (the header first) as an Excel workbook, for
+// RenderXLSX.  There is none by default, so that apps choose the library that
+// writes them, e.g. on initialization:
+//
+//     revel.XLSXEncoder = func(w io.Writer, rows iter.Seq[[]string]) error {
+//     	f := excelize.NewFile()
+//     	stream, _ := f.NewStreamWriter("Sheet1")
+//     	i := 1
+//     	for row := range rows {
+//     		...
+//     	}
+//     	...
+//     	return f.Write(w)
+//     }
+var XLSXEncoder func(w io.Writer, rows iter.Seq[[]string]) error
+
+// tableFlushRows is the number of rows of a rendered table that are sent at a
+// time.
+const tableFlushRows = 100
+
+func init() {
+	OnAppStart(func() {
+		comma := Config.StringDefault("results.csvComma", string(CSVComma))
+		if CSVComma, _ = utf8.DecodeRuneInString(comma); utf8.RuneCountInString(comma) != 1 ||
+			CSVComma == '"' || CSVComma == '\r' || CSVComma == '\n' || CSVComma == utf8.RuneError {
+			ERROR.Fatalln("Invalid results.csvComma:", comma)
+		}
+		CSVBOM = Config.BoolDefault("results.csvBOM", false)
+	})
+}
+
+// RenderCSV streams the rows as CSV, as they are given.  The rows are a slice
+// or a channel (received from until it is closed, as by RenderJsonArray) of
+// records: []string, other slices (e.g. []interface{}) of values, or structs
+// (or pointers to them), whose exported fields are the values, named by
+// their "csv" tags, e.g.
+//
+//     type orderRow struct {
+//     	ID     int       `csv:"Order"`
+//     	Placed time.Time `csv:"Placed at"`
+//     	Notes  string    `csv:"-"`
+//     }
+//
+// Values are written as by fmt.Print, except times, as RFC 3339, and nils, as
+// "".  The first row is the header: the result's Header, or else the names of
+// the fields of structs, unless NoHeader is set.  The result's fields set
+// the other options, e.g. to download it:
+//
+//     result := c.RenderCSV(orders)
+//     result.Name = "orders.csv"
+//     return result
+func (c *Controller) RenderCSV(rows interface{}) *TableResult {
+	return newTableResult(rows, false)
+}
+
+// RenderXLSX renders the rows as an Excel workbook, by XLSXEncoder, as
+// RenderCSV renders them.  The response is 500 if there is no XLSXEncoder.
+func (c *Controller) RenderXLSX(rows interface{}) *TableResult {
+	return newTableResult(rows, true)
+}
+
+// TableResult renders rows, e.g. as CSV.
+type TableResult struct {
+	Name     string   // the file name to download it as, or "" to show it inline
+	Header   []string // the header, or nil for the names of the fields of structs
+	NoHeader bool     // true to leave out the header
+	Comma    rune     // the delimiter of CSV fields
+	BOM      bool     // true to start CSV with a byte order mark
+
+	rows reflect.Value
+	xlsx bool
+}
+
+func newTableResult(rows interface{}, xlsx bool) *TableResult {
+	value := reflect.ValueOf(rows)
+	switch value.Kind() {
+	case reflect.Slice, reflect.Array:
+	case reflect.Chan:
+		if value.Type().ChanDir()&reflect.RecvDir == 0 {
+			panic("revel: the rows of a table must be received from a channel")
+		}
+	default:
+		panic("revel: the rows of a table must be a slice or a channel")
+	}
+	return &TableResult{Comma: CSVComma, BOM: CSVBOM, rows: value, xlsx: xlsx}
+}
+
+func (r *TableResult) Apply(req *Request, resp *Response) {
+	contentType := "text/csv; charset=utf-8"
+	if r.xlsx {
+		contentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+		if XLSXEncoder == nil {
+			resp.Status = http.StatusInternalServerError
+			ErrorResult{Error: errors.New("There is no XLSXEncoder to render Excel workbooks")}.Apply(req, resp)
+			return
+		}
+	}
+	if r.Name != "" {
+		resp.Out.Header().Set("Content-Disposition", contentDisposition(Attachment, r.Name))
+	}
+	resp.WriteHeader(http.StatusOK, contentType)
+
+	var err error
+	if r.xlsx {
+		err = XLSXEncoder(resp.Out, r.records(req.Context()))
+	} else {
+		err = r.writeCSV(req.Context(), resp.Out)
+	}
+	if err != nil {
+		// The status has been sent, so the response is just cut short.
+		ERROR.Println("Error rendering a table:", err)
+	}
+}
+
+// writeCSV writes the records as CSV, flushing them every tableFlushRows.
+func (r *TableResult) writeCSV(ctx context.Context, w io.Writer) error {
+	if r.BOM {
+		if _, err := io.WriteString(w, "\ufeff"); err != nil {
+			return err
+		}
+	}
+	flusher, _ := w.(http.Flusher)
+	csvWriter := csv.NewWriter(w)
+	csvWriter.Comma = r.Comma
+	written := 0
+	for record := range r.records(ctx) {
+		if err := csvWriter.Write(record); err != nil {
+			return err
+		}
+		if written++; written%tableFlushRows == 0 {
+			if csvWriter.Flush(); csvWriter.Error() != nil {
+				return csvWriter.Error()
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+	}
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+// records returns the records of the rows, after the header, until the rows
+// end or the context is done.
+func (r *TableResult) records(ctx context.Context) iter.Seq[[]string] {
+	return func(yield func([]string) bool) {
+		first := true
+		for row := range tableRows(ctx, r.rows) {
+			for row.Kind() == reflect.Interface || row.Kind() == reflect.Ptr {
+				if row.IsNil() {
+					break
+				}
+				row = row.Elem()
+			}
+			if first && !r.NoHeader {
+				header := r.Header
+				if header == nil && row.Kind() == reflect.Struct {
+					header = tableHeader(row.Type())
+				}
+				if header != nil && !yield(header) {
+					return
+				}
+			}
+			first = false
+			if !yield(tableRecord(row)) {
+				return
+			}
+		}
+		if first && !r.NoHeader && r.Header != nil {
+			yield(r.Header)
+		}
+	}
+}
+
+// tableRows returns the rows of a slice, or those received from a channel
+// until it is closed or the context is done.
+func tableRows(ctx context.Context, rows reflect.Value) iter.Seq[reflect.Value] {
+	return func(yield func(reflect.Value) bool) {
+		if rows.Kind() != reflect.Chan {
+			for i := 0; i < rows.Len(); i++ {
+				if ctx.Err() != nil || !yield(rows.Index(i)) {
+					return
+				}
+			}
+			return
+		}
+		cases := []reflect.SelectCase{
+			{Dir: reflect.SelectRecv, Chan: rows},
+			{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())},
+		}
+		for {
+			chosen, row, ok := reflect.Select(cases)
+			if chosen == 1 || !ok || !yield(row) {
+				return
+			}
+		}
+	}
+}
+
+// tableFields returns the indexes of the fields of a struct that are columns.
+func tableFields(typ reflect.Type) (fields []int) {
+	for i := 0; i < typ.NumField(); i++ {
+		if field := typ.Field(i); field.IsExported() && field.Tag.Get("csv") != "-" {
+			fields = append(fields, i)
+		}
+	}
+	return fields
+}
+
+// tableHeader returns the names of the columns of a struct: their "csv" tags,
+// or else the names of the fields.
+func tableHeader(typ reflect.Type) []string {
+	var header []string
+	for _, i := range tableFields(typ) {
+		name := typ.Field(i).Tag.Get("csv")
+		if name == "" {
+			name = typ.Field(i).Name
+		}
+		header = append(header, name)
+	}
+	return header
+}
+
+// tableRecord returns the values of a row: the elements of a slice, or the
+// fields of a struct.
+func tableRecord(row reflect.Value) []string {
+	if record, ok := row.Interface().([]string); ok {
+		return record
+	}
+	var record []string
+	switch row.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < row.Len(); i++ {
+			record = append(record, tableValue(row.Index(i)))
+		}
+	case reflect.Struct:
+		for _, i := range tableFields(row.Type()) {
+			record = append(record, tableValue(row.Field(i)))
+		}
+	default:
+		record = []string{tableValue(row)}
+	}
+	return record
+}
+
+// tableValue formats a value of a row.
+func tableValue(value reflect.Value) string {
+	if !value.IsValid() {
+		return ""
+	}
+	for value.Kind() == reflect.Interface || value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return ""
+		}
+		value = value.Elem()
+	}
+	if t, ok := value.Interface().(time.Time); ok {
+		return t.Format(time.RFC3339)
+	}
+	return fmt.Sprint(value.Interface())
+}
@@ -0,0 +1,92 @@
+package revel
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type orderRow struct {
+	ID     int       `csv:"Order"`
+	Placed time.Time `csv:"Placed at"`
+	Total  *float64
+	Notes  string `csv:"-"`
+	secret string
+}
+
+func TestRenderCSV(t *testing.T) {
+	total := 9.5
+	placed := time.Date(2013, 6, 1, 12, 0, 0, 0, time.UTC)
+	orders := make(chan *orderRow, 2)
+	orders <- &orderRow{ID: 1, Placed: placed, Total: &total, Notes: "Rush", secret: "x"}
+	orders <- &orderRow{ID: 2, Placed: placed}
+	close(orders)
+
+	for _, test := range []struct {
+		name     string
+		rows     interface{}
+		setup    func(r *TableResult)
+		expected string
+	}{
+		{"Structs", orders, nil, "Order,Placed at,Total\n1,2013-06-01T12:00:00Z,9.5\n2,2013-06-01T12:00:00Z,\n"},
+		{"Records", [][]string{{"a", "b,c"}, {"d", `"e"`}}, func(r *TableResult) {
+			r.Header = []string{"x", "y"}
+		}, "x,y\na,\"b,c\"\nd,\"\"\"e\"\"\"\n"},
+		{"Values", [][]interface{}{{1, nil, "a"}}, func(r *TableResult) {
+			r.Comma = ';'
+			r.BOM = true
+		}, "\ufeff1;;a\n"},
+		{"No header", []orderRow{{ID: 3}}, func(r *TableResult) {
+			r.NoHeader = true
+		}, "3,0001-01-01T00:00:00Z,\n"},
+		{"Empty", [][]string{}, func(r *TableResult) {
+			r.Header = []string{"x"}
+		}, "x\n"},
+	} {
+		req, _ := http.NewRequest("GET", "/orders.csv", nil)
+		resp := httptest.NewRecorder()
+		c := NewController(NewRequest(req), NewResponse(resp))
+		result := c.RenderCSV(test.rows)
+		result.Name = "orders.csv"
+		if test.setup != nil {
+			test.setup(result)
+		}
+		result.Apply(c.Request, c.Response)
+
+		eq(t, "Content-Type: "+test.name, resp.Header().Get("Content-Type"), "text/csv; charset=utf-8")
+		eq(t, "Content-Disposition: "+test.name, resp.Header().Get("Content-Disposition"), `attachment; filename="orders.csv"`)
+		eq(t, "Body: "+test.name, resp.Body.String(), test.expected)
+	}
+}
+
+func TestRenderXLSX(t *testing.T) {
+	startFakeBookingApp()
+	rows := []orderRow{{ID: 1}, {ID: 2}}
+	render := func() *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", "/orders.xlsx", nil)
+		resp := httptest.NewRecorder()
+		c := NewController(NewRequest(req), NewResponse(resp))
+		c.RenderXLSX(rows).Apply(c.Request, c.Response)
+		return resp
+	}
+	eq(t, "Status without an encoder", render().Code, http.StatusInternalServerError)
+
+	XLSXEncoder = func(w io.Writer, rows iter.Seq[[]string]) error {
+		var b bytes.Buffer
+		for row := range rows {
+			fmt.Fprintln(&b, row)
+		}
+		_, err := b.WriteTo(w)
+		return err
+	}
+	defer func() { XLSXEncoder = nil }()
+	resp := render()
+	eq(t, "Status", resp.Code, http.StatusOK)
+	eq(t, "Content-Type", resp.Header().Get("Content-Type"), "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	eq(t, "Rows", resp.Body.String(), "[Order Placed at Total]\n[1 0001-01-01T00:00:00Z ]\n[2 0001-01-01T00:00:00Z ]\n")
+}
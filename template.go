@@ -1,6 +1,8 @@
 package revel
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"html"
 	"html/template"
@@ -43,8 +45,10 @@ var whiteSpacePattern = regexp.MustCompile(`\s+`)
 var (
 	// The functions available for use in the templates.
 	TemplateFuncs = map[string]interface{}{
-		"url": ReverseUrl,
-		"eq":  Equal,
+		"url":          ReverseUrl,
+		"websocketUrl": ReverseWebSocketUrl,
+		"route":        ReverseNamedUrl,
+		"eq":           Equal,
 		"set": func(renderArgs map[string]interface{}, key string, value interface{}) template.HTML {
 			renderArgs[key] = value
 			return template.HTML("")
@@ -107,6 +111,14 @@ var (
 			return template.HTML(Message(renderArgs[CurrentLocaleRenderArg].(string), message, args...))
 		},
 
+		// Return a url for the action in the current locale, prefixed with it if
+		// it is one of the router's Locales.
+		// e.g. {{localeUrl . "Users.Show" .user.Id}}
+		"localeUrl": func(renderArgs map[string]interface{}, args ...interface{}) (string, error) {
+			locale, _ := renderArgs[CurrentLocaleRenderArg].(string)
+			return reverseUrl(locale, args...)
+		},
+
 		// Replaces newlines with <br>
 		"nl2br": func(text string) template.HTML {
 			return template.HTML(strings.Replace(template.HTMLEscapeString(text), "\n", "<br>", -1))
@@ -322,6 +334,55 @@ func parseTemplateError(err error) (templateName string, line int, description s
 	return templateName, line, description
 }
 
+// templateExecutionError describes the error in rendering the template, with
+// the source of the template that it was in.
+func templateExecutionError(tmpl Template, err error) *Error {
+	var templateContent []string
+	templateName, line, description := parseTemplateError(err)
+	if templateName == "" {
+		templateName = tmpl.Name()
+		templateContent = tmpl.Content()
+	} else {
+		if tmpl, err := MainTemplateLoader.Template(templateName); err == nil {
+			templateContent = tmpl.Content()
+		}
+	}
+	return &Error{
+		Title:       "Template Execution Error",
+		Path:        templateName,
+		Description: description,
+		Line:        line,
+		SourceLines: templateContent,
+	}
+}
+
+// RenderTemplateToString renders the template (e.g. "Mailer/Welcome.html")
+// with the args, by the same loader and functions as pages, for use outside
+// of a response, e.g. in emails, background jobs or webhooks.  The args are
+// given RunMode and DevMode, as pages are, unless they have them.  The error
+// in rendering it is an *Error, as a page would show it.
+func RenderTemplateToString(name string, args map[string]interface{}) (string, error) {
+	if MainTemplateLoader == nil {
+		return "", errors.New("The templates have not been loaded")
+	}
+	tmpl, err := MainTemplateLoader.Template(name)
+	if err != nil {
+		return "", err
+	}
+	renderArgs := map[string]interface{}{
+		"RunMode": RunMode,
+		"DevMode": DevMode,
+	}
+	for key, value := range args {
+		renderArgs[key] = value
+	}
+	var b bytes.Buffer
+	if err := tmpl.Render(&b, renderArgs); err != nil {
+		return "", templateExecutionError(tmpl, err)
+	}
+	return b.String(), nil
+}
+
 // Return the Template with the given name.  The name is the template's path
 // relative to a template loader root.
 //
@@ -358,7 +419,12 @@ func (gotmpl GoTemplate) Render(wr io.Writer, arg interface{}) error {
 }
 
 func (gotmpl GoTemplate) Content() []string {
-	content, _ := ReadLines(gotmpl.loader.templatePaths[gotmpl.Name()])
+	name := gotmpl.Name()
+	if gotmpl.Tree != nil {
+		// Blocks are in the file of the template that defines them.
+		name = gotmpl.Tree.ParseName
+	}
+	content, _ := ReadLines(gotmpl.loader.templatePaths[name])
 	return content
 }
 
@@ -369,29 +435,103 @@ func (gotmpl GoTemplate) Content() []string {
 // Return a url capable of invoking a given controller method:
 // "Application.ShowApp 123" => "/app/123"
 func ReverseUrl(args ...interface{}) (string, error) {
+	return reverseUrl("", args...)
+}
+
+// reverseUrl returns the url for the action and arguments, in the given
+// locale, if any.
+func reverseUrl(locale string, args ...interface{}) (string, error) {
+	action, argsByName, err := unbindActionArgs(args)
+	if err != nil {
+		return "", err
+	}
+	if locale != "" {
+		argsByName["locale"] = locale
+	}
+
+	return MainRouter.Reverse(action, argsByName).Url, nil
+}
+
+// Return the absolute ws:// (or wss://) url of the websocket route for the
+// action, given the action's arguments other than its websocket connection.
+// e.g. {{websocketUrl "Chat.Socket" .room}} => "ws://localhost:9000/chat/lobby"
+// for the route:
+//   WS  /chat/:room  Chat.Socket
+// and the action:
+//   func (c Chat) Socket(ws *websocket.Conn, room string) revel.Result
+func ReverseWebSocketUrl(args ...interface{}) (string, error) {
+	action, argsByName, err := unbindActionArgs(args)
+	if err != nil {
+		return "", err
+	}
+
+	actionDef := MainRouter.ReverseWebSocket(action, argsByName)
+	if actionDef == nil {
+		return "", fmt.Errorf("reversing %s: no websocket route", action)
+	}
+	return actionDef.AbsoluteURL(), nil
+}
+
+// unbindActionArgs returns the action given as the first of the arguments,
+// and the rest of the arguments by the names of the action's parameters.  Any
+// websocket connection parameter is skipped, as it is not given in the URL.
+func unbindActionArgs(args []interface{}) (string, map[string]string, error) {
 	if len(args) == 0 {
-		return "", fmt.Errorf("no arguments provided to reverse route")
+		return "", nil, fmt.Errorf("no arguments provided to reverse route")
 	}
 
 	action := args[0].(string)
 	actionSplit := strings.Split(action, ".")
 	if len(actionSplit) != 2 {
-		return "", fmt.Errorf("reversing '%s', expected 'Controller.Action'", action)
+		return "", nil, fmt.Errorf("reversing '%s', expected 'Controller.Action'", action)
 	}
 
 	// Look up the types.
 	var c Controller
 	if err := c.SetAction(actionSplit[0], actionSplit[1]); err != nil {
-		return "", fmt.Errorf("reversing %s: %s", action, err)
+		return "", nil, fmt.Errorf("reversing %s: %s", action, err)
+	}
+	var methodArgs []*MethodArg
+	for _, arg := range c.MethodType.Args {
+		if arg.Type != websocketType {
+			methodArgs = append(methodArgs, arg)
+		}
+	}
+	if len(args)-1 > len(methodArgs) {
+		return "", nil, fmt.Errorf("reversing %s: expected at most %d arguments, got %d",
+			action, len(methodArgs), len(args)-1)
 	}
 
 	// Unbind the arguments.
 	argsByName := make(map[string]string)
 	for i, argValue := range args[1:] {
-		Unbind(argsByName, c.MethodType.Args[i].Name, argValue)
+		Unbind(argsByName, methodArgs[i].Name, argValue)
 	}
+	return action, argsByName, nil
+}
 
-	return MainRouter.Reverse(args[0].(string), argsByName).Url, nil
+// Return a url for the route with the given name, and arguments given as
+// alternating parameter names and values.
+// e.g. {{route "users.show" "id" .user.Id}}
+func ReverseNamedUrl(name string, args ...interface{}) (string, error) {
+	if len(args)%2 != 0 {
+		return "", fmt.Errorf("reversing route %s: expected name/value pairs", name)
+	}
+
+	argsByName := make(map[string]string)
+	for i := 0; i < len(args); i += 2 {
+		argName, ok := args[i].(string)
+		if !ok {
+			return "", fmt.Errorf("reversing route %s: parameter name %v is not a string", name, args[i])
+		}
+		Unbind(argsByName, argName, args[i+1])
+	}
+
+	action := MainRouter.ReverseByName(name, argsByName)
+	if action == nil {
+		return "", fmt.Errorf("reversing route %s: no route by that name", name)
+	}
+	return action.Url, nil
 }
 
 func Slug(text string) string {
@@ -0,0 +1,138 @@
+package revel
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ParamTransformers are the transformations that may be applied to string
+// params as they are bound, by name: to all of them with "params.transform",
+// e.g. "trim, squish", and to the fields of structs with their transform tags,
+// e.g. `transform:"trim,lower"`.  Apps may add their own (e.g. in an init
+// function), such as Unicode normalization with golang.org/x/text:
+//
+//     revel.ParamTransformers["nfc"] = norm.NFC.String
+var ParamTransformers = map[string]func(string) string{
+	"trim":      strings.TrimSpace,
+	"lower":     strings.ToLower,
+	"upper":     strings.ToUpper,
+	"squish":    squish,
+	"stripHTML": stripHTML,
+	"utf8":      cleanUTF8,
+}
+
+// paramTransforms are the transformations applied to all string params, from
+// "params.transform".
+var paramTransforms []func(string) string
+
+func init() {
+	OnAppStart(func() {
+		names := Config.StringDefault("params.transform", "")
+		var err error
+		if paramTransforms, err = transformers(names); err != nil {
+			ERROR.Fatalln("Invalid params.transform:", err)
+		}
+	})
+}
+
+// transformers returns the ParamTransformers named in the list, which is
+// separated by commas.
+func transformers(names string) ([]func(string) string, error) {
+	var transforms []func(string) string
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		transform, ok := ParamTransformers[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown param transformer: %s", name)
+		}
+		transforms = append(transforms, transform)
+	}
+	return transforms, nil
+}
+
+// transform applies the params' transformations to a string that is bound.
+func (p *Params) transform(value string) string {
+	transforms := p.transforms
+	if transforms == nil {
+		transforms = paramTransforms
+	}
+	for _, f := range transforms {
+		value = f(value)
+	}
+	return value
+}
+
+// fieldTransforms returns the transformations of the strings bound to a field
+// with the transform tag: those of its tag after those of the params, or none
+// if it is "-", e.g. for a password that should be taken as given.
+func fieldTransforms(params *Params, tag string) []func(string) string {
+	if tag == "-" {
+		return []func(string) string{}
+	}
+	transforms, err := transformers(tag)
+	if err != nil {
+		WARN.Println("W: bindField:", err)
+	}
+	inherited := params.transforms
+	if inherited == nil {
+		inherited = paramTransforms
+	}
+	return append(inherited[:len(inherited):len(inherited)], transforms...)
+}
+
+// squish trims the string, and collapses each run of whitespace within it to a
+// single space.
+func squish(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// stripHTML removes the HTML tags and comments from the string, leaving their
+// text (and its entities) as it is.  A "<" that does not start a tag, as in
+// "a < b", is kept.
+func stripHTML(s string) string {
+	var b strings.Builder
+	for {
+		i := strings.IndexByte(s, '<')
+		if i < 0 || i == len(s)-1 {
+			b.WriteString(s)
+			return b.String()
+		}
+		b.WriteString(s[:i])
+		s = s[i:]
+
+		end := ">"
+		switch c := s[1]; {
+		case strings.HasPrefix(s, "<!--"):
+			end = "-->"
+		case c == '/' || c == '!' || c == '?' || unicode.IsLetter(rune(c)):
+		default:
+			b.WriteByte('<')
+			s = s[1:]
+			continue
+		}
+		j := strings.Index(s, end)
+		if j < 0 {
+			return b.String()
+		}
+		s = s[j+len(end):]
+	}
+}
+
+// cleanUTF8 replaces the invalid UTF-8 in the string with U+FFFD, and removes
+// its control characters other than tabs and newlines.
+func cleanUTF8(s string) string {
+	if !utf8.ValidString(s) {
+		s = strings.ToValidUTF8(s, "\uFFFD")
+	}
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) && r != '\t' && r != '\n' && r != '\r' {
+			return -1
+		}
+		return r
+	}, s)
+}
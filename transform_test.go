@@ -0,0 +1,61 @@
+package revel
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+type transformedUser struct {
+	Name     string
+	Email    string   `transform:"lower"`
+	Password string   `transform:"-"`
+	Bio      *string  `transform:"stripHTML,squish"`
+	Tags     []string `transform:"lower"`
+}
+
+func TestParamTransforms(t *testing.T) {
+	defer func(transforms []func(string) string) { paramTransforms = transforms }(paramTransforms)
+	var err error
+	if paramTransforms, err = transformers("trim"); err != nil {
+		t.Fatal(err)
+	}
+
+	params := &Params{Values: url.Values{
+		"q":             {"  hotels "},
+		"user.Name":     {" Bob "},
+		"user.Email":    {" Bob@Example.COM"},
+		"user.Password": {" secret "},
+		"user.Bio":      {"<p>Likes\n  <b>long</b> walks</p> "},
+		"user.Tags[0]":  {" Go"},
+		"user.Tags[1]":  {"WEB "},
+	}}
+	eq(t, "Query", Bind(params, "q", reflect.TypeOf("")).Interface(), "hotels")
+	user := Bind(params, "user", reflect.TypeOf(transformedUser{})).Interface().(transformedUser)
+	eq(t, "Name", user.Name, "Bob")
+	eq(t, "Email", user.Email, "bob@example.com")
+	eq(t, "Password", user.Password, " secret ")
+	eq(t, "Bio", *user.Bio, "Likes long walks")
+	eq(t, "Tags", fmt.Sprint(user.Tags), "[go web]")
+
+	if _, err := transformers("trim, shout"); err == nil {
+		t.Error("Expected an error for an unknown transformer")
+	}
+}
+
+func TestStripHTML(t *testing.T) {
+	for in, out := range map[string]string{
+		"<p>Hello, <b>world</b>!</p>":      "Hello, world!",
+		"a < b and b > c":                  "a < b and b > c",
+		"x<!-- <b>hidden</b> -->y":         "xy",
+		"<script>alert(1)</script>&amp; <": "alert(1)&amp; <",
+		"unclosed <a href='x'":             "unclosed ",
+	} {
+		eq(t, in, stripHTML(in), out)
+	}
+}
+
+func TestCleanUTF8(t *testing.T) {
+	eq(t, "Clean", cleanUTF8("a\x00b\tc\xffd\u00e9"), "ab\tc\uFFFDd\u00e9")
+}
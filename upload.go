@@ -0,0 +1,407 @@
+package revel
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"sync"
+)
+
+// The limits on the size of uploaded files, from "upload.maxFileSize" (for
+// each file) and "upload.maxSize" (for all the files of a request), e.g.
+// "10MB".  0, the default, is no limit.  An upload over a limit is cut short,
+// and fails with an *UploadTooLargeError, which renders 413 Request Entity Too
+// Large.  (The route's {maxbody} limits the whole request.)
+var (
+	MaxUploadFileSize int64
+	MaxUploadSize     int64
+)
+
+// UploadStorage keeps the files uploaded in multipart forms.  It is a
+// DiskUploadStore, in "upload.dir" or else the system's temp directory, unless
+// the app replaces it (e.g. in an init function), with an ObjectUploadStore to
+// save them to S3-compatible storage, or a MemoryUploadStore in tests.
+var UploadStorage UploadStore = DiskUploadStore{}
+
+// maxFormValuesSize limits the total size of the values (other than files) of
+// a multipart form, which are kept in memory.
+const maxFormValuesSize = 10 << 20 // 10 MB
+
+// UploadFiles, from "upload.files", sets whether the uploads are also given as
+// the deprecated Params.Files, which copies them from the UploadStorage (for
+// the multipart.FileHeaders to open).  It defaults to true, for the apps that
+// still read Params.Files; those that do not should turn it off.
+var UploadFiles = true
+
+var uploadProgressHooks []func(req *Request, upload *Upload)
+
+func init() {
+	OnAppStart(func() {
+		MaxUploadFileSize = uploadLimit("upload.maxFileSize")
+		MaxUploadSize = uploadLimit("upload.maxSize")
+		UploadFiles = Config.BoolDefault("upload.files", true)
+		if dir, ok := Config.String("upload.dir"); ok {
+			if _, disk := UploadStorage.(DiskUploadStore); disk {
+				UploadStorage = DiskUploadStore{Dir: dir}
+			}
+		}
+	})
+}
+
+// uploadLimit returns the size given by the option, or 0 if it is not set.
+func uploadLimit(option string) int64 {
+	value, ok := Config.String(option)
+	if !ok || value == "" {
+		return 0
+	}
+	limit, err := parseByteSize(value)
+	if err != nil || limit < 0 {
+		ERROR.Fatalf("Invalid %s: %s", option, value)
+	}
+	return limit
+}
+
+// OnUploadProgress adds a hook that is called as each uploaded file is
+// received, with the upload, whose Size is the number of bytes received so
+// far, e.g. to report the progress of the upload to the client (polling with
+// another request).  Hooks are called by the goroutine receiving the request,
+// so they should return quickly.
+func OnUploadProgress(hook func(req *Request, upload *Upload)) {
+	uploadProgressHooks = append(uploadProgressHooks, hook)
+}
+
+// Upload is a file uploaded in a multipart form.  It is kept by the
+// UploadStorage until the request's result has been applied, unless the
+// action keeps it.  Actions may take the upload itself as an argument, e.g.
+//   func (c Users) SetAvatar(avatar *revel.Upload) revel.Result
+// rather than its content, as an *os.File, io.Reader or []byte.
+type Upload struct {
+	Name        string               // The name of the form field, e.g. "avatar".
+	Filename    string               // The name of the file, as given by the client.
+	Header      textproto.MIMEHeader // The header of the file's part, e.g. the client's Content-Type.
+	ContentType string               // The MIME type of the content, as sniffed from its first 512 bytes.
+	Size        int64                // The number of bytes received.
+	Location    string               // Where the store keeps it, e.g. the path of a temp file.
+
+	store UploadStore
+	kept  bool
+}
+
+// Open opens the uploaded file to read.  Files kept by a DiskUploadStore are
+// opened as *os.File.
+func (u *Upload) Open() (io.ReadCloser, error) {
+	return u.store.Open(u)
+}
+
+// Keep leaves the upload in the store after the request, e.g. an upload saved
+// to the app's bucket by an ObjectUploadStore, to which its Location refers.
+func (u *Upload) Keep() {
+	u.kept = true
+}
+
+// UploadStore keeps the files uploaded in multipart forms, to which they are
+// written as they are received, rather than held in memory.
+type UploadStore interface {
+	// Save writes the content of the upload to the store, setting its
+	// Location.  It is cut short by an error if the upload is over a limit.
+	Save(upload *Upload, content io.Reader) error
+
+	// Open opens an upload that was saved.
+	Open(upload *Upload) (io.ReadCloser, error)
+
+	// Remove deletes an upload, once the request's result has been applied,
+	// whether or not it was saved completely, unless it was kept.
+	Remove(upload *Upload) error
+}
+
+// DiskUploadStore keeps uploads in temp files in Dir, or in the system's temp
+// directory if it is "".
+type DiskUploadStore struct {
+	Dir string
+}
+
+func (s DiskUploadStore) Save(upload *Upload, content io.Reader) error {
+	file, err := ioutil.TempFile(s.Dir, "revel-upload")
+	if err != nil {
+		return err
+	}
+	upload.Location = file.Name()
+	_, err = io.Copy(file, content)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func (s DiskUploadStore) Open(upload *Upload) (io.ReadCloser, error) {
+	return os.Open(upload.Location)
+}
+
+func (s DiskUploadStore) Remove(upload *Upload) error {
+	if upload.Location == "" {
+		return nil
+	}
+	return os.Remove(upload.Location)
+}
+
+// MemoryUploadStore keeps uploads in memory, e.g. for tests.  A pointer to
+// the zero value is ready to use.
+type MemoryUploadStore struct {
+	mu    sync.Mutex
+	files map[string][]byte
+	saved int
+}
+
+func (s *MemoryUploadStore) Save(upload *Upload, content io.Reader) error {
+	b, err := ioutil.ReadAll(content)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.files == nil {
+		s.files = make(map[string][]byte)
+	}
+	s.saved++
+	upload.Location = fmt.Sprintf("memory:%d", s.saved)
+	s.files[upload.Location] = b
+	return err
+}
+
+func (s *MemoryUploadStore) Open(upload *Upload) (io.ReadCloser, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.files[upload.Location]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return ioutil.NopCloser(bytes.NewReader(b)), nil
+}
+
+func (s *MemoryUploadStore) Remove(upload *Upload) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.files, upload.Location)
+	return nil
+}
+
+// Len returns the number of uploads in the store.
+func (s *MemoryUploadStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.files)
+}
+
+// ObjectStore is an object storage bucket, e.g. an adapter of an S3 client
+// (such as minio-go or the AWS SDK) for a bucket of S3 or a compatible store.
+type ObjectStore interface {
+	PutObject(key string, content io.Reader, contentType string) error
+	GetObject(key string) (io.ReadCloser, error)
+	DeleteObject(key string) error
+}
+
+// ObjectUploadStore keeps uploads in an ObjectStore, under random keys
+// starting with the Prefix, e.g. "uploads/" + 32 hex digits.  The uploads'
+// Locations are their keys.
+type ObjectUploadStore struct {
+	Objects ObjectStore
+	Prefix  string
+}
+
+func (s ObjectUploadStore) Save(upload *Upload, content io.Reader) error {
+	var id [16]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return err
+	}
+	upload.Location = s.Prefix + hex.EncodeToString(id[:])
+	return s.Objects.PutObject(upload.Location, content, upload.ContentType)
+}
+
+func (s ObjectUploadStore) Open(upload *Upload) (io.ReadCloser, error) {
+	return s.Objects.GetObject(upload.Location)
+}
+
+func (s ObjectUploadStore) Remove(upload *Upload) error {
+	if upload.Location == "" {
+		return nil
+	}
+	return s.Objects.DeleteObject(upload.Location)
+}
+
+// UploadTooLargeError is the error in receiving a file over MaxUploadFileSize
+// (with the Upload), or files over MaxUploadSize in all.
+type UploadTooLargeError struct {
+	Upload *Upload
+	Limit  int64
+}
+
+func (e *UploadTooLargeError) Error() string {
+	if e.Upload == nil {
+		return fmt.Sprintf("The uploaded files may be at most %d bytes in all", e.Limit)
+	}
+	return fmt.Sprintf("The uploaded file %s may be at most %d bytes", e.Upload.Filename, e.Limit)
+}
+
+// parseMultipart parses a multipart form as it is received, keeping its values
+// in params.Form, and writing its files to the UploadStorage.  The uploads are
+// added to params.Uploads as they are started, so that they are removed even
+// if it fails.
+func parseMultipart(params *Params, req *Request) error {
+	reader, err := req.MultipartReader()
+	if err != nil {
+		return err
+	}
+	params.Form = make(url.Values)
+	var received, valuesSize int64
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			if UploadFiles && len(params.Uploads) > 0 {
+				return params.copyFiles()
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		name := part.FormName()
+		if name == "" {
+			continue
+		}
+
+		filename := part.FileName()
+		if filename == "" {
+			value, err := ioutil.ReadAll(io.LimitReader(part, maxFormValuesSize-valuesSize+1))
+			if err != nil {
+				return err
+			}
+			if valuesSize += int64(len(value)); valuesSize > maxFormValuesSize {
+				return multipart.ErrMessageTooLarge
+			}
+			params.Form.Add(name, string(value))
+			continue
+		}
+
+		upload := &Upload{Name: name, Filename: filename, Header: part.Header, store: UploadStorage}
+		if params.Uploads == nil {
+			params.Uploads = make(map[string][]*Upload)
+		}
+		params.Uploads[name] = append(params.Uploads[name], upload)
+
+		// The content type is sniffed before the content is saved.
+		buffered := bufio.NewReaderSize(part, 512)
+		head, err := buffered.Peek(512)
+		if err != nil && err != io.EOF {
+			return err
+		}
+		upload.ContentType = http.DetectContentType(head)
+		content := &uploadReader{part: buffered, req: req, upload: upload, received: &received}
+		if err = UploadStorage.Save(upload, content); err != nil {
+			return err
+		}
+	}
+}
+
+// copyFiles sets the deprecated Files to copies of the uploads, read back from
+// the store as a multipart form, as only the mime/multipart package makes
+// FileHeaders that open.  They are held in memory, up to 32MB in all, and
+// after that in temp files.
+func (p *Params) copyFiles() error {
+	reader, writer := io.Pipe()
+	defer reader.Close()
+	form := multipart.NewWriter(writer)
+	go func() {
+		writer.CloseWithError(writeUploads(form, p.Uploads))
+	}()
+	var err error
+	p.filesForm, err = multipart.NewReader(reader, form.Boundary()).ReadForm(32 << 20)
+	if err != nil {
+		return err
+	}
+	p.Files = p.filesForm.File
+	return nil
+}
+
+// writeUploads writes the uploads to the multipart form, with their headers.
+func writeUploads(form *multipart.Writer, uploads map[string][]*Upload) error {
+	for _, uploads := range uploads {
+		for _, upload := range uploads {
+			part, err := form.CreatePart(upload.Header)
+			if err != nil {
+				return err
+			}
+			file, err := upload.Open()
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(part, file)
+			file.Close()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return form.Close()
+}
+
+// uploadReader reads the content of an uploaded file, counting its size
+// against the limits, and reporting its progress.
+type uploadReader struct {
+	part     io.Reader
+	req      *Request
+	upload   *Upload
+	received *int64 // the bytes of all the request's files received so far
+}
+
+func (r *uploadReader) Read(b []byte) (int, error) {
+	n, err := r.part.Read(b)
+	r.upload.Size += int64(n)
+	*r.received += int64(n)
+	if MaxUploadFileSize > 0 && r.upload.Size > MaxUploadFileSize {
+		return n, &UploadTooLargeError{Upload: r.upload, Limit: MaxUploadFileSize}
+	}
+	if MaxUploadSize > 0 && *r.received > MaxUploadSize {
+		return n, &UploadTooLargeError{Limit: MaxUploadSize}
+	}
+	if n > 0 {
+		for _, hook := range uploadProgressHooks {
+			hook(r.req, r.upload)
+		}
+	}
+	return n, err
+}
+
+// removeUploads closes the files opened to bind the uploads, and removes the
+// uploads (other than those kept) and the temp files that they were copied to.
+func (p *Params) removeUploads() {
+	for _, file := range p.opened {
+		file.Close()
+	}
+	if p.filesForm != nil {
+		if err := p.filesForm.RemoveAll(); err != nil {
+			WARN.Println("Error removing temporary files:", err)
+		}
+	}
+	for _, tmpFile := range p.tmpFiles {
+		tmpFile.Close()
+		if err := os.Remove(tmpFile.Name()); err != nil {
+			WARN.Println("Could not remove upload temp file:", err)
+		}
+	}
+	for _, uploads := range p.Uploads {
+		for _, upload := range uploads {
+			if upload.kept {
+				continue
+			}
+			if err := upload.store.Remove(upload); err != nil {
+				WARN.Println("Could not remove upload:", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package revel
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUploadLimits(t *testing.T) {
+	defer func(fileLimit, limit int64) {
+		MaxUploadFileSize, MaxUploadSize = fileLimit, limit
+	}(MaxUploadFileSize, MaxUploadSize)
+
+	for _, test := range []struct {
+		fileLimit, limit int64
+		tooLarge         bool
+	}{
+		{0, 0, false},
+		{8, 30, false},
+		{7, 0, true},
+		{0, 29, true},
+	} {
+		MaxUploadFileSize, MaxUploadSize = test.fileLimit, test.limit
+		c := NewController(NewRequest(getMultipartRequest()), NewResponse(httptest.NewRecorder()))
+		called := false
+		ParamsFilter(c, []Filter{func(*Controller, []Filter) { called = true }})
+
+		name := "Limits " + fmt.Sprint(test.fileLimit, test.limit)
+		eq(t, "Called action: "+name, called, !test.tooLarge)
+		if test.tooLarge {
+			eq(t, "Status: "+name, c.Response.Status, http.StatusRequestEntityTooLarge)
+		}
+		if eq(t, "Has uploads: "+name, len(c.Params.Uploads) > 0, true) {
+			c.cleanup()
+			for _, uploads := range c.Params.Uploads {
+				for _, upload := range uploads {
+					if _, err := os.Stat(upload.Location); !os.IsNotExist(err) {
+						t.Errorf("%s: expected %s to be removed, got %v", name, upload.Location, err)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestUploadProgress(t *testing.T) {
+	defer func(hooks []func(*Request, *Upload)) { uploadProgressHooks = hooks }(uploadProgressHooks)
+	received := map[string]int64{}
+	OnUploadProgress(func(req *Request, upload *Upload) {
+		received[upload.Name+" "+upload.Filename] = upload.Size
+	})
+
+	c := NewController(NewRequest(getMultipartRequest()), NewResponse(httptest.NewRecorder()))
+	ParamsFilter(c, NilChain)
+	defer c.cleanup()
+
+	eq(t, "Received", fmt.Sprint(received), fmt.Sprint(map[string]int64{
+		"file1 test.txt":       8,
+		"file2[] test.txt":     8,
+		"file2[] favicon.ico":  3,
+		"file3[0] test.txt":    8,
+		"file3[1] favicon.ico": 3,
+	}))
+}
+
+// A fake ObjectStore, as an S3 bucket.
+type fakeBucket map[string]string
+
+func (b fakeBucket) PutObject(key string, content io.Reader, contentType string) error {
+	data, err := ioutil.ReadAll(content)
+	b[key] = contentType + "\n" + string(data)
+	return err
+}
+
+func (b fakeBucket) GetObject(key string) (io.ReadCloser, error) {
+	data, ok := b[key]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return ioutil.NopCloser(strings.NewReader(data[strings.Index(data, "\n")+1:])), nil
+}
+
+func (b fakeBucket) DeleteObject(key string) error {
+	delete(b, key)
+	return nil
+}
+
+func TestUploadStores(t *testing.T) {
+	defer func(store UploadStore) { UploadStorage = store }(UploadStorage)
+	memory := &MemoryUploadStore{}
+	bucket := fakeBucket{}
+	for _, store := range []UploadStore{memory, ObjectUploadStore{Objects: bucket, Prefix: "uploads/"}} {
+		UploadStorage = store
+		c := NewController(NewRequest(getMultipartRequest()), NewResponse(httptest.NewRecorder()))
+		ParamsFilter(c, NilChain)
+
+		name := fmt.Sprintf("%T", store)
+		file1 := Bind(c.Params, "file1", reflect.TypeOf((*Upload)(nil))).Interface().(*Upload)
+		eq(t, "Filename in "+name, file1.Filename, "test.txt")
+		eq(t, "Content type in "+name, file1.ContentType, "text/plain; charset=utf-8")
+		eq(t, "Size in "+name, file1.Size, int64(8))
+		content := Bind(c.Params, "file1", reflect.TypeOf([]byte{})).Bytes()
+		eq(t, "Content in "+name, string(content), "content1")
+
+		file3 := Bind(c.Params, "file3", reflect.TypeOf([]*Upload{})).Interface().([]*Upload)
+		if eq(t, "Uploads in "+name, len(file3), 2) {
+			eq(t, "Second upload in "+name, file3[1].Filename, "favicon.ico")
+		}
+
+		file1.Keep()
+		c.cleanup()
+		reader, err := file1.Open()
+		if err != nil {
+			t.Fatalf("%s: could not open the kept upload: %v", name, err)
+		}
+		kept, _ := ioutil.ReadAll(reader)
+		eq(t, "Kept content in "+name, bytes.Equal(kept, content), true)
+	}
+	eq(t, "Uploads kept in memory", memory.Len(), 1)
+	eq(t, "Objects kept in the bucket", len(bucket), 1)
+	for key, object := range bucket {
+		eq(t, "Key", strings.HasPrefix(key, "uploads/"), true)
+		eq(t, "Object", object, "text/plain; charset=utf-8\ncontent1")
+	}
+}
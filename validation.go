@@ -20,6 +20,11 @@ func (e *ValidationError) String() string {
 	return e.Message
 }
 
+// Returns the Message, so that a ValidationError may be returned as an error.
+func (e *ValidationError) Error() string {
+	return e.String()
+}
+
 // A Validation context manages data validation and error messages.
 type Validation struct {
 	Errors []*ValidationError
@@ -172,6 +177,11 @@ func ValidationFilter(c *Controller, fc []Filter) {
 		Errors: restoreValidationErrors(c.Request.Request),
 		keep:   false,
 	}
+	if c.Params != nil {
+		// e.g. the body was invalid JSON
+		c.Validation.Errors = append(c.Validation.Errors, c.Params.bodyErrors...)
+		c.Params.validation = c.Validation
+	}
 
 	fc[0](c, fc[1:])
 
@@ -0,0 +1,100 @@
+package revel
+
+import (
+	"reflect"
+	"strings"
+)
+
+// ValueFormat is the format that the values returned by actions are rendered
+// in, when neither the route nor the request's Accept header asks for one.  It
+// is set by "results.format", which defaults to "json".
+var ValueFormat = "json"
+
+// valueFormats are the formats that values may be rendered in.
+var valueFormats = map[string]bool{
+	"json":  true,
+	"xml":   true,
+	"html":  true,
+	"txt":   true,
+	"proto": true,
+	"yaml":  true,
+}
+
+var resultType = reflect.TypeOf((*Result)(nil)).Elem()
+
+func init() {
+	OnAppStart(func() {
+		ValueFormat = Config.StringDefault("results.format", "json")
+		if !valueFormats[ValueFormat] {
+			ERROR.Fatalln("Invalid results.format:", ValueFormat)
+		}
+	})
+}
+
+// RenderValue renders a value returned by an action, e.g. a struct, in the
+// format that the route declares (e.g. {render=json}), or else that the
+// request accepts, or else ValueFormat.  (Methods of controllers that return
+// values are actions only if their comments declare them so, by "@Action" or
+// a route, e.g. "// @Route GET /users/:id".)  As HTML, the value is rendered by the
+// action's template as "value", e.g. "Users/Show.html"; if the action has no
+// template, ValueFormat is used instead.  Values are rendered as Protocol
+// Buffers (see RenderProto) only if they are messages; else, ValueFormat is
+// used.  Values are rendered as YAML, or in the media types of Renderers
+// that routes declare (e.g. {render=text/csv}), as RenderAuto renders them.
+func (c *Controller) RenderValue(value interface{}) Result {
+	switch format := c.valueFormat(); format {
+	case "xml":
+		return c.RenderXml(value)
+	case "txt":
+		return c.RenderText("%v", value)
+	case "proto":
+		if isProtoMessage(reflect.TypeOf(value)) {
+			return c.RenderProto(value)
+		}
+		if ValueFormat == "xml" {
+			return c.RenderXml(value)
+		}
+	case "html":
+		templatePath := c.Name + "/" + c.MethodType.Name + ".html"
+		if _, err := MainTemplateLoader.Template(templatePath); err == nil {
+			c.RenderArgs["value"] = value
+			return c.RenderTemplate(templatePath)
+		}
+		if ValueFormat == "xml" {
+			return c.RenderXml(value)
+		}
+	default:
+		if Renderers[routeRenderType(format)] != nil {
+			return c.RenderAuto(value)
+		}
+	}
+	return c.RenderJson(value)
+}
+
+// valueFormat returns the format to render the action's value in.
+func (c *Controller) valueFormat() string {
+	if c.Route != nil && c.Route.Route != nil && c.Route.Route.render != "" {
+		return c.Route.Route.render
+	}
+	// Requests that accept anything have the format "html", so ignore it.
+	if c.Request != nil && c.Request.Request != nil && valueFormats[c.Request.Format] {
+		if accept := c.Request.Header.Get("Accept"); accept != "" && !strings.HasPrefix(accept, "*/*") {
+			return c.Request.Format
+		}
+	}
+	return ValueFormat
+}
+
+// ValueResult returns the result of an action that returns a value rather than
+// a Result, and optionally an error: the result that err maps to, if it is not
+// nil, or else the rendered value.  Values that are Results are returned as is.
+// It is called by the generated adapters (MethodType.Invoke) for such actions.
+func ValueResult(c *Controller, value interface{}, err error) Result {
+	if err != nil {
+		return ActionResult(c, nil, err)
+	}
+	if result, ok := value.(Result); ok {
+		return result
+	}
+	return c.RenderValue(value)
+}
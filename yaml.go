@@ -0,0 +1,115 @@
+package revel
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// YAMLMarshal and YAMLUnmarshal encode and decode YAML, for RenderYAML and for
+// request bodies of YAML (application/yaml).  There are none by default, so
+// that apps choose the library, e.g. on initialization:
+//
+//     revel.YAMLMarshal = yaml.Marshal
+//     revel.YAMLUnmarshal = yaml.Unmarshal
+//
+// Until they are set, RenderYAML responds with 500, and YAML bodies are
+// reported as validation errors.
+var (
+	YAMLMarshal   func(value interface{}) ([]byte, error)
+	YAMLUnmarshal func(data []byte, value interface{}) error
+)
+
+// RenderYAML renders the value as YAML (application/yaml), by YAMLMarshal.
+func (c *Controller) RenderYAML(o interface{}) Result {
+	return RenderYAMLResult{o}
+}
+
+type RenderYAMLResult struct {
+	obj interface{}
+}
+
+func (r RenderYAMLResult) Apply(req *Request, resp *Response) {
+	if YAMLMarshal == nil {
+		resp.Status = http.StatusInternalServerError
+		ErrorResult{Error: errors.New("There is no YAMLMarshal to render YAML")}.Apply(req, resp)
+		return
+	}
+	b, err := YAMLMarshal(r.obj)
+	if err != nil {
+		ErrorResult{Error: err}.Apply(req, resp)
+		return
+	}
+	resp.WriteHeader(http.StatusOK, "application/yaml")
+	resp.Out.Write(b)
+}
+
+// renderYaml renders values as YAML for RenderAuto, if it can.
+func renderYaml(c *Controller, value interface{}) Result {
+	if YAMLMarshal == nil {
+		return nil
+	}
+	return c.RenderYAML(value)
+}
+
+// parseYaml parses YAML, bound as JSON is, e.g. "user: {name: Bob}" as
+// "user.name=Bob".
+func parseYaml(params *Params, req *Request) error {
+	if req.Body == nil {
+		return nil
+	}
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil || len(body) == 0 {
+		return err
+	}
+	if YAMLUnmarshal == nil {
+		params.bodyErrors = append(params.bodyErrors, &ValidationError{
+			Key:     "body",
+			Message: "YAML is not supported",
+		})
+		return nil
+	}
+	var document interface{}
+	if decodeErr := YAMLUnmarshal(body, &document); decodeErr != nil {
+		params.bodyErrors = append(params.bodyErrors, &ValidationError{
+			Key:     "body",
+			Message: "Invalid YAML: " + decodeErr.Error(),
+		})
+		return nil
+	}
+	params.body = yamlToJson(document)
+	params.Form = make(url.Values)
+	params.bodyKinds = make(map[string]string)
+	params.flattenBody("", params.body)
+	return nil
+}
+
+// yamlToJson converts a decoded YAML document to the values that JSON decodes
+// to: mappings (whose keys may be of any type) to map[string]interface{}, and
+// sequences to []interface{}.  Timestamps are converted to RFC 3339.
+func yamlToJson(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, elem := range v {
+			v[key] = yamlToJson(elem)
+		}
+		return v
+	case map[interface{}]interface{}:
+		object := make(map[string]interface{}, len(v))
+		for key, elem := range v {
+			object[fmt.Sprint(key)] = yamlToJson(elem)
+		}
+		return object
+	case []interface{}:
+		for i, elem := range v {
+			v[i] = yamlToJson(elem)
+		}
+		return v
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	}
+	return value
+}
@@ -0,0 +1,83 @@
+package revel
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeYAMLUnmarshal decodes documents of "key: value" lines into mappings
+// with keys of any type, as some YAML libraries do.
+func fakeYAMLUnmarshal(data []byte, value interface{}) error {
+	document := map[interface{}]interface{}{}
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		key, elem, ok := strings.Cut(line, ": ")
+		if !ok {
+			return errors.New("no mapping")
+		}
+		switch {
+		case strings.HasPrefix(elem, "["):
+			var seq []interface{}
+			for _, item := range strings.Split(strings.Trim(elem, "[]"), ", ") {
+				seq = append(seq, item)
+			}
+			document[key] = seq
+		case key == "age":
+			document[key] = 30
+		case key == "born":
+			document[key] = time.Date(1983, 6, 1, 0, 0, 0, 0, time.UTC)
+		default:
+			document[key] = elem
+		}
+	}
+	*value.(*interface{}) = document
+	return nil
+}
+
+func TestYAML(t *testing.T) {
+	startFakeBookingApp()
+	userArg := &MethodArg{"user", reflect.TypeOf((*jsonUser)(nil))}
+	parse := func(body string) *Controller {
+		req, _ := http.NewRequest("POST", "/users", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/yaml")
+		req.Header.Set("Accept", "application/yaml")
+		c := NewController(NewRequest(req), NewResponse(httptest.NewRecorder()))
+		c.MethodType = &MethodType{Name: "Create", Args: []*MethodArg{userArg}}
+		ParamsFilter(c, NilChain)
+		return c
+	}
+
+	// Without the hooks, YAML is neither parsed nor rendered.
+	c := parse("name: Bob")
+	eq(t, "Body errors without YAMLUnmarshal", len(c.Params.bodyErrors), 1)
+	eq(t, "Result without YAMLMarshal", fmt.Sprintf("%T", c.RenderAuto("Bob")), "revel.RenderJsonResult")
+	c.RenderYAML("Bob").Apply(c.Request, c.Response)
+	eq(t, "Status without YAMLMarshal", c.Response.Status, http.StatusInternalServerError)
+
+	YAMLMarshal = func(value interface{}) ([]byte, error) { return []byte(fmt.Sprintf("%v\n", value)), nil }
+	YAMLUnmarshal = fakeYAMLUnmarshal
+	defer func() { YAMLMarshal, YAMLUnmarshal = nil, nil }()
+
+	c = parse("name: Bob\nage: 30\ntags: [a, b]\nborn: 1983-06-01")
+	user := Bind(c.Params, "user", userArg.Type).Interface().(*jsonUser)
+	eq(t, "User", fmt.Sprintf("%+v", *user), "{Name:Bob Age:30 Tags:[a b] Address:{City:}}")
+	eq(t, "Timestamp", c.Params.Get("user.born"), "1983-06-01T00:00:00Z")
+
+	c = parse("Bob")
+	if eq(t, "Body errors", len(c.Params.bodyErrors), 1) {
+		eq(t, "Body error", c.Params.bodyErrors[0].Message, "Invalid YAML: no mapping")
+	}
+
+	result := c.RenderAuto(map[string]string{"name": "Bob"})
+	eq(t, "Negotiated result", fmt.Sprintf("%T", result), "revel.RenderYAMLResult")
+	resp := httptest.NewRecorder()
+	result.Apply(c.Request, NewResponse(resp))
+	eq(t, "Content-Type", resp.Header().Get("Content-Type"), "application/yaml")
+	eq(t, "Body", resp.Body.String(), "map[name:Bob]\n")
+	eq(t, "Format", ResolveFormat(c.Request.Request), "yaml")
+}